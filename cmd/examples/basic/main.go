@@ -71,15 +71,13 @@ func main() {
 	if err != nil {
 		log.Fatal("Failed to compile vertex shader:", err)
 	}
-	defer vertexShader.Delete()
 
 	fragmentShader, err := shader.CompileShaderFromFile("shaders/fragment/basic.frag", shader.FragmentShader)
 	if err != nil {
 		log.Fatal("Failed to compile fragment shader:", err)
 	}
-	defer fragmentShader.Delete()
 
-	// Create shader program
+	// Create shader program, which owns the shaders from here on
 	program, err := shader.CreateProgram(vertexShader, fragmentShader)
 	if err != nil {
 		log.Fatal("Failed to create shader program:", err)
@@ -1,16 +1,17 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"math/rand"
 	"runtime"
-	"unsafe"
 
 	"github.com/go-gl/gl/v4.1-core/gl"
 	"github.com/go-gl/glfw/v3.3/glfw"
 	"github.com/go-gl/mathgl/mgl32"
 	"github.com/yossideutsch/gogl/internal/platform"
+	"github.com/yossideutsch/gogl/pkg/layout"
 	"github.com/yossideutsch/gogl/pkg/pipeline"
 	"github.com/yossideutsch/gogl/pkg/resource"
 	"github.com/yossideutsch/gogl/pkg/shader"
@@ -22,14 +23,14 @@ const (
 	numParticles = 1024
 )
 
-// Particle structure matching the compute shader
+// Particle structure matching the compute shader; the std430 layout adds
+// the padding, so no padding fields are needed
 type Particle struct {
-	Position [2]float32
-	Velocity [2]float32
-	Color    [4]float32
-	Life     float32
-	Size     float32
-	_        [2]float32 // Padding to align to 16 bytes
+	Position mgl32.Vec2 `glsl:"position"`
+	Velocity mgl32.Vec2 `glsl:"velocity"`
+	Color    mgl32.Vec4 `glsl:"color"`
+	Life     float32    `glsl:"life"`
+	Size     float32    `glsl:"size"`
 }
 
 var (
@@ -46,7 +47,6 @@ struct Particle {
     vec4 color;
     float life;
     float size;
-    vec2 padding;
 };
 
 // Shader storage buffer objects
@@ -233,7 +233,7 @@ func main() {
 		glfw.WindowHint(glfw.ContextVersionMajor, 4)
 		glfw.WindowHint(glfw.ContextVersionMinor, 1)
 		
-		window, err = glfw.CreateWindow(windowWidth, windowHeight, "Compute Shader Demo (CPU Fallback)", nil, nil)
+		window, err = glfw.CreateWindow(windowWidth, windowHeight, "Compute Shader Demo (Transform Feedback Fallback)", nil, nil)
 		if err != nil {
 			log.Fatal("Failed to create window:", err)
 		}
@@ -262,18 +262,18 @@ func main() {
 		for _, note := range sysInfo.Notes {
 			fmt.Printf("• %s\n", note)
 		}
-		fmt.Println("\nRunning CPU-based particle simulation instead...")
+		fmt.Println("\nRunning the simulation with transform feedback instead...")
 		
-		// Run CPU fallback demo
-		runCPUParticleDemo(window)
+		// Run GPU fallback demo
+		runFallbackDemo(window)
 		return
 	}
 
 	// Create GPU compute demo
 	demo, err := NewComputeDemo()
 	if err != nil {
-		fmt.Printf("⚠️  Failed to create GPU demo, falling back to CPU: %v\n", err)
-		runCPUParticleDemo(window)
+		fmt.Printf("⚠️  Failed to create compute demo, falling back to transform feedback: %v\n", err)
+		runFallbackDemo(window)
 		return
 	}
 	defer demo.Cleanup()
@@ -343,9 +343,9 @@ func (d *ComputeDemo) initParticles() {
 	
 	for i := range d.particles {
 		d.particles[i] = Particle{
-			Position: [2]float32{float32(windowWidth / 2), float32(windowHeight + 100)},
-			Velocity: [2]float32{0, -100},
-			Color:    [4]float32{1, 1, 1, 1},
+			Position: mgl32.Vec2{float32(windowWidth / 2), float32(windowHeight + 100)},
+			Velocity: mgl32.Vec2{0, -100},
+			Color:    mgl32.Vec4{1, 1, 1, 1},
 			Life:     0, // Start dead so they get respawned
 			Size:     3.0,
 		}
@@ -357,9 +357,18 @@ func (d *ComputeDemo) createComputeShader() error {
 	if err != nil {
 		return err
 	}
-	defer computeShader.Delete()
 
+	// The program owns the shader and deletes it with itself
 	d.computeProgram, err = shader.CreateProgram(computeShader)
+	if err != nil {
+		return err
+	}
+
+	// Check the Go struct against the driver's layout of the storage block
+	err = layout.Std430.Validate(d.computeProgram, "ParticleBuffer", d.particles)
+	if errors.Is(err, layout.ErrNoInterfaceQuery) {
+		return nil
+	}
 	return err
 }
 
@@ -368,32 +377,24 @@ func (d *ComputeDemo) createRenderShader() error {
 	if err != nil {
 		return err
 	}
-	defer vertexShader.Delete()
 
 	fragmentShader, err := shader.CompileShader(renderFragmentSource, shader.FragmentShader)
 	if err != nil {
+		vertexShader.Delete()
 		return err
 	}
-	defer fragmentShader.Delete()
 
 	d.renderProgram, err = shader.CreateProgram(vertexShader, fragmentShader)
 	return err
 }
 
 func (d *ComputeDemo) createBuffers() error {
-	// Create SSBO for particle data
-	particleSize := int(unsafe.Sizeof(Particle{}))
-	totalSize := particleSize * numParticles
-
+	// Create SSBO with the initial particle data in std430 layout
 	var err error
-	d.particleSSBO, err = resource.NewShaderStorageBuffer(totalSize, resource.DynamicDraw)
+	d.particleSSBO, err = resource.NewShaderStorageBufferFor(d.particles, resource.DynamicDraw)
 	if err != nil {
 		return err
 	}
-
-	// Upload initial particle data
-	d.particleSSBO.Bind()
-	d.particleSSBO.UpdateData(0, unsafe.Pointer(&d.particles[0]), totalSize)
 	d.particleSSBO.BindBase(0) // Binding point 0
 
 	// Create VAO for rendering
@@ -407,13 +408,24 @@ func (d *ComputeDemo) createBuffers() error {
 
 	d.particleVAO.SetVertexBuffer(d.particleVBO)
 
-	// Set up vertex attributes to match the particle structure
-	stride := int32(unsafe.Sizeof(Particle{}))
-	d.particleVAO.AddFloatAttribute(0, 2, stride, 0)                                      // Position
-	d.particleVAO.AddFloatAttribute(1, 2, stride, uintptr(unsafe.Offsetof(d.particles[0].Velocity))) // Velocity
-	d.particleVAO.AddFloatAttribute(2, 4, stride, uintptr(unsafe.Offsetof(d.particles[0].Color)))    // Color
-	d.particleVAO.AddFloatAttribute(3, 1, stride, uintptr(unsafe.Offsetof(d.particles[0].Life)))     // Life
-	d.particleVAO.AddFloatAttribute(4, 1, stride, uintptr(unsafe.Offsetof(d.particles[0].Size)))     // Size
+	// Set up vertex attributes from the std430 layout of the particle structure
+	stride, err := layout.Std430.Stride(Particle{})
+	if err != nil {
+		return err
+	}
+	members, err := layout.Std430.Members(Particle{})
+	if err != nil {
+		return err
+	}
+	offsets := make(map[string]uintptr)
+	for _, m := range members {
+		offsets[m.Name] = uintptr(m.Offset)
+	}
+	d.particleVAO.AddFloatAttribute(0, 2, int32(stride), offsets["position"]) // Position
+	d.particleVAO.AddFloatAttribute(1, 2, int32(stride), offsets["velocity"]) // Velocity
+	d.particleVAO.AddFloatAttribute(2, 4, int32(stride), offsets["color"])    // Color
+	d.particleVAO.AddFloatAttribute(3, 1, int32(stride), offsets["life"])     // Life
+	d.particleVAO.AddFloatAttribute(4, 1, int32(stride), offsets["size"])     // Size
 
 	return nil
 }
@@ -499,7 +511,16 @@ func (d *ComputeDemo) Cleanup() {
 	}
 }
 
-// CPU-based particle demo for platforms without compute shader support
+// runFallbackDemo runs the transform feedback simulation and drops to the
+// CPU simulation only when it cannot be set up
+func runFallbackDemo(window *glfw.Window) {
+	if err := runTransformFeedbackDemo(window); err != nil {
+		fmt.Printf("⚠️  Transform feedback unavailable, falling back to CPU: %v\n", err)
+		runCPUParticleDemo(window)
+	}
+}
+
+// CPU-based particle demo for contexts where transform feedback fails
 func runCPUParticleDemo(window *glfw.Window) {
 	fmt.Println("Running CPU-based particle simulation...")
 	
@@ -544,14 +565,13 @@ void main() {
 	if err != nil {
 		log.Fatal("Failed to compile vertex shader:", err)
 	}
-	defer vertexShader.Delete()
 
 	fragmentShader, err := shader.CompileShader(fragmentSource, shader.FragmentShader)
 	if err != nil {
 		log.Fatal("Failed to compile fragment shader:", err)
 	}
-	defer fragmentShader.Delete()
 
+	// The program owns the shaders and deletes them with itself
 	program, err := shader.CreateProgram(vertexShader, fragmentShader)
 	if err != nil {
 		log.Fatal("Failed to create program:", err)
@@ -0,0 +1,331 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+	"github.com/go-gl/glfw/v3.3/glfw"
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/yossideutsch/gogl/pkg/pipeline"
+	"github.com/yossideutsch/gogl/pkg/resource"
+	"github.com/yossideutsch/gogl/pkg/shader"
+)
+
+// The update pass runs the particle simulation of the compute shader in a
+// vertex shader; transform feedback writes each particle to the other buffer
+var updateVertexSource = `#version 410 core
+layout(location = 0) in vec2 aPosition;
+layout(location = 1) in vec2 aVelocity;
+layout(location = 2) in vec4 aColor;
+layout(location = 3) in float aLife;
+layout(location = 4) in float aSize;
+
+out vec2 tfPosition;
+out vec2 tfVelocity;
+out vec4 tfColor;
+out float tfLife;
+out float tfSize;
+
+uniform float uDeltaTime;
+uniform float uGravity;
+uniform vec2 uAttractor;
+uniform float uAttractorStrength;
+uniform vec2 uViewportSize;
+uniform float uTime;
+
+uint hash(uint x) {
+    x += (x << 10u);
+    x ^= (x >> 6u);
+    x += (x << 3u);
+    x ^= (x >> 11u);
+    x += (x << 15u);
+    return x;
+}
+
+float random(uint seed) {
+    return float(hash(seed)) / 4294967296.0;
+}
+
+void main() {
+    vec2 position = aPosition;
+    vec2 velocity = aVelocity;
+    vec4 color = aColor;
+    float life = aLife - uDeltaTime;
+    float size = aSize;
+
+    if (life <= 0.0) {
+        uint seed = uint(gl_VertexID) + uint(uTime * 1000.0);
+        position = vec2(
+            uViewportSize.x * 0.5 + (random(seed) - 0.5) * 100.0,
+            uViewportSize.y + random(seed + 1u) * 100.0
+        );
+        velocity = vec2(
+            (random(seed + 2u) - 0.5) * 200.0,
+            -random(seed + 3u) * 150.0 - 50.0
+        );
+        color = vec4(
+            0.5 + random(seed + 4u) * 0.5,
+            0.3 + random(seed + 5u) * 0.4,
+            0.8 + random(seed + 6u) * 0.2,
+            1.0
+        );
+        life = 3.0 + random(seed + 7u) * 2.0;
+        size = 2.0 + random(seed + 8u) * 4.0;
+    } else {
+        velocity.y -= uGravity * uDeltaTime;
+
+        vec2 toAttractor = uAttractor - position;
+        float distance = length(toAttractor);
+        if (distance > 10.0) {
+            vec2 force = normalize(toAttractor) * uAttractorStrength / (distance * 0.01);
+            velocity += force * uDeltaTime;
+        }
+
+        position += velocity * uDeltaTime;
+
+        if (position.x < 0.0 || position.x > uViewportSize.x) {
+            velocity.x *= -0.8;
+            position.x = clamp(position.x, 0.0, uViewportSize.x);
+        }
+        if (position.y < 0.0) {
+            velocity.y *= -0.8;
+            position.y = 0.0;
+        }
+
+        color.a = life / 5.0;
+    }
+
+    tfPosition = position;
+    tfVelocity = velocity;
+    tfColor = color;
+    tfLife = life;
+    tfSize = size;
+}`
+
+// particleFloats is the number of interleaved floats captured per particle:
+// position, velocity, color, life and size
+const particleFloats = 2 + 2 + 4 + 1 + 1
+
+// TransformFeedbackDemo simulates the particles on the GPU without compute
+// shaders by ping-ponging two vertex buffers through transform feedback
+type TransformFeedbackDemo struct {
+	updateProgram *shader.Program
+	renderProgram *shader.Program
+
+	buffers  [2]*resource.VertexBuffer
+	arrays   [2]*resource.VertexArray
+	feedback *resource.TransformFeedback
+	current  int // Buffer holding the latest particle state
+
+	renderPipeline *pipeline.Pipeline
+
+	gravity           float32
+	attractorStrength float32
+	mousePos          mgl32.Vec2
+}
+
+// NewTransformFeedbackDemo creates the update and render programs and the
+// particle buffers
+func NewTransformFeedbackDemo() (*TransformFeedbackDemo, error) {
+	demo := &TransformFeedbackDemo{
+		gravity:           200.0,
+		attractorStrength: 50000.0,
+		mousePos:          mgl32.Vec2{windowWidth / 2, windowHeight / 2},
+		renderPipeline:    pipeline.New(),
+	}
+
+	if err := demo.createPrograms(); err != nil {
+		demo.Cleanup()
+		return nil, err
+	}
+	if err := demo.createBuffers(); err != nil {
+		demo.Cleanup()
+		return nil, fmt.Errorf("buffers: %w", err)
+	}
+	return demo, nil
+}
+
+func (d *TransformFeedbackDemo) createPrograms() error {
+	updateShader, err := shader.CompileShader(updateVertexSource, shader.VertexShader)
+	if err != nil {
+		return fmt.Errorf("update shader: %w", err)
+	}
+
+	// No fragment shader: the update pass only captures varyings. The
+	// programs own their shaders and delete them with themselves.
+	d.updateProgram, err = shader.CreateProgramWithOptions(shader.ProgramOptions{
+		TransformFeedbackVaryings: []string{"tfPosition", "tfVelocity", "tfColor", "tfLife", "tfSize"},
+	}, updateShader)
+	if err != nil {
+		return fmt.Errorf("update program: %w", err)
+	}
+
+	vertexShader, err := shader.CompileShader(renderVertexSource, shader.VertexShader)
+	if err != nil {
+		return fmt.Errorf("render shader: %w", err)
+	}
+
+	fragmentShader, err := shader.CompileShader(renderFragmentSource, shader.FragmentShader)
+	if err != nil {
+		vertexShader.Delete()
+		return fmt.Errorf("render shader: %w", err)
+	}
+
+	d.renderProgram, err = shader.CreateProgram(vertexShader, fragmentShader)
+	if err != nil {
+		return fmt.Errorf("render program: %w", err)
+	}
+	return nil
+}
+
+func (d *TransformFeedbackDemo) createBuffers() error {
+	// Particles start dead so the first update respawns them
+	data := make([]float32, numParticles*particleFloats)
+	for i := 0; i < numParticles; i++ {
+		p := data[i*particleFloats:]
+		p[0], p[1] = windowWidth/2, windowHeight+100 // Position
+		p[3] = -100                                  // Velocity
+		p[4], p[5], p[6], p[7] = 1, 1, 1, 1          // Color
+		p[9] = 3                                     // Size
+	}
+
+	var err error
+	d.feedback, err = resource.NewTransformFeedback()
+	if err != nil {
+		return err
+	}
+	d.feedback.RasterizerDiscard = true
+
+	const stride = particleFloats * 4
+	for i := range d.buffers {
+		d.buffers[i], err = resource.NewVertexBuffer(data, resource.StreamDraw)
+		if err != nil {
+			return err
+		}
+
+		d.arrays[i], err = resource.NewVertexArray()
+		if err != nil {
+			return err
+		}
+		d.arrays[i].SetVertexBuffer(d.buffers[i])
+		d.arrays[i].AddFloatAttribute(0, 2, stride, 0)  // Position
+		d.arrays[i].AddFloatAttribute(1, 2, stride, 8)  // Velocity
+		d.arrays[i].AddFloatAttribute(2, 4, stride, 16) // Color
+		d.arrays[i].AddFloatAttribute(3, 1, stride, 32) // Life
+		d.arrays[i].AddFloatAttribute(4, 1, stride, 36) // Size
+	}
+	return nil
+}
+
+// Update advances the simulation by capturing the update pass over the
+// current buffer into the other one
+func (d *TransformFeedbackDemo) Update(deltaTime float32, time float32) error {
+	src, dst := d.current, 1-d.current
+	if err := d.feedback.BindBuffer(0, d.buffers[dst]); err != nil {
+		return err
+	}
+
+	d.renderPipeline.SetProgram(d.updateProgram)
+	p := d.updateProgram
+	p.SetUniform1f(p.GetUniformLocation("uDeltaTime"), deltaTime)
+	p.SetUniform1f(p.GetUniformLocation("uGravity"), d.gravity)
+	p.SetUniform1f(p.GetUniformLocation("uAttractorStrength"), d.attractorStrength)
+	p.SetUniform1f(p.GetUniformLocation("uTime"), time)
+	p.SetUniform2f(p.GetUniformLocation("uAttractor"), d.mousePos.X(), d.mousePos.Y())
+	p.SetUniform2f(p.GetUniformLocation("uViewportSize"), windowWidth, windowHeight)
+
+	if err := d.feedback.Begin(gl.POINTS); err != nil {
+		return err
+	}
+	d.arrays[src].Draw(gl.POINTS, numParticles, 0)
+	d.feedback.End()
+
+	d.current = dst
+	return nil
+}
+
+// Render draws the particles captured by the last update
+func (d *TransformFeedbackDemo) Render() {
+	d.renderPipeline.SetClearColor(0.1, 0.1, 0.15, 1.0)
+	d.renderPipeline.Clear(true, true, false)
+
+	state := pipeline.NewBuilder().
+		WithBlending(true, pipeline.BlendSrcAlpha, pipeline.BlendOneMinusSrcAlpha).
+		WithDepthTest(false, false, pipeline.DepthLess).
+		WithProgram(d.renderProgram).
+		WithViewport(0, 0, windowWidth, windowHeight).
+		Build()
+	d.renderPipeline.SetState(state)
+
+	d.renderProgram.SetUniform2f(d.renderProgram.GetUniformLocation("uViewportSize"), windowWidth, windowHeight)
+
+	// The vertex count stays on the GPU
+	gl.Enable(gl.PROGRAM_POINT_SIZE)
+	d.feedback.Draw(d.arrays[d.current], gl.POINTS)
+}
+
+func (d *TransformFeedbackDemo) mouseCallback(w *glfw.Window, xpos, ypos float64) {
+	d.mousePos[0] = float32(xpos)
+	d.mousePos[1] = float32(ypos)
+}
+
+func (d *TransformFeedbackDemo) Cleanup() {
+	if d.updateProgram != nil {
+		d.updateProgram.Delete()
+	}
+	if d.renderProgram != nil {
+		d.renderProgram.Delete()
+	}
+	if d.feedback != nil {
+		d.feedback.Delete()
+	}
+	for i := range d.buffers {
+		if d.arrays[i] != nil {
+			d.arrays[i].Delete()
+		}
+		if d.buffers[i] != nil {
+			d.buffers[i].Delete()
+		}
+	}
+}
+
+// runTransformFeedbackDemo runs the GPU particle simulation on contexts
+// without compute shaders. It returns an error when the demo cannot be
+// set up, so the caller can fall back to the CPU simulation.
+func runTransformFeedbackDemo(window *glfw.Window) error {
+	demo, err := NewTransformFeedbackDemo()
+	if err != nil {
+		return err
+	}
+	defer demo.Cleanup()
+
+	fmt.Println("Running transform feedback particle simulation...")
+	fmt.Println("Controls:")
+	fmt.Println("Mouse - Move attractor")
+	fmt.Println("ESC - Exit")
+
+	window.SetCursorPosCallback(demo.mouseCallback)
+	window.SetKeyCallback(func(w *glfw.Window, key glfw.Key, scancode int, action glfw.Action, mods glfw.ModifierKey) {
+		if key == glfw.KeyEscape && action == glfw.Press {
+			w.SetShouldClose(true)
+		}
+	})
+	glfw.SwapInterval(1)
+
+	lastTime := glfw.GetTime()
+	for !window.ShouldClose() {
+		currentTime := glfw.GetTime()
+		deltaTime := float32(currentTime - lastTime)
+		lastTime = currentTime
+
+		glfw.PollEvents()
+
+		if err := demo.Update(deltaTime, float32(currentTime)); err != nil {
+			return err
+		}
+		demo.Render()
+
+		window.SwapBuffers()
+	}
+	return nil
+}
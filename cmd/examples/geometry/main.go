@@ -268,7 +268,9 @@ func (d *Demo) createPointProgram() error {
 	}
 	defer fragmentShader.Delete()
 
-	d.pointProgram, err = shader.CreateProgram(vertexShader, geometryShader, fragmentShader)
+	// Borrowed, so the deferred deletes above stay valid
+	d.pointProgram, err = shader.CreateProgramWithOptions(shader.ProgramOptions{Ownership: shader.BorrowShaders},
+		vertexShader, geometryShader, fragmentShader)
 	if err != nil {
 		return fmt.Errorf("program: %w", err)
 	}
@@ -295,7 +297,9 @@ func (d *Demo) createWireframeProgram() error {
 	}
 	defer fragmentShader.Delete()
 
-	d.wireframeProgram, err = shader.CreateProgram(vertexShader, geometryShader, fragmentShader)
+	// Borrowed, so the deferred deletes above stay valid
+	d.wireframeProgram, err = shader.CreateProgramWithOptions(shader.ProgramOptions{Ownership: shader.BorrowShaders},
+		vertexShader, geometryShader, fragmentShader)
 	if err != nil {
 		return fmt.Errorf("program: %w", err)
 	}
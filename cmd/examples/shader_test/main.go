@@ -4,12 +4,12 @@ import (
 "fmt"
 "log"
 "os"
-"path/filepath"
 "runtime"
+"strings"
 
 "github.com/go-gl/gl/v4.1-core/gl"
 "github.com/go-gl/glfw/v3.3/glfw"
-"github.com/yossideutsch/gogl/pkg/shader"
+"github.com/yossideutsch/gogl/pkg/library"
 )
 
 func init() {
@@ -47,158 +47,50 @@ log.Fatal("Failed to initialize OpenGL:", err)
 fmt.Println("Testing GoGL Shader Library")
 fmt.Printf("OpenGL Version: %s\n\n", gl.GoStr(gl.GetString(gl.VERSION)))
 
-// Test shader pairs
-shaderPairs := []struct {
-name     string
-vertex   string
-fragment string
-}{
-{"Basic", "shaders/vertex/basic.vert", "shaders/fragment/basic.frag"},
-{"Flat Color", "shaders/vertex/flat_color.vert", "shaders/fragment/flat_color.frag"},
-{"Phong Lighting", "shaders/vertex/phong.vert", "shaders/fragment/phong.frag"},
-{"Textured", "shaders/vertex/textured.vert", "shaders/fragment/textured.frag"},
-{"Skybox", "shaders/vertex/skybox.vert", "shaders/fragment/skybox.frag"},
-{"Screen Quad + Blur", "shaders/vertex/screen_quad.vert", "shaders/fragment/blur.frag"},
-{"Screen Quad + Grayscale", "shaders/vertex/screen_quad.vert", "shaders/fragment/grayscale.frag"},
-{"Standard", "shaders/vertex/standard.vert", "shaders/fragment/simple_texture.frag"},
-}
+// Test every program in the bundled shader library, grouped by kind
+kinds := []library.Kind{library.KindSurface, library.KindPostProcess, library.KindGeometry, library.KindCompute}
+
+var major, minor int32
+gl.GetIntegerv(gl.MAJOR_VERSION, &major)
+gl.GetIntegerv(gl.MINOR_VERSION, &minor)
+contextVersion := library.GLVersion{Major: int(major), Minor: int(minor)}
 
 passCount := 0
 failCount := 0
+skipCount := 0
 
-for _, pair := range shaderPairs {
-fmt.Printf("Testing: %s\n", pair.name)
-
-// Test vertex shader
-vertexShader, err := shader.CompileShaderFromFile(pair.vertex, shader.VertexShader)
-if err != nil {
-fmt.Printf("  ❌ Vertex shader failed: %v\n", err)
-failCount++
-continue
-}
-defer vertexShader.Delete()
-
-// Test fragment shader
-fragmentShader, err := shader.CompileShaderFromFile(pair.fragment, shader.FragmentShader)
-if err != nil {
-fmt.Printf("  ❌ Fragment shader failed: %v\n", err)
-failCount++
-continue
-}
-defer fragmentShader.Delete()
-
-// Test program linking
-program, err := shader.CreateProgram(vertexShader, fragmentShader)
-if err != nil {
-fmt.Printf("  ❌ Program linking failed: %v\n", err)
-failCount++
-continue
-}
-program.Delete()
-
-fmt.Printf("  ✅ Success\n")
-passCount++
-}
-
-// Test geometry shaders
-fmt.Println("\nTesting Geometry Shaders:")
-geometryTests := []struct {
-name     string
-geometry string
-}{
-{"Point to Quad", "shaders/geometry/point_to_quad.glsl"},
-{"Wireframe", "shaders/geometry/wireframe.glsl"},
-{"Normal Visualization", "shaders/geometry/normal_visualization.glsl"},
-{"Normal Lines", "shaders/geometry/normal_lines.glsl"},
-{"Explode", "shaders/geometry/explode.glsl"},
-}
-
-for _, test := range geometryTests {
-fmt.Printf("Testing: %s\n", test.name)
-
-// Need a basic vertex shader
-vertexShader, err := shader.CompileShaderFromFile("shaders/vertex/basic.vert", shader.VertexShader)
-if err != nil {
-fmt.Printf("  ❌ Vertex shader failed: %v\n", err)
-failCount++
-continue
-}
-defer vertexShader.Delete()
+for _, kind := range kinds {
+fmt.Printf("Testing %s programs:\n", kind)
 
-// Test geometry shader
-geometryShader, err := shader.CompileShaderFromFile(test.geometry, shader.GeometryShader)
-if err != nil {
-fmt.Printf("  ❌ Geometry shader failed: %v\n", err)
-failCount++
+for _, info := range library.Programs() {
+if info.Kind != kind {
 continue
 }
-defer geometryShader.Delete()
+fmt.Printf("Testing: %s (%s)\n", info.Name, strings.Join(info.Shaders, " + "))
 
-// Need a basic fragment shader
-fragmentShader, err := shader.CompileShaderFromFile("shaders/fragment/flat_color.frag", shader.FragmentShader)
-if err != nil {
-fmt.Printf("  ❌ Fragment shader failed: %v\n", err)
-failCount++
+if required := info.MinGLVersion(); !contextVersion.IsAtLeast(required) {
+fmt.Printf("  ⏭  Skipped: requires OpenGL %s\n", required)
+skipCount++
 continue
 }
-defer fragmentShader.Delete()
 
-// Test program linking
-program, err := shader.CreateProgram(vertexShader, geometryShader, fragmentShader)
+program, err := library.Build(info.Name)
 if err != nil {
-fmt.Printf("  ❌ Program linking failed: %v\n", err)
+fmt.Printf("  ❌ Failed: %v\n", err)
 failCount++
 continue
 }
 program.Delete()
 
-fmt.Printf("  ✅ Success\n")
+fmt.Printf("  ✅ Success (%d inputs, %d uniforms)\n", len(info.Inputs()), len(info.Uniforms()))
 passCount++
 }
-
-// Test additional post-processing shaders
-fmt.Println("\nTesting Post-Processing Shaders:")
-postProcessTests := []string{
-"edge_detection.frag",
-"invert.frag",
-"brightness_contrast.frag",
-"gamma_correction.frag",
-}
-
-for _, fragName := range postProcessTests {
-fmt.Printf("Testing: %s\n", fragName)
-
-vertexShader, err := shader.CompileShaderFromFile("shaders/vertex/screen_quad.vert", shader.VertexShader)
-if err != nil {
-fmt.Printf("  ❌ Vertex shader failed: %v\n", err)
-failCount++
-continue
-}
-defer vertexShader.Delete()
-
-fragmentShader, err := shader.CompileShaderFromFile(filepath.Join("shaders/fragment", fragName), shader.FragmentShader)
-if err != nil {
-fmt.Printf("  ❌ Fragment shader failed: %v\n", err)
-failCount++
-continue
-}
-defer fragmentShader.Delete()
-
-program, err := shader.CreateProgram(vertexShader, fragmentShader)
-if err != nil {
-fmt.Printf("  ❌ Program linking failed: %v\n", err)
-failCount++
-continue
-}
-program.Delete()
-
-fmt.Printf("  ✅ Success\n")
-passCount++
+fmt.Println()
 }
 
 // Summary
 fmt.Println("\n━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
-fmt.Printf("Test Summary: %d passed, %d failed\n", passCount, failCount)
+fmt.Printf("Test Summary: %d passed, %d failed, %d skipped\n", passCount, failCount, skipCount)
 
 if failCount > 0 {
 os.Exit(1)
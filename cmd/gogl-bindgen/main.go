@@ -0,0 +1,124 @@
+// Command gogl-bindgen generates typed Go bindings for GLSL programs.
+//
+// Usage:
+//
+//	gogl-bindgen -pkg shaders -o shaders_gen.go \
+//		-program Phong=shaders/vertex/phong.vert,shaders/fragment/phong.frag
+//
+// Each -program flag names a program and lists its shader files. The stage
+// of a file is inferred from its extension (.vert, .frag, .geom, .comp,
+// .tesc, .tese) or its parent directory (vertex, fragment, geometry,
+// compute), or can be given explicitly as "frag:path/to/file.glsl".
+// Positional arguments are shorthand for a program named after the first
+// file. It is intended to be run from a go:generate directive:
+//
+//	//go:generate go run github.com/yossideutsch/gogl/cmd/gogl-bindgen -pkg main -o bindings_gen.go -program Phong=phong.vert,phong.frag
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/yossideutsch/gogl/internal/bindgen"
+	"github.com/yossideutsch/gogl/pkg/shader"
+)
+
+// listFlag collects repeated string flags
+type listFlag []string
+
+func (l *listFlag) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *listFlag) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+func main() {
+	var includes, programs listFlag
+	output := flag.String("o", "", "output file (default stdout)")
+	pkg := flag.String("pkg", "", "package name of the generated file (default: the output directory name)")
+	quiet := flag.Bool("q", false, "do not report skipped declarations")
+	flag.Var(&includes, "I", "directory searched for #include files (repeatable)")
+	flag.Var(&programs, "program", "program as Name=file1,file2,... (repeatable)")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: gogl-bindgen [flags] [-program Name=files...] [files...]\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if err := run(*output, *pkg, includes, programs, flag.Args(), *quiet); err != nil {
+		fmt.Fprintln(os.Stderr, "gogl-bindgen:", err)
+		os.Exit(1)
+	}
+}
+
+func run(output, pkg string, includes, programs, args []string, quiet bool) error {
+	cfg := bindgen.Config{Package: pkg, Preprocessor: shader.NewPreprocessor(includes...)}
+	if cfg.Package == "" {
+		if output == "" {
+			return fmt.Errorf("-pkg is required when writing to stdout")
+		}
+		abs, err := filepath.Abs(filepath.Dir(output))
+		if err != nil {
+			return err
+		}
+		cfg.Package = filepath.Base(abs)
+	}
+
+	for _, spec := range programs {
+		name, files, ok := strings.Cut(spec, "=")
+		if !ok || name == "" || files == "" {
+			return fmt.Errorf("invalid -program %q, expected Name=file1,file2", spec)
+		}
+		prog, err := program(name, strings.Split(files, ","))
+		if err != nil {
+			return err
+		}
+		cfg.Programs = append(cfg.Programs, prog)
+	}
+	if len(args) > 0 {
+		base := filepath.Base(args[0])
+		prog, err := program(strings.TrimSuffix(base, filepath.Ext(base)), args)
+		if err != nil {
+			return err
+		}
+		cfg.Programs = append(cfg.Programs, prog)
+	}
+	if len(cfg.Programs) == 0 {
+		flag.Usage()
+		return fmt.Errorf("no programs given")
+	}
+
+	result, err := bindgen.Generate(cfg)
+	if err != nil {
+		return err
+	}
+	if !quiet {
+		for _, w := range result.Warnings {
+			fmt.Fprintln(os.Stderr, "gogl-bindgen: warning:", w)
+		}
+	}
+	if output == "" {
+		_, err = os.Stdout.Write(result.Code)
+		return err
+	}
+	return os.WriteFile(output, result.Code, 0644)
+}
+
+// program builds a bindgen program from file arguments
+func program(name string, args []string) (bindgen.Program, error) {
+	prog := bindgen.Program{Name: name}
+	for _, arg := range args {
+		file, err := bindgen.ParseFile(arg)
+		if err != nil {
+			return prog, err
+		}
+		prog.Files = append(prog.Files, file)
+	}
+	return prog, nil
+}
@@ -0,0 +1,194 @@
+// Command gogl-lint checks GLSL shaders without an OpenGL context.
+//
+// Usage:
+//
+//	gogl-lint [flags] [files or directories...]
+//
+// Directories are searched recursively for shader files whose stage can be
+// inferred from their extension or directory (see shader.StageFromPath);
+// the default is the shaders directory. Stage interfaces are checked for
+// every -program and, with -library, for the bundled programs of
+// pkg/library found under a linted directory.
+//
+// Findings are written as text, JSON or SARIF 2.1.0 (-format). The exit
+// status is 1 when an error is found, or a warning with -werror, and 2 when
+// the shaders cannot be read.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/yossideutsch/gogl/internal/lint"
+	"github.com/yossideutsch/gogl/pkg/library"
+	"github.com/yossideutsch/gogl/pkg/shader"
+)
+
+// listFlag collects repeated string flags
+type listFlag []string
+
+func (l *listFlag) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *listFlag) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+func main() {
+	var includes, programs listFlag
+	format := flag.String("format", "text", "output format: text, json or sarif")
+	output := flag.String("o", "", "output file (default stdout)")
+	useLibrary := flag.Bool("library", true, "check the interfaces of bundled library programs found under linted directories")
+	werror := flag.Bool("werror", false, "exit with status 1 on warnings")
+	flag.Var(&includes, "I", "directory searched for #include files (repeatable)")
+	flag.Var(&programs, "program", "program whose stage interfaces are checked, as Name=file1,file2,... (repeatable)")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: gogl-lint [flags] [files or directories...]\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	failed, err := run(*format, *output, includes, programs, flag.Args(), *useLibrary, *werror)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "gogl-lint:", err)
+		os.Exit(2)
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+func run(format, output string, includes, programs, args []string, useLibrary, werror bool) (bool, error) {
+	write, ok := map[string]func(io.Writer, []lint.Finding) error{
+		"text":  lint.WriteText,
+		"json":  lint.WriteJSON,
+		"sarif": lint.WriteSARIF,
+	}[format]
+	if !ok {
+		return false, fmt.Errorf("unknown format %q", format)
+	}
+
+	cfg := lint.Config{Preprocessor: shader.NewPreprocessor(includes...)}
+	if len(args) == 0 {
+		args = []string{"shaders"}
+	}
+	for _, arg := range args {
+		info, err := os.Stat(arg)
+		if err != nil {
+			return false, err
+		}
+		if !info.IsDir() {
+			file, err := parseFile(arg)
+			if err != nil {
+				return false, err
+			}
+			cfg.Files = append(cfg.Files, file)
+			continue
+		}
+		files, err := walk(arg)
+		if err != nil {
+			return false, err
+		}
+		cfg.Files = append(cfg.Files, files...)
+		if useLibrary {
+			cfg.Programs = append(cfg.Programs, libraryPrograms(arg)...)
+		}
+	}
+
+	for _, spec := range programs {
+		name, list, ok := strings.Cut(spec, "=")
+		if !ok || name == "" || list == "" {
+			return false, fmt.Errorf("invalid -program %q, expected Name=file1,file2", spec)
+		}
+		prog := lint.Program{Name: name}
+		for _, arg := range strings.Split(list, ",") {
+			file, err := parseFile(arg)
+			if err != nil {
+				return false, err
+			}
+			prog.Files = append(prog.Files, file)
+		}
+		cfg.Programs = append(cfg.Programs, prog)
+	}
+
+	findings := lint.Lint(cfg)
+
+	w := io.Writer(os.Stdout)
+	if output != "" {
+		f, err := os.Create(output)
+		if err != nil {
+			return false, err
+		}
+		defer f.Close()
+		w = f
+	}
+	if err := write(w, findings); err != nil {
+		return false, err
+	}
+
+	for _, f := range findings {
+		if f.Level == lint.LevelError || (werror && f.Level == lint.LevelWarning) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// parseFile parses a file argument of the form "path" or "stage:path"
+func parseFile(arg string) (lint.File, error) {
+	if prefix, file, ok := strings.Cut(arg, ":"); ok {
+		if stage, ok := shader.ParseShaderType(prefix); ok {
+			return lint.File{Path: file, Stage: stage}, nil
+		}
+	}
+	if stage, ok := shader.StageFromPath(arg); ok {
+		return lint.File{Path: arg, Stage: stage}, nil
+	}
+	return lint.File{}, fmt.Errorf("cannot infer shader stage of %s; use a vert:, frag:, geom:, comp:, tesc: or tese: prefix", arg)
+}
+
+// walk collects the shader files under dir whose stage can be inferred
+func walk(dir string) ([]lint.File, error) {
+	var files []lint.File
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		switch filepath.Ext(p) {
+		case ".vert", ".frag", ".geom", ".comp", ".tesc", ".tese", ".glsl":
+		default:
+			return nil
+		}
+		if stage, ok := shader.StageFromPath(p); ok {
+			files = append(files, lint.File{Path: p, Stage: stage})
+		}
+		return nil
+	})
+	return files, err
+}
+
+// libraryPrograms returns the bundled programs whose files all exist under dir
+func libraryPrograms(dir string) []lint.Program {
+	var programs []lint.Program
+	for _, info := range library.Programs() {
+		prog := lint.Program{Name: info.Name}
+		for _, s := range info.Stages() {
+			file := filepath.Join(dir, filepath.FromSlash(s.Path))
+			if _, err := os.Stat(file); err != nil {
+				break
+			}
+			prog.Files = append(prog.Files, lint.File{Path: file, Stage: s.Stage})
+		}
+		if len(prog.Files) == len(info.Shaders) {
+			programs = append(programs, prog)
+		}
+	}
+	return programs
+}
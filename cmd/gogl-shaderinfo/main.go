@@ -0,0 +1,199 @@
+// Command gogl-shaderinfo links shader files in a hidden OpenGL context and
+// prints the program interface the driver reports.
+//
+// Usage:
+//
+//	gogl-shaderinfo [flags] files...
+//
+// The stage of a file is inferred from its extension or directory (see
+// shader.StageFromPath), or given as "frag:path/to/file.glsl". The report
+// lists the active uniforms with their locations, the uniforms and uniform
+// blocks the driver optimized away, attributes, uniform and shader storage
+// blocks, subroutine uniforms, the compute work group size and the size of
+// the program binary, as text or JSON (-format).
+//
+// Programs with a compute stage are linked in an OpenGL 4.3 context and
+// others in 4.1, unless -gl selects a version. The exit status is 1 when
+// the files do not compile or link.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"strings"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+	"github.com/go-gl/glfw/v3.3/glfw"
+	"github.com/yossideutsch/gogl/internal/shaderinfo"
+	"github.com/yossideutsch/gogl/pkg/shader"
+)
+
+func init() {
+	// GL calls have to stay on the thread that owns the context
+	runtime.LockOSThread()
+}
+
+// listFlag collects repeated string flags
+type listFlag []string
+
+func (l *listFlag) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *listFlag) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+func main() {
+	var includes listFlag
+	format := flag.String("format", "text", "output format: text or json")
+	output := flag.String("o", "", "output file (default stdout)")
+	version := flag.String("gl", "", "OpenGL core version of the context, e.g. 4.1 (default 4.3 for compute programs, else 4.1)")
+	separable := flag.Bool("separable", false, "link as a separable program, so single stages can be inspected")
+	flag.Var(&includes, "I", "directory searched for #include files (repeatable)")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: gogl-shaderinfo [flags] files...\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if err := run(*format, *output, *version, includes, flag.Args(), *separable); err != nil {
+		fmt.Fprintln(os.Stderr, "gogl-shaderinfo:", err)
+		os.Exit(1)
+	}
+}
+
+func run(format, output, version string, includes, args []string, separable bool) error {
+	write, ok := map[string]func(io.Writer, *shaderinfo.Report) error{
+		"text": shaderinfo.WriteText,
+		"json": shaderinfo.WriteJSON,
+	}[format]
+	if !ok {
+		return fmt.Errorf("unknown format %q", format)
+	}
+	if len(args) == 0 {
+		flag.Usage()
+		return fmt.Errorf("no shader files given")
+	}
+
+	stages := make([]shaderinfo.Stage, 0, len(args))
+	compute := false
+	for _, arg := range args {
+		stage, err := parseFile(arg)
+		if err != nil {
+			return err
+		}
+		compute = compute || stage.Stage == shader.ComputeShader
+		stages = append(stages, stage)
+	}
+	if version == "" {
+		version = "4.1"
+		if compute {
+			version = "4.3"
+		}
+	}
+
+	window, err := createContext(version)
+	if err != nil {
+		return err
+	}
+	defer glfw.Terminate()
+	defer window.Destroy()
+
+	pp := shader.NewPreprocessor(includes...)
+	program, err := link(pp, stages, separable)
+	if err != nil {
+		return err
+	}
+	defer program.Delete()
+	report := shaderinfo.Inspect(program, stages)
+
+	w := io.Writer(os.Stdout)
+	if output != "" {
+		f, err := os.Create(output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	return write(w, report)
+}
+
+// parseFile parses a file argument of the form "path" or "stage:path"
+func parseFile(arg string) (shaderinfo.Stage, error) {
+	if prefix, file, ok := strings.Cut(arg, ":"); ok {
+		if stage, ok := shader.ParseShaderType(prefix); ok {
+			return shaderinfo.Stage{Stage: stage, Path: file}, nil
+		}
+	}
+	if stage, ok := shader.StageFromPath(arg); ok {
+		return shaderinfo.Stage{Stage: stage, Path: arg}, nil
+	}
+	return shaderinfo.Stage{}, fmt.Errorf("cannot infer shader stage of %s; use a vert:, frag:, geom:, comp:, tesc: or tese: prefix", arg)
+}
+
+// createContext makes a hidden window with an OpenGL core context current
+func createContext(version string) (*glfw.Window, error) {
+	var major, minor int
+	if _, err := fmt.Sscanf(version, "%d.%d", &major, &minor); err != nil {
+		return nil, fmt.Errorf("invalid -gl version %q, expected e.g. 4.1", version)
+	}
+
+	if err := glfw.Init(); err != nil {
+		return nil, fmt.Errorf("failed to initialize GLFW: %w", err)
+	}
+	glfw.WindowHint(glfw.ContextVersionMajor, major)
+	glfw.WindowHint(glfw.ContextVersionMinor, minor)
+	glfw.WindowHint(glfw.OpenGLProfile, glfw.OpenGLCoreProfile)
+	glfw.WindowHint(glfw.OpenGLForwardCompatible, glfw.True)
+	glfw.WindowHint(glfw.Visible, glfw.False)
+
+	window, err := glfw.CreateWindow(1, 1, "gogl-shaderinfo", nil, nil)
+	if err != nil {
+		glfw.Terminate()
+		return nil, fmt.Errorf("failed to create an OpenGL %s core context: %w", version, err)
+	}
+	window.MakeContextCurrent()
+
+	if err := gl.Init(); err != nil {
+		window.Destroy()
+		glfw.Terminate()
+		return nil, fmt.Errorf("failed to initialize OpenGL: %w", err)
+	}
+	return window, nil
+}
+
+// link preprocesses and compiles the stages and links them with a
+// retrievable binary, so its size can be reported
+func link(pp *shader.Preprocessor, stages []shaderinfo.Stage, separable bool) (*shader.Program, error) {
+	compiled := make([]*shader.Shader, 0, len(stages))
+	release := func() {
+		for _, s := range compiled {
+			s.Delete()
+		}
+	}
+	for i := range stages {
+		src, err := pp.ProcessFile(stages[i].Path)
+		if err != nil {
+			release()
+			return nil, err
+		}
+		stages[i].Source = src
+
+		s, err := pp.CompileSource(src, stages[i].Path, stages[i].Stage)
+		if err != nil {
+			release()
+			return nil, err
+		}
+		compiled = append(compiled, s)
+	}
+	return shader.CreateProgramWithOptions(shader.ProgramOptions{
+		BinaryRetrievable: true,
+		Separable:         separable,
+	}, compiled...)
+}
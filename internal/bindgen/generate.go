@@ -0,0 +1,610 @@
+// Package bindgen generates typed Go bindings for GLSL programs.
+//
+// For every program it emits a wrapper around *shader.Program with a typed
+// setter per uniform, constants for attribute locations declared with
+// layout(location = N), a resource.VertexLayout constructor and Go structs
+// for std140 uniform blocks and std430 storage blocks. A constructor checks
+// the linked program against the declarations at run time, so bindings that
+// have drifted from their shaders fail loudly instead of writing to -1.
+package bindgen
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/format"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/yossideutsch/gogl/pkg/glsl"
+	"github.com/yossideutsch/gogl/pkg/shader"
+)
+
+// File is a shader source of a program
+type File struct {
+	Path  string
+	Stage shader.ShaderType
+}
+
+// Program is a set of shader files linked together
+type Program struct {
+	Name  string // Go name of the generated type, e.g. "Phong"
+	Files []File
+}
+
+// Config controls code generation
+type Config struct {
+	Package  string
+	Programs []Program
+
+	// Preprocessor expands #include directives; shader.DefaultPreprocessor
+	// is used when nil
+	Preprocessor *shader.Preprocessor
+}
+
+// Result is the generated code and any declarations that were skipped
+type Result struct {
+	Code     []byte
+	Warnings []string
+}
+
+// ParseFile parses a file argument of the form "path" or "stage:path",
+// inferring the stage with shader.StageFromPath when omitted
+func ParseFile(arg string) (File, error) {
+	if prefix, path, ok := strings.Cut(arg, ":"); ok {
+		if stage, ok := shader.ParseShaderType(prefix); ok {
+			return File{Path: path, Stage: stage}, nil
+		}
+	}
+	if stage, ok := shader.StageFromPath(arg); ok {
+		return File{Path: arg, Stage: stage}, nil
+	}
+	return File{}, fmt.Errorf("cannot infer shader stage of %s; use a vert:, frag:, geom:, comp:, tesc: or tese: prefix", arg)
+}
+
+// glslType describes how a GLSL type maps onto Go
+type glslType struct {
+	goType      string // Go type of setter arguments and block fields
+	dataType    string // shader.DataType constant reported by reflection
+	setter      string // Setter call; %s is the value
+	arrayGoType string // Go type of array setters, empty if unsupported
+	arraySetter string
+	attribute   string // VertexLayout method, empty if not a vertex input
+	components  int    // Components per attribute location
+	columns     int    // Attribute locations taken by matrices
+	block       bool   // Supported by pkg/layout inside blocks
+}
+
+// types maps GLSL type names to their Go bindings
+var types = map[string]glslType{
+	"float": {goType: "float32", dataType: "TypeFloat", setter: "SetUniform1f(loc, %s)", arrayGoType: "[]float32", arraySetter: "SetUniform1fv(loc, %s)", attribute: "AddFloat", components: 1, block: true},
+	"vec2":  {goType: "mgl32.Vec2", dataType: "TypeVec2", setter: "SetUniformVec2(loc, %s)", arrayGoType: "[]mgl32.Vec2", arraySetter: "SetUniformVec2Array(loc, %s)", attribute: "AddFloat", components: 2, block: true},
+	"vec3":  {goType: "mgl32.Vec3", dataType: "TypeVec3", setter: "SetUniformVec3(loc, %s)", arrayGoType: "[]mgl32.Vec3", arraySetter: "SetUniformVec3Array(loc, %s)", attribute: "AddFloat", components: 3, block: true},
+	"vec4":  {goType: "mgl32.Vec4", dataType: "TypeVec4", setter: "SetUniformVec4(loc, %s)", arrayGoType: "[]mgl32.Vec4", arraySetter: "SetUniformVec4Array(loc, %s)", attribute: "AddFloat", components: 4, block: true},
+	"int":   {goType: "int32", dataType: "TypeInt", setter: "SetUniform1i(loc, %s)", arrayGoType: "[]int32", arraySetter: "SetUniform1iv(loc, %s)", attribute: "AddInt", components: 1, block: true},
+	"ivec2": {goType: "[2]int32", dataType: "TypeIVec2", setter: "SetUniform2i(loc, %[1]s[0], %[1]s[1])", attribute: "AddInt", components: 2},
+	"ivec3": {goType: "[3]int32", dataType: "TypeIVec3", setter: "SetUniform3i(loc, %[1]s[0], %[1]s[1], %[1]s[2])", attribute: "AddInt", components: 3},
+	"ivec4": {goType: "[4]int32", dataType: "TypeIVec4", setter: "SetUniform4i(loc, %[1]s[0], %[1]s[1], %[1]s[2], %[1]s[3])", attribute: "AddInt", components: 4},
+	"uint":  {goType: "uint32", dataType: "TypeUInt", setter: "SetUniform1ui(loc, %s)", arrayGoType: "[]uint32", arraySetter: "SetUniform1uiv(loc, %s)", attribute: "AddUInt", components: 1, block: true},
+	"uvec2": {goType: "[2]uint32", dataType: "TypeUVec2", setter: "SetUniform2ui(loc, %[1]s[0], %[1]s[1])", attribute: "AddUInt", components: 2},
+	"uvec3": {goType: "[3]uint32", dataType: "TypeUVec3", setter: "SetUniform3ui(loc, %[1]s[0], %[1]s[1], %[1]s[2])", attribute: "AddUInt", components: 3},
+	"uvec4": {goType: "[4]uint32", dataType: "TypeUVec4", setter: "SetUniform4ui(loc, %[1]s[0], %[1]s[1], %[1]s[2], %[1]s[3])", attribute: "AddUInt", components: 4},
+	"bool":  {goType: "bool", dataType: "TypeBool", setter: "SetUniformBool(loc, %s)", block: true},
+
+	"mat2":   {goType: "mgl32.Mat2", dataType: "TypeMat2", setter: "SetUniformMatrix2fv(loc, &%s)", arrayGoType: "[]mgl32.Mat2", arraySetter: "SetUniformMatrix2fvArray(loc, %s)", attribute: "AddFloat", components: 2, columns: 2, block: true},
+	"mat3":   {goType: "mgl32.Mat3", dataType: "TypeMat3", setter: "SetUniformMatrix3fv(loc, &%s)", arrayGoType: "[]mgl32.Mat3", arraySetter: "SetUniformMatrix3fvArray(loc, %s)", attribute: "AddFloat", components: 3, columns: 3, block: true},
+	"mat4":   {goType: "mgl32.Mat4", dataType: "TypeMat4", setter: "SetUniformMatrix4fv(loc, &%s)", arrayGoType: "[]mgl32.Mat4", arraySetter: "SetUniformMatrix4fvArray(loc, %s)", attribute: "AddFloat", components: 4, columns: 4, block: true},
+	"mat2x3": {goType: "mgl32.Mat3x2", dataType: "TypeMat2x3", setter: "SetUniformMatrix2x3fv(loc, &%s)", attribute: "AddFloat", components: 3, columns: 2, block: true},
+	"mat2x4": {goType: "mgl32.Mat4x2", dataType: "TypeMat2x4", setter: "SetUniformMatrix2x4fv(loc, &%s)", attribute: "AddFloat", components: 4, columns: 2, block: true},
+	"mat3x2": {goType: "mgl32.Mat2x3", dataType: "TypeMat3x2", setter: "SetUniformMatrix3x2fv(loc, &%s)", attribute: "AddFloat", components: 2, columns: 3, block: true},
+	"mat3x4": {goType: "mgl32.Mat4x3", dataType: "TypeMat3x4", setter: "SetUniformMatrix3x4fv(loc, &%s)", attribute: "AddFloat", components: 4, columns: 3, block: true},
+	"mat4x2": {goType: "mgl32.Mat2x4", dataType: "TypeMat4x2", setter: "SetUniformMatrix4x2fv(loc, &%s)", attribute: "AddFloat", components: 2, columns: 4, block: true},
+	"mat4x3": {goType: "mgl32.Mat3x4", dataType: "TypeMat4x3", setter: "SetUniformMatrix4x3fv(loc, &%s)", attribute: "AddFloat", components: 3, columns: 4, block: true},
+}
+
+// samplerTypes maps sampler type names to their shader.DataType constants
+var samplerTypes = map[string]string{
+	"sampler1D":         "TypeSampler1D",
+	"sampler2D":         "TypeSampler2D",
+	"sampler3D":         "TypeSampler3D",
+	"samplerCube":       "TypeSamplerCube",
+	"sampler2DShadow":   "TypeSampler2DShadow",
+	"sampler2DArray":    "TypeSampler2DArray",
+	"samplerCubeShadow": "TypeSamplerCubeShadow",
+	"samplerBuffer":     "TypeSamplerBuffer",
+	"sampler2DRect":     "TypeSampler2DRect",
+	"sampler2DMS":       "TypeSampler2DMS",
+	"isampler2D":        "TypeISampler2D",
+	"usampler2D":        "TypeUSampler2D",
+}
+
+// lookupType returns the Go mapping of a GLSL type
+func lookupType(name string) (glslType, bool) {
+	if t, ok := types[name]; ok {
+		return t, true
+	}
+	if strings.Contains(name, "sampler") {
+		// Samplers are set to a texture unit
+		return glslType{
+			goType:      "int32",
+			dataType:    samplerTypes[name],
+			setter:      "SetUniformSampler(loc, %s)",
+			arrayGoType: "[]int32",
+			arraySetter: "SetUniform1iv(loc, %s)",
+		}, true
+	}
+	return glslType{}, false
+}
+
+// goName converts a GLSL identifier to an exported Go identifier
+func goName(name string) string {
+	var b strings.Builder
+	for _, part := range strings.Split(name, "_") {
+		if part == "" {
+			continue
+		}
+		b.WriteString(strings.ToUpper(part[:1]))
+		b.WriteString(part[1:])
+	}
+	s := b.String()
+	if s == "" || (s[0] >= '0' && s[0] <= '9') {
+		s = "X" + s
+	}
+	return s
+}
+
+// program is the merged interface of all stages of a program
+type program struct {
+	Name       string
+	Attributes []*glsl.Variable
+	Uniforms   []*glsl.Variable
+	Blocks     []*glsl.Block
+	Structs    map[string]*glsl.Struct
+	Files      []string
+}
+
+// generator accumulates the generated source of one file
+type generator struct {
+	body     bytes.Buffer
+	imports  map[string]bool
+	warnings []string
+}
+
+func (g *generator) printf(format string, args ...interface{}) {
+	fmt.Fprintf(&g.body, format, args...)
+}
+
+func (g *generator) warnf(format string, args ...interface{}) {
+	g.warnings = append(g.warnings, fmt.Sprintf(format, args...))
+}
+
+// Generate parses the programs of cfg and returns their Go bindings
+func Generate(cfg Config) (*Result, error) {
+	if cfg.Package == "" {
+		return nil, fmt.Errorf("package name cannot be empty")
+	}
+	pp := cfg.Preprocessor
+	if pp == nil {
+		pp = shader.DefaultPreprocessor
+	}
+
+	g := &generator{imports: map[string]bool{"github.com/yossideutsch/gogl/pkg/shader": true}}
+	seen := make(map[string]bool)
+	for _, cp := range cfg.Programs {
+		prog, err := load(pp, cp)
+		if err != nil {
+			return nil, err
+		}
+		if seen[prog.Name] {
+			return nil, fmt.Errorf("duplicate program name %s", prog.Name)
+		}
+		seen[prog.Name] = true
+		if err := g.program(prog); err != nil {
+			return nil, fmt.Errorf("program %s: %w", prog.Name, err)
+		}
+	}
+
+	var out bytes.Buffer
+	out.WriteString("// Code generated by gogl-bindgen. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&out, "package %s\n\n", cfg.Package)
+	imports := make([]string, 0, len(g.imports))
+	for path := range g.imports {
+		imports = append(imports, path)
+	}
+	sort.Slice(imports, func(i, j int) bool {
+		si, sj := strings.Contains(imports[i], "."), strings.Contains(imports[j], ".")
+		if si != sj {
+			return sj
+		}
+		return imports[i] < imports[j]
+	})
+	out.WriteString("import (\n")
+	for i, path := range imports {
+		// Standard library first, separated from module imports
+		if i > 0 && !strings.Contains(imports[i-1], ".") && strings.Contains(path, ".") {
+			out.WriteString("\n")
+		}
+		fmt.Fprintf(&out, "\t%q\n", path)
+	}
+	out.WriteString(")\n")
+	out.Write(g.body.Bytes())
+
+	code, err := format.Source(out.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("formatting generated code: %w", err)
+	}
+	return &Result{Code: code, Warnings: g.warnings}, nil
+}
+
+// load preprocesses and parses the stages of a program and merges their interfaces
+func load(pp *shader.Preprocessor, cp Program) (*program, error) {
+	if len(cp.Files) == 0 {
+		return nil, fmt.Errorf("program %s has no shader files", cp.Name)
+	}
+	prog := &program{Name: goName(cp.Name), Structs: make(map[string]*glsl.Struct)}
+	uniforms := make(map[string]*glsl.Variable)
+	blocks := make(map[string]*glsl.Block)
+
+	for _, f := range cp.Files {
+		src, err := pp.ProcessFile(f.Path)
+		if err != nil {
+			return nil, err
+		}
+		decls, err := glsl.ParseWithOptions(src.Code, glsl.Options{Name: f.Path})
+		if err != nil {
+			var syntax *glsl.Error
+			if errors.As(err, &syntax) {
+				if loc, ok := src.Map.Resolve(syntax.Pos.Line); ok {
+					return nil, fmt.Errorf("%s: %s", loc, syntax.Msg)
+				}
+			}
+			return nil, err
+		}
+		prog.Files = append(prog.Files, filepath.ToSlash(f.Path))
+
+		if f.Stage == shader.VertexShader {
+			prog.Attributes = decls.Inputs()
+		}
+		for _, def := range decls.Structs {
+			prog.Structs[def.Name] = def
+		}
+		for _, u := range decls.Uniforms() {
+			if prev, ok := uniforms[u.Name]; ok {
+				if prev.Type != u.Type || prev.ArraySize != u.ArraySize {
+					return nil, fmt.Errorf("%s: uniform %s is declared as %s in another stage", f.Path, u.Name, prev.Type)
+				}
+				continue
+			}
+			uniforms[u.Name] = u
+			prog.Uniforms = append(prog.Uniforms, u)
+		}
+		for _, b := range decls.Blocks {
+			if b.Storage != glsl.StorageUniform && b.Storage != glsl.StorageBuffer {
+				continue
+			}
+			if _, ok := blocks[b.Name]; ok {
+				continue
+			}
+			blocks[b.Name] = b
+			prog.Blocks = append(prog.Blocks, b)
+		}
+	}
+	return prog, nil
+}
+
+// location returns the layout(location = N) of a variable, -1 when unset
+func location(v *glsl.Variable) int {
+	if loc, ok := v.Location(); ok {
+		return loc
+	}
+	return -1
+}
+
+// program emits the bindings of one program
+func (g *generator) program(prog *program) error {
+	name := prog.Name
+
+	// Attribute location constants
+	var located []*glsl.Variable
+	for _, a := range prog.Attributes {
+		if strings.HasPrefix(a.Name, "gl_") {
+			continue
+		}
+		if location(a) < 0 {
+			g.warnf("%s: attribute %s has no layout(location); no constant generated", name, a.Name)
+			continue
+		}
+		located = append(located, a)
+	}
+	if len(located) > 0 {
+		g.printf("\n// Vertex attribute locations of the %s program\nconst (\n", name)
+		for _, a := range located {
+			g.printf("%s%sLocation = %d\n", name, goName(a.Name), location(a))
+		}
+		g.printf(")\n")
+	}
+
+	// Block structs
+	structs := make(map[string]string) // GLSL struct name -> Go type name
+	var checks []string
+	for _, b := range prog.Blocks {
+		check, err := g.block(prog, b, structs)
+		if err != nil {
+			return err
+		}
+		if check != "" {
+			checks = append(checks, check)
+		}
+	}
+
+	// Wrapper type and constructor
+	g.imports["fmt"] = true
+	g.printf("\n// %s wraps a program linked from %s\n", name, strings.Join(prog.Files, ", "))
+	g.printf("type %s struct {\n*shader.Program\n}\n", name)
+	g.printf("\n// New%s wraps p after checking that its active attributes, uniforms and\n", name)
+	g.printf("// blocks match the declarations the bindings were generated from\n")
+	g.printf("func New%s(p *shader.Program) (*%s, error) {\n", name, name)
+	g.printf("if p == nil {\nreturn nil, fmt.Errorf(\"%s: program is nil\")\n}\n", name)
+	for _, a := range located {
+		constant := name + goName(a.Name) + "Location"
+		g.printf("if info, ok := p.Attribute(%q); ok && info.Location != %s {\n", a.Name, constant)
+		g.printf("return nil, fmt.Errorf(\"%s: attribute %s is at location %%d, expected %%d\", info.Location, %s)\n}\n", name, a.Name, constant)
+	}
+
+	setters, err := g.setters(prog)
+	if err != nil {
+		return err
+	}
+	for _, s := range setters {
+		if s.check == "" {
+			continue
+		}
+		g.printf("if info, ok := p.Uniform(%q); ok && info.Type != shader.%s {\n", s.check, s.t.dataType)
+		g.printf("return nil, fmt.Errorf(\"%s: uniform %s is declared as %%s, expected %s\", info.Type)\n}\n", name, s.check, s.glslType)
+	}
+	for _, check := range checks {
+		g.printf("%s", check)
+	}
+	g.printf("return &%s{Program: p}, nil\n}\n", name)
+
+	for _, s := range setters {
+		g.setter(name, s)
+	}
+
+	g.vertexLayout(prog, located)
+	return nil
+}
+
+// uniformSetter is a setter of one uniform or struct uniform member
+type uniformSetter struct {
+	goName   string
+	glslName string // Uniform name, with %d verbs for struct array indices
+	indices  int
+	glslType string
+	array    int
+	t        glslType
+	check    string // Name checked by the constructor, empty to skip
+}
+
+// setters collects the setters of the default uniform block
+func (g *generator) setters(prog *program) ([]uniformSetter, error) {
+	var setters []uniformSetter
+	names := make(map[string]string)
+	var add func(u *glsl.Variable, goPrefix, glslPrefix string, indices int) error
+	add = func(u *glsl.Variable, goPrefix, glslPrefix string, indices int) error {
+		goID := goPrefix + goName(u.Name)
+		glslName := glslPrefix + u.Name
+		if def, ok := prog.Structs[u.Type]; ok {
+			switch {
+			case u.ArraySize < 0:
+				g.warnf("%s: uniform %s is a runtime-sized array; no setter generated", prog.Name, glslName)
+				return nil
+			case u.ArraySize > 0:
+				glslName += "[%d]"
+				indices++
+			}
+			for _, m := range def.Members {
+				if err := add(m, goID, glslName+".", indices); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+
+		t, ok := lookupType(u.Type)
+		if !ok || (u.ArraySize != 0 && t.arraySetter == "") {
+			g.warnf("%s: uniform %s of type %s has no typed setter", prog.Name, glslName, u.Type)
+			return nil
+		}
+		if prev, ok := names[goID]; ok {
+			return fmt.Errorf("uniforms %s and %s both map to Set%s", prev, glslName, goID)
+		}
+		names[goID] = glslName
+		s := uniformSetter{goName: goID, glslName: glslName, indices: indices, glslType: u.Type, array: u.ArraySize, t: t}
+		if indices == 0 && t.dataType != "" {
+			s.check = glslName
+		}
+		setters = append(setters, s)
+		return nil
+	}
+
+	for _, u := range prog.Uniforms {
+		if err := add(u, "", "", 0); err != nil {
+			return nil, err
+		}
+	}
+	return setters, nil
+}
+
+// setter emits the setter method of one uniform
+func (g *generator) setter(typeName string, s uniformSetter) {
+	params := make([]string, 0, s.indices+1)
+	args := make([]string, 0, s.indices)
+	for i := 0; i < s.indices; i++ {
+		arg := "i"
+		if s.indices > 1 {
+			arg = "i" + strconv.Itoa(i)
+		}
+		args = append(args, arg)
+		params = append(params, arg+" int")
+	}
+	goType, call := s.t.goType, s.t.setter
+	glslType := s.glslType
+	if s.array != 0 {
+		goType, call = s.t.arrayGoType, s.t.arraySetter
+		glslType = fmt.Sprintf("%s[%d]", s.glslType, s.array)
+	}
+	if strings.Contains(goType, "mgl32.") {
+		g.imports["github.com/go-gl/mathgl/mgl32"] = true
+	}
+	params = append(params, "v "+goType)
+
+	nameExpr := strconv.Quote(s.glslName)
+	shown := s.glslName
+	if s.indices > 0 {
+		g.imports["fmt"] = true
+		nameExpr = fmt.Sprintf("fmt.Sprintf(%s, %s)", nameExpr, strings.Join(args, ", "))
+		shown = strings.ReplaceAll(s.glslName, "%d", "")
+	}
+
+	g.printf("\n// Set%s sets the %s uniform %s\n", s.goName, glslType, shown)
+	g.printf("func (p *%s) Set%s(%s) error {\n", typeName, s.goName, strings.Join(params, ", "))
+	g.printf("loc := p.GetUniformLocation(%s)\n", nameExpr)
+	g.printf("if loc == -1 {\nreturn nil // Inactive in the linked program\n}\n")
+	g.printf("return p.%s\n}\n", fmt.Sprintf(call, "v"))
+}
+
+// vertexLayout emits a VertexLayout constructor when every attribute has a location
+func (g *generator) vertexLayout(prog *program, located []*glsl.Variable) {
+	if len(located) == 0 {
+		return
+	}
+	for _, a := range prog.Attributes {
+		if location(a) < 0 && !strings.HasPrefix(a.Name, "gl_") {
+			g.warnf("%s: not every attribute has a location; no vertex layout generated", prog.Name)
+			return
+		}
+	}
+
+	var calls []string
+	for _, a := range located {
+		t, ok := lookupType(a.Type)
+		if !ok || t.attribute == "" || a.ArraySize != 0 {
+			g.warnf("%s: attribute %s of type %s cannot be described by a vertex layout", prog.Name, a.Name, a.Type)
+			return
+		}
+		columns := t.columns
+		if columns == 0 {
+			columns = 1
+		}
+		for c := 0; c < columns; c++ {
+			calls = append(calls, fmt.Sprintf("%s(%d, %d)", t.attribute, location(a)+c, t.components))
+		}
+	}
+
+	g.imports["github.com/yossideutsch/gogl/pkg/resource"] = true
+	g.printf("\n// New%sVertexLayout returns the interleaved vertex layout of the %s\n", prog.Name, prog.Name)
+	g.printf("// attributes in declaration order\n")
+	g.printf("func New%sVertexLayout() *resource.VertexLayout {\n", prog.Name)
+	g.printf("return resource.NewVertexLayout().\n%s\n}\n", strings.Join(calls, ".\n"))
+}
+
+// block emits the Go struct of a uniform or storage block and returns the
+// constructor statement validating it, if any
+func (g *generator) block(prog *program, b *glsl.Block, structs map[string]string) (string, error) {
+	var layoutName string
+	switch {
+	case b.Storage == glsl.StorageUniform && b.MemoryLayout() == "std140":
+		layoutName = "Std140"
+	case b.Storage == glsl.StorageBuffer && b.MemoryLayout() == "std430":
+		layoutName = "Std430"
+	default:
+		g.warnf("%s: %s block %s uses the %s layout; no struct generated", prog.Name, b.Storage, b.Name, b.MemoryLayout())
+		return "", nil
+	}
+	for i, m := range b.Members {
+		if m.ArraySize < 0 && (b.Storage != glsl.StorageBuffer || i != len(b.Members)-1) {
+			return "", fmt.Errorf("block %s: only the last member of a buffer block can be a runtime-sized array", b.Name)
+		}
+		if err := supported(prog, m); err != nil {
+			g.warnf("%s: block %s: %v; no struct generated", prog.Name, b.Name, err)
+			return "", nil
+		}
+	}
+
+	// Nested structs first, in order of use
+	var emit func(members []*glsl.Variable)
+	emit = func(members []*glsl.Variable) {
+		for _, m := range members {
+			def, ok := prog.Structs[m.Type]
+			if !ok || structs[m.Type] != "" {
+				continue
+			}
+			emit(def.Members)
+			typeName := prog.Name + goName(def.Name)
+			structs[m.Type] = typeName
+			g.printf("\n// %s mirrors the GLSL struct %s\n", typeName, def.Name)
+			g.printf("type %s struct {\n", typeName)
+			g.fields(def.Members, structs)
+			g.printf("}\n")
+		}
+	}
+	emit(b.Members)
+
+	kind := "uniform block"
+	lookup := "UniformBlock"
+	if b.Storage == glsl.StorageBuffer {
+		kind = "shader storage block"
+		lookup = "StorageBlock"
+	}
+	typeName := prog.Name + goName(b.Name)
+	g.printf("\n// %s mirrors the %s %s in %s layout\n", typeName, kind, b.Name, b.MemoryLayout())
+	g.printf("type %s struct {\n", typeName)
+	g.fields(b.Members, structs)
+	g.printf("}\n")
+
+	g.imports["github.com/yossideutsch/gogl/pkg/layout"] = true
+	return fmt.Sprintf("if _, ok := p.%s(%q); ok {\nif err := layout.%s.Validate(p, %q, %s{}); err != nil {\nreturn nil, fmt.Errorf(\"%s: %%w\", err)\n}\n}\n",
+		lookup, b.Name, layoutName, b.Name, typeName, prog.Name), nil
+}
+
+// supported reports whether a block member can be encoded by pkg/layout
+func supported(prog *program, m *glsl.Variable) error {
+	if def, ok := prog.Structs[m.Type]; ok {
+		for _, member := range def.Members {
+			if member.ArraySize < 0 {
+				return fmt.Errorf("struct %s contains a runtime-sized array", def.Name)
+			}
+			if err := supported(prog, member); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if t, ok := types[m.Type]; ok && t.block {
+		return nil
+	}
+	return fmt.Errorf("member %s has unsupported type %s", m.Name, m.Type)
+}
+
+// fields emits the struct fields of block or struct members
+func (g *generator) fields(members []*glsl.Variable, structs map[string]string) {
+	for _, m := range members {
+		goType := structs[m.Type]
+		if goType == "" {
+			goType = types[m.Type].goType
+		}
+		if strings.HasPrefix(goType, "mgl32.") {
+			g.imports["github.com/go-gl/mathgl/mgl32"] = true
+		}
+		switch {
+		case m.ArraySize < 0:
+			goType = "[]" + goType
+		case m.ArraySize > 0:
+			goType = fmt.Sprintf("[%d]%s", m.ArraySize, goType)
+		}
+		g.printf("%s %s `glsl:%q`\n", goName(m.Name), goType, m.Name)
+	}
+}
@@ -0,0 +1,374 @@
+// Package lint checks GLSL shaders without an OpenGL context.
+//
+// Files are parsed with pkg/glsl after #include expansion, and findings are
+// reported at their original file and line. Programs, when given, are also
+// checked for matching interfaces between consecutive stages.
+package lint
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/yossideutsch/gogl/pkg/glsl"
+	"github.com/yossideutsch/gogl/pkg/shader"
+)
+
+// Level is the severity of a finding, named as in SARIF
+type Level string
+
+const (
+	LevelError   Level = "error"
+	LevelWarning Level = "warning"
+	LevelNote    Level = "note"
+)
+
+// Rule describes a check
+type Rule struct {
+	ID          string
+	Level       Level
+	Description string
+}
+
+// Rules lists every check in reporting order
+var Rules = []Rule{
+	{"syntax", LevelError, "Shader cannot be preprocessed or parsed"},
+	{"version", LevelError, "#version is missing or exceeds 410 core, the newest version available on macOS"},
+	{"requires-gl43", LevelWarning, "Shader uses compute or storage buffer features that need OpenGL 4.3"},
+	{"interface-mismatch", LevelError, "Stage input has no output of the previous stage with the same name, type and interpolation"},
+	{"unused-uniform", LevelWarning, "Uniform is declared but never used"},
+	{"missing-location", LevelWarning, "Vertex input or fragment output has no layout(location) qualifier"},
+}
+
+// rule returns the rule with the given ID
+func rule(id string) Rule {
+	for _, r := range Rules {
+		if r.ID == id {
+			return r
+		}
+	}
+	panic("lint: unknown rule " + id)
+}
+
+// Finding is a problem at a location in a shader file
+type Finding struct {
+	Rule    string `json:"rule"`
+	Level   Level  `json:"level"`
+	File    string `json:"file"`
+	Line    int    `json:"line"`
+	Column  int    `json:"column"`
+	Message string `json:"message"`
+}
+
+// String formats the finding as file:line:column: level: message [rule]
+func (f Finding) String() string {
+	return fmt.Sprintf("%s:%d:%d: %s: %s [%s]", f.File, f.Line, f.Column, f.Level, f.Message, f.Rule)
+}
+
+// File is a shader file with its stage
+type File struct {
+	Path  string
+	Stage shader.ShaderType
+}
+
+// Program is a set of files linked together, in any order
+type Program struct {
+	Name  string
+	Files []File
+}
+
+// Config selects what to lint
+type Config struct {
+	Files    []File
+	Programs []Program
+
+	// Preprocessor expands #include directives; shader.DefaultPreprocessor
+	// is used when nil
+	Preprocessor *shader.Preprocessor
+}
+
+// MaxVersion is the newest GLSL version accepted without a finding
+const MaxVersion = 410
+
+// unit is a parsed shader file
+type unit struct {
+	file   File
+	source *shader.Source
+	ast    *glsl.Shader // nil when the file failed to parse
+}
+
+// linter accumulates findings
+type linter struct {
+	pp       *shader.Preprocessor
+	units    map[string]*unit
+	findings []Finding
+}
+
+// Lint checks every file and program of cfg and returns the findings
+// sorted by file and position
+func Lint(cfg Config) []Finding {
+	l := &linter{pp: cfg.Preprocessor, units: make(map[string]*unit)}
+	if l.pp == nil {
+		l.pp = shader.DefaultPreprocessor
+	}
+
+	for _, f := range cfg.Files {
+		l.load(f)
+	}
+	for _, p := range cfg.Programs {
+		for _, f := range p.Files {
+			l.load(f)
+		}
+	}
+
+	paths := make([]string, 0, len(l.units))
+	for path := range l.units {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		if u := l.units[path]; u.ast != nil {
+			l.checkVersion(u)
+			l.checkFeatures(u)
+			l.checkLocations(u)
+			l.checkUniforms(u)
+		}
+	}
+	for _, p := range cfg.Programs {
+		l.checkInterfaces(p)
+	}
+
+	sort.SliceStable(l.findings, func(i, j int) bool {
+		a, b := l.findings[i], l.findings[j]
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+
+	// Files included by several units report the same finding once
+	findings := l.findings[:0]
+	for i, f := range l.findings {
+		if i == 0 || f != l.findings[i-1] {
+			findings = append(findings, f)
+		}
+	}
+	return findings
+}
+
+// load preprocesses and parses a file once, reporting failures as findings
+func (l *linter) load(f File) *unit {
+	key := filepath.Clean(f.Path)
+	if u, ok := l.units[key]; ok {
+		return u
+	}
+	u := &unit{file: f}
+	l.units[key] = u
+
+	src, err := l.pp.ProcessFile(f.Path)
+	if err != nil {
+		l.findings = append(l.findings, Finding{Rule: "syntax", Level: LevelError, File: displayPath(f.Path), Line: 1, Column: 1, Message: err.Error()})
+		return u
+	}
+	u.source = src
+
+	ast, err := glsl.Parse(src.Code)
+	if err != nil {
+		var syntax *glsl.Error
+		if !errors.As(err, &syntax) {
+			syntax = &glsl.Error{Pos: glsl.Pos{Line: 1, Column: 1}, Msg: err.Error()}
+		}
+		l.report(u, "syntax", syntax.Pos, "%s", syntax.Msg)
+		return u
+	}
+	u.ast = ast
+	return u
+}
+
+// displayPath returns a slash-separated path for reports
+func displayPath(path string) string {
+	return filepath.ToSlash(filepath.Clean(path))
+}
+
+// report adds a finding at a position of the expanded source of u
+func (l *linter) report(u *unit, id string, pos glsl.Pos, format string, args ...interface{}) {
+	r := rule(id)
+	f := Finding{Rule: r.ID, Level: r.Level, File: displayPath(u.file.Path), Line: pos.Line, Column: pos.Column, Message: fmt.Sprintf(format, args...)}
+	if u.source != nil {
+		if loc, ok := u.source.Map.Resolve(pos.Line); ok {
+			f.File, f.Line = displayPath(loc.File), loc.Line
+		}
+	}
+	if f.Line < 1 {
+		f.Line, f.Column = 1, 1
+	}
+	l.findings = append(l.findings, f)
+}
+
+// checkVersion flags missing, too new and non-core #version directives
+func (l *linter) checkVersion(u *unit) {
+	v := u.ast.Version
+	switch {
+	case v == nil:
+		l.report(u, "version", glsl.Pos{Line: 1, Column: 1}, "missing #version; use #version %d core", MaxVersion)
+	case v.Profile != "" && v.Profile != "core":
+		l.report(u, "version", v.Pos, "#version %d %s is not a core profile", v.Number, v.Profile)
+	case v.Number > MaxVersion && u.file.Stage != shader.ComputeShader:
+		l.report(u, "version", v.Pos, "#version %d exceeds %d core, the newest version available on macOS", v.Number, MaxVersion)
+	}
+}
+
+// checkFeatures flags features that need OpenGL 4.3
+func (l *linter) checkFeatures(u *unit) {
+	if u.file.Stage == shader.ComputeShader {
+		pos := glsl.Pos{Line: 1, Column: 1}
+		if u.ast.Version != nil {
+			pos = u.ast.Version.Pos
+		}
+		l.report(u, "requires-gl43", pos, "compute shaders need OpenGL 4.3, which macOS does not provide")
+		return
+	}
+	for _, b := range u.ast.Blocks {
+		if b.Storage == glsl.StorageBuffer {
+			l.report(u, "requires-gl43", b.Pos, "shader storage block %s needs OpenGL 4.3", b.Name)
+		}
+	}
+}
+
+// checkLocations flags vertex inputs, and fragment outputs when there are
+// several, without an explicit location
+func (l *linter) checkLocations(u *unit) {
+	var vars []*glsl.Variable
+	kind := ""
+	switch u.file.Stage {
+	case shader.VertexShader:
+		vars, kind = u.ast.Inputs(), "vertex input"
+	case shader.FragmentShader:
+		if outputs := u.ast.Outputs(); len(outputs) > 1 {
+			vars, kind = outputs, "fragment output"
+		}
+	}
+	for _, v := range vars {
+		if _, ok := v.Location(); !ok {
+			l.report(u, "missing-location", v.Pos, "%s %s has no layout(location = N); its location is chosen by the linker", kind, v.Name)
+		}
+	}
+}
+
+// checkUniforms flags default-block uniforms that are never referenced
+func (l *linter) checkUniforms(u *unit) {
+	for _, v := range u.ast.Uniforms() {
+		if len(u.ast.References(v.Name)) == 0 {
+			l.report(u, "unused-uniform", v.Pos, "uniform %s is declared but never used", v.Name)
+		}
+	}
+}
+
+// pipelineOrder lists graphics stages in the order data flows between them
+var pipelineOrder = []shader.ShaderType{
+	shader.VertexShader,
+	shader.TessControlShader,
+	shader.TessEvaluationShader,
+	shader.GeometryShader,
+	shader.FragmentShader,
+}
+
+// arrayedInputs are stages whose inputs are per-vertex arrays
+var arrayedInputs = map[shader.ShaderType]bool{
+	shader.TessControlShader:    true,
+	shader.TessEvaluationShader: true,
+	shader.GeometryShader:       true,
+}
+
+// checkInterfaces matches the inputs of each stage to the outputs of the previous one
+func (l *linter) checkInterfaces(p Program) {
+	stages := make(map[shader.ShaderType]*unit)
+	for _, f := range p.Files {
+		stages[f.Stage] = l.load(f)
+	}
+
+	var prev *unit
+	for _, stage := range pipelineOrder {
+		u, ok := stages[stage]
+		if !ok {
+			continue
+		}
+		if prev != nil && prev.ast != nil && u.ast != nil {
+			l.matchStages(prev, u)
+		}
+		prev = u
+	}
+}
+
+// elementType returns the type of a variable as seen across the interface,
+// dropping the per-vertex array dimension of arrayed stages
+func elementType(v *glsl.Variable, arrayed bool) string {
+	if arrayed && v.ArraySize != 0 {
+		return v.Type
+	}
+	return v.TypeString()
+}
+
+// matchStages checks the inputs of consumer against the outputs of producer
+func (l *linter) matchStages(producer, consumer *unit) {
+	from, to := producer.file.Stage, consumer.file.Stage
+	outputs := producer.ast.Outputs()
+
+	for _, in := range consumer.ast.Inputs() {
+		if strings.HasPrefix(in.Name, "gl_") {
+			continue
+		}
+		var out *glsl.Variable
+		if loc, ok := in.Location(); ok {
+			for _, o := range outputs {
+				if oloc, ok := o.Location(); ok && oloc == loc {
+					out = o
+				}
+			}
+		}
+		if out == nil {
+			for _, o := range outputs {
+				if o.Name == in.Name {
+					out = o
+				}
+			}
+		}
+		if out == nil {
+			l.report(consumer, "interface-mismatch", in.Pos, "%s input %s has no matching %s output in %s", to, in.Name, from, displayPath(producer.file.Path))
+			continue
+		}
+
+		inType, outType := elementType(in, arrayedInputs[to]), elementType(out, from == shader.TessControlShader)
+		if inType != outType {
+			l.report(consumer, "interface-mismatch", in.Pos, "%s input %s is %s but the %s output is %s", to, in.Name, inType, from, outType)
+		}
+		if in.Interpolation() != out.Interpolation() {
+			l.report(consumer, "interface-mismatch", in.Pos, "%s input %s is %s but the %s output is %s", to, in.Name, in.Interpolation(), from, out.Interpolation())
+		}
+	}
+
+	for _, in := range consumer.ast.Blocks {
+		if in.Storage != glsl.StorageIn || strings.HasPrefix(in.Name, "gl_") {
+			continue
+		}
+		out, ok := producer.ast.Block(in.Name)
+		if !ok || out.Storage != glsl.StorageOut {
+			l.report(consumer, "interface-mismatch", in.Pos, "%s input block %s has no matching %s output block", to, in.Name, from)
+			continue
+		}
+		for _, m := range in.Members {
+			om, ok := out.Member(m.Name)
+			switch {
+			case !ok:
+				l.report(consumer, "interface-mismatch", m.Pos, "member %s of input block %s is missing from the %s output block", m.Name, in.Name, from)
+			case om.TypeString() != m.TypeString():
+				l.report(consumer, "interface-mismatch", m.Pos, "member %s of block %s is %s but the %s output is %s", m.Name, in.Name, m.TypeString(), from, om.TypeString())
+			}
+		}
+	}
+}
@@ -110,6 +110,8 @@ type SystemInfo struct {
 	Vendor          GPUVendor
 	VendorString    string
 	RendererString  string
+	VersionString   string // Full GL_VERSION string, including the driver version
+	ES              bool   // OpenGL ES context, versions are ES versions
 	Capabilities    Capabilities
 	
 	// Platform-specific notes
@@ -153,6 +155,8 @@ func (d *Detector) Detect() (*SystemInfo, error) {
 	// Get vendor and renderer
 	info.VendorString = gl.GoStr(gl.GetString(gl.VENDOR))
 	info.RendererString = gl.GoStr(gl.GetString(gl.RENDERER))
+	info.VersionString = gl.GoStr(gl.GetString(gl.VERSION))
+	info.ES = strings.HasPrefix(info.VersionString, "OpenGL ES")
 	info.Vendor = d.detectVendor(info.VendorString, info.RendererString)
 
 	// Query capabilities
@@ -181,8 +185,8 @@ func (d *Detector) detectPlatform() Platform {
 func (d *Detector) detectOpenGLVersion() (OpenGLVersion, error) {
 	versionStr := gl.GoStr(gl.GetString(gl.VERSION))
 	
-	// Parse version string (e.g., "4.1 Metal - 89.4" or "4.6.0")
-	parts := strings.Fields(versionStr)
+	// Parse version string (e.g., "4.1 Metal - 89.4", "4.6.0" or "OpenGL ES 3.0 Mesa 22.3.6")
+	parts := strings.Fields(strings.TrimPrefix(versionStr, "OpenGL ES "))
 	if len(parts) == 0 {
 		return OpenGLVersion{}, fmt.Errorf("empty version string")
 	}
@@ -217,8 +221,8 @@ func (d *Detector) detectOpenGLVersion() (OpenGLVersion, error) {
 func (d *Detector) detectGLSLVersion() (OpenGLVersion, error) {
 	versionStr := gl.GoStr(gl.GetString(gl.SHADING_LANGUAGE_VERSION))
 	
-	// Parse GLSL version (e.g., "4.10" or "4.60")
-	parts := strings.Fields(versionStr)
+	// Parse GLSL version (e.g., "4.10", "4.60" or "OpenGL ES GLSL ES 3.00")
+	parts := strings.Fields(strings.TrimPrefix(versionStr, "OpenGL ES GLSL ES "))
 	if len(parts) == 0 {
 		return OpenGLVersion{}, fmt.Errorf("empty GLSL version string")
 	}
@@ -332,12 +336,16 @@ func (d *Detector) generateNotes(info *SystemInfo) []string {
 		notes = append(notes, "Consider using Metal for production applications on macOS")
 	}
 
+	if info.ES {
+		notes = append(notes, fmt.Sprintf("OpenGL ES context: shaders are translated to GLSL %d%02d es", info.GLSLVersion.Major, info.GLSLVersion.Minor))
+	}
+
 	// Version-specific warnings
-	if info.OpenGLVersion.Compare(OpenGLVersion{3, 3}) < 0 {
+	if !info.ES && info.OpenGLVersion.Compare(OpenGLVersion{3, 3}) < 0 {
 		notes = append(notes, "WARNING: OpenGL version is quite old, consider updating drivers")
 	}
 
-	if info.OpenGLVersion.Compare(OpenGLVersion{4, 0}) < 0 {
+	if !info.ES && info.OpenGLVersion.Compare(OpenGLVersion{4, 0}) < 0 {
 		notes = append(notes, "Some modern features may not be available")
 	}
 
@@ -408,6 +416,7 @@ func (d *Detector) PrintInfo() {
 	fmt.Printf("GLSL Version: %s\n", info.GLSLVersion)
 	fmt.Printf("Vendor: %s (%s)\n", info.Vendor, info.VendorString)
 	fmt.Printf("Renderer: %s\n", info.RendererString)
+	fmt.Printf("Driver Version: %s\n", info.VersionString)
 	
 	fmt.Println("\n=== Capabilities ===")
 	fmt.Printf("Max Texture Size: %d\n", info.Capabilities.MaxTextureSize)
@@ -0,0 +1,93 @@
+package shaderinfo
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+// WriteJSON writes the report as indented JSON
+func WriteJSON(w io.Writer, r *Report) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// WriteText writes the report as aligned sections, leaving out empty ones
+func WriteText(w io.Writer, r *Report) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+
+	for _, s := range r.Stages {
+		fmt.Fprintf(tw, "%s\t%s\n", s.Stage, s.Path)
+	}
+	if r.BinarySize > 0 {
+		fmt.Fprintf(tw, "binary\t%d bytes (format 0x%x)\n", r.BinarySize, r.BinaryFormat)
+	} else {
+		fmt.Fprintf(tw, "binary\tnot available\n")
+	}
+	if r.WorkGroupSize != nil {
+		fmt.Fprintf(tw, "work group size\t%d x %d x %d\n", r.WorkGroupSize[0], r.WorkGroupSize[1], r.WorkGroupSize[2])
+	}
+
+	if len(r.Uniforms) > 0 {
+		fmt.Fprintf(tw, "\nUniforms (%d):\n", len(r.Uniforms))
+		for _, u := range r.Uniforms {
+			fmt.Fprintf(tw, "  %s\t%s\tlocation %d\n", u.Name, typeString(u.Type, u.Size), u.Location)
+		}
+	}
+	if len(r.Inactive) > 0 {
+		fmt.Fprintf(tw, "\nInactive, optimized away by the driver (%d):\n", len(r.Inactive))
+		for _, d := range r.Inactive {
+			kind := d.Type
+			if kind == "" {
+				kind = d.Kind
+			}
+			fmt.Fprintf(tw, "  %s\t%s\t%s:%d\n", d.Name, kind, d.File, d.Line)
+		}
+	}
+	if len(r.Attributes) > 0 {
+		fmt.Fprintf(tw, "\nAttributes (%d):\n", len(r.Attributes))
+		for _, a := range r.Attributes {
+			fmt.Fprintf(tw, "  %s\t%s\tlocation %d\n", a.Name, typeString(a.Type, a.Size), a.Location)
+		}
+	}
+	writeBlocks(tw, "Uniform blocks", r.UniformBlocks)
+	writeBlocks(tw, "Storage blocks", r.StorageBlocks)
+	if len(r.Subroutines) > 0 {
+		fmt.Fprintf(tw, "\nSubroutine uniforms (%d):\n", len(r.Subroutines))
+		for _, s := range r.Subroutines {
+			fmt.Fprintf(tw, "  %s\t%s\tlocation %d\t%s\n", s.Name, s.Stage, s.Location, strings.Join(s.Compatible, ", "))
+		}
+	}
+	if len(r.Notes) > 0 {
+		fmt.Fprintln(tw)
+		for _, note := range r.Notes {
+			fmt.Fprintf(tw, "note: %s\n", note)
+		}
+	}
+	return tw.Flush()
+}
+
+// writeBlocks writes a section of blocks and their members
+func writeBlocks(w io.Writer, title string, blocks []Block) {
+	if len(blocks) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "\n%s (%d):\n", title, len(blocks))
+	for _, b := range blocks {
+		fmt.Fprintf(w, "  %s\tbinding %d\t%d bytes\n", b.Name, b.Binding, b.DataSize)
+		for _, m := range b.Members {
+			fmt.Fprintf(w, "    %s\t%s\toffset %d\n", m.Name, typeString(m.Type, m.Size), m.Offset)
+		}
+	}
+}
+
+// typeString appends the array size to array types
+func typeString(typ string, size int32) string {
+	if size > 1 {
+		return fmt.Sprintf("%s[%d]", typ, size)
+	}
+	return typ
+}
@@ -0,0 +1,241 @@
+// Package shaderinfo describes the interface of a linked program as the
+// driver reflects it, for gogl-shaderinfo.
+//
+// Besides the active uniforms, attributes, blocks and subroutines, the
+// report lists uniforms, uniform blocks and subroutine uniforms that are
+// declared in the source but not active. That is what a uniform which
+// "does nothing" usually is: the driver removed it because it does not
+// contribute to any output.
+package shaderinfo
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/yossideutsch/gogl/pkg/glsl"
+	"github.com/yossideutsch/gogl/pkg/shader"
+)
+
+// Stage is a shader file of the inspected program
+type Stage struct {
+	Stage  shader.ShaderType
+	Path   string
+	Source *shader.Source // Preprocessed source, used to find inactive declarations
+}
+
+// Report is the reflected interface of a program
+type Report struct {
+	Stages        []StageFile       `json:"stages"`
+	Uniforms      []Uniform         `json:"uniforms"`
+	Inactive      []Declaration     `json:"inactive"`
+	Attributes    []Attribute       `json:"attributes"`
+	UniformBlocks []Block           `json:"uniformBlocks"`
+	StorageBlocks []Block           `json:"storageBlocks,omitempty"`
+	Subroutines   []StageSubroutine `json:"subroutines,omitempty"`
+	WorkGroupSize *[3]int32         `json:"workGroupSize,omitempty"` // Compute programs only
+	BinarySize    int               `json:"binarySize"`              // 0 when the driver keeps no binary
+	BinaryFormat  uint32            `json:"binaryFormat,omitempty"`
+	Notes         []string          `json:"notes,omitempty"` // Stages whose declarations could not be checked
+}
+
+// StageFile is a stage and the file it was compiled from
+type StageFile struct {
+	Stage string `json:"stage"`
+	Path  string `json:"path"`
+}
+
+// Uniform is an active default-block uniform
+type Uniform struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Size     int32  `json:"size"`
+	Location int32  `json:"location"`
+}
+
+// Declaration is a uniform, uniform block or subroutine uniform declared in
+// a shader file but not active in the linked program
+type Declaration struct {
+	Name string `json:"name"`
+	Kind string `json:"kind"` // "uniform", "uniform block" or "subroutine uniform"
+	Type string `json:"type,omitempty"`
+	File string `json:"file"`
+	Line int    `json:"line"`
+}
+
+// Attribute is an active vertex attribute
+type Attribute struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Size     int32  `json:"size"`
+	Location int32  `json:"location"`
+}
+
+// Block is an active uniform or shader storage block
+type Block struct {
+	Name     string   `json:"name"`
+	Binding  uint32   `json:"binding"`
+	DataSize int32    `json:"dataSize"`
+	Members  []Member `json:"members"`
+}
+
+// Member is a member of a block
+type Member struct {
+	Name   string `json:"name"`
+	Type   string `json:"type"`
+	Size   int32  `json:"size"`
+	Offset int32  `json:"offset"`
+}
+
+// StageSubroutine is an active subroutine uniform of a stage
+type StageSubroutine struct {
+	Stage      string   `json:"stage"`
+	Name       string   `json:"name"`
+	Location   int32    `json:"location"`
+	Size       int32    `json:"size"`
+	Compatible []string `json:"compatible"`
+}
+
+// Inspect reports the interface of a linked program. Stages are the files
+// it was linked from; Inactive is empty without them.
+func Inspect(program *shader.Program, stages []Stage) *Report {
+	r := &Report{
+		Uniforms:      []Uniform{},
+		Inactive:      []Declaration{},
+		Attributes:    []Attribute{},
+		UniformBlocks: []Block{},
+	}
+
+	for _, s := range stages {
+		r.Stages = append(r.Stages, StageFile{Stage: s.Stage.String(), Path: s.Path})
+	}
+
+	for _, u := range program.Uniforms() {
+		if u.BlockIndex != -1 {
+			continue
+		}
+		r.Uniforms = append(r.Uniforms, Uniform{Name: u.Name, Type: u.Type.String(), Size: u.Size, Location: u.Location})
+	}
+	sort.Slice(r.Uniforms, func(i, j int) bool { return r.Uniforms[i].Location < r.Uniforms[j].Location })
+
+	for _, a := range program.Attributes() {
+		r.Attributes = append(r.Attributes, Attribute{Name: a.Name, Type: a.Type.String(), Size: a.Size, Location: a.Location})
+	}
+	sort.Slice(r.Attributes, func(i, j int) bool { return r.Attributes[i].Location < r.Attributes[j].Location })
+
+	for _, b := range program.UniformBlocks() {
+		block := Block{Name: b.Name, Binding: b.Binding, DataSize: b.DataSize, Members: []Member{}}
+		for _, m := range b.Members {
+			block.Members = append(block.Members, Member{Name: m.Name, Type: m.Type.String(), Size: m.Size, Offset: m.Offset})
+		}
+		r.UniformBlocks = append(r.UniformBlocks, block)
+	}
+	for _, b := range program.StorageBlocks() {
+		block := Block{Name: b.Name, Binding: b.Binding, DataSize: b.DataSize, Members: []Member{}}
+		for _, m := range b.Members {
+			block.Members = append(block.Members, Member{Name: m.Name, Type: m.Type.String(), Size: m.Size, Offset: m.Offset})
+		}
+		r.StorageBlocks = append(r.StorageBlocks, block)
+	}
+
+	for _, stage := range program.Stages() {
+		for _, u := range program.SubroutineUniforms(stage) {
+			r.Subroutines = append(r.Subroutines, StageSubroutine{
+				Stage:      stage.String(),
+				Name:       u.Name,
+				Location:   u.Location,
+				Size:       u.Size,
+				Compatible: u.Compatible,
+			})
+		}
+	}
+
+	if program.HasStage(shader.ComputeShader) {
+		x, y, z := program.GetWorkGroupSize()
+		r.WorkGroupSize = &[3]int32{x, y, z}
+	}
+
+	if format, data, err := program.Binary(); err == nil {
+		r.BinarySize = len(data)
+		r.BinaryFormat = format
+	}
+
+	r.inactive(program, stages)
+	return r
+}
+
+// inactive adds the uniforms, uniform blocks and subroutine uniforms
+// declared in the stage sources that the program does not report as active
+func (r *Report) inactive(program *shader.Program, stages []Stage) {
+	seen := make(map[string]bool)
+	for _, s := range stages {
+		if s.Source == nil {
+			continue
+		}
+		ast, err := glsl.Parse(s.Source.Code)
+		if err != nil {
+			// pkg/glsl does not parse everything drivers accept
+			r.Notes = append(r.Notes, fmt.Sprintf("inactive declarations of %s were not checked: %v", s.Path, err))
+			continue
+		}
+
+		for _, v := range ast.Uniforms() {
+			if seen[v.Name] || uniformActive(program, v.Name) {
+				continue
+			}
+			seen[v.Name] = true
+			r.Inactive = append(r.Inactive, declaration(s, v.Name, "uniform", v.TypeString(), v.Pos.Line))
+		}
+		for _, b := range ast.Blocks {
+			if b.Storage != glsl.StorageUniform || seen[b.Name] {
+				continue
+			}
+			if _, ok := program.UniformBlock(b.Name); ok {
+				continue
+			}
+			seen[b.Name] = true
+			r.Inactive = append(r.Inactive, declaration(s, b.Name, "uniform block", "", b.Pos.Line))
+		}
+		for _, v := range ast.SubroutineUniforms {
+			if subroutineActive(program, s.Stage, v.Name) {
+				continue
+			}
+			r.Inactive = append(r.Inactive, declaration(s, v.Name, "subroutine uniform", v.TypeString(), v.Pos.Line))
+		}
+	}
+}
+
+// uniformActive reports whether a declared uniform, or any member or
+// element of it, is active
+func uniformActive(program *shader.Program, name string) bool {
+	if _, ok := program.Uniform(name); ok {
+		return true
+	}
+	for _, u := range program.Uniforms() {
+		if strings.HasPrefix(u.Name, name+".") || strings.HasPrefix(u.Name, name+"[") {
+			return true
+		}
+	}
+	return false
+}
+
+// subroutineActive reports whether a declared subroutine uniform is active
+// in a stage
+func subroutineActive(program *shader.Program, stage shader.ShaderType, name string) bool {
+	for _, u := range program.SubroutineUniforms(stage) {
+		if u.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// declaration builds a Declaration at the original file and line of a
+// line of the preprocessed source
+func declaration(s Stage, name, kind, typ string, line int) Declaration {
+	d := Declaration{Name: name, Kind: kind, Type: typ, File: s.Path, Line: line}
+	if loc, ok := s.Source.Map.Resolve(line); ok {
+		d.File, d.Line = loc.File, loc.Line
+	}
+	return d
+}
@@ -0,0 +1,352 @@
+package glsl
+
+import "strconv"
+
+// Storage is the storage qualifier of a variable or block
+type Storage int
+
+const (
+	StorageNone Storage = iota // Plain globals and locals
+	StorageConst
+	StorageIn
+	StorageOut
+	StorageInOut // Function parameters only
+	StorageUniform
+	StorageBuffer
+	StorageShared
+)
+
+// String returns the GLSL keyword of the storage qualifier
+func (s Storage) String() string {
+	switch s {
+	case StorageConst:
+		return "const"
+	case StorageIn:
+		return "in"
+	case StorageOut:
+		return "out"
+	case StorageInOut:
+		return "inout"
+	case StorageUniform:
+		return "uniform"
+	case StorageBuffer:
+		return "buffer"
+	case StorageShared:
+		return "shared"
+	default:
+		return ""
+	}
+}
+
+// LayoutQualifier is one entry of a layout(...) qualifier
+type LayoutQualifier struct {
+	Name  string
+	Value string // Empty for qualifiers without "= value"
+	Pos   Pos
+}
+
+// Layout is a layout(...) qualifier list; later entries override earlier ones
+type Layout []LayoutQualifier
+
+// Has reports whether the layout contains the named qualifier
+func (l Layout) Has(name string) bool {
+	_, ok := l.Value(name)
+	return ok
+}
+
+// Value returns the value of the named qualifier
+func (l Layout) Value(name string) (string, bool) {
+	for i := len(l) - 1; i >= 0; i-- {
+		if l[i].Name == name {
+			return l[i].Value, true
+		}
+	}
+	return "", false
+}
+
+// Int returns the integer value of the named qualifier
+func (l Layout) Int(name string) (int, bool) {
+	value, ok := l.Value(name)
+	if !ok {
+		return 0, false
+	}
+	n, err := parseInt(value)
+	if err != nil {
+		return 0, false
+	}
+	return int(n), true
+}
+
+// Variable is a global variable, block or struct member, or function parameter
+type Variable struct {
+	Name      string
+	Type      string // Type name, e.g. "vec3" or a struct name
+	ArraySize int    // 0 for non-arrays, -1 for unsized arrays
+	Storage   Storage
+	Layout    Layout
+
+	// Other qualifiers in source order: interpolation (flat, smooth,
+	// noperspective), auxiliary (centroid, sample, patch), precision,
+	// invariant, precise and memory qualifiers
+	Qualifiers []string
+
+	Init []Token // Initializer tokens, nil without initializer
+	Pos  Pos     // Position of the name
+}
+
+// IsArray reports whether the variable is an array
+func (v *Variable) IsArray() bool {
+	return v.ArraySize != 0
+}
+
+// Location returns the layout(location = N) of the variable
+func (v *Variable) Location() (int, bool) {
+	return v.Layout.Int("location")
+}
+
+// HasQualifier reports whether the variable was declared with a qualifier
+func (v *Variable) HasQualifier(name string) bool {
+	for _, q := range v.Qualifiers {
+		if q == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Interpolation returns the interpolation qualifier, "smooth" when unspecified
+func (v *Variable) Interpolation() string {
+	for _, q := range v.Qualifiers {
+		if q == "flat" || q == "smooth" || q == "noperspective" {
+			return q
+		}
+	}
+	return "smooth"
+}
+
+// TypeString returns the type including the array suffix, e.g. "vec4[3]"
+func (v *Variable) TypeString() string {
+	switch {
+	case v.ArraySize < 0:
+		return v.Type + "[]"
+	case v.ArraySize > 0:
+		return v.Type + "[" + strconv.Itoa(v.ArraySize) + "]"
+	}
+	return v.Type
+}
+
+// Block is an interface block: a uniform or shader storage block, or an
+// in/out block between stages
+type Block struct {
+	Storage    Storage
+	Name       string
+	Instance   string // Instance name, empty for anonymous blocks
+	ArraySize  int    // Instance array size, as for Variable
+	Layout     Layout
+	Qualifiers []string
+	Members    []*Variable
+	Pos        Pos // Position of the block name
+}
+
+// Member returns the member with the given name
+func (b *Block) Member(name string) (*Variable, bool) {
+	for _, m := range b.Members {
+		if m.Name == name {
+			return m, true
+		}
+	}
+	return nil, false
+}
+
+// MemoryLayout returns the memory layout of a uniform or storage block:
+// std140, std430, shared or packed. Uniform blocks default to shared and
+// storage blocks to std430, as this package cannot see enclosing
+// default layouts declared with "layout(std140) uniform;".
+func (b *Block) MemoryLayout() string {
+	for i := len(b.Layout) - 1; i >= 0; i-- {
+		switch name := b.Layout[i].Name; name {
+		case "std140", "std430", "shared", "packed":
+			return name
+		}
+	}
+	if b.Storage == StorageBuffer {
+		return "std430"
+	}
+	return "shared"
+}
+
+// Struct is a struct type declaration
+type Struct struct {
+	Name    string
+	Members []*Variable
+	Pos     Pos
+}
+
+// Function is a function definition or prototype
+type Function struct {
+	Name       string
+	ReturnType string
+	Params     []*Variable // Storage is StorageIn, StorageOut or StorageInOut
+	Prototype  bool        // Declared without a body
+	Body       []Token     // Tokens between the braces of the body
+
+	// Subroutines lists the subroutine types the function implements, as
+	// in "subroutine(Shade, Tint) vec3 f(vec3 c) { ... }"
+	Subroutines []string
+	Pos         Pos
+}
+
+// LayoutDecl is a default layout declaration without a variable, such as
+// "layout(local_size_x = 16) in;" or "layout(triangle_strip, max_vertices = 4) out;"
+type LayoutDecl struct {
+	Storage Storage
+	Layout  Layout
+	Pos     Pos
+}
+
+// Precision is a default precision statement such as "precision highp float;"
+type Precision struct {
+	Qualifier string
+	Type      string
+	Pos       Pos
+}
+
+// Version is the #version directive
+type Version struct {
+	Number  int
+	Profile string // "core", "compatibility", "es" or empty
+	Pos     Pos
+}
+
+// Extension is an #extension directive
+type Extension struct {
+	Name     string
+	Behavior string // require, enable, warn or disable
+	Pos      Pos
+}
+
+// Shader is the global structure of a GLSL translation unit
+type Shader struct {
+	Version    *Version // nil without #version
+	Extensions []*Extension
+	Directives []*Directive // Directives in active sections, in source order
+
+	Structs    []*Struct
+	Variables  []*Variable // Global variables outside blocks
+	Blocks     []*Block
+	Functions  []*Function
+	Layouts    []*LayoutDecl
+	Precisions []*Precision
+
+	SubroutineTypes    []*Function // Subroutine type declarations, such as "subroutine vec3 Shade(vec3 c);"
+	SubroutineUniforms []*Variable // Subroutine uniforms, not part of Variables; Type is the subroutine type
+
+	Tokens []Token // All code tokens of active sections, ending with EOF
+}
+
+// filter returns the global variables with the given storage
+func (s *Shader) filter(storage Storage) []*Variable {
+	var vars []*Variable
+	for _, v := range s.Variables {
+		if v.Storage == storage {
+			vars = append(vars, v)
+		}
+	}
+	return vars
+}
+
+// Inputs returns the global in variables, excluding in blocks
+func (s *Shader) Inputs() []*Variable {
+	return s.filter(StorageIn)
+}
+
+// Outputs returns the global out variables, excluding out blocks
+func (s *Shader) Outputs() []*Variable {
+	return s.filter(StorageOut)
+}
+
+// Uniforms returns the uniforms of the default uniform block
+func (s *Shader) Uniforms() []*Variable {
+	return s.filter(StorageUniform)
+}
+
+// Variable returns the global variable with the given name
+func (s *Shader) Variable(name string) (*Variable, bool) {
+	for _, v := range s.Variables {
+		if v.Name == name {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+// Block returns the block with the given block name
+func (s *Shader) Block(name string) (*Block, bool) {
+	for _, b := range s.Blocks {
+		if b.Name == name {
+			return b, true
+		}
+	}
+	return nil, false
+}
+
+// Struct returns the struct type with the given name
+func (s *Shader) Struct(name string) (*Struct, bool) {
+	for _, st := range s.Structs {
+		if st.Name == name {
+			return st, true
+		}
+	}
+	return nil, false
+}
+
+// Function returns the first function definition with the given name,
+// falling back to a prototype
+func (s *Shader) Function(name string) (*Function, bool) {
+	var proto *Function
+	for _, f := range s.Functions {
+		if f.Name != name {
+			continue
+		}
+		if !f.Prototype {
+			return f, true
+		}
+		if proto == nil {
+			proto = f
+		}
+	}
+	return proto, proto != nil
+}
+
+// Layout returns the merged default layout declared for a storage
+// qualifier, e.g. the local size of a compute shader for StorageIn
+func (s *Shader) Layout(storage Storage) Layout {
+	var layout Layout
+	for _, decl := range s.Layouts {
+		if decl.Storage == storage {
+			layout = append(layout, decl.Layout...)
+		}
+	}
+	return layout
+}
+
+// References returns the positions where an identifier is used in
+// function bodies and global initializers. Struct member selections
+// (".name") are not references to a global of the same name.
+func (s *Shader) References(name string) []Pos {
+	var refs []Pos
+	scan := func(tokens []Token) {
+		for i, tok := range tokens {
+			if tok.Kind == Ident && tok.Text == name && (i == 0 || tokens[i-1].Text != ".") {
+				refs = append(refs, tok.Pos)
+			}
+		}
+	}
+	for _, v := range s.Variables {
+		scan(v.Init)
+	}
+	for _, f := range s.Functions {
+		scan(f.Body)
+	}
+	return refs
+}
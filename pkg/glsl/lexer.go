@@ -0,0 +1,621 @@
+package glsl
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Pos is a position in GLSL source
+type Pos struct {
+	Offset int // Byte offset, 0-based
+	Line   int // 1-based
+	Column int // 1-based, in bytes
+}
+
+// String formats the position as line:column
+func (p Pos) String() string {
+	return fmt.Sprintf("%d:%d", p.Line, p.Column)
+}
+
+// TokenKind classifies tokens
+type TokenKind int
+
+const (
+	EOF TokenKind = iota
+	Ident
+	IntLit
+	FloatLit
+	Punct
+)
+
+// String returns the name of the token kind
+func (k TokenKind) String() string {
+	switch k {
+	case EOF:
+		return "end of file"
+	case Ident:
+		return "identifier"
+	case IntLit:
+		return "integer"
+	case FloatLit:
+		return "float"
+	case Punct:
+		return "punctuation"
+	default:
+		return "unknown"
+	}
+}
+
+// Token is a lexical token of GLSL code outside preprocessor directives
+type Token struct {
+	Kind TokenKind
+	Text string
+	Pos  Pos
+}
+
+// End returns the byte offset just past the token
+func (t Token) End() int {
+	return t.Pos.Offset + len(t.Text)
+}
+
+// Directive is a preprocessor line in an active conditional section
+type Directive struct {
+	Name string // Directive name without "#", e.g. "version" or "define"
+	Args string // Remaining text with comments removed and continuations joined
+	Pos  Pos
+	End  int // Byte offset of the end of the line, excluding the newline
+}
+
+// Error is a syntax error at a position in the source
+type Error struct {
+	Name string // Source name from Options, may be empty
+	Pos  Pos
+	Msg  string
+}
+
+func (e *Error) Error() string {
+	if e.Name != "" {
+		return fmt.Sprintf("%s:%d:%d: %s", e.Name, e.Pos.Line, e.Pos.Column, e.Msg)
+	}
+	return fmt.Sprintf("%d:%d: %s", e.Pos.Line, e.Pos.Column, e.Msg)
+}
+
+// operators lists multi-character punctuation, longest first
+var operators = []string{
+	"<<=", ">>=",
+	"++", "--", "<<", ">>", "<=", ">=", "==", "!=", "&&", "||", "^^",
+	"+=", "-=", "*=", "/=", "%=", "&=", "^=", "|=",
+}
+
+// conditional is an open #if section
+type conditional struct {
+	parentActive bool // Whether the enclosing section is active
+	taken        bool // Whether a branch of this section was taken
+	active       bool // Whether the current branch is active
+	pos          Pos
+}
+
+// lexer tokenizes GLSL source and evaluates conditional directives
+type lexer struct {
+	name    string
+	src     string
+	offset  int
+	line    int
+	column  int
+	defines map[string]string
+
+	tokens     []Token
+	directives []*Directive
+	conds      []conditional
+	lineStart  bool
+}
+
+func (l *lexer) pos() Pos {
+	return Pos{Offset: l.offset, Line: l.line, Column: l.column}
+}
+
+func (l *lexer) errorf(pos Pos, format string, args ...interface{}) error {
+	return &Error{Name: l.name, Pos: pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+// active reports whether code at the current position is compiled
+func (l *lexer) active() bool {
+	return len(l.conds) == 0 || l.conds[len(l.conds)-1].active
+}
+
+// advance moves past n bytes, tracking lines and columns
+func (l *lexer) advance(n int) {
+	for i := 0; i < n && l.offset < len(l.src); i++ {
+		if l.src[l.offset] == '\n' {
+			l.line++
+			l.column = 1
+		} else {
+			l.column++
+		}
+		l.offset++
+	}
+}
+
+// lex tokenizes the whole source
+func (l *lexer) lex() error {
+	l.line, l.column, l.lineStart = 1, 1, true
+	for l.offset < len(l.src) {
+		c := l.src[l.offset]
+		rest := l.src[l.offset:]
+		switch {
+		case c == '\n':
+			l.advance(1)
+			l.lineStart = true
+		case c == ' ' || c == '\t' || c == '\r' || c == '\f' || c == '\v':
+			l.advance(1)
+		case c == '\\' && (strings.HasPrefix(rest, "\\\n") || strings.HasPrefix(rest, "\\\r\n")):
+			l.advance(strings.IndexByte(rest, '\n') + 1)
+		case strings.HasPrefix(rest, "//"):
+			l.skipLineComment()
+		case strings.HasPrefix(rest, "/*"):
+			if err := l.skipBlockComment(); err != nil {
+				return err
+			}
+		case c == '#' && l.lineStart:
+			if err := l.directive(); err != nil {
+				return err
+			}
+		default:
+			l.lineStart = false
+			tok, err := l.token()
+			if err != nil {
+				if !l.active() {
+					l.advance(1) // Skipped sections need not be valid GLSL
+					continue
+				}
+				return err
+			}
+			if l.active() {
+				l.tokens = append(l.tokens, tok)
+			}
+		}
+	}
+	if len(l.conds) > 0 {
+		return l.errorf(l.conds[len(l.conds)-1].pos, "unterminated #if")
+	}
+	l.tokens = append(l.tokens, Token{Kind: EOF, Pos: l.pos()})
+	return nil
+}
+
+func (l *lexer) skipLineComment() {
+	for l.offset < len(l.src) && l.src[l.offset] != '\n' {
+		if strings.HasPrefix(l.src[l.offset:], "\\\n") {
+			l.advance(2)
+			continue
+		}
+		l.advance(1)
+	}
+}
+
+func (l *lexer) skipBlockComment() error {
+	start := l.pos()
+	end := strings.Index(l.src[l.offset+2:], "*/")
+	if end < 0 {
+		return l.errorf(start, "unterminated comment")
+	}
+	l.advance(end + 4)
+	return nil
+}
+
+// token scans one token at the current offset
+func (l *lexer) token() (Token, error) {
+	start := l.pos()
+	rest := l.src[l.offset:]
+	c := rest[0]
+
+	switch {
+	case isIdentStart(c):
+		n := 1
+		for n < len(rest) && isIdentPart(rest[n]) {
+			n++
+		}
+		l.advance(n)
+		return Token{Kind: Ident, Text: rest[:n], Pos: start}, nil
+
+	case isDigit(c) || (c == '.' && len(rest) > 1 && isDigit(rest[1])):
+		n, kind := scanNumber(rest)
+		l.advance(n)
+		return Token{Kind: kind, Text: rest[:n], Pos: start}, nil
+	}
+
+	for _, op := range operators {
+		if strings.HasPrefix(rest, op) {
+			l.advance(len(op))
+			return Token{Kind: Punct, Text: op, Pos: start}, nil
+		}
+	}
+	if strings.IndexByte("+-*/%<>=!&|^~?:;,.()[]{}", c) >= 0 {
+		l.advance(1)
+		return Token{Kind: Punct, Text: rest[:1], Pos: start}, nil
+	}
+	return Token{}, l.errorf(start, "unexpected character %q", c)
+}
+
+// scanNumber returns the length and kind of a numeric literal
+func scanNumber(s string) (int, TokenKind) {
+	n := 0
+	kind := IntLit
+	if strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0X") {
+		n = 2
+		for n < len(s) && isHexDigit(s[n]) {
+			n++
+		}
+	} else {
+		for n < len(s) && isDigit(s[n]) {
+			n++
+		}
+		if n < len(s) && s[n] == '.' {
+			kind = FloatLit
+			n++
+			for n < len(s) && isDigit(s[n]) {
+				n++
+			}
+		}
+		if n < len(s) && (s[n] == 'e' || s[n] == 'E') {
+			m := n + 1
+			if m < len(s) && (s[m] == '+' || s[m] == '-') {
+				m++
+			}
+			if m < len(s) && isDigit(s[m]) {
+				kind = FloatLit
+				n = m
+				for n < len(s) && isDigit(s[n]) {
+					n++
+				}
+			}
+		}
+	}
+	switch {
+	case kind == IntLit && n < len(s) && (s[n] == 'u' || s[n] == 'U'):
+		n++
+	case strings.HasPrefix(s[n:], "lf") || strings.HasPrefix(s[n:], "LF"):
+		kind = FloatLit
+		n += 2
+	case n < len(s) && (s[n] == 'f' || s[n] == 'F'):
+		kind = FloatLit
+		n++
+	}
+	return n, kind
+}
+
+// directive handles a preprocessor line
+func (l *lexer) directive() error {
+	start := l.pos()
+	var text strings.Builder
+	for l.offset < len(l.src) && l.src[l.offset] != '\n' {
+		rest := l.src[l.offset:]
+		switch {
+		case strings.HasPrefix(rest, "\\\n"), strings.HasPrefix(rest, "\\\r\n"):
+			l.advance(strings.IndexByte(rest, '\n') + 1)
+			text.WriteByte(' ')
+		case strings.HasPrefix(rest, "//"):
+			l.skipLineComment()
+		case strings.HasPrefix(rest, "/*"):
+			if err := l.skipBlockComment(); err != nil {
+				return err
+			}
+			text.WriteByte(' ')
+		default:
+			text.WriteByte(rest[0])
+			l.advance(1)
+		}
+	}
+	end := l.offset
+	if end > start.Offset && l.src[end-1] == '\r' {
+		end--
+	}
+
+	line := strings.TrimSpace(strings.TrimPrefix(text.String(), "#"))
+	name := line
+	args := ""
+	if i := strings.IndexAny(line, " \t("); i >= 0 {
+		name, args = line[:i], strings.TrimSpace(line[i:])
+	}
+
+	switch name {
+	case "if", "ifdef", "ifndef":
+		parent := l.active()
+		value := false
+		if parent {
+			var err error
+			if value, err = l.condition(name, args, start); err != nil {
+				return err
+			}
+		}
+		l.conds = append(l.conds, conditional{parentActive: parent, taken: value, active: parent && value, pos: start})
+		return nil
+
+	case "elif", "else":
+		if len(l.conds) == 0 {
+			return l.errorf(start, "#%s without #if", name)
+		}
+		c := &l.conds[len(l.conds)-1]
+		value := name == "else"
+		if name == "elif" && c.parentActive && !c.taken {
+			var err error
+			if value, err = l.condition("if", args, start); err != nil {
+				return err
+			}
+		}
+		c.active = c.parentActive && !c.taken && value
+		c.taken = c.taken || c.active
+		return nil
+
+	case "endif":
+		if len(l.conds) == 0 {
+			return l.errorf(start, "#endif without #if")
+		}
+		l.conds = l.conds[:len(l.conds)-1]
+		return nil
+	}
+
+	if !l.active() || name == "" {
+		return nil
+	}
+	switch name {
+	case "define":
+		fields := strings.Fields(args)
+		if len(fields) == 0 {
+			return l.errorf(start, "#define without a macro name")
+		}
+		macro := fields[0]
+		if i := strings.IndexByte(macro, '('); i >= 0 {
+			macro = macro[:i] // Function-like macros are recorded but not expanded
+		}
+		l.defines[macro] = strings.TrimSpace(strings.TrimPrefix(args, fields[0]))
+	case "undef":
+		delete(l.defines, strings.TrimSpace(args))
+	}
+	l.directives = append(l.directives, &Directive{Name: name, Args: args, Pos: start, End: end})
+	return nil
+}
+
+// condition evaluates the argument of #if, #ifdef or #ifndef
+func (l *lexer) condition(name, args string, pos Pos) (bool, error) {
+	switch name {
+	case "ifdef", "ifndef":
+		fields := strings.Fields(args)
+		if len(fields) != 1 {
+			return false, l.errorf(pos, "#%s expects one macro name", name)
+		}
+		_, defined := l.defines[fields[0]]
+		return defined == (name == "ifdef"), nil
+	}
+
+	tokens, err := exprTokens(args)
+	var value int64
+	if err == nil {
+		value, err = evaluate(tokens, l.defines, 0)
+	}
+	if err != nil {
+		return false, l.errorf(pos, "invalid #if expression %q: %v", args, err)
+	}
+	return value != 0, nil
+}
+
+// exprTokens tokenizes a single-line expression, such as the argument of
+// #if or the value of a macro
+func exprTokens(src string) ([]Token, error) {
+	sub := &lexer{src: src, line: 1, column: 1}
+	var tokens []Token
+	for sub.offset < len(sub.src) {
+		if c := sub.src[sub.offset]; c == ' ' || c == '\t' {
+			sub.advance(1)
+			continue
+		}
+		tok, err := sub.token()
+		if err != nil {
+			return nil, fmt.Errorf("%s", err.(*Error).Msg)
+		}
+		tokens = append(tokens, tok)
+	}
+	return tokens, nil
+}
+
+// evaluate computes an integer constant expression
+func evaluate(tokens []Token, defines map[string]string, depth int) (int64, error) {
+	if depth > 32 {
+		return 0, fmt.Errorf("macros expand recursively")
+	}
+	e := &evaluator{tokens: tokens, defines: defines, depth: depth}
+	value, err := e.expr(0)
+	if err == nil && e.pos < len(e.tokens) {
+		err = fmt.Errorf("unexpected %q", e.tokens[e.pos].Text)
+	}
+	return value, err
+}
+
+// binaryPrecedence lists #if operators by precedence, lowest first
+var binaryPrecedence = map[string]int{
+	"||": 1, "&&": 2, "|": 3, "^": 4, "&": 5,
+	"==": 6, "!=": 6, "<": 7, ">": 7, "<=": 7, ">=": 7,
+	"<<": 8, ">>": 8, "+": 9, "-": 9, "*": 10, "/": 10, "%": 10,
+}
+
+// evaluator computes integer #if expressions
+type evaluator struct {
+	tokens  []Token
+	pos     int
+	defines map[string]string
+	depth   int
+}
+
+func (e *evaluator) peek() string {
+	if e.pos < len(e.tokens) {
+		return e.tokens[e.pos].Text
+	}
+	return ""
+}
+
+// expr parses a binary expression whose operators bind tighter than minPrec
+func (e *evaluator) expr(minPrec int) (int64, error) {
+	left, err := e.unary()
+	if err != nil {
+		return 0, err
+	}
+	for {
+		op := e.peek()
+		prec, ok := binaryPrecedence[op]
+		if !ok || prec <= minPrec {
+			return left, nil
+		}
+		e.pos++
+		right, err := e.expr(prec)
+		if err != nil {
+			return 0, err
+		}
+		if left, err = apply(op, left, right); err != nil {
+			return 0, err
+		}
+	}
+}
+
+func (e *evaluator) unary() (int64, error) {
+	if e.pos >= len(e.tokens) {
+		return 0, fmt.Errorf("unexpected end of expression")
+	}
+	tok := e.tokens[e.pos]
+	e.pos++
+	switch {
+	case tok.Text == "(":
+		value, err := e.expr(0)
+		if err != nil {
+			return 0, err
+		}
+		if e.peek() != ")" {
+			return 0, fmt.Errorf("missing )")
+		}
+		e.pos++
+		return value, nil
+	case tok.Text == "!" || tok.Text == "-" || tok.Text == "+" || tok.Text == "~":
+		value, err := e.unary()
+		if err != nil {
+			return 0, err
+		}
+		switch tok.Text {
+		case "!":
+			return boolInt(value == 0), nil
+		case "-":
+			return -value, nil
+		case "~":
+			return ^value, nil
+		}
+		return value, nil
+	case tok.Text == "defined":
+		paren := e.peek() == "("
+		if paren {
+			e.pos++
+		}
+		if e.pos >= len(e.tokens) || e.tokens[e.pos].Kind != Ident {
+			return 0, fmt.Errorf("defined expects a macro name")
+		}
+		_, ok := e.defines[e.tokens[e.pos].Text]
+		e.pos++
+		if paren {
+			if e.peek() != ")" {
+				return 0, fmt.Errorf("missing )")
+			}
+			e.pos++
+		}
+		return boolInt(ok), nil
+	case tok.Kind == IntLit:
+		return parseInt(tok.Text)
+	case tok.Kind == Ident:
+		// Macros expand to their value, undefined names evaluate to 0
+		value, ok := e.defines[tok.Text]
+		if !ok || value == "" {
+			return 0, nil
+		}
+		tokens, err := exprTokens(value)
+		if err != nil {
+			return 0, fmt.Errorf("macro %s: %v", tok.Text, err)
+		}
+		return evaluate(tokens, e.defines, e.depth+1)
+	}
+	return 0, fmt.Errorf("unexpected %q", tok.Text)
+}
+
+func apply(op string, a, b int64) (int64, error) {
+	switch op {
+	case "||":
+		return boolInt(a != 0 || b != 0), nil
+	case "&&":
+		return boolInt(a != 0 && b != 0), nil
+	case "|":
+		return a | b, nil
+	case "^":
+		return a ^ b, nil
+	case "&":
+		return a & b, nil
+	case "==":
+		return boolInt(a == b), nil
+	case "!=":
+		return boolInt(a != b), nil
+	case "<":
+		return boolInt(a < b), nil
+	case ">":
+		return boolInt(a > b), nil
+	case "<=":
+		return boolInt(a <= b), nil
+	case ">=":
+		return boolInt(a >= b), nil
+	case "<<":
+		return a << uint(b), nil
+	case ">>":
+		return a >> uint(b), nil
+	case "+":
+		return a + b, nil
+	case "-":
+		return a - b, nil
+	case "*":
+		return a * b, nil
+	case "/", "%":
+		if b == 0 {
+			return 0, fmt.Errorf("division by zero")
+		}
+		if op == "/" {
+			return a / b, nil
+		}
+		return a % b, nil
+	}
+	return 0, fmt.Errorf("unknown operator %s", op)
+}
+
+func boolInt(b bool) int64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// parseInt parses a GLSL integer literal
+func parseInt(text string) (int64, error) {
+	text = strings.TrimRight(text, "uU")
+	value, err := strconv.ParseInt(text, 0, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid integer %q", text)
+	}
+	return value, nil
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isIdentPart(c byte) bool {
+	return isIdentStart(c) || isDigit(c)
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isHexDigit(c byte) bool {
+	return isDigit(c) || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
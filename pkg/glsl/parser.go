@@ -0,0 +1,809 @@
+// Package glsl is a pure-Go GLSL lexer and parser for analysing shaders
+// without an OpenGL context.
+//
+// It covers the global structure of the #version 410 subset used by this
+// project: version and extension directives, layout qualifiers, in/out
+// variables, uniforms, interface blocks, structs, functions and subroutines.
+// Conditional directives (#if, #ifdef, #ifndef, #elif, #else, #endif) are
+// evaluated, and integer constant expressions of literals, object-like
+// macros and const ints are evaluated in array sizes and layout values, but
+// macros are not expanded in code. Function bodies are kept as token lists
+// rather than statement trees, and #include must be expanded beforehand,
+// e.g. with shader.Preprocessor.
+package glsl
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Options configure parsing
+type Options struct {
+	// Name identifies the source in error messages
+	Name string
+
+	// Defines are macros defined before the first line, as injected by
+	// shader variants
+	Defines map[string]string
+}
+
+// Parse parses GLSL source
+func Parse(src string) (*Shader, error) {
+	return ParseWithOptions(src, Options{})
+}
+
+// ParseWithOptions parses GLSL source with predefined macros
+func ParseWithOptions(src string, opts Options) (*Shader, error) {
+	defines := make(map[string]string, len(opts.Defines))
+	for name, value := range opts.Defines {
+		defines[name] = value
+	}
+
+	l := &lexer{name: opts.Name, src: src, defines: defines}
+	if err := l.lex(); err != nil {
+		return nil, err
+	}
+
+	p := &parser{name: opts.Name, tokens: l.tokens, defines: defines, shader: &Shader{Directives: l.directives, Tokens: l.tokens}}
+	if err := p.directives(); err != nil {
+		return nil, err
+	}
+	for p.peek().Kind != EOF {
+		if err := p.global(); err != nil {
+			return nil, err
+		}
+	}
+	return p.shader, nil
+}
+
+// storageQualifiers maps storage keywords to their Storage
+var storageQualifiers = map[string]Storage{
+	"const":     StorageConst,
+	"in":        StorageIn,
+	"out":       StorageOut,
+	"inout":     StorageInOut,
+	"uniform":   StorageUniform,
+	"buffer":    StorageBuffer,
+	"shared":    StorageShared,
+	"attribute": StorageIn,  // Legacy vertex inputs
+	"varying":   StorageOut, // Legacy, resolved per stage by callers
+}
+
+// otherQualifiers are recorded in Variable.Qualifiers
+var otherQualifiers = map[string]bool{
+	"flat": true, "smooth": true, "noperspective": true,
+	"centroid": true, "sample": true, "patch": true,
+	"invariant": true, "precise": true,
+	"highp": true, "mediump": true, "lowp": true,
+	"coherent": true, "volatile": true, "restrict": true, "readonly": true, "writeonly": true,
+}
+
+// parser builds a Shader from tokens
+type parser struct {
+	name    string
+	tokens  []Token
+	pos     int
+	defines map[string]string
+	shader  *Shader
+}
+
+func (p *parser) peek() Token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) peekAt(offset int) Token {
+	if p.pos+offset < len(p.tokens) {
+		return p.tokens[p.pos+offset]
+	}
+	return p.tokens[len(p.tokens)-1]
+}
+
+func (p *parser) next() Token {
+	tok := p.tokens[p.pos]
+	if tok.Kind != EOF {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *parser) errorf(pos Pos, format string, args ...interface{}) error {
+	return &Error{Name: p.name, Pos: pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+// describe formats a token for error messages
+func describe(tok Token) string {
+	if tok.Kind == EOF {
+		return "end of file"
+	}
+	return strconv.Quote(tok.Text)
+}
+
+func (p *parser) expect(text string) (Token, error) {
+	tok := p.peek()
+	if tok.Text != text || tok.Kind == EOF {
+		return tok, p.errorf(tok.Pos, "expected %q, found %s", text, describe(tok))
+	}
+	return p.next(), nil
+}
+
+func (p *parser) ident(what string) (Token, error) {
+	tok := p.peek()
+	if tok.Kind != Ident {
+		return tok, p.errorf(tok.Pos, "expected %s, found %s", what, describe(tok))
+	}
+	return p.next(), nil
+}
+
+// directives interprets #version and #extension
+func (p *parser) directives() error {
+	for i, d := range p.shader.Directives {
+		switch d.Name {
+		case "version":
+			if p.shader.Version != nil {
+				return p.errorf(d.Pos, "duplicate #version")
+			}
+			for _, prev := range p.shader.Directives[:i] {
+				if prev.Name != "version" && prev.Name != "line" {
+					return p.errorf(d.Pos, "#version must come before other directives")
+				}
+			}
+			if len(p.tokens) > 1 && p.tokens[0].Pos.Offset < d.Pos.Offset {
+				return p.errorf(d.Pos, "#version must come before any code")
+			}
+			fields := strings.Fields(d.Args)
+			if len(fields) == 0 || len(fields) > 2 {
+				return p.errorf(d.Pos, "invalid #version %q", d.Args)
+			}
+			number, err := strconv.Atoi(fields[0])
+			if err != nil {
+				return p.errorf(d.Pos, "invalid #version number %q", fields[0])
+			}
+			v := &Version{Number: number, Pos: d.Pos}
+			if len(fields) == 2 {
+				v.Profile = fields[1]
+			}
+			p.shader.Version = v
+
+		case "extension":
+			name, behavior, ok := strings.Cut(d.Args, ":")
+			name, behavior = strings.TrimSpace(name), strings.TrimSpace(behavior)
+			if !ok || name == "" || behavior == "" {
+				return p.errorf(d.Pos, "invalid #extension %q", d.Args)
+			}
+			p.shader.Extensions = append(p.shader.Extensions, &Extension{Name: name, Behavior: behavior, Pos: d.Pos})
+		}
+	}
+	return nil
+}
+
+// qualifiers holds the qualifiers preceding a declaration
+type qualifiers struct {
+	storage  Storage
+	explicit bool // Whether a storage qualifier was given
+	layout   Layout
+	other    []string
+}
+
+// qualifierList parses any sequence of qualifiers
+func (p *parser) qualifierList() (qualifiers, error) {
+	var q qualifiers
+	for {
+		tok := p.peek()
+		if tok.Kind != Ident {
+			return q, nil
+		}
+		switch {
+		case tok.Text == "layout":
+			p.next()
+			layout, err := p.layout()
+			if err != nil {
+				return q, err
+			}
+			q.layout = append(q.layout, layout...)
+		default:
+			if storage, ok := storageQualifiers[tok.Text]; ok {
+				switch {
+				case !q.explicit:
+					q.storage, q.explicit = storage, true
+				case storage == StorageConst:
+					// "const in" parameters
+					q.other = append(q.other, tok.Text)
+				case q.storage == StorageConst:
+					q.other = append(q.other, "const")
+					q.storage = storage
+				default:
+					return q, p.errorf(tok.Pos, "multiple storage qualifiers")
+				}
+				p.next()
+				continue
+			}
+			if !otherQualifiers[tok.Text] {
+				return q, nil
+			}
+			p.next()
+			q.other = append(q.other, tok.Text)
+		}
+	}
+}
+
+// layout parses "(id, id = value, ...)" after the layout keyword
+func (p *parser) layout() (Layout, error) {
+	if _, err := p.expect("("); err != nil {
+		return nil, err
+	}
+	var layout Layout
+	for {
+		name, err := p.ident("layout qualifier")
+		if err != nil {
+			return nil, err
+		}
+		q := LayoutQualifier{Name: name.Text, Pos: name.Pos}
+		if p.peek().Text == "=" {
+			p.next()
+			value, err := p.constant()
+			if err != nil {
+				return nil, err
+			}
+			q.Value = strconv.Itoa(value)
+		}
+		layout = append(layout, q)
+		if p.peek().Text != "," {
+			break
+		}
+		p.next()
+	}
+	if _, err := p.expect(")"); err != nil {
+		return nil, err
+	}
+	return layout, nil
+}
+
+// constant parses an integral constant expression up to a top-level ",",
+// ")" or "]", such as 4, 2*3 or MAX_LIGHTS + 1. Names are macros or const
+// int variables.
+func (p *parser) constant() (int, error) {
+	start := p.peek()
+	var tokens []Token
+	for depth := 0; ; {
+		tok := p.peek()
+		if tok.Kind == EOF || depth == 0 && (tok.Text == "," || tok.Text == ")" || tok.Text == "]") {
+			break
+		}
+		switch tok.Text {
+		case "(":
+			depth++
+		case ")":
+			depth--
+		}
+		tokens = append(tokens, p.next())
+	}
+	if len(tokens) == 0 {
+		return 0, p.errorf(start.Pos, "expected an integer constant, found %s", describe(start))
+	}
+
+	tokens, err := p.constTokens(tokens, 0)
+	if err != nil {
+		return 0, err
+	}
+	value, err := evaluate(tokens, p.defines, 1)
+	if err != nil {
+		return 0, p.errorf(start.Pos, "invalid integer constant: %v", err)
+	}
+	return int(value), nil
+}
+
+// constTokens replaces the const int variables of a constant expression
+// by their parenthesized initializers, leaving macros to evaluate
+func (p *parser) constTokens(tokens []Token, depth int) ([]Token, error) {
+	if depth > 32 {
+		return nil, p.errorf(tokens[0].Pos, "constants are defined recursively")
+	}
+	out := make([]Token, 0, len(tokens))
+	for _, tok := range tokens {
+		switch tok.Kind {
+		case IntLit, Punct:
+			out = append(out, tok)
+			continue
+		case Ident:
+			if _, ok := p.defines[tok.Text]; ok {
+				out = append(out, tok)
+				continue
+			}
+			v, ok := p.shader.Variable(tok.Text)
+			if !ok || v.Storage != StorageConst || v.Init == nil || v.IsArray() || (v.Type != "int" && v.Type != "uint") {
+				break
+			}
+			init, err := p.constTokens(v.Init, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, Token{Kind: Punct, Text: "(", Pos: tok.Pos})
+			out = append(out, init...)
+			out = append(out, Token{Kind: Punct, Text: ")", Pos: tok.Pos})
+			continue
+		}
+		return nil, p.errorf(tok.Pos, "expected an integer constant, found %s", describe(tok))
+	}
+	return out, nil
+}
+
+// arraySize parses an optional "[N]" or "[]" suffix
+func (p *parser) arraySize() (int, error) {
+	if p.peek().Text != "[" {
+		return 0, nil
+	}
+	p.next()
+	size := -1
+	if p.peek().Text != "]" {
+		pos := p.peek().Pos
+		n, err := p.constant()
+		if err != nil {
+			return 0, err
+		}
+		if n <= 0 {
+			return 0, p.errorf(pos, "array size must be positive, got %d", n)
+		}
+		size = n
+	}
+	if _, err := p.expect("]"); err != nil {
+		return 0, err
+	}
+	if p.peek().Text == "[" {
+		return 0, p.errorf(p.peek().Pos, "arrays of arrays are not supported")
+	}
+	return size, nil
+}
+
+// members parses struct or block members after "{" up to and including "}"
+func (p *parser) members() ([]*Variable, error) {
+	var members []*Variable
+	for p.peek().Text != "}" {
+		if p.peek().Kind == EOF {
+			return nil, p.errorf(p.peek().Pos, "expected \"}\", found end of file")
+		}
+		q, err := p.qualifierList()
+		if err != nil {
+			return nil, err
+		}
+		var typeName string
+		if p.peek().Text == "struct" {
+			def, err := p.structDef()
+			if err != nil {
+				return nil, err
+			}
+			typeName = def.Name
+		} else {
+			tok, err := p.ident("member type")
+			if err != nil {
+				return nil, err
+			}
+			typeName = tok.Text
+		}
+		typeArray, err := p.arraySize()
+		if err != nil {
+			return nil, err
+		}
+		for {
+			name, err := p.ident("member name")
+			if err != nil {
+				return nil, err
+			}
+			size, err := p.arraySize()
+			if err != nil {
+				return nil, err
+			}
+			if typeArray != 0 {
+				if size != 0 {
+					return nil, p.errorf(name.Pos, "arrays of arrays are not supported")
+				}
+				size = typeArray
+			}
+			members = append(members, &Variable{Name: name.Text, Type: typeName, ArraySize: size, Storage: q.storage, Layout: q.layout, Qualifiers: q.other, Pos: name.Pos})
+			if p.peek().Text != "," {
+				break
+			}
+			p.next()
+		}
+		if _, err := p.expect(";"); err != nil {
+			return nil, err
+		}
+	}
+	p.next()
+	return members, nil
+}
+
+// structDef parses "struct Name { members }" and records the struct
+func (p *parser) structDef() (*Struct, error) {
+	p.next()
+	name, err := p.ident("struct name")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	members, err := p.members()
+	if err != nil {
+		return nil, err
+	}
+	if len(members) == 0 {
+		return nil, p.errorf(name.Pos, "struct %s has no members", name.Text)
+	}
+	def := &Struct{Name: name.Text, Members: members, Pos: name.Pos}
+	p.shader.Structs = append(p.shader.Structs, def)
+	return def, nil
+}
+
+// global parses one global declaration or function
+func (p *parser) global() error {
+	start := p.peek()
+	if start.Text == ";" {
+		p.next()
+		return nil
+	}
+	if start.Text == "precision" {
+		p.next()
+		qualifier, err := p.ident("precision qualifier")
+		if err != nil {
+			return err
+		}
+		typeName, err := p.ident("type")
+		if err != nil {
+			return err
+		}
+		p.shader.Precisions = append(p.shader.Precisions, &Precision{Qualifier: qualifier.Text, Type: typeName.Text, Pos: start.Pos})
+		_, err = p.expect(";")
+		return err
+	}
+
+	q, err := p.qualifierList()
+	if err != nil {
+		return err
+	}
+	if p.peek().Text == "subroutine" {
+		return p.subroutine(q, start)
+	}
+
+	// Default layouts such as "layout(triangles) in;"
+	if p.peek().Text == ";" {
+		if q.layout == nil || !q.explicit {
+			return p.errorf(p.peek().Pos, "declaration declares nothing")
+		}
+		p.next()
+		p.shader.Layouts = append(p.shader.Layouts, &LayoutDecl{Storage: q.storage, Layout: q.layout, Pos: start.Pos})
+		return nil
+	}
+
+	// Redeclarations such as "invariant gl_Position;"
+	if p.peek().Kind == Ident && p.peekAt(1).Text == ";" && q.layout == nil && !q.explicit {
+		p.next()
+		p.next()
+		return nil
+	}
+
+	// Interface blocks
+	if q.explicit && p.peek().Kind == Ident && p.peekAt(1).Text == "{" && p.peek().Text != "struct" {
+		return p.block(q)
+	}
+
+	var typeName string
+	if p.peek().Text == "struct" {
+		def, err := p.structDef()
+		if err != nil {
+			return err
+		}
+		if p.peek().Text == ";" {
+			p.next()
+			return nil
+		}
+		typeName = def.Name
+	} else {
+		tok, err := p.ident("type")
+		if err != nil {
+			return err
+		}
+		typeName = tok.Text
+	}
+	typeArray, err := p.arraySize()
+	if err != nil {
+		return err
+	}
+
+	name, err := p.ident("name")
+	if err != nil {
+		return err
+	}
+	if p.peek().Text == "(" {
+		if q.explicit || q.layout != nil || typeArray != 0 {
+			return p.errorf(start.Pos, "invalid qualifiers on function %s", name.Text)
+		}
+		return p.function(typeName, name)
+	}
+	return p.declarators(q, typeName, typeArray, name)
+}
+
+// declarators parses "name [N] [= init], ... ;" after the first name
+func (p *parser) declarators(q qualifiers, typeName string, typeArray int, name Token) error {
+	location, hasLocation := q.layout.Int("location")
+	for {
+		size, err := p.arraySize()
+		if err != nil {
+			return err
+		}
+		if typeArray != 0 {
+			if size != 0 {
+				return p.errorf(name.Pos, "arrays of arrays are not supported")
+			}
+			size = typeArray
+		}
+		v := &Variable{Name: name.Text, Type: typeName, ArraySize: size, Storage: q.storage, Layout: q.layout, Qualifiers: q.other, Pos: name.Pos}
+		if p.peek().Text == "=" {
+			p.next()
+			init, err := p.initializer()
+			if err != nil {
+				return err
+			}
+			v.Init = init
+		}
+		p.shader.Variables = append(p.shader.Variables, v)
+
+		if p.peek().Text != "," {
+			break
+		}
+		p.next()
+		if name, err = p.ident("name"); err != nil {
+			return err
+		}
+		if hasLocation {
+			// Each declarator of "layout(location = N) in vec3 a, b;" takes the next location
+			location++
+			q.layout = append(append(Layout{}, q.layout...), LayoutQualifier{Name: "location", Value: strconv.Itoa(location), Pos: name.Pos})
+		}
+	}
+	_, err := p.expect(";")
+	return err
+}
+
+// initializer collects tokens up to a top-level "," or ";"
+func (p *parser) initializer() ([]Token, error) {
+	start := p.pos
+	depth := 0
+	for {
+		tok := p.peek()
+		switch tok.Text {
+		case "(", "[", "{":
+			depth++
+		case ")", "]", "}":
+			depth--
+		case ",", ";":
+			if depth == 0 {
+				if p.pos == start {
+					return nil, p.errorf(tok.Pos, "missing initializer")
+				}
+				return p.tokens[start:p.pos], nil
+			}
+		}
+		if tok.Kind == EOF || depth < 0 {
+			return nil, p.errorf(tok.Pos, "unterminated initializer")
+		}
+		p.next()
+	}
+}
+
+// block parses an interface block after its qualifiers
+func (p *parser) block(q qualifiers) error {
+	name := p.next()
+	p.next() // "{"
+	switch q.storage {
+	case StorageIn, StorageOut, StorageUniform, StorageBuffer:
+	default:
+		return p.errorf(name.Pos, "%s blocks are not allowed", q.storage)
+	}
+	members, err := p.members()
+	if err != nil {
+		return err
+	}
+	b := &Block{Storage: q.storage, Name: name.Text, Layout: q.layout, Qualifiers: q.other, Members: members, Pos: name.Pos}
+	if p.peek().Kind == Ident {
+		b.Instance = p.next().Text
+		if b.ArraySize, err = p.arraySize(); err != nil {
+			return err
+		}
+	}
+	p.shader.Blocks = append(p.shader.Blocks, b)
+	_, err = p.expect(";")
+	return err
+}
+
+// subroutine parses a declaration starting with the subroutine keyword:
+// a subroutine type "subroutine vec4 Shade(vec3 n);", a function
+// implementing subroutine types "subroutine(Shade) vec4 f(vec3 n) { ... }"
+// or subroutine uniforms "subroutine uniform Shade uShade;"
+func (p *parser) subroutine(q qualifiers, start Token) error {
+	p.next() // "subroutine"
+	if p.peek().Text == "(" {
+		if q.explicit || q.layout != nil {
+			return p.errorf(start.Pos, "invalid qualifiers on subroutine function")
+		}
+		p.next()
+		var types []string
+		for {
+			typeName, err := p.ident("subroutine type")
+			if err != nil {
+				return err
+			}
+			types = append(types, typeName.Text)
+			if p.peek().Text != "," {
+				break
+			}
+			p.next()
+		}
+		if _, err := p.expect(")"); err != nil {
+			return err
+		}
+		f, err := p.prototype()
+		if err != nil {
+			return err
+		}
+		f.Subroutines = types
+		return p.body(f)
+	}
+
+	inner, err := p.qualifierList()
+	if err != nil {
+		return err
+	}
+	if inner.storage == StorageUniform && !q.explicit && inner.layout == nil {
+		return p.subroutineUniforms(q.layout)
+	}
+	if q.explicit || q.layout != nil || inner.explicit || inner.layout != nil {
+		return p.errorf(start.Pos, "invalid qualifiers on subroutine type")
+	}
+	f, err := p.prototype()
+	if err != nil {
+		return err
+	}
+	if _, err := p.expect(";"); err != nil {
+		return err
+	}
+	f.Prototype = true
+	p.shader.SubroutineTypes = append(p.shader.SubroutineTypes, f)
+	return nil
+}
+
+// subroutineUniforms parses "Type name [N], ... ;" after "subroutine uniform"
+func (p *parser) subroutineUniforms(layout Layout) error {
+	typeName, err := p.ident("subroutine type")
+	if err != nil {
+		return err
+	}
+	for {
+		name, err := p.ident("name")
+		if err != nil {
+			return err
+		}
+		size, err := p.arraySize()
+		if err != nil {
+			return err
+		}
+		p.shader.SubroutineUniforms = append(p.shader.SubroutineUniforms, &Variable{Name: name.Text, Type: typeName.Text, ArraySize: size, Storage: StorageUniform, Layout: layout, Pos: name.Pos})
+		if p.peek().Text != "," {
+			break
+		}
+		p.next()
+	}
+	_, err = p.expect(";")
+	return err
+}
+
+// prototype parses "returnType name(params)"
+func (p *parser) prototype() (*Function, error) {
+	returnType, err := p.ident("return type")
+	if err != nil {
+		return nil, err
+	}
+	name, err := p.ident("function name")
+	if err != nil {
+		return nil, err
+	}
+	if p.peek().Text != "(" {
+		return nil, p.errorf(p.peek().Pos, "expected \"(\", found %s", describe(p.peek()))
+	}
+	return p.params(returnType.Text, name)
+}
+
+// function parses a function prototype or definition after its name
+func (p *parser) function(returnType string, name Token) error {
+	f, err := p.params(returnType, name)
+	if err != nil {
+		return err
+	}
+	return p.body(f)
+}
+
+// params parses the parameter list of a function after its name
+func (p *parser) params(returnType string, name Token) (*Function, error) {
+	f := &Function{Name: name.Text, ReturnType: returnType, Pos: name.Pos}
+	p.next() // "("
+	if p.peek().Text == "void" && p.peekAt(1).Text == ")" {
+		p.next()
+	}
+	for p.peek().Text != ")" {
+		q, err := p.qualifierList()
+		if err != nil {
+			return nil, err
+		}
+		typeName, err := p.ident("parameter type")
+		if err != nil {
+			return nil, err
+		}
+		param := &Variable{Type: typeName.Text, Storage: q.storage, Layout: q.layout, Qualifiers: q.other, Pos: typeName.Pos}
+		if !q.explicit {
+			param.Storage = StorageIn
+		}
+		if q.storage == StorageConst {
+			param.Storage = StorageIn
+			param.Qualifiers = append(param.Qualifiers, "const")
+		}
+		if param.ArraySize, err = p.arraySize(); err != nil {
+			return nil, err
+		}
+		if p.peek().Kind == Ident {
+			tok := p.next()
+			param.Name, param.Pos = tok.Text, tok.Pos
+			size, err := p.arraySize()
+			if err != nil {
+				return nil, err
+			}
+			if size != 0 {
+				param.ArraySize = size
+			}
+		}
+		f.Params = append(f.Params, param)
+		if p.peek().Text != "," {
+			break
+		}
+		p.next()
+	}
+	if _, err := p.expect(")"); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// body parses the ";" of a prototype or the body of a definition and
+// records the function
+func (p *parser) body(f *Function) error {
+	if p.peek().Text == ";" {
+		p.next()
+		f.Prototype = true
+		p.shader.Functions = append(p.shader.Functions, f)
+		return nil
+	}
+	open, err := p.expect("{")
+	if err != nil {
+		return err
+	}
+	start := p.pos
+	for depth := 1; ; {
+		tok := p.peek()
+		switch tok.Text {
+		case "{":
+			depth++
+		case "}":
+			depth--
+		}
+		if tok.Kind == EOF {
+			return p.errorf(open.Pos, "unterminated body of function %s", f.Name)
+		}
+		if depth == 0 {
+			break
+		}
+		p.next()
+	}
+	f.Body = p.tokens[start:p.pos:p.pos]
+	p.next()
+	p.shader.Functions = append(p.shader.Functions, f)
+	return nil
+}
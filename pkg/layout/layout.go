@@ -0,0 +1,727 @@
+// Package layout marshals Go structs into the memory layouts GLSL uses for
+// interface blocks, so uniform and shader storage buffer contents are built
+// from typed Go values instead of hand-padded raw memory, and checks them
+// against the layout the driver reflected for a linked program.
+//
+// Struct fields map to block members in declaration order. The member name
+// is taken from a `glsl:"name"` tag, or from the field name with its first
+// letter lowercased. Fields tagged `glsl:"-"` and unexported fields are
+// skipped.
+//
+// Supported field types:
+//   - float32, int32, uint32 and bool (float, int, uint, bool)
+//   - mgl32.Vec2, Vec3 and Vec4 (vec2, vec3, vec4)
+//   - mgl32 square and non-square matrices (mgl32.Mat3x2 is mat2x3)
+//   - fixed-size arrays and nested structs of the above
+//   - with Std430, a slice as the last field of the top-level struct for a
+//     runtime-sized array (Particle particles[];)
+//
+// Std430 also marshals a slice of structs directly, laid out as the
+// elements of a runtime-sized array.
+//
+// Example usage:
+//
+//	type Light struct {
+//	    Position mgl32.Vec3 `glsl:"position"`
+//	    Color    mgl32.Vec3 `glsl:"color"`
+//	}
+//
+//	type Lights struct {
+//	    Lights [4]Light `glsl:"lights"`
+//	    Count  int32    `glsl:"count"`
+//	}
+//
+//	// Reports every member whose offset, stride or type differs, by name
+//	if err := layout.Std140.Validate(program, "Lights", Lights{}); err != nil {
+//	    log.Fatal(err)
+//	}
+//	err = ubo.Write(&lights)
+package layout
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/yossideutsch/gogl/pkg/shader"
+)
+
+// Layout is a GLSL block memory layout
+type Layout int
+
+const (
+	// Std140 is the layout of uniform blocks: arrays, matrix columns and
+	// structs are aligned to 16 bytes
+	Std140 Layout = iota
+
+	// Std430 is the layout of shader storage blocks: like std140, but arrays,
+	// matrix columns and structs only take the alignment of their members
+	Std430
+)
+
+func (l Layout) String() string {
+	switch l {
+	case Std140:
+		return "std140"
+	case Std430:
+		return "std430"
+	default:
+		return "unknown"
+	}
+}
+
+// Member is a leaf member of a marshaled struct, named the way OpenGL names
+// uniform block members: "color", "light.color", "lights[2].color". Arrays
+// of scalars, vectors and matrices are a single member.
+type Member struct {
+	Name         string
+	Type         shader.DataType
+	Offset       int
+	Size         int // Array length, 1 for non-arrays, 0 for runtime-sized arrays
+	ArrayStride  int // 0 for non-arrays
+	MatrixStride int // 0 for non-matrices
+}
+
+// kind classifies the nodes of a type layout
+type kind int
+
+const (
+	scalarKind kind = iota
+	vectorKind
+	matrixKind
+	arrayKind
+	structKind
+)
+
+// node is the computed layout of a Go type
+type node struct {
+	kind   kind
+	glType shader.DataType
+	align  int
+	size   int
+
+	// Vectors and matrices
+	rows      int // Components of a vector or matrix column
+	columns   int
+	colStride int
+
+	// Arrays
+	elem    *node
+	length  int
+	stride  int
+	runtime bool // Runtime-sized array, or struct ending in one
+
+	// Structs
+	fields []field
+}
+
+// field is a struct member placed at an offset
+type field struct {
+	name   string
+	index  int
+	offset int
+	node   *node
+}
+
+var (
+	vectorTypes = map[reflect.Type]shader.DataType{
+		reflect.TypeOf(mgl32.Vec2{}): shader.TypeVec2,
+		reflect.TypeOf(mgl32.Vec3{}): shader.TypeVec3,
+		reflect.TypeOf(mgl32.Vec4{}): shader.TypeVec4,
+	}
+
+	// matrixTypes maps mgl32 matrices, named rows x columns, to the GLSL
+	// type, named columns x rows
+	matrixTypes = map[reflect.Type]struct {
+		glType        shader.DataType
+		columns, rows int
+	}{
+		reflect.TypeOf(mgl32.Mat2{}):   {shader.TypeMat2, 2, 2},
+		reflect.TypeOf(mgl32.Mat3{}):   {shader.TypeMat3, 3, 3},
+		reflect.TypeOf(mgl32.Mat4{}):   {shader.TypeMat4, 4, 4},
+		reflect.TypeOf(mgl32.Mat3x2{}): {shader.TypeMat2x3, 2, 3},
+		reflect.TypeOf(mgl32.Mat4x2{}): {shader.TypeMat2x4, 2, 4},
+		reflect.TypeOf(mgl32.Mat2x3{}): {shader.TypeMat3x2, 3, 2},
+		reflect.TypeOf(mgl32.Mat4x3{}): {shader.TypeMat3x4, 3, 4},
+		reflect.TypeOf(mgl32.Mat2x4{}): {shader.TypeMat4x2, 4, 2},
+		reflect.TypeOf(mgl32.Mat3x4{}): {shader.TypeMat4x3, 4, 3},
+	}
+)
+
+// cacheKey identifies a computed layout
+type cacheKey struct {
+	layout Layout
+	t      reflect.Type
+}
+
+// nodes caches computed layouts, since buffers are typically rewritten every frame
+var nodes sync.Map
+
+func roundUp(n, align int) int {
+	return (n + align - 1) / align * align
+}
+
+// vectorAlign is the base alignment of a float vector with n components
+func vectorAlign(n int) int {
+	if n == 2 {
+		return 8
+	}
+	return 16
+}
+
+// layoutOf computes the layout of t, caching the result
+func (l Layout) layoutOf(t reflect.Type) (*node, error) {
+	key := cacheKey{l, t}
+	if n, ok := nodes.Load(key); ok {
+		return n.(*node), nil
+	}
+	n, err := l.build(t, t.String())
+	if err != nil {
+		return nil, err
+	}
+	nodes.Store(key, n)
+	return n, nil
+}
+
+// build computes the layout of t; path names the field for errors
+func (l Layout) build(t reflect.Type, path string) (*node, error) {
+	if glType, ok := vectorTypes[t]; ok {
+		rows := t.Len()
+		return &node{kind: vectorKind, glType: glType, align: vectorAlign(rows), size: 4 * rows, rows: rows, columns: 1}, nil
+	}
+	if m, ok := matrixTypes[t]; ok {
+		stride := 16 // std140 rounds every column up to a vec4
+		if l == Std430 {
+			stride = vectorAlign(m.rows)
+		}
+		return &node{kind: matrixKind, glType: m.glType, align: stride, size: m.columns * stride,
+			rows: m.rows, columns: m.columns, colStride: stride}, nil
+	}
+
+	switch t.Kind() {
+	case reflect.Float32:
+		return &node{kind: scalarKind, glType: shader.TypeFloat, align: 4, size: 4}, nil
+	case reflect.Int32:
+		return &node{kind: scalarKind, glType: shader.TypeInt, align: 4, size: 4}, nil
+	case reflect.Uint32:
+		return &node{kind: scalarKind, glType: shader.TypeUInt, align: 4, size: 4}, nil
+	case reflect.Bool:
+		return &node{kind: scalarKind, glType: shader.TypeBool, align: 4, size: 4}, nil
+	case reflect.Int, reflect.Uint, reflect.Float64:
+		return nil, fmt.Errorf("%s: type %s has no fixed GLSL size, use int32, uint32 or float32", path, t)
+
+	case reflect.Array:
+		if t.Len() == 0 {
+			return nil, fmt.Errorf("%s: arrays cannot be empty", path)
+		}
+		return l.array(t, path, t.Len())
+
+	case reflect.Slice:
+		if l != Std430 {
+			return nil, fmt.Errorf("%s: runtime-sized arrays are only supported by std430", path)
+		}
+		return l.array(t, path, 0)
+
+	case reflect.Struct:
+		n := &node{kind: structKind, align: 4}
+		if l == Std140 {
+			n.align = 16
+		}
+		offset := 0
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			name, ok := memberName(f)
+			if !ok {
+				continue
+			}
+			if n.runtime {
+				return nil, fmt.Errorf("%s: only the last field can be a runtime-sized array", path)
+			}
+			child, err := l.build(f.Type, path+"."+f.Name)
+			if err != nil {
+				return nil, err
+			}
+			if child.kind == structKind && child.runtime {
+				return nil, fmt.Errorf("%s.%s: runtime-sized arrays must be direct block members", path, f.Name)
+			}
+			n.runtime = child.runtime
+			offset = roundUp(offset, child.align)
+			n.fields = append(n.fields, field{name: name, index: i, offset: offset, node: child})
+			offset += child.size
+			if child.align > n.align {
+				n.align = child.align
+			}
+		}
+		if len(n.fields) == 0 {
+			return nil, fmt.Errorf("%s: struct has no exported fields", path)
+		}
+		n.size = roundUp(offset, n.align)
+		return n, nil
+	}
+
+	return nil, fmt.Errorf("%s: unsupported type %s", path, t)
+}
+
+// array computes the layout of an array type; length 0 is runtime-sized
+func (l Layout) array(t reflect.Type, path string, length int) (*node, error) {
+	elem, err := l.build(t.Elem(), path+"[]")
+	if err != nil {
+		return nil, err
+	}
+	if elem.runtime {
+		return nil, fmt.Errorf("%s: arrays cannot contain runtime-sized arrays", path)
+	}
+	align := elem.align
+	if l == Std140 {
+		align = roundUp(align, 16)
+	}
+	stride := roundUp(elem.size, align)
+	return &node{kind: arrayKind, align: align, size: length * stride, elem: elem, length: length, stride: stride, runtime: length == 0}, nil
+}
+
+// sizeOf returns the encoded size of v, including runtime-sized arrays
+func (n *node) sizeOf(v reflect.Value) int {
+	switch {
+	case !n.runtime:
+		return n.size
+	case n.kind == arrayKind:
+		return v.Len() * n.stride
+	default:
+		last := n.fields[len(n.fields)-1]
+		return last.offset + last.node.sizeOf(v.Field(last.index))
+	}
+}
+
+// minSize is the size of n with one element in its runtime-sized array,
+// which is how OpenGL reports the size of such blocks
+func (n *node) minSize() int {
+	switch {
+	case !n.runtime:
+		return n.size
+	case n.kind == arrayKind:
+		return n.stride
+	default:
+		last := n.fields[len(n.fields)-1]
+		return last.offset + last.node.minSize()
+	}
+}
+
+// memberName returns the GLSL member name of a struct field
+func memberName(f reflect.StructField) (string, bool) {
+	if !f.IsExported() {
+		return "", false
+	}
+	if tag, ok := f.Tag.Lookup("glsl"); ok {
+		if tag == "-" {
+			return "", false
+		}
+		if tag != "" {
+			return tag, true
+		}
+	}
+	r, size := utf8.DecodeRuneInString(f.Name)
+	return string(unicode.ToLower(r)) + f.Name[size:], true
+}
+
+// root returns the struct or slice value behind v and its layout
+func (l Layout) root(v interface{}) (reflect.Value, *node, error) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return rv, nil, fmt.Errorf("cannot lay out a nil %T", v)
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct && (rv.Kind() != reflect.Slice || l != Std430) {
+		if l == Std430 {
+			return rv, nil, fmt.Errorf("%s layout requires a struct or slice, got %T", l, v)
+		}
+		return rv, nil, fmt.Errorf("%s layout requires a struct, got %T", l, v)
+	}
+	n, err := l.layoutOf(rv.Type())
+	return rv, n, err
+}
+
+// Size returns the number of bytes v marshals to
+func (l Layout) Size(v interface{}) (int, error) {
+	rv, n, err := l.root(v)
+	if err != nil {
+		return 0, err
+	}
+	return n.sizeOf(rv), nil
+}
+
+// Stride returns the array stride of v's type, the distance between two
+// elements of a slice of v
+func (l Layout) Stride(v interface{}) (int, error) {
+	_, n, err := l.root(v)
+	if err != nil {
+		return 0, err
+	}
+	if n.runtime {
+		return 0, fmt.Errorf("%T cannot be an array element", v)
+	}
+	align := n.align
+	if l == Std140 {
+		align = roundUp(align, 16)
+	}
+	return roundUp(n.size, align), nil
+}
+
+// Marshal encodes v, a struct or, with Std430, a slice (or pointers to
+// them), with the layout. Padding bytes are zero.
+func (l Layout) Marshal(v interface{}) ([]byte, error) {
+	rv, n, err := l.root(v)
+	if err != nil {
+		return nil, err
+	}
+	buf := make([]byte, n.sizeOf(rv))
+	n.encode(buf, rv)
+	return buf, nil
+}
+
+// Unmarshal decodes data into v, a pointer to a struct or, with Std430, to
+// a slice. Runtime-sized arrays are resized to the number of whole elements
+// in data, reusing their capacity.
+func (l Layout) Unmarshal(data []byte, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return fmt.Errorf("unmarshal requires a non-nil pointer, got %T", v)
+	}
+	rv, n, err := l.root(v)
+	if err != nil {
+		return err
+	}
+	if need := n.minSize() - n.trailingElement(); len(data) < need {
+		return fmt.Errorf("%d bytes are too short for %s, need %d", len(data), rv.Type(), need)
+	}
+	n.decode(data, rv)
+	return nil
+}
+
+// trailingElement is the size of one element of n's runtime-sized array
+func (n *node) trailingElement() int {
+	switch {
+	case !n.runtime:
+		return 0
+	case n.kind == arrayKind:
+		return n.stride
+	default:
+		return n.fields[len(n.fields)-1].node.trailingElement()
+	}
+}
+
+// encode writes v at the start of buf
+func (n *node) encode(buf []byte, v reflect.Value) {
+	switch n.kind {
+	case scalarKind:
+		var bits uint32
+		switch v.Kind() {
+		case reflect.Float32:
+			bits = math.Float32bits(float32(v.Float()))
+		case reflect.Int32:
+			bits = uint32(int32(v.Int()))
+		case reflect.Uint32:
+			bits = uint32(v.Uint())
+		case reflect.Bool:
+			if v.Bool() {
+				bits = 1
+			}
+		}
+		binary.LittleEndian.PutUint32(buf, bits)
+	case vectorKind, matrixKind:
+		// mgl32 matrices are column-major, like GLSL
+		for c := 0; c < n.columns; c++ {
+			for r := 0; r < n.rows; r++ {
+				bits := math.Float32bits(float32(v.Index(c*n.rows + r).Float()))
+				binary.LittleEndian.PutUint32(buf[c*n.colStride+4*r:], bits)
+			}
+		}
+	case arrayKind:
+		for i := 0; i < v.Len(); i++ {
+			n.elem.encode(buf[i*n.stride:], v.Index(i))
+		}
+	case structKind:
+		for _, f := range n.fields {
+			f.node.encode(buf[f.offset:], v.Field(f.index))
+		}
+	}
+}
+
+// decode reads v from the start of buf
+func (n *node) decode(buf []byte, v reflect.Value) {
+	switch n.kind {
+	case scalarKind:
+		bits := binary.LittleEndian.Uint32(buf)
+		switch v.Kind() {
+		case reflect.Float32:
+			v.SetFloat(float64(math.Float32frombits(bits)))
+		case reflect.Int32:
+			v.SetInt(int64(int32(bits)))
+		case reflect.Uint32:
+			v.SetUint(uint64(bits))
+		case reflect.Bool:
+			v.SetBool(bits != 0)
+		}
+	case vectorKind, matrixKind:
+		for c := 0; c < n.columns; c++ {
+			for r := 0; r < n.rows; r++ {
+				bits := binary.LittleEndian.Uint32(buf[c*n.colStride+4*r:])
+				v.Index(c*n.rows + r).SetFloat(float64(math.Float32frombits(bits)))
+			}
+		}
+	case arrayKind:
+		if n.runtime {
+			count := len(buf) / n.stride
+			if v.Cap() >= count {
+				v.SetLen(count)
+			} else {
+				v.Set(reflect.MakeSlice(v.Type(), count, count))
+			}
+		}
+		for i := 0; i < v.Len(); i++ {
+			n.elem.decode(buf[i*n.stride:], v.Index(i))
+		}
+	case structKind:
+		for _, f := range n.fields {
+			f.node.decode(buf[f.offset:], v.Field(f.index))
+		}
+	}
+}
+
+// Members returns the leaf members of v with their offsets, in offset order
+func (l Layout) Members(v interface{}) ([]Member, error) {
+	_, n, err := l.root(v)
+	if err != nil {
+		return nil, err
+	}
+	var members []Member
+	n.members("", 0, &members)
+	return members, nil
+}
+
+// members appends the leaf members of n placed at base
+func (n *node) members(name string, base int, out *[]Member) {
+	switch n.kind {
+	case scalarKind, vectorKind:
+		*out = append(*out, Member{Name: name, Type: n.glType, Offset: base, Size: 1})
+	case matrixKind:
+		*out = append(*out, Member{Name: name, Type: n.glType, Offset: base, Size: 1, MatrixStride: n.colStride})
+	case arrayKind:
+		if n.elem.kind == structKind || n.elem.kind == arrayKind {
+			// OpenGL reports every element of struct arrays and arrays of
+			// arrays, and the first element of runtime-sized ones
+			length := n.length
+			if n.runtime {
+				length = 1
+			}
+			for i := 0; i < length; i++ {
+				n.elem.members(fmt.Sprintf("%s[%d]", name, i), base+i*n.stride, out)
+			}
+			return
+		}
+		*out = append(*out, Member{Name: name, Type: n.elem.glType, Offset: base, Size: n.length,
+			ArrayStride: n.stride, MatrixStride: n.elem.colStride})
+	case structKind:
+		for _, f := range n.fields {
+			child := f.name
+			if name != "" {
+				child = name + "." + f.name
+			}
+			f.node.members(child, base+f.offset, out)
+		}
+	}
+}
+
+// Mismatch describes how a block member differs from the Go field marshaled for it
+type Mismatch struct {
+	Member  string
+	Problem string
+}
+
+// MismatchError reports the members of a uniform or shader storage block
+// whose reflected layout differs from the Go type
+type MismatchError struct {
+	Block      string
+	GoType     string
+	Layout     Layout
+	Mismatches []Mismatch
+}
+
+func (e *MismatchError) Error() string {
+	lines := make([]string, len(e.Mismatches))
+	for i, m := range e.Mismatches {
+		lines[i] = fmt.Sprintf("  %s: %s", m.Member, m.Problem)
+	}
+	return fmt.Sprintf("%s layout of %s does not match block %q:\n%s", e.Layout, e.GoType, e.Block, strings.Join(lines, "\n"))
+}
+
+// ErrNoInterfaceQuery is returned by Std430 validation when the context
+// cannot reflect shader storage blocks (OpenGL 4.3 or
+// ARB_program_interface_query is required); the layout was not checked
+var ErrNoInterfaceQuery = errors.New("shader storage blocks cannot be reflected by this context")
+
+// reflected is a block member as reported by the driver
+type reflected struct {
+	name                      string
+	glType                    shader.DataType
+	offset, size              int
+	arrayStride, matrixStride int
+	topLevelStride            int // Storage blocks only
+}
+
+// compare checks the layout of v against reflected block members
+func (l Layout) compare(blockName string, dataSize int, want []reflected, v interface{}) error {
+	rv, n, err := l.root(v)
+	if err != nil {
+		return err
+	}
+
+	var members []Member
+	n.members("", 0, &members)
+	byName := make(map[string]Member, len(members))
+	for _, m := range members {
+		byName[m.Name] = m
+	}
+
+	var mismatches []Mismatch
+	for _, w := range want {
+		// Members of blocks with an instance name are reported as "Block.member"
+		name := strings.TrimPrefix(w.name, blockName+".")
+		goName := name
+		if rv.Kind() == reflect.Slice {
+			// A slice stands for the block's runtime-sized array, whatever its name
+			if open := strings.IndexByte(name, '['); open > 0 {
+				goName = name[open:]
+			}
+		}
+		got, ok := byName[goName]
+		if !ok {
+			mismatches = append(mismatches, Mismatch{Member: name, Problem: "no matching Go field"})
+			continue
+		}
+
+		var problems []string
+		if got.Type != w.glType {
+			problems = append(problems, fmt.Sprintf("type is %s in the block, %s in Go", w.glType, got.Type))
+		}
+		if got.Offset != w.offset {
+			problems = append(problems, fmt.Sprintf("offset is %d in the block, %d in Go", w.offset, got.Offset))
+		}
+		if got.Size != w.size {
+			problems = append(problems, fmt.Sprintf("array length is %d in the block, %d in Go", w.size, got.Size))
+		}
+		if w.arrayStride != 0 && got.ArrayStride != w.arrayStride {
+			problems = append(problems, fmt.Sprintf("array stride is %d in the block, %d in Go", w.arrayStride, got.ArrayStride))
+		}
+		if w.matrixStride != 0 && got.MatrixStride != w.matrixStride {
+			problems = append(problems, fmt.Sprintf("matrix stride is %d in the block, %d in Go", w.matrixStride, got.MatrixStride))
+		}
+		if w.topLevelStride != 0 {
+			if stride, ok := topLevelStride(n, goName); ok && stride != w.topLevelStride {
+				problems = append(problems, fmt.Sprintf("top-level array stride is %d in the block, %d in Go", w.topLevelStride, stride))
+			}
+		}
+		if len(problems) > 0 {
+			mismatches = append(mismatches, Mismatch{Member: name, Problem: strings.Join(problems, "; ")})
+		}
+	}
+
+	if size := n.minSize(); size < dataSize {
+		mismatches = append(mismatches, Mismatch{
+			Member:  blockName,
+			Problem: fmt.Sprintf("block is %d bytes, Go type marshals to %d", dataSize, size),
+		})
+	}
+
+	if len(mismatches) > 0 {
+		return &MismatchError{Block: blockName, GoType: rv.Type().String(), Layout: l, Mismatches: mismatches}
+	}
+	return nil
+}
+
+// topLevelStride returns the stride of the outermost array a member name
+// belongs to, e.g. particles in "particles[0].position"
+func topLevelStride(n *node, name string) (int, bool) {
+	if n.kind == arrayKind {
+		return n.stride, true
+	}
+	top := name
+	if end := strings.IndexAny(name, ".["); end >= 0 {
+		top = name[:end]
+	}
+	for _, f := range n.fields {
+		if f.name == top && f.node.kind == arrayKind {
+			return f.node.stride, true
+		}
+	}
+	return 0, false
+}
+
+// Check compares the layout of v with a reflected uniform block. Every
+// active block member must have a Go field with the same type, offset,
+// array length and strides; mismatches are returned as a *MismatchError.
+// Go fields for members the driver optimized away are ignored.
+func (l Layout) Check(block shader.UniformBlockInfo, v interface{}) error {
+	want := make([]reflected, len(block.Members))
+	for i, m := range block.Members {
+		want[i] = reflected{
+			name:         m.Name,
+			glType:       m.Type,
+			offset:       int(m.Offset),
+			size:         int(m.Size),
+			arrayStride:  int(m.ArrayStride),
+			matrixStride: int(m.MatrixStride),
+		}
+	}
+	return l.compare(block.Name, int(block.DataSize), want, v)
+}
+
+// CheckStorage compares the layout of v with a reflected shader storage
+// block, like Check. v is a struct whose last field may be a slice, or a
+// slice standing for the block's only member, a runtime-sized array.
+func (l Layout) CheckStorage(block shader.StorageBlockInfo, v interface{}) error {
+	want := make([]reflected, len(block.Members))
+	for i, m := range block.Members {
+		want[i] = reflected{
+			name:           m.Name,
+			glType:         m.Type,
+			offset:         int(m.Offset),
+			size:           int(m.Size),
+			arrayStride:    int(m.ArrayStride),
+			matrixStride:   int(m.MatrixStride),
+			topLevelStride: int(m.TopLevelArrayStride),
+		}
+	}
+	return l.compare(block.Name, int(block.DataSize), want, v)
+}
+
+// Validate checks v against a block of a linked program: a uniform block
+// for Std140 (see Check) and a shader storage block for Std430 (see
+// CheckStorage). Std430 validation returns an error wrapping
+// ErrNoInterfaceQuery when the context cannot reflect storage blocks.
+func (l Layout) Validate(p *shader.Program, blockName string, v interface{}) error {
+	if l == Std430 {
+		if !p.StorageBlocksReflected() {
+			return fmt.Errorf("cannot validate block %q: %w", blockName, ErrNoInterfaceQuery)
+		}
+		block, ok := p.StorageBlock(blockName)
+		if !ok {
+			return fmt.Errorf("shader storage block %q is not active in program %d", blockName, p.ID)
+		}
+		return l.CheckStorage(block, v)
+	}
+
+	block, ok := p.UniformBlock(blockName)
+	if !ok {
+		return fmt.Errorf("uniform block %q is not active in program %d", blockName, p.ID)
+	}
+	return l.Check(block, v)
+}
@@ -0,0 +1,305 @@
+package library
+
+import "github.com/yossideutsch/gogl/pkg/shader"
+
+// attr declares a vertex attribute with an explicit location
+func attr(location int32, name string, t shader.DataType) Variable {
+	return Variable{Name: name, Type: t, Location: location}
+}
+
+// v declares a variable without an explicit location
+func v(name string, t shader.DataType) Variable {
+	return Variable{Name: name, Type: t, Location: -1}
+}
+
+var (
+	gl32 = GLVersion{3, 2}
+	gl41 = GLVersion{4, 1}
+	gl43 = GLVersion{4, 3}
+)
+
+// shaderInfos describes every bundled shader file, mirroring shaders/README.md
+var shaderInfos = map[string]ShaderInfo{
+	// Vertex shaders
+	"vertex/basic.vert": {
+		Stage:       shader.VertexShader,
+		Description: "Position and color with a single MVP matrix",
+		Inputs:      []Variable{attr(0, "aPosition", shader.TypeVec3), attr(1, "aColor", shader.TypeVec3)},
+		Outputs:     []Variable{v("vColor", shader.TypeVec3)},
+		Uniforms:    []Variable{v("uModelViewProjection", shader.TypeMat4)},
+	},
+	"vertex/flat_color.vert": {
+		Stage:       shader.VertexShader,
+		Description: "Transforms positions and passes a uniform color",
+		Inputs:      []Variable{attr(0, "aPosition", shader.TypeVec3)},
+		Outputs:     []Variable{v("vColor", shader.TypeVec3)},
+		Uniforms: []Variable{
+			v("uModel", shader.TypeMat4), v("uView", shader.TypeMat4), v("uProjection", shader.TypeMat4),
+			v("uColor", shader.TypeVec3),
+		},
+	},
+	"vertex/phong.vert": {
+		Stage:       shader.VertexShader,
+		Description: "World-space position, normal and color for Phong lighting",
+		Inputs: []Variable{
+			attr(0, "aPosition", shader.TypeVec3), attr(1, "aNormal", shader.TypeVec3), attr(2, "aColor", shader.TypeVec3),
+		},
+		Outputs:  []Variable{v("vFragPos", shader.TypeVec3), v("vNormal", shader.TypeVec3), v("vColor", shader.TypeVec3)},
+		Uniforms: []Variable{v("uModel", shader.TypeMat4), v("uView", shader.TypeMat4), v("uProjection", shader.TypeMat4)},
+	},
+	"vertex/screen_quad.vert": {
+		Stage:       shader.VertexShader,
+		Description: "Full-screen quad for post-processing",
+		Inputs:      []Variable{attr(0, "aPosition", shader.TypeVec2), attr(1, "aTexCoord", shader.TypeVec2)},
+		Outputs:     []Variable{v("vTexCoord", shader.TypeVec2)},
+	},
+	"vertex/skybox.vert": {
+		Stage:       shader.VertexShader,
+		Description: "Skybox cube at infinite depth",
+		Inputs:      []Variable{attr(0, "aPosition", shader.TypeVec3)},
+		Outputs:     []Variable{v("vTexCoord", shader.TypeVec3)},
+		Uniforms:    []Variable{v("uView", shader.TypeMat4), v("uProjection", shader.TypeMat4)},
+	},
+	"vertex/standard.vert": {
+		Stage:       shader.VertexShader,
+		Description: "General-purpose lit and textured meshes",
+		Inputs: []Variable{
+			attr(0, "aPosition", shader.TypeVec3), attr(1, "aNormal", shader.TypeVec3), attr(2, "aTexCoord", shader.TypeVec2),
+		},
+		Outputs: []Variable{v("vFragPos", shader.TypeVec3), v("vNormal", shader.TypeVec3), v("vTexCoord", shader.TypeVec2)},
+		Uniforms: []Variable{
+			v("uModel", shader.TypeMat4), v("uView", shader.TypeMat4), v("uProjection", shader.TypeMat4),
+			v("uNormalMatrix", shader.TypeMat3),
+		},
+	},
+	"vertex/textured.vert": {
+		Stage:       shader.VertexShader,
+		Description: "Textured meshes with lighting inputs",
+		Inputs: []Variable{
+			attr(0, "aPosition", shader.TypeVec3), attr(1, "aTexCoord", shader.TypeVec2), attr(2, "aNormal", shader.TypeVec3),
+		},
+		Outputs:  []Variable{v("vTexCoord", shader.TypeVec2), v("vNormal", shader.TypeVec3), v("vFragPos", shader.TypeVec3)},
+		Uniforms: []Variable{v("uModel", shader.TypeMat4), v("uView", shader.TypeMat4), v("uProjection", shader.TypeMat4)},
+	},
+	"vertex/world_space.vert": {
+		Stage:       shader.VertexShader,
+		Description: "World-space vertices for geometry shaders that apply view and projection",
+		Inputs: []Variable{
+			attr(0, "aPosition", shader.TypeVec3), attr(1, "aNormal", shader.TypeVec3), attr(2, "aColor", shader.TypeVec3),
+		},
+		Outputs: []Variable{
+			v("vPosition", shader.TypeVec3), v("vFragPos", shader.TypeVec3), v("vNormal", shader.TypeVec3), v("vColor", shader.TypeVec3),
+		},
+		Uniforms: []Variable{v("uModel", shader.TypeMat4)},
+	},
+
+	// Fragment shaders
+	"fragment/basic.frag": {
+		Stage:       shader.FragmentShader,
+		Description: "Vertex color animated over time",
+		Inputs:      []Variable{v("vColor", shader.TypeVec3)},
+		Outputs:     []Variable{v("fragColor", shader.TypeVec4)},
+		Uniforms:    []Variable{v("uTime", shader.TypeFloat)},
+	},
+	"fragment/flat_color.frag": {
+		Stage:       shader.FragmentShader,
+		Description: "Solid color output",
+		Inputs:      []Variable{v("vColor", shader.TypeVec3)},
+		Outputs:     []Variable{v("fragColor", shader.TypeVec4)},
+	},
+	"fragment/geometry_color.frag": {
+		Stage:       shader.FragmentShader,
+		Description: "Solid color output for geometry shader stages",
+		Inputs:      []Variable{v("fColor", shader.TypeVec3)},
+		Outputs:     []Variable{v("fragColor", shader.TypeVec4)},
+	},
+	"fragment/phong.frag": {
+		Stage:       shader.FragmentShader,
+		Description: "Per-fragment Phong lighting",
+		Inputs:      []Variable{v("vFragPos", shader.TypeVec3), v("vNormal", shader.TypeVec3), v("vColor", shader.TypeVec3)},
+		Outputs:     []Variable{v("fragColor", shader.TypeVec4)},
+		Uniforms: []Variable{
+			v("uLightPos", shader.TypeVec3), v("uViewPos", shader.TypeVec3), v("uLightColor", shader.TypeVec3),
+			v("uAmbientStrength", shader.TypeFloat), v("uSpecularStrength", shader.TypeFloat), v("uShininess", shader.TypeFloat),
+		},
+	},
+	"fragment/point_sprite.frag": {
+		Stage:       shader.FragmentShader,
+		Description: "Round, soft-edged point sprites",
+		Inputs:      []Variable{v("fColor", shader.TypeVec3), v("fTexCoord", shader.TypeVec2)},
+		Outputs:     []Variable{v("fragColor", shader.TypeVec4)},
+	},
+	"fragment/simple_texture.frag": {
+		Stage:       shader.FragmentShader,
+		Description: "Unlit texture sampling",
+		Inputs:      []Variable{v("vTexCoord", shader.TypeVec2)},
+		Outputs:     []Variable{v("fragColor", shader.TypeVec4)},
+		Uniforms:    []Variable{v("uTexture", shader.TypeSampler2D)},
+	},
+	"fragment/skybox.frag": {
+		Stage:       shader.FragmentShader,
+		Description: "Cubemap sampling for skyboxes",
+		Inputs:      []Variable{v("vTexCoord", shader.TypeVec3)},
+		Outputs:     []Variable{v("fragColor", shader.TypeVec4)},
+		Uniforms:    []Variable{v("uSkybox", shader.TypeSamplerCube)},
+	},
+	"fragment/textured.frag": {
+		Stage:       shader.FragmentShader,
+		Description: "Texture sampling with Phong lighting",
+		Inputs:      []Variable{v("vTexCoord", shader.TypeVec2), v("vNormal", shader.TypeVec3), v("vFragPos", shader.TypeVec3)},
+		Outputs:     []Variable{v("fragColor", shader.TypeVec4)},
+		Uniforms: []Variable{
+			v("uTexture", shader.TypeSampler2D), v("uLightPos", shader.TypeVec3), v("uViewPos", shader.TypeVec3),
+			v("uLightColor", shader.TypeVec3),
+		},
+	},
+
+	// Post-processing fragment shaders
+	"fragment/blur.frag":                postEffect("Box blur", v("uBlurRadius", shader.TypeFloat)),
+	"fragment/brightness_contrast.frag": postEffect("Brightness and contrast adjustment", v("uBrightness", shader.TypeFloat), v("uContrast", shader.TypeFloat)),
+	"fragment/edge_detection.frag":      postEffect("Edge detection with a convolution kernel"),
+	"fragment/gamma_correction.frag":    postEffect("Gamma correction", v("uGamma", shader.TypeFloat)),
+	"fragment/grayscale.frag":           postEffect("Luminance grayscale"),
+	"fragment/invert.frag":              postEffect("Color inversion"),
+
+	// Geometry shaders
+	"geometry/explode.glsl": {
+		Stage:           shader.GeometryShader,
+		Description:     "Moves triangles along their face normal",
+		Inputs:          []Variable{v("vColor", shader.TypeVec3), v("vNormal", shader.TypeVec3)},
+		Outputs:         []Variable{v("fColor", shader.TypeVec3)},
+		Uniforms:        []Variable{v("uProjection", shader.TypeMat4), v("uView", shader.TypeMat4), v("uExplodeDistance", shader.TypeFloat)},
+		InputPrimitive:  "triangles",
+		OutputPrimitive: "triangle_strip",
+		MaxVertices:     3,
+	},
+	"geometry/normal_lines.glsl": {
+		Stage:           shader.GeometryShader,
+		Description:     "Lines along vertex normals",
+		Inputs:          []Variable{v("vNormal", shader.TypeVec3), v("vFragPos", shader.TypeVec3)},
+		Outputs:         []Variable{v("fColor", shader.TypeVec3)},
+		Uniforms:        []Variable{v("uProjection", shader.TypeMat4), v("uView", shader.TypeMat4), v("uNormalLength", shader.TypeFloat)},
+		InputPrimitive:  "triangles",
+		OutputPrimitive: "line_strip",
+		MaxVertices:     6,
+	},
+	"geometry/normal_visualization.glsl": {
+		Stage:       shader.GeometryShader,
+		Description: "Lines along face normals from triangle centers",
+		Inputs:      []Variable{v("vPosition", shader.TypeVec3), v("vNormal", shader.TypeVec3)},
+		Outputs:     []Variable{v("fColor", shader.TypeVec3)},
+		Uniforms: []Variable{
+			v("uProjection", shader.TypeMat4), v("uView", shader.TypeMat4), v("uModel", shader.TypeMat4),
+			v("uNormalLength", shader.TypeFloat),
+		},
+		InputPrimitive:  "triangles",
+		OutputPrimitive: "line_strip",
+		MaxVertices:     6,
+	},
+	"geometry/point_to_quad.glsl": {
+		Stage:           shader.GeometryShader,
+		Description:     "Expands points into billboard quads",
+		Inputs:          []Variable{v("vColor", shader.TypeVec3)},
+		Outputs:         []Variable{v("fColor", shader.TypeVec3), v("fTexCoord", shader.TypeVec2)},
+		Uniforms:        []Variable{v("uProjection", shader.TypeMat4), v("uView", shader.TypeMat4), v("uPointSize", shader.TypeFloat)},
+		InputPrimitive:  "points",
+		OutputPrimitive: "triangle_strip",
+		MaxVertices:     4,
+	},
+	"geometry/wireframe.glsl": {
+		Stage:           shader.GeometryShader,
+		Description:     "Triangle outlines",
+		Inputs:          []Variable{v("vColor", shader.TypeVec3)},
+		Outputs:         []Variable{v("fColor", shader.TypeVec3)},
+		InputPrimitive:  "triangles",
+		OutputPrimitive: "line_strip",
+		MaxVertices:     4,
+	},
+
+	// Compute shaders
+	"compute/image_processing.glsl": {
+		Stage:       shader.ComputeShader,
+		Description: "Blur, edge detection and brightness/contrast image filters",
+		Uniforms: []Variable{
+			v("inputImage", shader.TypeImage2D), v("outputImage", shader.TypeImage2D),
+			v("uBlurRadius", shader.TypeFloat), v("uBrightness", shader.TypeFloat), v("uContrast", shader.TypeFloat),
+			v("uFilterType", shader.TypeInt),
+		},
+		MinGLVersion:  gl43,
+		WorkGroupSize: [3]int{16, 16, 1},
+	},
+	"compute/particle_simulation.glsl": {
+		Stage:       shader.ComputeShader,
+		Description: "Particle physics over a std430 ParticleBuffer at binding 0",
+		Uniforms: []Variable{
+			v("uDeltaTime", shader.TypeFloat), v("uGravity", shader.TypeFloat), v("uAttractor", shader.TypeVec2),
+			v("uAttractorStrength", shader.TypeFloat), v("uViewportSize", shader.TypeVec2),
+		},
+		MinGLVersion:  gl43,
+		WorkGroupSize: [3]int{16, 16, 1},
+	},
+}
+
+// postEffect describes a post-processing fragment shader over a screen quad
+func postEffect(description string, uniforms ...Variable) ShaderInfo {
+	return ShaderInfo{
+		Stage:       shader.FragmentShader,
+		Description: description,
+		Inputs:      []Variable{v("vTexCoord", shader.TypeVec2)},
+		Outputs:     []Variable{v("fragColor", shader.TypeVec4)},
+		Uniforms:    append([]Variable{v("uScreenTexture", shader.TypeSampler2D)}, uniforms...),
+	}
+}
+
+// programInfos lists the bundled stage combinations that link together
+var programInfos = map[string]ProgramInfo{
+	"basic":          {Kind: KindSurface, Description: "Vertex colors animated over time", Shaders: []string{"vertex/basic.vert", "fragment/basic.frag"}},
+	"flat_color":     {Kind: KindSurface, Description: "Meshes in a single uniform color", Shaders: []string{"vertex/flat_color.vert", "fragment/flat_color.frag"}},
+	"phong":          {Kind: KindSurface, Description: "Vertex-colored meshes with Phong lighting", Shaders: []string{"vertex/phong.vert", "fragment/phong.frag"}},
+	"textured":       {Kind: KindSurface, Description: "Textured meshes with Phong lighting", Shaders: []string{"vertex/textured.vert", "fragment/textured.frag"}},
+	"simple_texture": {Kind: KindSurface, Description: "Unlit textured meshes", Shaders: []string{"vertex/textured.vert", "fragment/simple_texture.frag"}},
+	"standard":       {Kind: KindSurface, Description: "Textured meshes lit with an explicit normal matrix", Shaders: []string{"vertex/standard.vert", "fragment/textured.frag"}},
+	"skybox":         {Kind: KindSurface, Description: "Cubemap skybox", Shaders: []string{"vertex/skybox.vert", "fragment/skybox.frag"}},
+
+	"blur":                postProgram("Box blur", "blur"),
+	"brightness_contrast": postProgram("Brightness and contrast adjustment", "brightness_contrast"),
+	"edge_detection":      postProgram("Edge detection", "edge_detection"),
+	"gamma_correction":    postProgram("Gamma correction", "gamma_correction"),
+	"grayscale":           postProgram("Grayscale", "grayscale"),
+	"invert":              postProgram("Color inversion", "invert"),
+
+	"point_sprites":        {Kind: KindGeometry, Description: "Points expanded into round billboards", Shaders: []string{"vertex/world_space.vert", "geometry/point_to_quad.glsl", "fragment/point_sprite.frag"}},
+	"wireframe":            {Kind: KindGeometry, Description: "Triangle outlines in vertex colors", Shaders: []string{"vertex/phong.vert", "geometry/wireframe.glsl", "fragment/geometry_color.frag"}},
+	"normal_lines":         {Kind: KindGeometry, Description: "Per-vertex normal debug lines", Shaders: []string{"vertex/world_space.vert", "geometry/normal_lines.glsl", "fragment/geometry_color.frag"}},
+	"normal_visualization": {Kind: KindGeometry, Description: "Per-face normal debug lines", Shaders: []string{"vertex/world_space.vert", "geometry/normal_visualization.glsl", "fragment/geometry_color.frag"}},
+	"explode":              {Kind: KindGeometry, Description: "Triangles pushed along their face normals", Shaders: []string{"vertex/world_space.vert", "geometry/explode.glsl", "fragment/geometry_color.frag"}},
+
+	"particle_simulation": {Kind: KindCompute, Description: "GPU particle simulation", Shaders: []string{"compute/particle_simulation.glsl"}},
+	"image_processing":    {Kind: KindCompute, Description: "GPU image filters", Shaders: []string{"compute/image_processing.glsl"}},
+}
+
+// postProgram combines the screen quad with a post-processing fragment shader
+func postProgram(description, effect string) ProgramInfo {
+	return ProgramInfo{
+		Kind:        KindPostProcess,
+		Description: description,
+		Shaders:     []string{"vertex/screen_quad.vert", "fragment/" + effect + ".frag"},
+	}
+}
+
+func init() {
+	for path, info := range shaderInfos {
+		info.Path = path
+		if info.MinGLVersion == (GLVersion{}) {
+			info.MinGLVersion = gl41
+			if info.Stage == shader.GeometryShader {
+				info.MinGLVersion = gl32
+			}
+		}
+		shaderInfos[path] = info
+	}
+	for name, info := range programInfos {
+		info.Name = name
+		programInfos[name] = info
+	}
+}
@@ -0,0 +1,293 @@
+// Package library exposes the shader programs bundled in the shaders/
+// directory. The GLSL files are embedded in the binary, and every program
+// comes with metadata describing its inputs, outputs, uniforms and the
+// OpenGL version it needs.
+//
+// Example usage:
+//
+//	phong, err := library.Phong()
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	defer phong.Delete()
+//
+//	blur, err := library.PostProcess("blur")
+//
+//	info, _ := library.Lookup("phong")
+//	for _, u := range info.Uniforms() {
+//	    fmt.Println(u.Name, u.Type)
+//	}
+package library
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+	"github.com/yossideutsch/gogl/pkg/shader"
+	"github.com/yossideutsch/gogl/shaders"
+)
+
+// GLVersion is an OpenGL version
+type GLVersion struct {
+	Major int
+	Minor int
+}
+
+func (v GLVersion) String() string {
+	return fmt.Sprintf("%d.%d", v.Major, v.Minor)
+}
+
+// IsAtLeast checks if this version is at least other
+func (v GLVersion) IsAtLeast(other GLVersion) bool {
+	if v.Major != other.Major {
+		return v.Major > other.Major
+	}
+	return v.Minor >= other.Minor
+}
+
+// Variable is a typed shader input, output or uniform
+type Variable struct {
+	Name     string
+	Type     shader.DataType
+	Location int32 // Explicit layout location, -1 when unset
+}
+
+// Kind classifies bundled programs
+type Kind int
+
+const (
+	KindSurface     Kind = iota // Renders meshes
+	KindPostProcess             // Full-screen effect over a screen quad
+	KindGeometry                // Uses a geometry shader stage
+	KindCompute                 // Compute-only program
+)
+
+func (k Kind) String() string {
+	switch k {
+	case KindSurface:
+		return "surface"
+	case KindPostProcess:
+		return "post-process"
+	case KindGeometry:
+		return "geometry"
+	case KindCompute:
+		return "compute"
+	default:
+		return "unknown"
+	}
+}
+
+// ShaderInfo describes a bundled shader file
+type ShaderInfo struct {
+	Path         string // Path inside shaders.FS, e.g. "vertex/phong.vert"
+	Stage        shader.ShaderType
+	Description  string
+	Inputs       []Variable
+	Outputs      []Variable
+	Uniforms     []Variable
+	MinGLVersion GLVersion
+
+	// Geometry shader layout
+	InputPrimitive  string
+	OutputPrimitive string
+	MaxVertices     int
+
+	// Compute shader layout
+	WorkGroupSize [3]int
+}
+
+// ProgramInfo describes a bundled program, a set of shader files that link together
+type ProgramInfo struct {
+	Name        string
+	Kind        Kind
+	Description string
+	Shaders     []string // Paths in pipeline order
+}
+
+// Stages returns the metadata of the program's shader files
+func (p ProgramInfo) Stages() []ShaderInfo {
+	stages := make([]ShaderInfo, 0, len(p.Shaders))
+	for _, path := range p.Shaders {
+		stages = append(stages, shaderInfos[path])
+	}
+	return stages
+}
+
+// Inputs returns the vertex attributes (or nothing for compute programs)
+func (p ProgramInfo) Inputs() []Variable {
+	if len(p.Shaders) == 0 {
+		return nil
+	}
+	return shaderInfos[p.Shaders[0]].Inputs
+}
+
+// Outputs returns the outputs of the last stage
+func (p ProgramInfo) Outputs() []Variable {
+	if len(p.Shaders) == 0 {
+		return nil
+	}
+	return shaderInfos[p.Shaders[len(p.Shaders)-1]].Outputs
+}
+
+// Uniforms returns the uniforms of all stages; uniforms shared between
+// stages are listed once
+func (p ProgramInfo) Uniforms() []Variable {
+	var uniforms []Variable
+	seen := make(map[string]bool)
+	for _, stage := range p.Stages() {
+		for _, u := range stage.Uniforms {
+			if !seen[u.Name] {
+				seen[u.Name] = true
+				uniforms = append(uniforms, u)
+			}
+		}
+	}
+	return uniforms
+}
+
+// MinGLVersion returns the highest OpenGL version required by any stage
+func (p ProgramInfo) MinGLVersion() GLVersion {
+	version := GLVersion{}
+	for _, stage := range p.Stages() {
+		if !version.IsAtLeast(stage.MinGLVersion) {
+			version = stage.MinGLVersion
+		}
+	}
+	return version
+}
+
+// preprocessor resolves the bundled files and their includes from the embedded FS
+var preprocessor = shader.NewFSPreprocessor(shaders.FS)
+
+// Shaders returns the metadata of every bundled shader file, sorted by path
+func Shaders() []ShaderInfo {
+	infos := make([]ShaderInfo, 0, len(shaderInfos))
+	for _, info := range shaderInfos {
+		infos = append(infos, info)
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Path < infos[j].Path })
+	return infos
+}
+
+// Shader returns the metadata of a bundled shader file
+func Shader(path string) (ShaderInfo, bool) {
+	info, ok := shaderInfos[path]
+	return info, ok
+}
+
+// Programs returns every bundled program, sorted by name
+func Programs() []ProgramInfo {
+	infos := make([]ProgramInfo, 0, len(programInfos))
+	for _, info := range programInfos {
+		infos = append(infos, info)
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name })
+	return infos
+}
+
+// Lookup returns the metadata of a bundled program
+func Lookup(name string) (ProgramInfo, bool) {
+	info, ok := programInfos[name]
+	return info, ok
+}
+
+// PostEffects returns the names of the bundled post-processing effects
+func PostEffects() []string {
+	var names []string
+	for _, info := range Programs() {
+		if info.Kind == KindPostProcess {
+			names = append(names, info.Name)
+		}
+	}
+	return names
+}
+
+// contextVersion returns the version of the current GL context
+func contextVersion() GLVersion {
+	var major, minor int32
+	gl.GetIntegerv(gl.MAJOR_VERSION, &major)
+	gl.GetIntegerv(gl.MINOR_VERSION, &minor)
+	return GLVersion{int(major), int(minor)}
+}
+
+// Build compiles and links a bundled program by name. It must be called
+// with a current GL context that meets the program's MinGLVersion, or one
+// its shaders are translated for, such as OpenGL 3.3 or OpenGL ES 3.0 (see
+// shader.Translate).
+func Build(name string) (*shader.Program, error) {
+	info, ok := programInfos[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown library program %q", name)
+	}
+	if required, current := info.MinGLVersion(), contextVersion(); !current.IsAtLeast(required) {
+		if target, err := shader.TargetDialect(); err != nil || !target.Translated() {
+			return nil, fmt.Errorf("library program %q requires OpenGL %s, context is %s", name, required, current)
+		}
+	}
+
+	compiled := make([]*shader.Shader, 0, len(info.Shaders))
+	for _, stage := range info.Stages() {
+		s, err := preprocessor.CompileShaderFromFile(stage.Path, stage.Stage)
+		if err != nil {
+			for _, c := range compiled {
+				c.Delete()
+			}
+			return nil, err
+		}
+		compiled = append(compiled, s)
+	}
+	return shader.CreateProgram(compiled...)
+}
+
+// Basic returns the vertex-colored program with a time uniform
+func Basic() (*shader.Program, error) { return Build("basic") }
+
+// FlatColor returns the program that draws meshes in a uniform color
+func FlatColor() (*shader.Program, error) { return Build("flat_color") }
+
+// Phong returns the per-fragment Phong lighting program
+func Phong() (*shader.Program, error) { return Build("phong") }
+
+// Textured returns the textured program with Phong lighting
+func Textured() (*shader.Program, error) { return Build("textured") }
+
+// SimpleTexture returns the unlit textured program
+func SimpleTexture() (*shader.Program, error) { return Build("simple_texture") }
+
+// Standard returns the lit textured program with a normal matrix uniform
+func Standard() (*shader.Program, error) { return Build("standard") }
+
+// Skybox returns the cubemap skybox program
+func Skybox() (*shader.Program, error) { return Build("skybox") }
+
+// PostProcess returns a full-screen effect drawn over a screen quad; see
+// PostEffects for the available names
+func PostProcess(effect string) (*shader.Program, error) {
+	if info, ok := programInfos[effect]; !ok || info.Kind != KindPostProcess {
+		return nil, fmt.Errorf("unknown post-processing effect %q (available: %v)", effect, PostEffects())
+	}
+	return Build(effect)
+}
+
+// PointSprites returns the program that expands points into round billboards
+func PointSprites() (*shader.Program, error) { return Build("point_sprites") }
+
+// Wireframe returns the program that draws triangle outlines
+func Wireframe() (*shader.Program, error) { return Build("wireframe") }
+
+// NormalLines returns the debug program that draws per-vertex normals
+func NormalLines() (*shader.Program, error) { return Build("normal_lines") }
+
+// NormalVisualization returns the debug program that draws per-face normals
+func NormalVisualization() (*shader.Program, error) { return Build("normal_visualization") }
+
+// Explode returns the program that pushes triangles along their face normals
+func Explode() (*shader.Program, error) { return Build("explode") }
+
+// ParticleSimulation returns the compute program that advances particles
+// stored in a shader storage buffer (OpenGL 4.3+)
+func ParticleSimulation() (*shader.Program, error) { return Build("particle_simulation") }
+
+// ImageProcessing returns the compute image filter program (OpenGL 4.3+)
+func ImageProcessing() (*shader.Program, error) { return Build("image_processing") }
@@ -0,0 +1,487 @@
+package material
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/yossideutsch/gogl/pkg/library"
+	"github.com/yossideutsch/gogl/pkg/resource"
+	"github.com/yossideutsch/gogl/pkg/shader"
+)
+
+// document is the JSON form of a material
+type document struct {
+	Name     string                     `json:"name,omitempty"`
+	Program  string                     `json:"program,omitempty"`
+	Shaders  map[string]string          `json:"shaders,omitempty"`
+	Uniforms map[string]json.RawMessage `json:"uniforms,omitempty"`
+	Textures map[string]TextureFile     `json:"textures,omitempty"`
+}
+
+// TextureFile describes a texture loaded from image files. Paths are
+// relative to the material file.
+type TextureFile struct {
+	Path   string   `json:"path,omitempty"`   // Image of a 2D texture
+	Layers []string `json:"layers,omitempty"` // Images of the layers of a texture array
+	Filter string   `json:"filter,omitempty"` // "linear" (default), "nearest" or "mipmap"
+	Wrap   string   `json:"wrap,omitempty"`   // "repeat" (default), "clamp" or "mirror"
+}
+
+// UnmarshalJSON accepts a bare image path as well as an object
+func (f *TextureFile) UnmarshalJSON(data []byte) error {
+	var path string
+	if err := json.Unmarshal(data, &path); err == nil {
+		*f = TextureFile{Path: path}
+		return nil
+	}
+	type plain TextureFile // Without the UnmarshalJSON method
+	return json.Unmarshal(data, (*plain)(f))
+}
+
+// MarshalJSON writes textures with default settings as a bare image path
+func (f TextureFile) MarshalJSON() ([]byte, error) {
+	if f.Layers == nil && f.Filter == "" && f.Wrap == "" {
+		return json.Marshal(f.Path)
+	}
+	type plain TextureFile
+	return json.Marshal(plain(f))
+}
+
+// config returns the texture configuration for the filter and wrap names
+func (f TextureFile) config() (resource.TextureConfig, error) {
+	config := resource.DefaultTextureConfig()
+	switch f.Filter {
+	case "", "linear":
+	case "nearest":
+		config.MinFilter, config.MagFilter = resource.FilterNearest, resource.FilterNearest
+	case "mipmap":
+		config.MinFilter = resource.FilterLinearMipmapLinear
+		config.GenerateMipmap = true
+	default:
+		return config, fmt.Errorf("unknown texture filter %q (expected linear, nearest or mipmap)", f.Filter)
+	}
+	switch f.Wrap {
+	case "", "repeat":
+	case "clamp":
+		config.WrapS, config.WrapT = resource.WrapClampToEdge, resource.WrapClampToEdge
+	case "mirror":
+		config.WrapS, config.WrapT = resource.WrapMirroredRepeat, resource.WrapMirroredRepeat
+	default:
+		return config, fmt.Errorf("unknown texture wrap %q (expected repeat, clamp or mirror)", f.Wrap)
+	}
+	return config, nil
+}
+
+// Loader loads materials from JSON files. Materials loaded by the same
+// Loader share programs built from the same source and textures loaded
+// from the same files with the same settings. The Loader owns them; Delete
+// frees them once its materials are no longer drawn.
+type Loader struct {
+	programs map[string]*shader.Program
+	textures map[string]Texture
+}
+
+// NewLoader creates an empty material loader. The zero Loader is also
+// ready to use.
+func NewLoader() *Loader {
+	return &Loader{
+		programs: make(map[string]*shader.Program),
+		textures: make(map[string]Texture),
+	}
+}
+
+// Load reads a material from a JSON file
+func (l *Loader) Load(path string) (*Material, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read material file: %w", err)
+	}
+	m, err := l.Parse(data, filepath.Dir(path))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return m, nil
+}
+
+// Parse decodes a material from JSON, resolving shader and texture paths
+// relative to dir
+func (l *Loader) Parse(data []byte, dir string) (*Material, error) {
+	var doc document
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("invalid material JSON: %w", err)
+	}
+
+	source := ProgramSource{Library: doc.Program, Shaders: doc.Shaders}
+	program, err := l.program(source, dir)
+	if err != nil {
+		return nil, err
+	}
+	m := New(doc.Name, program)
+	m.Source = source
+
+	// Sorted so errors are reported for the same entry every time
+	names := make([]string, 0, len(doc.Uniforms))
+	for name := range doc.Uniforms {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		info, err := m.uniform(name)
+		if err != nil {
+			return nil, err
+		}
+		value, err := decodeValue(info, doc.Uniforms[name])
+		if err != nil {
+			return nil, fmt.Errorf("material %q: uniform %q: %w", m.Name, name, err)
+		}
+		if err := m.Set(name, value); err != nil {
+			return nil, err
+		}
+	}
+
+	samplers := make([]string, 0, len(doc.Textures))
+	for sampler := range doc.Textures {
+		samplers = append(samplers, sampler)
+	}
+	sort.Strings(samplers)
+	for _, sampler := range samplers {
+		file := doc.Textures[sampler]
+		texture, err := l.texture(file, dir)
+		if err != nil {
+			return nil, fmt.Errorf("material %q: texture %q: %w", m.Name, sampler, err)
+		}
+		if err := m.SetTexture(sampler, texture); err != nil {
+			return nil, err
+		}
+		m.files[sampler] = file
+	}
+	return m, nil
+}
+
+// program returns the shared program for source, building it on first use
+func (l *Loader) program(source ProgramSource, dir string) (*shader.Program, error) {
+	if (source.Library == "") == (len(source.Shaders) == 0) {
+		return nil, fmt.Errorf("material needs either a library \"program\" or \"shaders\"")
+	}
+	if l.programs == nil {
+		l.programs = make(map[string]*shader.Program)
+	}
+	if source.Library != "" {
+		key := "library:" + source.Library
+		if program, ok := l.programs[key]; ok {
+			return program, nil
+		}
+		program, err := library.Build(source.Library)
+		if err != nil {
+			return nil, err
+		}
+		l.programs[key] = program
+		return program, nil
+	}
+
+	names := make([]string, 0, len(source.Shaders))
+	for name := range source.Shaders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	paths := make([]string, len(names))
+	stages := make([]shader.ShaderType, len(names))
+	for i, name := range names {
+		stage, ok := shader.ParseShaderType(name)
+		if !ok {
+			return nil, fmt.Errorf("unknown shader stage %q", name)
+		}
+		stages[i] = stage
+		paths[i] = filepath.Join(dir, source.Shaders[name])
+	}
+	key := "files:" + strings.Join(paths, "\x00")
+	if program, ok := l.programs[key]; ok {
+		return program, nil
+	}
+
+	compiled := make([]*shader.Shader, 0, len(paths))
+	for i, path := range paths {
+		s, err := shader.CompileShaderFromFile(path, stages[i])
+		if err != nil {
+			for _, c := range compiled {
+				c.Delete()
+			}
+			return nil, err
+		}
+		compiled = append(compiled, s)
+	}
+	program, err := shader.CreateProgram(compiled...)
+	if err != nil {
+		// Stage sets rejected before linking leave the shaders to the caller
+		for _, c := range compiled {
+			c.Delete()
+		}
+		return nil, err
+	}
+	l.programs[key] = program
+	return program, nil
+}
+
+// texture returns the shared texture for file, loading it on first use
+func (l *Loader) texture(file TextureFile, dir string) (Texture, error) {
+	if (file.Path == "") == (len(file.Layers) == 0) {
+		return nil, fmt.Errorf("texture needs either a \"path\" or \"layers\"")
+	}
+	config, err := file.config()
+	if err != nil {
+		return nil, err
+	}
+
+	var paths []string
+	if file.Path != "" {
+		paths = []string{filepath.Join(dir, file.Path)}
+	} else {
+		for _, layer := range file.Layers {
+			paths = append(paths, filepath.Join(dir, layer))
+		}
+	}
+	key := fmt.Sprintf("%t\x00%v\x00%s", file.Path == "", config, strings.Join(paths, "\x00"))
+	if texture, ok := l.textures[key]; ok {
+		return texture, nil
+	}
+	if l.textures == nil {
+		l.textures = make(map[string]Texture)
+	}
+
+	var texture Texture
+	if file.Path != "" {
+		texture, err = resource.LoadTexture2D(paths[0], config)
+	} else {
+		texture, err = resource.LoadTextureArray(paths, config)
+	}
+	if err != nil {
+		return nil, err
+	}
+	l.textures[key] = texture
+	return texture, nil
+}
+
+// Delete frees every program and texture the loader loaded
+func (l *Loader) Delete() {
+	for _, program := range l.programs {
+		program.Delete()
+	}
+	for _, texture := range l.textures {
+		switch t := texture.(type) {
+		case *resource.Texture2D:
+			t.Delete()
+		case *resource.TextureArray:
+			t.Delete()
+		}
+	}
+	l.programs = make(map[string]*shader.Program)
+	l.textures = make(map[string]Texture)
+}
+
+// MarshalJSON encodes the material in the format Loader reads. Every
+// texture must have been loaded from a file.
+func (m *Material) MarshalJSON() ([]byte, error) {
+	doc := document{
+		Name:     m.Name,
+		Program:  m.Source.Library,
+		Shaders:  m.Source.Shaders,
+		Uniforms: make(map[string]json.RawMessage, len(m.values)),
+		Textures: make(map[string]TextureFile, len(m.textures)),
+	}
+	if (doc.Program == "") == (len(doc.Shaders) == 0) {
+		return nil, fmt.Errorf("material %q needs either Source.Library or Source.Shaders to be saved", m.Name)
+	}
+	for name, value := range m.values {
+		raw, err := json.Marshal(value)
+		if err != nil {
+			return nil, fmt.Errorf("material %q: uniform %q: %w", m.Name, name, err)
+		}
+		doc.Uniforms[name] = raw
+	}
+	for sampler := range m.textures {
+		file, ok := m.files[sampler]
+		if !ok {
+			return nil, fmt.Errorf("material %q: texture %q was not loaded from a file", m.Name, sampler)
+		}
+		doc.Textures[sampler] = file
+	}
+	return json.Marshal(doc)
+}
+
+// Save writes the material to a JSON file. Shader and texture paths are
+// written as they were loaded, relative to the original material file.
+func (m *Material) Save(path string) error {
+	data, err := json.MarshalIndent(m, "", "    ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to write material file: %w", err)
+	}
+	return nil
+}
+
+// floatComponents is the number of floats in one element of each float
+// uniform type
+var floatComponents = map[shader.DataType]int{
+	shader.TypeFloat: 1,
+	shader.TypeVec2:  2,
+	shader.TypeVec3:  3,
+	shader.TypeVec4:  4,
+	shader.TypeMat2:  4,
+	shader.TypeMat3:  9,
+	shader.TypeMat4:  16,
+}
+
+// decodeValue converts a JSON value to the Go type Program.Set expects for
+// the reflected uniform
+func decodeValue(info shader.UniformInfo, raw json.RawMessage) (interface{}, error) {
+	elements := []json.RawMessage{raw}
+	if info.Size > 1 {
+		elements = nil
+		if err := json.Unmarshal(raw, &elements); err != nil {
+			return nil, fmt.Errorf("expected an array for %s[%d]", info.Type, info.Size)
+		}
+		if len(elements) == 0 || len(elements) > int(info.Size) {
+			return nil, fmt.Errorf("expected 1 to %d elements for %s[%d], got %d", info.Size, info.Type, info.Size, len(elements))
+		}
+	}
+
+	switch info.Type {
+	case shader.TypeInt, shader.TypeUInt, shader.TypeBool:
+		return decodeScalars(info, elements)
+	}
+	n, ok := floatComponents[info.Type]
+	if !ok {
+		return nil, fmt.Errorf("%s values are not supported in materials", info.Type)
+	}
+
+	floats := make([][]float32, len(elements))
+	for i, element := range elements {
+		if n == 1 {
+			var v float32
+			if err := json.Unmarshal(element, &v); err != nil {
+				return nil, fmt.Errorf("expected a number for %s", info.Type)
+			}
+			floats[i] = []float32{v}
+			continue
+		}
+		if err := json.Unmarshal(element, &floats[i]); err != nil || len(floats[i]) != n {
+			return nil, fmt.Errorf("expected an array of %d numbers for %s", n, info.Type)
+		}
+	}
+
+	if info.Size == 1 {
+		f := floats[0]
+		switch info.Type {
+		case shader.TypeFloat:
+			return f[0], nil
+		case shader.TypeVec2:
+			return mgl32.Vec2{f[0], f[1]}, nil
+		case shader.TypeVec3:
+			return mgl32.Vec3{f[0], f[1], f[2]}, nil
+		case shader.TypeVec4:
+			return mgl32.Vec4{f[0], f[1], f[2], f[3]}, nil
+		case shader.TypeMat2:
+			var m mgl32.Mat2
+			copy(m[:], f)
+			return m, nil
+		case shader.TypeMat3:
+			var m mgl32.Mat3
+			copy(m[:], f)
+			return m, nil
+		default:
+			var m mgl32.Mat4
+			copy(m[:], f)
+			return m, nil
+		}
+	}
+
+	switch info.Type {
+	case shader.TypeFloat:
+		values := make([]float32, len(floats))
+		for i, f := range floats {
+			values[i] = f[0]
+		}
+		return values, nil
+	case shader.TypeVec2:
+		values := make([]mgl32.Vec2, len(floats))
+		for i, f := range floats {
+			copy(values[i][:], f)
+		}
+		return values, nil
+	case shader.TypeVec3:
+		values := make([]mgl32.Vec3, len(floats))
+		for i, f := range floats {
+			copy(values[i][:], f)
+		}
+		return values, nil
+	case shader.TypeVec4:
+		values := make([]mgl32.Vec4, len(floats))
+		for i, f := range floats {
+			copy(values[i][:], f)
+		}
+		return values, nil
+	case shader.TypeMat2:
+		values := make([]mgl32.Mat2, len(floats))
+		for i, f := range floats {
+			copy(values[i][:], f)
+		}
+		return values, nil
+	case shader.TypeMat3:
+		values := make([]mgl32.Mat3, len(floats))
+		for i, f := range floats {
+			copy(values[i][:], f)
+		}
+		return values, nil
+	default:
+		values := make([]mgl32.Mat4, len(floats))
+		for i, f := range floats {
+			copy(values[i][:], f)
+		}
+		return values, nil
+	}
+}
+
+// decodeScalars converts JSON int, uint and bool values
+func decodeScalars(info shader.UniformInfo, elements []json.RawMessage) (interface{}, error) {
+	switch info.Type {
+	case shader.TypeBool:
+		if info.Size > 1 {
+			return nil, fmt.Errorf("bool arrays are not supported in materials")
+		}
+		var v bool
+		if err := json.Unmarshal(elements[0], &v); err != nil {
+			return nil, fmt.Errorf("expected true or false for bool")
+		}
+		return v, nil
+	case shader.TypeInt:
+		values := make([]int32, len(elements))
+		for i, element := range elements {
+			if err := json.Unmarshal(element, &values[i]); err != nil {
+				return nil, fmt.Errorf("expected an integer for int")
+			}
+		}
+		if info.Size == 1 {
+			return values[0], nil
+		}
+		return values, nil
+	default:
+		values := make([]uint32, len(elements))
+		for i, element := range elements {
+			if err := json.Unmarshal(element, &values[i]); err != nil {
+				return nil, fmt.Errorf("expected a non-negative integer for uint")
+			}
+		}
+		if info.Size == 1 {
+			return values[0], nil
+		}
+		return values, nil
+	}
+}
@@ -0,0 +1,196 @@
+// Package material bundles a shader program with the uniform values and
+// textures it is drawn with. Apply makes the program current, binds every
+// texture to its own texture unit, points the sampler uniforms at those
+// units and uploads the values, replacing the per-example wiring of
+// locations and units.
+//
+// Materials can be described in JSON files so their parameters are tuned
+// without recompiling:
+//
+//	{
+//	    "name": "brick",
+//	    "program": "textured",
+//	    "uniforms": {
+//	        "uLightColor": [1, 0.95, 0.9]
+//	    },
+//	    "textures": {
+//	        "uTexture": {"path": "brick.png", "filter": "mipmap"}
+//	    }
+//	}
+//
+// "program" names a bundled library program; "shaders" lists shader files
+// by stage instead. Uniform values are checked against the reflected
+// uniform types when the file is loaded: numbers for scalars, arrays for
+// vectors, column-major arrays for matrices and arrays of those for
+// uniform arrays. A texture is an image path, or an object with "path" or
+// "layers" (a texture array) and optional "filter" and "wrap".
+//
+// Example usage:
+//
+//	loader := material.NewLoader()
+//	defer loader.Delete()
+//
+//	brick, err := loader.Load("materials/brick.json")
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	brick.Set("uLightPos", lightPos)
+//	if err := brick.Apply(); err != nil {
+//	    log.Fatal(err)
+//	}
+package material
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/yossideutsch/gogl/pkg/shader"
+)
+
+// Texture is a texture a material binds to a sampler, such as
+// *resource.Texture2D or *resource.TextureArray
+type Texture interface {
+	Bind(unit uint32)
+}
+
+// ProgramSource names the program of a material in its JSON file
+type ProgramSource struct {
+	Library string            // Bundled program name, see library.Lookup
+	Shaders map[string]string // Shader files by stage name ("vertex", "frag", ...)
+}
+
+// Material is a program with its uniform values and textures. It does not
+// own the program or the textures; programs and textures loaded from JSON
+// belong to the Loader.
+type Material struct {
+	Name    string
+	Program *shader.Program
+
+	// Source is the program written by MarshalJSON and Save
+	Source ProgramSource
+
+	values   map[string]interface{}
+	textures map[string]Texture
+	files    map[string]TextureFile // Files of textures loaded by a Loader, by sampler
+}
+
+// New creates a material without values or textures. A Material literal
+// with a Name and Program works as well.
+func New(name string, program *shader.Program) *Material {
+	return &Material{
+		Name:     name,
+		Program:  program,
+		values:   make(map[string]interface{}),
+		textures: make(map[string]Texture),
+		files:    make(map[string]TextureFile),
+	}
+}
+
+// uniform looks up an active default-block uniform of the program
+func (m *Material) uniform(name string) (shader.UniformInfo, error) {
+	if m.Program == nil {
+		return shader.UniformInfo{}, fmt.Errorf("material %q has no program", m.Name)
+	}
+	info, ok := m.Program.Uniform(name)
+	if !ok {
+		return info, fmt.Errorf("material %q: uniform %q is not an active uniform of its program", m.Name, name)
+	}
+	if info.BlockIndex != -1 {
+		return info, fmt.Errorf("material %q: uniform %q is a uniform block member", m.Name, name)
+	}
+	return info, nil
+}
+
+// Set stores a uniform value that Apply uploads with Program.Set, which
+// checks the Go type against the uniform's type. Samplers are set by
+// SetTexture instead.
+func (m *Material) Set(name string, value interface{}) error {
+	info, err := m.uniform(name)
+	if err != nil {
+		return err
+	}
+	if info.Type.IsSampler() {
+		return fmt.Errorf("material %q: uniform %q is a %s, use SetTexture", m.Name, name, info.Type)
+	}
+	if m.values == nil {
+		m.values = make(map[string]interface{})
+	}
+	m.values[name] = value
+	return nil
+}
+
+// Value returns the value stored for a uniform
+func (m *Material) Value(name string) (interface{}, bool) {
+	value, ok := m.values[name]
+	return value, ok
+}
+
+// Uniforms returns the names of the uniforms with stored values, sorted
+func (m *Material) Uniforms() []string {
+	names := make([]string, 0, len(m.values))
+	for name := range m.values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SetTexture binds texture to the sampler uniform called sampler
+func (m *Material) SetTexture(sampler string, texture Texture) error {
+	info, err := m.uniform(sampler)
+	if err != nil {
+		return err
+	}
+	if !info.Type.IsSampler() {
+		return fmt.Errorf("material %q: uniform %q is a %s, not a sampler", m.Name, sampler, info.Type)
+	}
+	if texture == nil {
+		return fmt.Errorf("material %q: texture for %q cannot be nil", m.Name, sampler)
+	}
+	if m.textures == nil {
+		m.textures = make(map[string]Texture)
+	}
+	m.textures[sampler] = texture
+	delete(m.files, sampler)
+	return nil
+}
+
+// Texture returns the texture bound to a sampler
+func (m *Material) Texture(sampler string) (Texture, bool) {
+	texture, ok := m.textures[sampler]
+	return texture, ok
+}
+
+// Samplers returns the names of the samplers with textures, sorted. Apply
+// binds the texture of the i-th sampler to texture unit i.
+func (m *Material) Samplers() []string {
+	samplers := make([]string, 0, len(m.textures))
+	for sampler := range m.textures {
+		samplers = append(samplers, sampler)
+	}
+	sort.Strings(samplers)
+	return samplers
+}
+
+// Apply makes the program current, binds the textures to texture units
+// 0, 1, ... in sampler name order, sets the sampler uniforms to their units
+// and uploads the stored values
+func (m *Material) Apply() error {
+	if m.Program == nil {
+		return fmt.Errorf("material %q has no program", m.Name)
+	}
+	m.Program.Use()
+
+	for unit, sampler := range m.Samplers() {
+		m.textures[sampler].Bind(uint32(unit))
+		if err := m.Program.Set(sampler, int32(unit)); err != nil {
+			return fmt.Errorf("material %q: %w", m.Name, err)
+		}
+	}
+	for _, name := range m.Uniforms() {
+		if err := m.Program.Set(name, m.values[name]); err != nil {
+			return fmt.Errorf("material %q: %w", m.Name, err)
+		}
+	}
+	return nil
+}
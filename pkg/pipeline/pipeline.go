@@ -61,8 +61,9 @@ const (
 
 // State represents the complete OpenGL rendering state
 type State struct {
-	// Shader program
-	Program *shader.Program
+	// Shader program; set either Program or ProgramPipeline
+	Program         *shader.Program
+	ProgramPipeline *shader.ProgramPipeline
 
 	// Blending
 	BlendEnabled bool
@@ -121,6 +122,7 @@ type Pipeline struct {
 	stateStack   []*State
 	// Cache to avoid redundant state changes
 	lastProgramID  uint32
+	lastPipelineID uint32
 	lastBlendState bool
 	lastDepthState bool
 	lastCullState  bool
@@ -140,10 +142,15 @@ func (p *Pipeline) SetState(state *State) error {
 		return fmt.Errorf("state cannot be nil")
 	}
 
-	// Apply shader program only if changed
+	// Apply shader program or program pipeline only if changed
 	if state.Program != nil && (p.lastProgramID != state.Program.ID) {
 		state.Program.Use()
 		p.lastProgramID = state.Program.ID
+		p.lastPipelineID = 0
+	} else if state.Program == nil && state.ProgramPipeline != nil && (p.lastPipelineID != state.ProgramPipeline.ID) {
+		state.ProgramPipeline.Bind()
+		p.lastPipelineID = state.ProgramPipeline.ID
+		p.lastProgramID = 0
 	}
 
 	// Apply blending state only if changed
@@ -232,18 +239,37 @@ func (p *Pipeline) PopState() error {
 	return p.SetState(state)
 }
 
-// SetProgram sets the shader program with caching
+// SetProgram sets the shader program with caching. The program replaces a
+// program pipeline set with SetProgramPipeline.
 func (p *Pipeline) SetProgram(program *shader.Program) {
 	if program != nil && p.lastProgramID != program.ID {
 		p.currentState.Program = program
+		p.currentState.ProgramPipeline = nil
 		program.Use()
 		p.lastProgramID = program.ID
+		p.lastPipelineID = 0
 	} else if program == nil && p.lastProgramID != 0 {
 		p.currentState.Program = nil
 		p.lastProgramID = 0
 	}
 }
 
+// SetProgramPipeline binds a program pipeline with caching. The pipeline
+// replaces a program set with SetProgram, which is no longer current.
+func (p *Pipeline) SetProgramPipeline(programPipeline *shader.ProgramPipeline) {
+	if programPipeline != nil && p.lastPipelineID != programPipeline.ID {
+		p.currentState.Program = nil
+		p.currentState.ProgramPipeline = programPipeline
+		programPipeline.Bind()
+		p.lastPipelineID = programPipeline.ID
+		p.lastProgramID = 0
+	} else if programPipeline == nil && p.lastPipelineID != 0 {
+		p.currentState.ProgramPipeline = nil
+		gl.BindProgramPipeline(0)
+		p.lastPipelineID = 0
+	}
+}
+
 // SetBlending configures blending
 func (p *Pipeline) SetBlending(enabled bool, src, dst BlendFunc) {
 	p.currentState.BlendEnabled = enabled
@@ -343,6 +369,12 @@ func (b *Builder) WithProgram(program *shader.Program) *Builder {
 	return b
 }
 
+// WithProgramPipeline sets the program pipeline
+func (b *Builder) WithProgramPipeline(programPipeline *shader.ProgramPipeline) *Builder {
+	b.state.ProgramPipeline = programPipeline
+	return b
+}
+
 // WithBlending configures blending
 func (b *Builder) WithBlending(enabled bool, src, dst BlendFunc) *Builder {
 	b.state.BlendEnabled = enabled
@@ -402,5 +434,9 @@ func (s *State) Validate() error {
 		return fmt.Errorf("invalid blend function: both source and destination are ZERO")
 	}
 
+	if s.Program != nil && s.ProgramPipeline != nil {
+		return fmt.Errorf("invalid shader state: both Program and ProgramPipeline are set")
+	}
+
 	return nil
 }
\ No newline at end of file
@@ -1,10 +1,13 @@
 package resource
 
 import (
+	"encoding/binary"
 	"fmt"
+	"math"
 	"unsafe"
 
 	"github.com/go-gl/gl/v4.1-core/gl"
+	"github.com/yossideutsch/gogl/pkg/layout"
 )
 
 // BufferUsage represents how the buffer will be used
@@ -96,6 +99,21 @@ func NewVertexBuffer(data []float32, usage BufferUsage) (*VertexBuffer, error) {
 	return &VertexBuffer{Buffer: buffer}, nil
 }
 
+// NewVertexBufferSize creates an uninitialized vertex buffer of size bytes,
+// e.g. as a transform feedback capture buffer
+func NewVertexBufferSize(size int, usage BufferUsage) (*VertexBuffer, error) {
+	if size <= 0 {
+		return nil, fmt.Errorf("vertex buffer size must be positive")
+	}
+
+	buffer, err := createBuffer(ArrayBuffer, nil, size, usage)
+	if err != nil {
+		return nil, err
+	}
+
+	return &VertexBuffer{Buffer: buffer}, nil
+}
+
 // NewIndexBuffer creates a new index buffer
 func NewIndexBuffer(data []uint32, usage BufferUsage) (*IndexBuffer, error) {
 	size := len(data) * 4 // uint32 is 4 bytes
@@ -149,6 +167,25 @@ func NewUniformBuffer(size int, usage BufferUsage) (*UniformBuffer, error) {
 	}, nil
 }
 
+// NewUniformBufferFor creates a uniform buffer sized for v, a Go struct
+// marshaled with the std140 layout, and uploads v
+func NewUniformBufferFor(v interface{}, usage BufferUsage) (*UniformBuffer, error) {
+	data, err := layout.Std140.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	buffer, err := createBuffer(UniformBufferTarget, gl.Ptr(data), len(data), usage)
+	if err != nil {
+		return nil, err
+	}
+
+	return &UniformBuffer{
+		Buffer:       buffer,
+		BindingPoint: 0,
+	}, nil
+}
+
 // NewShaderStorageBuffer creates a new shader storage buffer
 func NewShaderStorageBuffer(size int, usage BufferUsage) (*ShaderStorageBuffer, error) {
 	buffer, err := createBuffer(ShaderStorageBufferTarget, nil, size, usage)
@@ -162,6 +199,28 @@ func NewShaderStorageBuffer(size int, usage BufferUsage) (*ShaderStorageBuffer,
 	}, nil
 }
 
+// NewShaderStorageBufferFor creates a shader storage buffer sized for v, a
+// Go struct or slice of structs marshaled with the std430 layout, and uploads v
+func NewShaderStorageBufferFor(v interface{}, usage BufferUsage) (*ShaderStorageBuffer, error) {
+	data, err := layout.Std430.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return nil, fmt.Errorf("shader storage buffer cannot be empty")
+	}
+
+	buffer, err := createBuffer(ShaderStorageBufferTarget, gl.Ptr(data), len(data), usage)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ShaderStorageBuffer{
+		Buffer:       buffer,
+		BindingPoint: 0,
+	}, nil
+}
+
 // Bind binds the buffer
 func (b *Buffer) Bind() {
 	gl.BindBuffer(uint32(b.Target), b.ID)
@@ -184,6 +243,30 @@ func (b *Buffer) Update(offset int, data unsafe.Pointer, size int) error {
 	return nil
 }
 
+// UpdateBytes updates the buffer with raw bytes
+func (b *Buffer) UpdateBytes(offset int, data []byte) error {
+	if len(data) == 0 {
+		return nil
+	}
+	return b.Update(offset, gl.Ptr(data), len(data))
+}
+
+// ReadBytes reads size bytes of buffer data starting at offset
+func (b *Buffer) ReadBytes(offset int, size int) ([]byte, error) {
+	if offset < 0 || size < 0 || offset+size > b.Size {
+		return nil, fmt.Errorf("read exceeds buffer size")
+	}
+
+	data := make([]byte, size)
+	if size == 0 {
+		return data, nil
+	}
+	b.Bind()
+	gl.GetBufferSubData(uint32(b.Target), offset, size, gl.Ptr(data))
+	b.Unbind()
+	return data, nil
+}
+
 // UpdateFloat32 updates the buffer with float32 data
 func (v *VertexBuffer) UpdateFloat32(offset int, data []float32) error {
 	size := len(data) * 4
@@ -193,6 +276,21 @@ func (v *VertexBuffer) UpdateFloat32(offset int, data []float32) error {
 	return v.Update(offset, gl.Ptr(data), size)
 }
 
+// ReadFloat32 reads count float32 values starting at byte offset, e.g.
+// varyings captured by transform feedback
+func (v *VertexBuffer) ReadFloat32(offset int, count int) ([]float32, error) {
+	data, err := v.ReadBytes(offset, count*4)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make([]float32, count)
+	for i := range values {
+		values[i] = math.Float32frombits(binary.LittleEndian.Uint32(data[i*4:]))
+	}
+	return values, nil
+}
+
 // UpdateUint32 updates the index buffer with uint32 data
 func (i *IndexBuffer) UpdateUint32(offset int, data []uint32) error {
 	size := len(data) * 4
@@ -225,6 +323,17 @@ func (u *UniformBuffer) UpdateData(offset int, data unsafe.Pointer, size int) er
 	return u.Update(offset, data, size)
 }
 
+// Write marshals v, a Go struct, with the std140 layout and uploads it to
+// the start of the buffer. Use layout.Std140.Validate to check the struct
+// against the program's uniform block once.
+func (u *UniformBuffer) Write(v interface{}) error {
+	data, err := layout.Std140.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return u.UpdateBytes(0, data)
+}
+
 // BindBase binds the shader storage buffer to a binding point
 func (s *ShaderStorageBuffer) BindBase(bindingPoint uint32) {
 	s.BindingPoint = bindingPoint
@@ -236,6 +345,28 @@ func (s *ShaderStorageBuffer) UpdateData(offset int, data unsafe.Pointer, size i
 	return s.Update(offset, data, size)
 }
 
+// Write marshals v, a Go struct or slice of structs, with the std430 layout
+// and uploads it to the start of the buffer
+func (s *ShaderStorageBuffer) Write(v interface{}) error {
+	data, err := layout.Std430.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return s.UpdateBytes(0, data)
+}
+
+// Read reads the whole buffer back and decodes it with the std430 layout
+// into v, a pointer to a struct or slice. Slices are resized to the number
+// of elements that fit in the buffer. Issue a memory barrier with
+// gl.BUFFER_UPDATE_BARRIER_BIT after compute dispatches that write the buffer.
+func (s *ShaderStorageBuffer) Read(v interface{}) error {
+	data, err := s.ReadBytes(0, s.Size)
+	if err != nil {
+		return err
+	}
+	return layout.Std430.Unmarshal(data, v)
+}
+
 // Delete deletes the buffer
 func (b *Buffer) Delete() {
 	if b.ID != 0 {
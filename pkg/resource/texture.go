@@ -109,8 +109,8 @@ func NewTexture2DFromData(width, height int32, format TextureFormat, data unsafe
 	return texture, nil
 }
 
-// LoadTexture2D loads a texture from a file
-func LoadTexture2D(filepath string, config TextureConfig) (*Texture2D, error) {
+// loadRGBA decodes an image file and converts it to RGBA
+func loadRGBA(filepath string) (*image.RGBA, error) {
 	// Open file
 	file, err := os.Open(filepath)
 	if err != nil {
@@ -127,6 +127,15 @@ func LoadTexture2D(filepath string, config TextureConfig) (*Texture2D, error) {
 	// Convert to RGBA
 	rgba := image.NewRGBA(img.Bounds())
 	draw.Draw(rgba, rgba.Bounds(), img, image.Point{0, 0}, draw.Src)
+	return rgba, nil
+}
+
+// LoadTexture2D loads a texture from a file
+func LoadTexture2D(filepath string, config TextureConfig) (*Texture2D, error) {
+	rgba, err := loadRGBA(filepath)
+	if err != nil {
+		return nil, err
+	}
 
 	// Create texture
 	width := int32(rgba.Bounds().Dx())
@@ -328,6 +337,37 @@ func NewTextureArray(width, height, layers int32, format TextureFormat, config T
 	return texture, nil
 }
 
+// LoadTextureArray loads a texture array with one layer per image file. All
+// images must have the same size.
+func LoadTextureArray(filepaths []string, config TextureConfig) (*TextureArray, error) {
+	if len(filepaths) == 0 {
+		return nil, fmt.Errorf("texture array needs at least one layer")
+	}
+
+	layers := make([]*image.RGBA, len(filepaths))
+	for i, path := range filepaths {
+		rgba, err := loadRGBA(path)
+		if err != nil {
+			return nil, err
+		}
+		if i > 0 && rgba.Bounds().Size() != layers[0].Bounds().Size() {
+			return nil, fmt.Errorf("texture array layer %s is %v, expected %v like %s",
+				path, rgba.Bounds().Size(), layers[0].Bounds().Size(), filepaths[0])
+		}
+		layers[i] = rgba
+	}
+
+	size := layers[0].Bounds().Size()
+	texture, err := NewTextureArray(int32(size.X), int32(size.Y), int32(len(layers)), FormatRGBA, config)
+	if err != nil {
+		return nil, err
+	}
+	for i, rgba := range layers {
+		texture.SetLayerData(int32(i), gl.Ptr(rgba.Pix))
+	}
+	return texture, nil
+}
+
 // Bind binds the texture array
 func (ta *TextureArray) Bind(unit uint32) {
 	gl.ActiveTexture(gl.TEXTURE0 + unit)
@@ -0,0 +1,193 @@
+package resource
+
+import (
+	"fmt"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+)
+
+// TransformFeedback represents an OpenGL transform feedback object that
+// captures the varyings of a program into vertex buffers. The program must
+// be linked with shader.ProgramOptions.TransformFeedbackVaryings.
+type TransformFeedback struct {
+	ID      uint32
+	Buffers map[uint32]*VertexBuffer // Capture buffers by binding index
+
+	// RasterizerDiscard stops primitives after capture, for passes that
+	// only update buffers
+	RasterizerDiscard bool
+
+	query  uint32
+	active bool
+	paused bool
+}
+
+// NewTransformFeedback creates a new transform feedback object
+func NewTransformFeedback() (*TransformFeedback, error) {
+	var id uint32
+	gl.GenTransformFeedbacks(1, &id)
+	if id == 0 {
+		return nil, fmt.Errorf("failed to generate transform feedback")
+	}
+
+	var query uint32
+	gl.GenQueries(1, &query)
+	if query == 0 {
+		gl.DeleteTransformFeedbacks(1, &id)
+		return nil, fmt.Errorf("failed to generate primitives written query")
+	}
+
+	return &TransformFeedback{
+		ID:      id,
+		Buffers: make(map[uint32]*VertexBuffer),
+		query:   query,
+	}, nil
+}
+
+// Bind binds the transform feedback object
+func (tf *TransformFeedback) Bind() {
+	gl.BindTransformFeedback(gl.TRANSFORM_FEEDBACK, tf.ID)
+}
+
+// Unbind restores the default transform feedback object
+func (tf *TransformFeedback) Unbind() {
+	gl.BindTransformFeedback(gl.TRANSFORM_FEEDBACK, 0)
+}
+
+// BindBuffer attaches the whole of vb as the capture buffer at index. With
+// interleaved varyings only index 0 is used; with separate varyings
+// varying i is written to index i.
+func (tf *TransformFeedback) BindBuffer(index uint32, vb *VertexBuffer) error {
+	if tf.active {
+		return fmt.Errorf("cannot change capture buffers while transform feedback is active")
+	}
+	if vb == nil {
+		return fmt.Errorf("capture buffer cannot be nil")
+	}
+
+	tf.Bind()
+	gl.BindBufferBase(gl.TRANSFORM_FEEDBACK_BUFFER, index, vb.ID)
+	tf.Unbind()
+	tf.Buffers[index] = vb
+	return nil
+}
+
+// BindBufferRange attaches size bytes of vb starting at offset as the
+// capture buffer at index. Offset and size must be multiples of 4.
+func (tf *TransformFeedback) BindBufferRange(index uint32, vb *VertexBuffer, offset, size int) error {
+	if tf.active {
+		return fmt.Errorf("cannot change capture buffers while transform feedback is active")
+	}
+	if vb == nil {
+		return fmt.Errorf("capture buffer cannot be nil")
+	}
+	if offset < 0 || size <= 0 || offset+size > vb.Size {
+		return fmt.Errorf("range exceeds buffer size")
+	}
+	if offset%4 != 0 || size%4 != 0 {
+		return fmt.Errorf("range offset and size must be multiples of 4")
+	}
+
+	tf.Bind()
+	gl.BindBufferRange(gl.TRANSFORM_FEEDBACK_BUFFER, index, vb.ID, offset, size)
+	tf.Unbind()
+	tf.Buffers[index] = vb
+	return nil
+}
+
+// Begin starts capturing primitives of mode, which must be gl.POINTS,
+// gl.LINES or gl.TRIANGLES and match the primitives drawn (or emitted by a
+// geometry shader). The capturing program must be in use. The object stays
+// bound until End.
+func (tf *TransformFeedback) Begin(mode uint32) error {
+	if tf.active {
+		return fmt.Errorf("transform feedback is already active")
+	}
+	switch mode {
+	case gl.POINTS, gl.LINES, gl.TRIANGLES:
+	default:
+		return fmt.Errorf("invalid transform feedback primitive mode 0x%x", mode)
+	}
+	if len(tf.Buffers) == 0 {
+		return fmt.Errorf("no capture buffer bound")
+	}
+
+	tf.Bind()
+	if tf.RasterizerDiscard {
+		gl.Enable(gl.RASTERIZER_DISCARD)
+	}
+	gl.BeginQuery(gl.TRANSFORM_FEEDBACK_PRIMITIVES_WRITTEN, tf.query)
+	gl.BeginTransformFeedback(mode)
+	tf.active = true
+	tf.paused = false
+	return nil
+}
+
+// Pause suspends capturing, e.g. to draw with another program
+func (tf *TransformFeedback) Pause() {
+	if tf.active && !tf.paused {
+		gl.PauseTransformFeedback()
+		tf.paused = true
+	}
+}
+
+// Resume continues capturing after Pause
+func (tf *TransformFeedback) Resume() {
+	if tf.active && tf.paused {
+		gl.ResumeTransformFeedback()
+		tf.paused = false
+	}
+}
+
+// End stops capturing and unbinds the object
+func (tf *TransformFeedback) End() {
+	if !tf.active {
+		return
+	}
+	gl.EndTransformFeedback()
+	gl.EndQuery(gl.TRANSFORM_FEEDBACK_PRIMITIVES_WRITTEN)
+	if tf.RasterizerDiscard {
+		gl.Disable(gl.RASTERIZER_DISCARD)
+	}
+	tf.Unbind()
+	tf.active = false
+	tf.paused = false
+}
+
+// IsActive reports whether the object is between Begin and End
+func (tf *TransformFeedback) IsActive() bool {
+	return tf.active
+}
+
+// PrimitivesWritten returns the number of primitives written to the
+// capture buffers by the last Begin/End pair. It waits for the GPU to
+// finish the capture; use Draw to consume the result without a stall.
+func (tf *TransformFeedback) PrimitivesWritten() uint32 {
+	var written uint32
+	gl.GetQueryObjectuiv(tf.query, gl.QUERY_RESULT, &written)
+	return written
+}
+
+// Draw draws va with the vertex count captured by the last Begin/End pair,
+// without reading the count back to the CPU. va must source its
+// attributes from a capture buffer of this object.
+func (tf *TransformFeedback) Draw(va *VertexArray, mode uint32) {
+	va.Bind()
+	gl.DrawTransformFeedback(mode, tf.ID)
+	va.Unbind()
+}
+
+// Delete deletes the transform feedback object and its query. The capture
+// buffers are not deleted.
+func (tf *TransformFeedback) Delete() {
+	tf.End()
+	if tf.query != 0 {
+		gl.DeleteQueries(1, &tf.query)
+		tf.query = 0
+	}
+	if tf.ID != 0 {
+		gl.DeleteTransformFeedbacks(1, &tf.ID)
+		tf.ID = 0
+	}
+	tf.Buffers = nil
+}
@@ -222,6 +222,22 @@ func (vl *VertexLayout) AddInt(location uint32, count int32) *VertexLayout {
 	return vl
 }
 
+// AddUInt adds an unsigned integer attribute to the layout
+func (vl *VertexLayout) AddUInt(location uint32, count int32) *VertexLayout {
+	attr := VertexAttribute{
+		Location:   location,
+		Size:       count,
+		Type:       UInt,
+		Normalized: false,
+		Stride:     0, // Will be set when applied
+		Offset:     uintptr(vl.Stride),
+		Divisor:    0,
+	}
+	vl.Attributes = append(vl.Attributes, attr)
+	vl.Stride += count * 4 // uint32 is 4 bytes
+	return vl
+}
+
 // AddUByte adds an unsigned byte attribute to the layout
 func (vl *VertexLayout) AddUByte(location uint32, count int32, normalized bool) *VertexLayout {
 	attr := VertexAttribute{
@@ -0,0 +1,219 @@
+package shader
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+)
+
+var (
+	parallelMu sync.Mutex
+	parallel   *bool // Parallel compile support, nil until detected
+)
+
+// ParallelCompileSupported reports whether the current context compiles
+// and links in the background (KHR_parallel_shader_compile or
+// ARB_parallel_shader_compile), detected on first use. When it does, the
+// driver is allowed to use as many compiler threads as it likes.
+func ParallelCompileSupported() bool {
+	parallelMu.Lock()
+	defer parallelMu.Unlock()
+	if parallel != nil {
+		return *parallel
+	}
+
+	supported := true
+	switch {
+	case hasExtension("GL_KHR_parallel_shader_compile"):
+		gl.MaxShaderCompilerThreadsKHR(0xFFFFFFFF)
+	case hasExtension("GL_ARB_parallel_shader_compile"):
+		gl.MaxShaderCompilerThreadsARB(0xFFFFFFFF)
+	default:
+		supported = false
+	}
+	parallel = &supported
+	return supported
+}
+
+// AsyncShader is a shader compile started with CompileAsync. The compile
+// status is only queried by Wait, so issuing many compiles before waiting
+// lets the driver work on them while the application keeps rendering.
+type AsyncShader struct {
+	job    *compileJob
+	shader *Shader
+	err    error
+	done   bool
+}
+
+// CompileAsync starts compiling a shader from source and returns without
+// waiting for the result
+func CompileAsync(source string, shaderType ShaderType) *AsyncShader {
+	job, err := startCompile(source, shaderType, "", nil)
+	return &AsyncShader{job: job, err: err, done: err != nil}
+}
+
+// CompileFileAsync preprocesses a shader file and starts compiling it,
+// reporting compile errors against the original files and lines
+func (pp *Preprocessor) CompileFileAsync(path string, shaderType ShaderType) *AsyncShader {
+	src, err := pp.ProcessFile(path)
+	if err != nil {
+		return &AsyncShader{err: err, done: true}
+	}
+	job, err := startCompile(src.Code, shaderType, path, src.Map)
+	if err != nil {
+		return &AsyncShader{err: err, done: true}
+	}
+	job.shader.origin = &shaderOrigin{
+		path:         path,
+		files:        src.Files,
+		preprocessor: pp,
+	}
+	return &AsyncShader{job: job}
+}
+
+// Ready reports whether Wait would return without blocking. Without
+// parallel compile support the driver cannot be asked, so Ready reports
+// true and Wait blocks for as long as the driver still needs.
+func (s *AsyncShader) Ready() bool {
+	if s.done {
+		return true
+	}
+	return completed(func(pname uint32, status *int32) {
+		gl.GetShaderiv(s.job.shader.ID, pname, status)
+	})
+}
+
+// Wait returns the compiled shader or its *CompileError, waiting for the
+// driver if needed. Later calls return the same result.
+func (s *AsyncShader) Wait() (*Shader, error) {
+	if !s.done {
+		s.shader, s.err = s.job.finish()
+		s.done = true
+	}
+	return s.shader, s.err
+}
+
+// pending returns the shader object, compiled or not, or the error that
+// prevented the compile from starting
+func (s *AsyncShader) pending() (*Shader, error) {
+	if s.done {
+		return s.shader, s.err
+	}
+	return s.job.shader, nil
+}
+
+// AsyncProgram is a program link started with LinkAsync
+type AsyncProgram struct {
+	shaders []*AsyncShader
+	opts    ProgramOptions
+	program *Program // Linking program, nil when the link could not start
+	err     error
+	done    bool
+}
+
+// LinkAsync starts linking shaders from CompileAsync without waiting for
+// their compiles or the link
+func LinkAsync(shaders ...*AsyncShader) *AsyncProgram {
+	return LinkAsyncWithOptions(ProgramOptions{}, shaders...)
+}
+
+// LinkAsyncWithOptions starts linking shaders with opts. Unless they are
+// borrowed (see ShaderOwnership), the shaders are deleted when compiling
+// or linking fails, as CreateProgram does on link failure.
+func LinkAsyncWithOptions(opts ProgramOptions, shaders ...*AsyncShader) *AsyncProgram {
+	a := &AsyncProgram{shaders: shaders, opts: opts}
+	if len(shaders) == 0 {
+		return a.fail(fmt.Errorf("at least one shader is required"))
+	}
+
+	pending := make([]*Shader, len(shaders))
+	for i, s := range shaders {
+		if s == nil {
+			return a.fail(fmt.Errorf("shader cannot be nil"))
+		}
+		shader, err := s.pending()
+		if err != nil {
+			return a.fail(err)
+		}
+		pending[i] = shader
+	}
+	if err := validateStages(pending, opts); err != nil {
+		return a.fail(err)
+	}
+
+	program, err := startLink(pending, opts)
+	if err != nil {
+		return a.fail(err)
+	}
+	a.program = program
+	return a
+}
+
+// fail records err and deletes the shaders unless they are borrowed.
+// Shaders still compiling are finished with an error, so their Wait does
+// not query the deleted objects.
+func (a *AsyncProgram) fail(err error) *AsyncProgram {
+	for _, s := range a.shaders {
+		if s == nil || a.opts.Ownership == BorrowShaders {
+			continue
+		}
+		if shader, _ := s.pending(); shader != nil {
+			shader.Delete()
+		}
+		if !s.done {
+			s.err = fmt.Errorf("shader deleted after program failed: %w", err)
+			s.done = true
+		}
+	}
+	a.err = err
+	a.done = true
+	return a
+}
+
+// Ready reports whether Wait would return without blocking; see
+// AsyncShader.Ready for contexts without parallel compile support
+func (a *AsyncProgram) Ready() bool {
+	if a.done {
+		return true
+	}
+	return completed(func(pname uint32, status *int32) {
+		gl.GetProgramiv(a.program.ID, pname, status)
+	})
+}
+
+// Wait returns the linked program, or the *CompileError of the first shader
+// that failed or the *LinkError, waiting for the driver if needed. Later
+// calls return the same result.
+func (a *AsyncProgram) Wait() (*Program, error) {
+	if a.done {
+		return a.program, a.err
+	}
+
+	for _, s := range a.shaders {
+		if _, err := s.Wait(); err != nil {
+			a.program.deleteObject()
+			a.program = nil
+			a.fail(err)
+			return nil, err
+		}
+	}
+	if err := a.program.finishLink(); err != nil {
+		a.program = nil
+		a.fail(err)
+		return nil, err
+	}
+	a.done = true
+	return a.program, nil
+}
+
+// completed reports whether the driver finished the object queried by
+// query, or true when it cannot tell
+func completed(query func(pname uint32, status *int32)) bool {
+	if !ParallelCompileSupported() {
+		return true
+	}
+	var status int32
+	query(gl.COMPLETION_STATUS_KHR, &status)
+	return status == gl.TRUE
+}
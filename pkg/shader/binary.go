@@ -0,0 +1,294 @@
+package shader
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+	"github.com/yossideutsch/gogl/internal/platform"
+)
+
+// Binary returns the driver-specific binary of a linked program. Link the
+// program with ProgramOptions.BinaryRetrievable to make sure the driver
+// keeps it.
+func (p *Program) Binary() (uint32, []byte, error) {
+	if p.ID == 0 {
+		return 0, nil, fmt.Errorf("program not initialized")
+	}
+
+	var length int32
+	gl.GetProgramiv(p.ID, gl.PROGRAM_BINARY_LENGTH, &length)
+	if length <= 0 {
+		return 0, nil, fmt.Errorf("program %d has no binary", p.ID)
+	}
+
+	data := make([]byte, length)
+	var format uint32
+	gl.GetProgramBinary(p.ID, length, &length, &format, gl.Ptr(data))
+	if err := checkGLError("glGetProgramBinary"); err != nil {
+		return 0, nil, err
+	}
+	return format, data[:length], nil
+}
+
+// LoadProgramBinary creates a program from a binary returned by
+// Program.Binary. Drivers reject binaries after driver updates, in which case
+// a *LinkError is returned and the program has to be built from source.
+// Programs loaded this way have no attached shaders and cannot be reloaded.
+func LoadProgramBinary(format uint32, data []byte) (*Program, error) {
+	return loadProgramBinary(format, data, ProgramOptions{})
+}
+
+// loadProgramBinary creates a program from a binary linked with opts
+func loadProgramBinary(format uint32, data []byte, opts ProgramOptions) (*Program, error) {
+	if len(data) == 0 {
+		return nil, fmt.Errorf("program binary cannot be empty")
+	}
+
+	programID := gl.CreateProgram()
+	if programID == 0 {
+		return nil, fmt.Errorf("failed to create program: OpenGL context may not be initialized")
+	}
+
+	if opts.Separable {
+		gl.ProgramParameteri(programID, gl.PROGRAM_SEPARABLE, gl.TRUE)
+	}
+	gl.ProgramBinary(programID, format, gl.Ptr(data), int32(len(data)))
+	// Rejected binaries raise GL_INVALID_ENUM on some drivers; the link status covers it
+	gl.GetError()
+
+	var status int32
+	gl.GetProgramiv(programID, gl.LINK_STATUS, &status)
+	if status == gl.FALSE {
+		log := programInfoLog(programID)
+		gl.DeleteProgram(programID)
+		return nil, newLinkError(log, nil)
+	}
+
+	opts.BinaryRetrievable = true
+	program := &Program{
+		ID:      programID,
+		options: opts,
+	}
+	program.reflect()
+	return program, nil
+}
+
+// BinaryCache stores linked program binaries on disk, keyed by the hash of
+// all stage sources and link options plus the GL vendor, renderer and driver
+// version, so programs are only compiled once per driver. Rejected or
+// corrupt entries fall back to a full build transparently; cache write
+// failures are ignored.
+type BinaryCache struct {
+	Dir string
+
+	// Preprocessor expands includes for ProgramFromFiles; nil uses DefaultPreprocessor
+	Preprocessor *Preprocessor
+
+	driver    string // Identifies the driver the binaries were built by
+	supported bool   // False when the driver exposes no binary formats
+	hits      int
+	misses    int
+}
+
+// NewBinaryCache creates a cache that stores binaries in dir. It must be
+// called with a current GL context.
+func NewBinaryCache(dir string) (*BinaryCache, error) {
+	info, err := platform.New().Detect()
+	if err != nil {
+		return nil, fmt.Errorf("failed to identify driver: %w", err)
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create binary cache directory: %w", err)
+	}
+
+	var formats int32
+	gl.GetIntegerv(gl.NUM_PROGRAM_BINARY_FORMATS, &formats)
+
+	return &BinaryCache{
+		Dir:       dir,
+		driver:    info.VendorString + "\x00" + info.RendererString + "\x00" + info.VersionString,
+		supported: formats > 0,
+	}, nil
+}
+
+// Supported reports whether the driver can save program binaries; when it
+// cannot, every program is built from source
+func (c *BinaryCache) Supported() bool {
+	return c.supported
+}
+
+// Stats returns the number of programs loaded from and missing in the cache
+func (c *BinaryCache) Stats() (hits, misses int) {
+	return c.hits, c.misses
+}
+
+// key hashes the driver identity, the link options and the stage sources
+func (c *BinaryCache) key(sources map[ShaderType]string, opts ProgramOptions) string {
+	stages := make([]ShaderType, 0, len(sources))
+	for stage := range sources {
+		stages = append(stages, stage)
+	}
+	sort.Slice(stages, func(i, j int) bool { return stages[i] < stages[j] })
+
+	// The retrieval hint does not change the linked program
+	opts.BinaryRetrievable = false
+
+	h := sha256.New()
+	h.Write([]byte(c.driver))
+	fmt.Fprintf(h, "\x00%#v", opts)
+	for _, stage := range stages {
+		fmt.Fprintf(h, "\x00%d\x00%s", stage, sources[stage])
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Program returns a program for the given stage sources, loading it from
+// the cache when possible and compiling, linking and storing it otherwise
+func (c *BinaryCache) Program(sources map[ShaderType]string) (*Program, error) {
+	return c.ProgramWithOptions(ProgramOptions{}, sources)
+}
+
+// ProgramWithOptions is like Program for programs linked with opts
+func (c *BinaryCache) ProgramWithOptions(opts ProgramOptions, sources map[ShaderType]string) (*Program, error) {
+	return c.program(sources, opts, func(stage ShaderType) (*Shader, error) {
+		return CompileShader(sources[stage], stage)
+	})
+}
+
+// ProgramFromFiles is like Program for shader files. Includes are expanded
+// before hashing, so editing an included file invalidates the entry.
+func (c *BinaryCache) ProgramFromFiles(files map[ShaderType]string) (*Program, error) {
+	return c.ProgramFromFilesWithOptions(ProgramOptions{}, files)
+}
+
+// ProgramFromFilesWithOptions is like ProgramFromFiles for programs linked
+// with opts
+func (c *BinaryCache) ProgramFromFilesWithOptions(opts ProgramOptions, files map[ShaderType]string) (*Program, error) {
+	pp := c.Preprocessor
+	if pp == nil {
+		pp = DefaultPreprocessor
+	}
+	sources := make(map[ShaderType]*Source, len(files))
+	code := make(map[ShaderType]string, len(files))
+	for stage, path := range files {
+		src, err := pp.ProcessFile(path)
+		if err != nil {
+			return nil, err
+		}
+		sources[stage] = src
+		code[stage] = src.Code
+	}
+	return c.program(code, opts, func(stage ShaderType) (*Shader, error) {
+		return pp.compileSource(sources[stage], files[stage], stage, nil)
+	})
+}
+
+func (c *BinaryCache) program(sources map[ShaderType]string, opts ProgramOptions, compile func(ShaderType) (*Shader, error)) (*Program, error) {
+	if len(sources) == 0 {
+		return nil, fmt.Errorf("at least one shader is required")
+	}
+
+	// The cache compiles the shaders, so the program always owns them
+	if opts.Ownership == BorrowShaders {
+		opts.Ownership = OwnShaders
+	}
+
+	path := filepath.Join(c.Dir, c.key(sources, opts)+".bin")
+	if c.supported {
+		if program, ok := c.load(path, opts); ok {
+			c.hits++
+			program.applyBindings(sourceBindings(sources))
+			return program, nil
+		}
+	}
+	c.misses++
+
+	shaders := make([]*Shader, 0, len(sources))
+	for stage := range sources {
+		shader, err := compile(stage)
+		if err != nil {
+			for _, compiled := range shaders {
+				compiled.Delete()
+			}
+			return nil, err
+		}
+		shaders = append(shaders, shader)
+	}
+
+	opts.BinaryRetrievable = opts.BinaryRetrievable || c.supported
+	program, err := CreateProgramWithOptions(opts, shaders...)
+	if err != nil {
+		// The cache compiled the shaders, so nobody else can delete them
+		for _, shader := range shaders {
+			shader.Delete()
+		}
+		return nil, err
+	}
+	if c.supported {
+		c.store(path, program)
+	}
+	return program, nil
+}
+
+// load reads a cache entry linked with opts; entries the driver rejects
+// are removed
+func (c *BinaryCache) load(path string, opts ProgramOptions) (*Program, bool) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+	if len(data) > 4 {
+		format := binary.LittleEndian.Uint32(data)
+		if program, err := loadProgramBinary(format, data[4:], opts); err == nil {
+			return program, true
+		}
+	}
+	os.Remove(path)
+	return nil, false
+}
+
+// store writes a program binary atomically so concurrent processes never
+// read a partial entry
+func (c *BinaryCache) store(path string, program *Program) {
+	format, data, err := program.Binary()
+	if err != nil {
+		return
+	}
+
+	tmp, err := os.CreateTemp(c.Dir, ".tmp-*")
+	if err != nil {
+		return
+	}
+	var header [4]byte
+	binary.LittleEndian.PutUint32(header[:], format)
+	_, err = tmp.Write(append(header[:], data...))
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+}
+
+// Clear removes every entry from the cache directory
+func (c *BinaryCache) Clear() error {
+	entries, err := filepath.Glob(filepath.Join(c.Dir, "*.bin"))
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if err := os.Remove(entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,273 @@
+package shader
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Severity classifies a compiler or linker diagnostic
+type Severity int
+
+const (
+	SeverityError Severity = iota
+	SeverityWarning
+	SeverityInfo
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityError:
+		return "error"
+	case SeverityWarning:
+		return "warning"
+	default:
+		return "info"
+	}
+}
+
+// Diagnostic is a single message from a driver info log
+type Diagnostic struct {
+	File       string // Original file, empty when unknown
+	Line       int    // 1-based line, 0 when the message has no location
+	Column     int    // 1-based column, 0 when the driver does not report one
+	Severity   Severity
+	Message    string
+	SourceLine string     // Text of the offending line, when known
+	Stage      ShaderType // Stage a link diagnostic refers to, 0 when unknown
+}
+
+// String formats the diagnostic as file:line:column: severity: message,
+// naming the stage instead when a link diagnostic has no file
+func (d Diagnostic) String() string {
+	var b strings.Builder
+	if d.File != "" {
+		b.WriteString(d.File)
+		b.WriteString(":")
+	} else if d.Stage != 0 {
+		b.WriteString(d.Stage.String())
+		b.WriteString(" shader:")
+	}
+	if d.Line > 0 {
+		b.WriteString(strconv.Itoa(d.Line))
+		b.WriteString(":")
+		if d.Column > 0 {
+			b.WriteString(strconv.Itoa(d.Column))
+			b.WriteString(":")
+		}
+	}
+	if b.Len() > 0 {
+		b.WriteString(" ")
+	}
+	b.WriteString(d.Severity.String())
+	b.WriteString(": ")
+	b.WriteString(d.Message)
+	return b.String()
+}
+
+// Info log line formats of the major drivers
+var (
+	// Mesa: "0:12(5): error: `x' undeclared"
+	mesaLogPattern = regexp.MustCompile(`^\s*\d+:(\d+)\((\d+)\):\s*(?i:(error|warning|info))\s*:?\s*(.*)$`)
+	// NVIDIA: "0(12) : error C1008: undefined variable "x""
+	nvidiaLogPattern = regexp.MustCompile(`^\s*\d+\((\d+)\)\s*:\s*(?i:(fatal error|error|warning))\s*(?:[A-Z]\d+)?\s*:\s*(.*)$`)
+	// AMD, Apple and Intel: "ERROR: 0:12: 'x' : undeclared identifier"
+	khronosLogPattern = regexp.MustCompile(`^\s*(?i:(error|warning|info)):\s*\d+:(\d+):\s*(.*)$`)
+	// Messages without a location, e.g. Mesa link errors: "error: vertex shader lacks `main'"
+	bareLogPattern = regexp.MustCompile(`^\s*(?i:(error|warning|info))\s*:\s*(.*)$`)
+	// Summary lines that repeat information already reported
+	summaryLogPattern = regexp.MustCompile(`(?i)^\s*(error|warning):\s*\d+ compilation (error|warning)s?\b`)
+)
+
+// parseSeverity maps a driver severity keyword to a Severity
+func parseSeverity(s string) Severity {
+	switch strings.ToLower(s) {
+	case "error", "fatal error":
+		return SeverityError
+	case "warning":
+		return SeverityWarning
+	default:
+		return SeverityInfo
+	}
+}
+
+// ParseInfoLog parses a shader or program info log into diagnostics.
+// It understands the Mesa, NVIDIA, AMD, Apple and Intel log formats; line
+// numbers refer to the source passed to the driver. Lines that match no
+// known format continue the previous diagnostic.
+func ParseInfoLog(log string) []Diagnostic {
+	var diagnostics []Diagnostic
+
+	for _, line := range strings.Split(log, "\n") {
+		line = strings.TrimRight(line, "\r\x00")
+		if strings.TrimSpace(line) == "" || summaryLogPattern.MatchString(line) {
+			continue
+		}
+
+		var d Diagnostic
+		if m := mesaLogPattern.FindStringSubmatch(line); m != nil {
+			d.Line, _ = strconv.Atoi(m[1])
+			d.Column, _ = strconv.Atoi(m[2])
+			d.Severity = parseSeverity(m[3])
+			d.Message = m[4]
+		} else if m := nvidiaLogPattern.FindStringSubmatch(line); m != nil {
+			d.Line, _ = strconv.Atoi(m[1])
+			d.Severity = parseSeverity(m[2])
+			d.Message = m[3]
+		} else if m := khronosLogPattern.FindStringSubmatch(line); m != nil {
+			d.Severity = parseSeverity(m[1])
+			d.Line, _ = strconv.Atoi(m[2])
+			d.Message = m[3]
+		} else if m := bareLogPattern.FindStringSubmatch(line); m != nil {
+			d.Severity = parseSeverity(m[1])
+			d.Message = m[2]
+		} else if len(diagnostics) > 0 {
+			last := &diagnostics[len(diagnostics)-1]
+			last.Message += "\n" + strings.TrimSpace(line)
+			continue
+		} else {
+			d.Severity = SeverityInfo
+			d.Message = strings.TrimSpace(line)
+		}
+
+		d.Message = strings.TrimSpace(d.Message)
+		diagnostics = append(diagnostics, d)
+	}
+	return diagnostics
+}
+
+// resolveDiagnostics attaches file names and source lines to diagnostics
+// parsed from the log of source, mapping locations through smap when set
+func resolveDiagnostics(diagnostics []Diagnostic, source, name string, smap *SourceMap) []Diagnostic {
+	if len(diagnostics) == 0 {
+		return diagnostics
+	}
+
+	lines := strings.Split(source, "\n")
+	for i := range diagnostics {
+		d := &diagnostics[i]
+		if d.Line < 1 {
+			continue
+		}
+		if d.Line <= len(lines) {
+			d.SourceLine = lines[d.Line-1]
+		}
+		if loc, ok := smap.Resolve(d.Line); ok {
+			d.File, d.Line = loc.File, loc.Line
+		} else {
+			d.File = name
+		}
+	}
+	return diagnostics
+}
+
+// FormatDiagnostics renders diagnostics one per line, each followed by an
+// excerpt of the offending source line and a caret under the reported
+// column (or under the first non-blank character when there is none)
+func FormatDiagnostics(diagnostics []Diagnostic) string {
+	var b strings.Builder
+	for _, d := range diagnostics {
+		b.WriteString(d.String())
+		b.WriteString("\n")
+		if d.SourceLine == "" {
+			continue
+		}
+
+		// Columns count bytes of the raw line, so the caret offset is the
+		// width of the raw prefix after the same tab expansion
+		excerpt := strings.ReplaceAll(d.SourceLine, "\t", "    ")
+		offset := len(excerpt) - len(strings.TrimLeft(excerpt, " "))
+		if d.Column >= 1 && d.Column <= len(d.SourceLine)+1 {
+			offset = len(strings.ReplaceAll(d.SourceLine[:d.Column-1], "\t", "    "))
+		}
+		fmt.Fprintf(&b, "    %s\n    %s^\n", excerpt, strings.Repeat(" ", offset))
+	}
+	return b.String()
+}
+
+// CompileError reports a failed shader compilation. Use errors.As to
+// retrieve it from the errors returned by the compile functions.
+type CompileError struct {
+	Stage       ShaderType
+	File        string // Root file, empty for in-memory sources
+	Log         string // Raw driver info log
+	Diagnostics []Diagnostic
+}
+
+func (e *CompileError) Error() string {
+	prefix := fmt.Sprintf("failed to compile %s shader", e.Stage)
+	if e.File != "" {
+		prefix += " " + e.File
+	}
+	if len(e.Diagnostics) == 0 {
+		return prefix + ": " + e.Log
+	}
+
+	messages := make([]string, len(e.Diagnostics))
+	for i, d := range e.Diagnostics {
+		messages[i] = d.String()
+	}
+	return prefix + ": " + strings.Join(messages, "\n")
+}
+
+// LinkError reports a failed program link. Link logs rarely carry source
+// locations; diagnostics that name a stage, or all of them when a single
+// stage was linked, are attributed to that stage and its root file.
+type LinkError struct {
+	Stages      []ShaderType // Stages of the linked shaders, empty for program binaries
+	Log         string       // Raw driver info log
+	Diagnostics []Diagnostic
+}
+
+// newLinkError builds a LinkError from the info log of a program linked
+// from shaders, attributing its diagnostics to stages and files
+func newLinkError(log string, shaders []*Shader) *LinkError {
+	e := &LinkError{Log: log, Diagnostics: ParseInfoLog(log)}
+	files := make(map[ShaderType]string)
+	for _, s := range shaders {
+		e.Stages = append(e.Stages, s.Type)
+		if s.origin != nil {
+			files[s.Type] = s.origin.path
+		}
+	}
+
+	for i := range e.Diagnostics {
+		d := &e.Diagnostics[i]
+		if len(e.Stages) == 1 {
+			d.Stage = e.Stages[0]
+		} else {
+			d.Stage = namedStage(d.Message, e.Stages)
+		}
+		if d.File == "" && d.Stage != 0 {
+			d.File = files[d.Stage]
+		}
+	}
+	return e
+}
+
+// namedStage returns the stage whose name appears first in a link message,
+// e.g. "vertex shader lacks `main'", or 0 when it names none of stages
+func namedStage(message string, stages []ShaderType) ShaderType {
+	message = strings.ToLower(message)
+	var stage ShaderType
+	first := len(message)
+	for _, s := range stages {
+		if i := strings.Index(message, s.String()+" shader"); i >= 0 && i < first {
+			stage, first = s, i
+		}
+	}
+	return stage
+}
+
+func (e *LinkError) Error() string {
+	if len(e.Diagnostics) == 0 {
+		return "failed to link program: " + e.Log
+	}
+
+	messages := make([]string, len(e.Diagnostics))
+	for i, d := range e.Diagnostics {
+		messages[i] = d.String()
+	}
+	return "failed to link program: " + strings.Join(messages, "\n")
+}
@@ -0,0 +1,199 @@
+package shader
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Manager shares programs between their users. Programs are keyed by the
+// hash of their stage sources and link options, so identical requests
+// return the same *Program, and each request counts as a reference that
+// Release gives back; the GL program is deleted with the last reference.
+// Users of a shared program also share its uniform and subroutine state.
+type Manager struct {
+	// Preprocessor expands includes for ProgramFromFiles; nil uses DefaultPreprocessor
+	Preprocessor *Preprocessor
+
+	entries  map[string]*managedProgram
+	programs map[*Program]*managedProgram
+}
+
+// managedProgram is a shared program and its reference count
+type managedProgram struct {
+	key     string
+	program *Program
+	refs    int
+}
+
+// NewManager creates an empty program manager. The zero Manager is also
+// ready to use.
+func NewManager() *Manager {
+	return &Manager{
+		entries:  make(map[string]*managedProgram),
+		programs: make(map[*Program]*managedProgram),
+	}
+}
+
+// managerKey hashes stage sources and link options, including the shader
+// ownership mode, into a manager key
+func managerKey(sources map[ShaderType]string, opts ProgramOptions) string {
+	stages := make([]ShaderType, 0, len(sources))
+	for stage := range sources {
+		stages = append(stages, stage)
+	}
+	sort.Slice(stages, func(i, j int) bool { return stages[i] < stages[j] })
+
+	h := sha256.New()
+	fmt.Fprintf(h, "%t\x00%t\x00%d\x00%d\x00%s", opts.BinaryRetrievable, opts.Separable, opts.Ownership,
+		opts.TransformFeedbackMode, strings.Join(opts.TransformFeedbackVaryings, ","))
+	for _, stage := range stages {
+		fmt.Fprintf(h, "\x00%d\x00%s", stage, sources[stage])
+	}
+	return string(h.Sum(nil))
+}
+
+// Program returns the program for the given stage sources, building it on
+// the first request and adding a reference on every request
+func (m *Manager) Program(sources map[ShaderType]string) (*Program, error) {
+	return m.ProgramWithOptions(ProgramOptions{}, sources)
+}
+
+// ProgramWithOptions is like Program for programs linked with opts
+func (m *Manager) ProgramWithOptions(opts ProgramOptions, sources map[ShaderType]string) (*Program, error) {
+	return m.acquire(sources, opts, func(stage ShaderType) (*Shader, error) {
+		return CompileShader(sources[stage], stage)
+	})
+}
+
+// ProgramFromFiles is like Program for shader files. Includes are expanded
+// before hashing, so edited files produce a new program.
+func (m *Manager) ProgramFromFiles(files map[ShaderType]string) (*Program, error) {
+	return m.ProgramFromFilesWithOptions(ProgramOptions{}, files)
+}
+
+// ProgramFromFilesWithOptions is like ProgramFromFiles for programs linked with opts
+func (m *Manager) ProgramFromFilesWithOptions(opts ProgramOptions, files map[ShaderType]string) (*Program, error) {
+	pp := m.Preprocessor
+	if pp == nil {
+		pp = DefaultPreprocessor
+	}
+	sources := make(map[ShaderType]*Source, len(files))
+	code := make(map[ShaderType]string, len(files))
+	for stage, path := range files {
+		src, err := pp.ProcessFile(path)
+		if err != nil {
+			return nil, err
+		}
+		sources[stage] = src
+		code[stage] = src.Code
+	}
+	return m.acquire(code, opts, func(stage ShaderType) (*Shader, error) {
+		return pp.compileSource(sources[stage], files[stage], stage, nil)
+	})
+}
+
+func (m *Manager) acquire(sources map[ShaderType]string, opts ProgramOptions, compile func(ShaderType) (*Shader, error)) (*Program, error) {
+	if len(sources) == 0 {
+		return nil, fmt.Errorf("at least one shader is required")
+	}
+
+	// The manager compiles the shaders, so the program always owns them
+	if opts.Ownership == BorrowShaders {
+		opts.Ownership = OwnShaders
+	}
+
+	key := managerKey(sources, opts)
+	if entry, ok := m.entries[key]; ok {
+		// Programs deleted behind the manager's back are rebuilt
+		if entry.program.ID != 0 {
+			entry.refs++
+			return entry.program, nil
+		}
+		m.forget(entry)
+	}
+
+	stages := make([]ShaderType, 0, len(sources))
+	for stage := range sources {
+		stages = append(stages, stage)
+	}
+	sort.Slice(stages, func(i, j int) bool { return stages[i] < stages[j] })
+
+	shaders := make([]*Shader, 0, len(stages))
+	for _, stage := range stages {
+		shader, err := compile(stage)
+		if err != nil {
+			for _, compiled := range shaders {
+				compiled.Delete()
+			}
+			return nil, err
+		}
+		shaders = append(shaders, shader)
+	}
+
+	program, err := CreateProgramWithOptions(opts, shaders...)
+	if err != nil {
+		// Invalid stage sets are rejected before the program takes the shaders
+		for _, shader := range shaders {
+			shader.Delete()
+		}
+		return nil, err
+	}
+
+	// Managers built as struct literals get their maps on first use
+	if m.entries == nil {
+		m.entries = make(map[string]*managedProgram)
+		m.programs = make(map[*Program]*managedProgram)
+	}
+	entry := &managedProgram{key: key, program: program, refs: 1}
+	m.entries[key] = entry
+	m.programs[program] = entry
+	return program, nil
+}
+
+// Release gives back one reference to a program returned by the manager
+// and deletes the program when it was the last one
+func (m *Manager) Release(program *Program) error {
+	entry, ok := m.programs[program]
+	if !ok {
+		return fmt.Errorf("program is not managed or already deleted")
+	}
+
+	entry.refs--
+	if entry.refs > 0 {
+		return nil
+	}
+	m.forget(entry)
+	program.Delete()
+	return nil
+}
+
+// forget removes an entry without deleting its program
+func (m *Manager) forget(entry *managedProgram) {
+	delete(m.entries, entry.key)
+	delete(m.programs, entry.program)
+}
+
+// Refs returns the number of unreleased references to a program, or 0 for
+// programs the manager does not hold
+func (m *Manager) Refs(program *Program) int {
+	if entry, ok := m.programs[program]; ok {
+		return entry.refs
+	}
+	return 0
+}
+
+// Len returns the number of live programs
+func (m *Manager) Len() int {
+	return len(m.entries)
+}
+
+// Clear deletes all programs regardless of their references
+func (m *Manager) Clear() {
+	for _, entry := range m.entries {
+		entry.program.Delete()
+	}
+	m.entries = make(map[string]*managedProgram)
+	m.programs = make(map[*Program]*managedProgram)
+}
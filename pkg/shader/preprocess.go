@@ -0,0 +1,405 @@
+package shader
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// maxIncludeDepth bounds include nesting as a safety net against runaway expansion
+const maxIncludeDepth = 32
+
+// SourceLocation identifies a line in an original shader file
+type SourceLocation struct {
+	File string
+	Line int // 1-based
+}
+
+// String formats the location as file:line
+func (l SourceLocation) String() string {
+	return fmt.Sprintf("%s:%d", l.File, l.Line)
+}
+
+// SourceMap maps lines of an expanded shader source back to the files and
+// lines they were read from
+type SourceMap struct {
+	lines []SourceLocation // Indexed by 0-based expanded line
+}
+
+// Resolve returns the original location of a 1-based line of the expanded source
+func (m *SourceMap) Resolve(line int) (SourceLocation, bool) {
+	if m == nil || line < 1 || line > len(m.lines) {
+		return SourceLocation{}, false
+	}
+	return m.lines[line-1], true
+}
+
+// logLocationPattern matches the source-string/line prefixes drivers put in
+// info logs: Mesa "0:12(5):", NVIDIA "0(12) :" and AMD/Apple/Intel "0:12:"
+var logLocationPattern = regexp.MustCompile(`\b\d+(?::(\d+)\((\d+)\)|\((\d+)\)|:(\d+):)`)
+
+// RewriteLog replaces expanded line numbers in a driver info log with the
+// original file and line
+func (m *SourceMap) RewriteLog(log string) string {
+	if m == nil {
+		return log
+	}
+
+	lines := strings.Split(log, "\n")
+	for i, line := range lines {
+		match := logLocationPattern.FindStringSubmatchIndex(line)
+		if match == nil {
+			continue
+		}
+
+		var lineNum, column string
+		switch {
+		case match[2] != -1: // Mesa: line(column)
+			lineNum, column = line[match[2]:match[3]], line[match[4]:match[5]]
+		case match[6] != -1: // NVIDIA: (line)
+			lineNum = line[match[6]:match[7]]
+		default: // AMD/Apple/Intel: line:
+			lineNum = line[match[8]:match[9]]
+		}
+
+		n, err := strconv.Atoi(lineNum)
+		if err != nil {
+			continue
+		}
+		loc, ok := m.Resolve(n)
+		if !ok {
+			continue
+		}
+
+		replacement := loc.String()
+		if column != "" {
+			replacement += ":" + column
+		}
+		end := match[1]
+		if strings.HasSuffix(line[match[0]:end], ":") {
+			end-- // Keep the separator that followed the location
+		}
+		lines[i] = line[:match[0]] + replacement + line[end:]
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Source is a preprocessed shader source together with its source map
+type Source struct {
+	Code  string     // Expanded GLSL code
+	Map   *SourceMap // Maps expanded lines to original files
+	Files []string   // Every file that contributed to Code, root file first
+}
+
+// Preprocessor expands #include directives in GLSL shader files.
+//
+// Quoted includes (#include "file.glsl") are resolved relative to the
+// including file first and then against SearchPaths; angled includes
+// (#include <file.glsl>) only use SearchPaths. Files are included once when
+// they contain "#pragma once" or a classic #ifndef/#define guard, and include
+// cycles are reported as errors.
+//
+// Conditionals other than include guards are left to the GLSL compiler, so
+// an #include inside an inactive #if or #ifdef block is still expanded and
+// its file must exist.
+type Preprocessor struct {
+	SearchPaths []string
+
+	// FS, when set, replaces the OS filesystem. File names and search paths
+	// are then slash-separated and relative to the FS root, as io/fs requires.
+	FS fs.FS
+}
+
+// DefaultPreprocessor is used by CompileShaderFromFile
+var DefaultPreprocessor = NewPreprocessor()
+
+// NewPreprocessor creates a preprocessor with the given include search paths
+func NewPreprocessor(searchPaths ...string) *Preprocessor {
+	return &Preprocessor{SearchPaths: searchPaths}
+}
+
+// NewFSPreprocessor creates a preprocessor that reads files and includes
+// from fsys, e.g. an embed.FS
+func NewFSPreprocessor(fsys fs.FS, searchPaths ...string) *Preprocessor {
+	return &Preprocessor{SearchPaths: searchPaths, FS: fsys}
+}
+
+// readFile reads a file from FS or the OS filesystem
+func (pp *Preprocessor) readFile(name string) ([]byte, error) {
+	if pp.FS != nil {
+		return fs.ReadFile(pp.FS, name)
+	}
+	return os.ReadFile(name)
+}
+
+// stat describes a file in FS or the OS filesystem
+func (pp *Preprocessor) stat(name string) (fs.FileInfo, error) {
+	if pp.FS != nil {
+		return fs.Stat(pp.FS, name)
+	}
+	return os.Stat(name)
+}
+
+// join, dir and clean manipulate names with the path syntax of the filesystem in use
+func (pp *Preprocessor) join(elem ...string) string {
+	if pp.FS != nil {
+		return path.Join(elem...)
+	}
+	return filepath.Join(elem...)
+}
+
+func (pp *Preprocessor) dir(name string) string {
+	if pp.FS != nil {
+		return path.Dir(name)
+	}
+	return filepath.Dir(name)
+}
+
+func (pp *Preprocessor) clean(name string) string {
+	if pp.FS != nil {
+		return path.Clean(name)
+	}
+	return filepath.Clean(name)
+}
+
+// expansion holds the state of a single ProcessFile run
+type expansion struct {
+	code   strings.Builder
+	smap   *SourceMap
+	files  []string
+	seen   map[string]bool
+	once   map[string]bool
+	guards map[string]bool
+	stack  []string
+}
+
+// ProcessFile reads a shader file and expands its includes
+func (pp *Preprocessor) ProcessFile(name string) (*Source, error) {
+	data, err := pp.readFile(name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read shader file %s: %w", name, err)
+	}
+	return pp.Process(string(data), name)
+}
+
+// Process expands the includes of an in-memory source; name is used for
+// relative include resolution and in the source map
+func (pp *Preprocessor) Process(source, name string) (*Source, error) {
+	e := &expansion{
+		smap:   &SourceMap{},
+		seen:   make(map[string]bool),
+		once:   make(map[string]bool),
+		guards: make(map[string]bool),
+	}
+	if err := pp.expand(e, pp.clean(name), source); err != nil {
+		return nil, err
+	}
+
+	return &Source{
+		Code:  e.code.String(),
+		Map:   e.smap,
+		Files: e.files,
+	}, nil
+}
+
+// expand appends a file's lines to the expansion, recursing into includes
+func (pp *Preprocessor) expand(e *expansion, path, source string) error {
+	if e.once[path] {
+		return nil
+	}
+
+	source = strings.ReplaceAll(source, "\r\n", "\n")
+	lines := strings.Split(strings.TrimSuffix(source, "\n"), "\n")
+
+	// A guarded file that is already open is skipped like the compiler
+	// would, so mutually including headers are not an include cycle
+	if guard := includeGuard(lines); guard != "" {
+		if e.guards[guard] {
+			return nil
+		}
+		e.guards[guard] = true
+	}
+
+	for i, p := range e.stack {
+		if p == path {
+			cycle := append(append([]string{}, e.stack[i:]...), path)
+			return fmt.Errorf("include cycle: %s", strings.Join(cycle, " -> "))
+		}
+	}
+	if len(e.stack) >= maxIncludeDepth {
+		return fmt.Errorf("%s: includes nested deeper than %d levels", path, maxIncludeDepth)
+	}
+
+	if !e.seen[path] {
+		e.seen[path] = true
+		e.files = append(e.files, path)
+	}
+
+	e.stack = append(e.stack, path)
+	defer func() { e.stack = e.stack[:len(e.stack)-1] }()
+
+	for i, line := range lines {
+		name, arg := directive(line)
+		switch {
+		case name == "include":
+			target, angled, err := parseIncludeTarget(arg)
+			if err != nil {
+				return fmt.Errorf("%s:%d: %w", path, i+1, err)
+			}
+			resolved, data, err := pp.resolve(target, pp.dir(path), angled)
+			if err != nil {
+				return fmt.Errorf("%s:%d: %w", path, i+1, err)
+			}
+			if err := pp.expand(e, resolved, data); err != nil {
+				return err
+			}
+			continue
+		case name == "pragma" && strings.TrimSpace(arg) == "once":
+			e.once[path] = true
+			line = ""
+		case name == "version" && len(e.stack) > 1:
+			line = "" // Only the root file may declare the version
+		}
+
+		e.code.WriteString(line)
+		e.code.WriteByte('\n')
+		e.smap.lines = append(e.smap.lines, SourceLocation{File: path, Line: i + 1})
+	}
+	return nil
+}
+
+// CompileShaderFromFile preprocesses a shader file and compiles it, reporting
+// compile errors against the original files and lines
+func (pp *Preprocessor) CompileShaderFromFile(path string, shaderType ShaderType) (*Shader, error) {
+	src, err := pp.ProcessFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return pp.compileSource(src, path, shaderType, nil)
+}
+
+// CompileSource compiles a source returned by ProcessFile for path, so the
+// compiled code is exactly the source the caller inspected. The shader is
+// reloaded from path like one from CompileShaderFromFile.
+func (pp *Preprocessor) CompileSource(src *Source, path string, shaderType ShaderType) (*Shader, error) {
+	if src == nil {
+		return nil, fmt.Errorf("shader source cannot be nil")
+	}
+	return pp.compileSource(src, path, shaderType, nil)
+}
+
+// compileSource compiles a source preprocessed from path with defines
+// injected, recording where it came from so the shader can be reloaded
+func (pp *Preprocessor) compileSource(src *Source, path string, shaderType ShaderType, defines Defines) (*Shader, error) {
+	variant, err := src.WithDefines(defines)
+	if err != nil {
+		return nil, err
+	}
+	shader, err := compileShader(variant.Code, shaderType, path, variant.Map)
+	if err != nil {
+		return nil, err
+	}
+	shader.origin = &shaderOrigin{
+		path:         path,
+		files:        src.Files,
+		defines:      defines,
+		preprocessor: pp,
+	}
+	return shader, nil
+}
+
+// resolve locates an include target and returns its path and contents
+func (pp *Preprocessor) resolve(target, dir string, angled bool) (string, string, error) {
+	var candidates []string
+	if pp.FS == nil && filepath.IsAbs(target) {
+		candidates = append(candidates, target)
+	} else {
+		if !angled {
+			candidates = append(candidates, pp.join(dir, target))
+		}
+		for _, searchPath := range pp.SearchPaths {
+			candidates = append(candidates, pp.join(searchPath, target))
+		}
+	}
+
+	for _, candidate := range candidates {
+		if pp.FS != nil && !fs.ValidPath(candidate) {
+			continue // Escapes the FS root
+		}
+		data, err := pp.readFile(candidate)
+		if err == nil {
+			return pp.clean(candidate), string(data), nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return "", "", fmt.Errorf("failed to read include %s: %w", candidate, err)
+		}
+	}
+	return "", "", fmt.Errorf("cannot find include %q", target)
+}
+
+// directive splits a preprocessor line into its directive name and argument
+func directive(line string) (string, string) {
+	trimmed := strings.TrimSpace(line)
+	if !strings.HasPrefix(trimmed, "#") {
+		return "", ""
+	}
+	trimmed = strings.TrimSpace(trimmed[1:])
+	end := strings.IndexFunc(trimmed, func(r rune) bool {
+		return !(r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9')
+	})
+	if end == -1 {
+		return trimmed, ""
+	}
+	return trimmed[:end], strings.TrimSpace(trimmed[end:])
+}
+
+// parseIncludeTarget extracts the file name from "file" or <file>
+func parseIncludeTarget(arg string) (string, bool, error) {
+	if len(arg) >= 2 {
+		switch {
+		case arg[0] == '"':
+			if end := strings.IndexByte(arg[1:], '"'); end > 0 {
+				return arg[1 : end+1], false, nil
+			}
+		case arg[0] == '<':
+			if end := strings.IndexByte(arg[1:], '>'); end > 0 {
+				return arg[1 : end+1], true, nil
+			}
+		}
+	}
+	return "", false, fmt.Errorf("malformed #include %s", arg)
+}
+
+// includeGuard returns the macro of a classic "#ifndef X / #define X" guard
+// wrapping the whole file, or "" when the file has none
+func includeGuard(lines []string) string {
+	var significant []string
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "//") {
+			continue
+		}
+		significant = append(significant, trimmed)
+	}
+	if len(significant) < 3 {
+		return ""
+	}
+
+	name, guard := directive(significant[0])
+	if name != "ifndef" || guard == "" {
+		return ""
+	}
+	if name, arg := directive(significant[1]); name != "define" || strings.TrimSpace(strings.SplitN(arg, " ", 2)[0]) != guard {
+		return ""
+	}
+	if name, _ := directive(significant[len(significant)-1]); name != "endif" {
+		return ""
+	}
+	return guard
+}
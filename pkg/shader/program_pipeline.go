@@ -0,0 +1,303 @@
+package shader
+
+import (
+	"fmt"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+)
+
+// pipelineOrder lists the shader stages in the order they execute
+var pipelineOrder = []ShaderType{VertexShader, TessControlShader, TessEvaluationShader, GeometryShader, FragmentShader, ComputeShader}
+
+// stageBit returns the glUseProgramStages bit of a shader stage
+func stageBit(stage ShaderType) uint32 {
+	switch stage {
+	case VertexShader:
+		return gl.VERTEX_SHADER_BIT
+	case TessControlShader:
+		return gl.TESS_CONTROL_SHADER_BIT
+	case TessEvaluationShader:
+		return gl.TESS_EVALUATION_SHADER_BIT
+	case GeometryShader:
+		return gl.GEOMETRY_SHADER_BIT
+	case FragmentShader:
+		return gl.FRAGMENT_SHADER_BIT
+	case ComputeShader:
+		return gl.COMPUTE_SHADER_BIT
+	default:
+		return 0
+	}
+}
+
+// addStage records a linked stage, keeping pipeline order
+func (p *Program) addStage(stage ShaderType) {
+	if p.HasStage(stage) {
+		return
+	}
+	p.stages = append(p.stages, stage)
+	ordered := p.stages[:0]
+	for _, s := range pipelineOrder {
+		for _, linked := range p.stages {
+			if linked == s {
+				ordered = append(ordered, s)
+			}
+		}
+	}
+	p.stages = ordered
+}
+
+// Stages returns the shader stages linked into the program in pipeline
+// order. It is empty for programs loaded from a binary.
+func (p *Program) Stages() []ShaderType {
+	return p.stages
+}
+
+// HasStage reports whether the program contains the given stage
+func (p *Program) HasStage(stage ShaderType) bool {
+	for _, s := range p.stages {
+		if s == stage {
+			return true
+		}
+	}
+	return false
+}
+
+// Separable reports whether the program was linked for use in a ProgramPipeline
+func (p *Program) Separable() bool {
+	return p.options.Separable
+}
+
+// CreateShaderProgram compiles and links a single-stage separable program
+// from source with glCreateShaderProgramv, translating it for the target
+// dialect like CompileShader. Compile and link problems are both reported
+// as a *CompileError. Vertex stages that feed later separable stages should
+// redeclare gl_PerVertex for portability.
+func CreateShaderProgram(source string, shaderType ShaderType) (*Program, error) {
+	if source == "" {
+		return nil, fmt.Errorf("shader source cannot be empty")
+	}
+	if stageBit(shaderType) == 0 {
+		return nil, fmt.Errorf("invalid shader type 0x%x", uint32(shaderType))
+	}
+
+	translated, warnings, err := translate(source, shaderType, "", nil)
+	if err != nil {
+		return nil, err
+	}
+	source = translated.Source.Code
+	cSource, free := gl.Strs(source + "\x00")
+	defer free()
+
+	programID := gl.CreateShaderProgramv(uint32(shaderType), 1, cSource)
+	if err := checkGLError("glCreateShaderProgramv"); err != nil {
+		if programID != 0 {
+			gl.DeleteProgram(programID)
+		}
+		return nil, err
+	}
+	if programID == 0 {
+		return nil, fmt.Errorf("failed to create program: OpenGL context may not be initialized")
+	}
+
+	var status int32
+	gl.GetProgramiv(programID, gl.LINK_STATUS, &status)
+	log := programInfoLog(programID)
+	diagnostics := append(warnings, resolveDiagnostics(ParseInfoLog(log), source, "", translated.Source.Map)...)
+	if status == gl.FALSE {
+		gl.DeleteProgram(programID)
+		return nil, &CompileError{Stage: shaderType, Log: translated.Source.Map.RewriteLog(log), Diagnostics: diagnostics}
+	}
+
+	program := &Program{
+		ID:          programID,
+		Diagnostics: diagnostics,
+		options:     ProgramOptions{Separable: true},
+		stages:      []ShaderType{shaderType},
+	}
+	program.applyBindings(translated.Bindings)
+	program.reflect()
+	return program, nil
+}
+
+// ProgramPipeline combines separable programs into one pipeline, so a
+// stage program can be shared between many pipelines without relinking,
+// e.g. one screen quad vertex program with every post-processing fragment
+// program. The pipeline does not own its programs.
+type ProgramPipeline struct {
+	ID     uint32
+	stages map[ShaderType]*Program
+	active *Program
+}
+
+// NewProgramPipeline creates an empty program pipeline object
+func NewProgramPipeline() (*ProgramPipeline, error) {
+	var id uint32
+	gl.GenProgramPipelines(1, &id)
+	if id == 0 {
+		return nil, fmt.Errorf("failed to create program pipeline: OpenGL context may not be initialized")
+	}
+	return &ProgramPipeline{
+		ID:     id,
+		stages: make(map[ShaderType]*Program),
+	}, nil
+}
+
+// UseStages makes the pipeline take the given stages from program. Without
+// stages every stage linked into program is used.
+func (pp *ProgramPipeline) UseStages(program *Program, stages ...ShaderType) error {
+	if pp.ID == 0 {
+		return fmt.Errorf("program pipeline not initialized")
+	}
+	if program == nil || program.ID == 0 {
+		return fmt.Errorf("program not initialized")
+	}
+	if !program.Separable() {
+		return fmt.Errorf("program %d is not separable", program.ID)
+	}
+	if len(stages) == 0 {
+		stages = program.Stages()
+	}
+	if len(stages) == 0 {
+		return fmt.Errorf("program %d has no known stages", program.ID)
+	}
+
+	var mask uint32
+	for _, stage := range stages {
+		if stageBit(stage) == 0 {
+			return fmt.Errorf("invalid shader type 0x%x", uint32(stage))
+		}
+		if !program.HasStage(stage) {
+			return fmt.Errorf("program %d has no %s stage", program.ID, stage)
+		}
+		mask |= stageBit(stage)
+	}
+
+	gl.UseProgramStages(pp.ID, mask, program.ID)
+	if err := checkGLError("glUseProgramStages"); err != nil {
+		return err
+	}
+	for _, stage := range stages {
+		pp.stages[stage] = program
+	}
+	if program.pipelines == nil {
+		program.pipelines = make(map[*ProgramPipeline]bool)
+	}
+	program.pipelines[pp] = true
+	return nil
+}
+
+// rebind points the pipeline stages taken from program at its current
+// object after Program.Reload replaced it. It reports false when the
+// pipeline no longer uses the program.
+func (pp *ProgramPipeline) rebind(program *Program) bool {
+	if pp.ID == 0 {
+		return false
+	}
+	var mask uint32
+	for stage, p := range pp.stages {
+		if p == program {
+			mask |= stageBit(stage)
+		}
+	}
+	if mask == 0 {
+		return false
+	}
+	gl.UseProgramStages(pp.ID, mask, program.ID)
+	if pp.active == program {
+		gl.ActiveShaderProgram(pp.ID, program.ID)
+	}
+	return true
+}
+
+// clearProgram removes the stages a deleted program provided
+func (pp *ProgramPipeline) clearProgram(program *Program) {
+	if pp.ID == 0 {
+		return
+	}
+	for stage, p := range pp.stages {
+		if p == program {
+			pp.ClearStage(stage)
+		}
+	}
+	if pp.active == program {
+		pp.SetActiveProgram(nil)
+	}
+}
+
+// ClearStage removes a stage from the pipeline
+func (pp *ProgramPipeline) ClearStage(stage ShaderType) {
+	if bit := stageBit(stage); bit != 0 && pp.ID != 0 {
+		gl.UseProgramStages(pp.ID, bit, 0)
+		delete(pp.stages, stage)
+	}
+}
+
+// Stage returns the program providing a stage, or nil
+func (pp *ProgramPipeline) Stage(stage ShaderType) *Program {
+	return pp.stages[stage]
+}
+
+// SetActiveProgram selects the stage program that the Program uniform
+// setters affect while the pipeline is bound
+func (pp *ProgramPipeline) SetActiveProgram(program *Program) {
+	if program == nil {
+		gl.ActiveShaderProgram(pp.ID, 0)
+		pp.active = nil
+		return
+	}
+	gl.ActiveShaderProgram(pp.ID, program.ID)
+	pp.active = program
+}
+
+// ActiveProgram returns the program selected with SetActiveProgram
+func (pp *ProgramPipeline) ActiveProgram() *Program {
+	return pp.active
+}
+
+// Bind makes the pipeline current and applies the subroutine selection of
+// each stage program. Programs made current with Program.Use take
+// precedence over pipelines, so Bind clears the current program.
+func (pp *ProgramPipeline) Bind() {
+	gl.UseProgram(0)
+	gl.BindProgramPipeline(pp.ID)
+	for stage, program := range pp.stages {
+		program.applySubroutines(stage)
+	}
+}
+
+// Validate checks that the pipeline stages can run together (use only in debug builds)
+func (pp *ProgramPipeline) Validate() error {
+	if pp.ID == 0 {
+		return fmt.Errorf("program pipeline not initialized")
+	}
+
+	gl.ValidateProgramPipeline(pp.ID)
+	if err := checkGLError("glValidateProgramPipeline"); err != nil {
+		return err
+	}
+
+	var status int32
+	gl.GetProgramPipelineiv(pp.ID, gl.VALIDATE_STATUS, &status)
+	if status == gl.FALSE {
+		var logLength int32
+		gl.GetProgramPipelineiv(pp.ID, gl.INFO_LOG_LENGTH, &logLength)
+		log := ""
+		if logLength > 1 {
+			buf := make([]byte, logLength)
+			gl.GetProgramPipelineInfoLog(pp.ID, logLength, nil, &buf[0])
+			log = string(buf[:logLength-1])
+		}
+		return fmt.Errorf("program pipeline validation failed: %s", log)
+	}
+	return nil
+}
+
+// Delete deletes the pipeline object; its programs are left alive
+func (pp *ProgramPipeline) Delete() {
+	if pp.ID != 0 {
+		gl.DeleteProgramPipelines(1, &pp.ID)
+		pp.ID = 0
+		pp.stages = nil
+		pp.active = nil
+	}
+}
@@ -0,0 +1,492 @@
+package shader
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+)
+
+// DataType represents the GLSL type of an active uniform or attribute
+// as reported by the driver
+type DataType uint32
+
+const (
+	TypeFloat  DataType = gl.FLOAT
+	TypeVec2   DataType = gl.FLOAT_VEC2
+	TypeVec3   DataType = gl.FLOAT_VEC3
+	TypeVec4   DataType = gl.FLOAT_VEC4
+	TypeDouble DataType = gl.DOUBLE
+	TypeInt    DataType = gl.INT
+	TypeIVec2  DataType = gl.INT_VEC2
+	TypeIVec3  DataType = gl.INT_VEC3
+	TypeIVec4  DataType = gl.INT_VEC4
+	TypeUInt   DataType = gl.UNSIGNED_INT
+	TypeUVec2  DataType = gl.UNSIGNED_INT_VEC2
+	TypeUVec3  DataType = gl.UNSIGNED_INT_VEC3
+	TypeUVec4  DataType = gl.UNSIGNED_INT_VEC4
+	TypeBool   DataType = gl.BOOL
+	TypeBVec2  DataType = gl.BOOL_VEC2
+	TypeBVec3  DataType = gl.BOOL_VEC3
+	TypeBVec4  DataType = gl.BOOL_VEC4
+	TypeMat2   DataType = gl.FLOAT_MAT2
+	TypeMat3   DataType = gl.FLOAT_MAT3
+	TypeMat4   DataType = gl.FLOAT_MAT4
+	TypeMat2x3 DataType = gl.FLOAT_MAT2x3
+	TypeMat2x4 DataType = gl.FLOAT_MAT2x4
+	TypeMat3x2 DataType = gl.FLOAT_MAT3x2
+	TypeMat3x4 DataType = gl.FLOAT_MAT3x4
+	TypeMat4x2 DataType = gl.FLOAT_MAT4x2
+	TypeMat4x3 DataType = gl.FLOAT_MAT4x3
+
+	TypeSampler1D         DataType = gl.SAMPLER_1D
+	TypeSampler2D         DataType = gl.SAMPLER_2D
+	TypeSampler3D         DataType = gl.SAMPLER_3D
+	TypeSamplerCube       DataType = gl.SAMPLER_CUBE
+	TypeSampler2DShadow   DataType = gl.SAMPLER_2D_SHADOW
+	TypeSampler2DArray    DataType = gl.SAMPLER_2D_ARRAY
+	TypeSamplerCubeShadow DataType = gl.SAMPLER_CUBE_SHADOW
+	TypeSamplerBuffer     DataType = gl.SAMPLER_BUFFER
+	TypeSampler2DRect     DataType = gl.SAMPLER_2D_RECT
+	TypeSampler2DMS       DataType = gl.SAMPLER_2D_MULTISAMPLE
+	TypeISampler2D        DataType = gl.INT_SAMPLER_2D
+	TypeUSampler2D        DataType = gl.UNSIGNED_INT_SAMPLER_2D
+	TypeImage2D           DataType = gl.IMAGE_2D
+	TypeAtomicCounter     DataType = gl.UNSIGNED_INT_ATOMIC_COUNTER
+)
+
+// dataTypeNames maps driver type enums to their GLSL spelling
+var dataTypeNames = map[DataType]string{
+	TypeFloat:             "float",
+	TypeVec2:              "vec2",
+	TypeVec3:              "vec3",
+	TypeVec4:              "vec4",
+	TypeDouble:            "double",
+	TypeInt:               "int",
+	TypeIVec2:             "ivec2",
+	TypeIVec3:             "ivec3",
+	TypeIVec4:             "ivec4",
+	TypeUInt:              "uint",
+	TypeUVec2:             "uvec2",
+	TypeUVec3:             "uvec3",
+	TypeUVec4:             "uvec4",
+	TypeBool:              "bool",
+	TypeBVec2:             "bvec2",
+	TypeBVec3:             "bvec3",
+	TypeBVec4:             "bvec4",
+	TypeMat2:              "mat2",
+	TypeMat3:              "mat3",
+	TypeMat4:              "mat4",
+	TypeMat2x3:            "mat2x3",
+	TypeMat2x4:            "mat2x4",
+	TypeMat3x2:            "mat3x2",
+	TypeMat3x4:            "mat3x4",
+	TypeMat4x2:            "mat4x2",
+	TypeMat4x3:            "mat4x3",
+	TypeSampler1D:         "sampler1D",
+	TypeSampler2D:         "sampler2D",
+	TypeSampler3D:         "sampler3D",
+	TypeSamplerCube:       "samplerCube",
+	TypeSampler2DShadow:   "sampler2DShadow",
+	TypeSampler2DArray:    "sampler2DArray",
+	TypeSamplerCubeShadow: "samplerCubeShadow",
+	TypeSamplerBuffer:     "samplerBuffer",
+	TypeSampler2DRect:     "sampler2DRect",
+	TypeSampler2DMS:       "sampler2DMS",
+	TypeISampler2D:        "isampler2D",
+	TypeUSampler2D:        "usampler2D",
+	TypeImage2D:           "image2D",
+	TypeAtomicCounter:     "atomic_uint",
+}
+
+// String returns the GLSL name of the type
+func (t DataType) String() string {
+	if name, ok := dataTypeNames[t]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+// IsSampler reports whether the type is an opaque sampler type set through an int uniform
+func (t DataType) IsSampler() bool {
+	return strings.Contains(t.String(), "sampler")
+}
+
+// UniformInfo describes an active uniform of a linked program
+type UniformInfo struct {
+	Name     string   // Name without the trailing "[0]" of arrays
+	Type     DataType // GLSL type
+	Size     int32    // Array size, 1 for non-arrays
+	Location int32    // Location, -1 for uniforms inside a uniform block
+
+	// Layout inside a uniform block (BlockIndex is -1 for the default block)
+	BlockIndex   int32
+	Offset       int32
+	ArrayStride  int32
+	MatrixStride int32
+}
+
+// IsArray reports whether the uniform was declared as an array
+func (u UniformInfo) IsArray() bool {
+	return u.Size > 1
+}
+
+// AttributeInfo describes an active vertex attribute of a linked program
+type AttributeInfo struct {
+	Name     string
+	Type     DataType
+	Size     int32
+	Location int32
+}
+
+// UniformBlockInfo describes an active uniform block of a linked program
+type UniformBlockInfo struct {
+	Name     string
+	Index    uint32
+	Binding  uint32
+	DataSize int32
+	Members  []UniformInfo // Sorted by offset
+}
+
+// StorageBlockInfo describes an active shader storage block of a linked program
+type StorageBlockInfo struct {
+	Name     string
+	Index    uint32
+	Binding  uint32
+	DataSize int32                // Minimum buffer size; runtime-sized arrays count one element
+	Members  []BufferVariableInfo // Sorted by offset
+}
+
+// BufferVariableInfo describes a member of a shader storage block
+type BufferVariableInfo struct {
+	Name         string   // Name without the trailing "[0]" of arrays
+	Type         DataType // GLSL type
+	Size         int32    // Array size, 1 for non-arrays, 0 for runtime-sized arrays
+	Offset       int32
+	ArrayStride  int32
+	MatrixStride int32
+
+	// Outermost array the member belongs to, e.g. particles in "particles[0].position"
+	TopLevelArraySize   int32 // 0 when runtime-sized
+	TopLevelArrayStride int32
+}
+
+// reflection holds the introspected interface of a linked program
+type reflection struct {
+	uniforms   []UniformInfo
+	attributes []AttributeInfo
+	blocks     []UniformBlockInfo
+	storage    []StorageBlockInfo
+	varyings   []VaryingInfo
+
+	subroutines map[ShaderType]*stageSubroutines
+
+	uniformIndex   map[string]int
+	attributeIndex map[string]int
+	blockIndex     map[string]int
+	storageIndex   map[string]int
+	locationIndex  map[int32]int // Base location of default-block uniforms
+
+	storageReflected bool // Whether the context could query storage blocks
+}
+
+// reflect introspects the active uniforms, attributes and uniform blocks of
+// the linked program and seeds the uniform location cache
+func (p *Program) reflect() {
+	r := &reflection{
+		uniformIndex:   make(map[string]int),
+		attributeIndex: make(map[string]int),
+		blockIndex:     make(map[string]int),
+		storageIndex:   make(map[string]int),
+		locationIndex:  make(map[int32]int),
+	}
+	p.locations = make(map[string]int32)
+
+	var count, maxLength int32
+	gl.GetProgramiv(p.ID, gl.ACTIVE_UNIFORMS, &count)
+	gl.GetProgramiv(p.ID, gl.ACTIVE_UNIFORM_MAX_LENGTH, &maxLength)
+	nameBuf := make([]byte, maxLength+1)
+
+	for i := int32(0); i < count; i++ {
+		var length, size int32
+		var xtype uint32
+		gl.GetActiveUniform(p.ID, uint32(i), int32(len(nameBuf)), &length, &size, &xtype, &nameBuf[0])
+		name := string(nameBuf[:length])
+
+		index := uint32(i)
+		info := UniformInfo{
+			Name:     strings.TrimSuffix(name, "[0]"),
+			Type:     DataType(xtype),
+			Size:     size,
+			Location: -1,
+		}
+		gl.GetActiveUniformsiv(p.ID, 1, &index, gl.UNIFORM_BLOCK_INDEX, &info.BlockIndex)
+		gl.GetActiveUniformsiv(p.ID, 1, &index, gl.UNIFORM_OFFSET, &info.Offset)
+		gl.GetActiveUniformsiv(p.ID, 1, &index, gl.UNIFORM_ARRAY_STRIDE, &info.ArrayStride)
+		gl.GetActiveUniformsiv(p.ID, 1, &index, gl.UNIFORM_MATRIX_STRIDE, &info.MatrixStride)
+
+		if info.BlockIndex == -1 {
+			info.Location = gl.GetUniformLocation(p.ID, &nameBuf[0])
+			p.locations[info.Name] = info.Location
+			p.locations[name] = info.Location
+		}
+
+		r.uniforms = append(r.uniforms, info)
+	}
+
+	gl.GetProgramiv(p.ID, gl.ACTIVE_ATTRIBUTES, &count)
+	gl.GetProgramiv(p.ID, gl.ACTIVE_ATTRIBUTE_MAX_LENGTH, &maxLength)
+	nameBuf = make([]byte, maxLength+1)
+
+	for i := int32(0); i < count; i++ {
+		var length, size int32
+		var xtype uint32
+		gl.GetActiveAttrib(p.ID, uint32(i), int32(len(nameBuf)), &length, &size, &xtype, &nameBuf[0])
+		name := string(nameBuf[:length])
+
+		r.attributes = append(r.attributes, AttributeInfo{
+			Name:     strings.TrimSuffix(name, "[0]"),
+			Type:     DataType(xtype),
+			Size:     size,
+			Location: gl.GetAttribLocation(p.ID, &nameBuf[0]),
+		})
+	}
+
+	gl.GetProgramiv(p.ID, gl.ACTIVE_UNIFORM_BLOCKS, &count)
+	gl.GetProgramiv(p.ID, gl.ACTIVE_UNIFORM_BLOCK_MAX_NAME_LENGTH, &maxLength)
+	nameBuf = make([]byte, maxLength+1)
+
+	for i := int32(0); i < count; i++ {
+		var length, binding int32
+		gl.GetActiveUniformBlockName(p.ID, uint32(i), int32(len(nameBuf)), &length, &nameBuf[0])
+
+		block := UniformBlockInfo{
+			Name:  string(nameBuf[:length]),
+			Index: uint32(i),
+		}
+		gl.GetActiveUniformBlockiv(p.ID, uint32(i), gl.UNIFORM_BLOCK_BINDING, &binding)
+		gl.GetActiveUniformBlockiv(p.ID, uint32(i), gl.UNIFORM_BLOCK_DATA_SIZE, &block.DataSize)
+		block.Binding = uint32(binding)
+
+		for _, u := range r.uniforms {
+			if u.BlockIndex == i {
+				block.Members = append(block.Members, u)
+			}
+		}
+		sort.Slice(block.Members, func(a, b int) bool {
+			return block.Members[a].Offset < block.Members[b].Offset
+		})
+
+		r.blocks = append(r.blocks, block)
+	}
+
+	sort.Slice(r.uniforms, func(a, b int) bool { return r.uniforms[a].Name < r.uniforms[b].Name })
+	sort.Slice(r.attributes, func(a, b int) bool { return r.attributes[a].Location < r.attributes[b].Location })
+
+	for i, u := range r.uniforms {
+		r.uniformIndex[u.Name] = i
+		if u.Location != -1 {
+			r.locationIndex[u.Location] = i
+		}
+	}
+	for i, a := range r.attributes {
+		r.attributeIndex[a.Name] = i
+	}
+	for i, b := range r.blocks {
+		r.blockIndex[b.Name] = i
+	}
+	r.varyings = p.reflectVaryings()
+	r.subroutines = p.reflectSubroutines()
+
+	if supportsInterfaceQuery() {
+		r.storageReflected = true
+		r.storage = p.reflectStorageBlocks()
+		for i, b := range r.storage {
+			r.storageIndex[b.Name] = i
+		}
+	}
+
+	p.reflection = r
+}
+
+// supportsInterfaceQuery reports whether the current context provides
+// program interface queries (OpenGL 4.3 or ARB_program_interface_query)
+func supportsInterfaceQuery() bool {
+	var major, minor int32
+	gl.GetIntegerv(gl.MAJOR_VERSION, &major)
+	gl.GetIntegerv(gl.MINOR_VERSION, &minor)
+	if major > 4 || major == 4 && minor >= 3 {
+		return true
+	}
+
+	return hasExtension("GL_ARB_program_interface_query")
+}
+
+// hasExtension reports whether the current context advertises extension
+func hasExtension(extension string) bool {
+	var extensions int32
+	gl.GetIntegerv(gl.NUM_EXTENSIONS, &extensions)
+	for i := int32(0); i < extensions; i++ {
+		if gl.GoStr(gl.GetStringi(gl.EXTENSIONS, uint32(i))) == extension {
+			return true
+		}
+	}
+	return false
+}
+
+// reflectStorageBlocks introspects the active shader storage blocks
+func (p *Program) reflectStorageBlocks() []StorageBlockInfo {
+	var count, maxLength, maxVariableLength int32
+	gl.GetProgramInterfaceiv(p.ID, gl.SHADER_STORAGE_BLOCK, gl.ACTIVE_RESOURCES, &count)
+	gl.GetProgramInterfaceiv(p.ID, gl.SHADER_STORAGE_BLOCK, gl.MAX_NAME_LENGTH, &maxLength)
+	gl.GetProgramInterfaceiv(p.ID, gl.BUFFER_VARIABLE, gl.MAX_NAME_LENGTH, &maxVariableLength)
+	nameBuf := make([]byte, maxLength+1)
+	variableBuf := make([]byte, maxVariableLength+1)
+
+	blockProps := []uint32{gl.BUFFER_BINDING, gl.BUFFER_DATA_SIZE, gl.NUM_ACTIVE_VARIABLES}
+	variableProps := []uint32{gl.TYPE, gl.ARRAY_SIZE, gl.OFFSET, gl.ARRAY_STRIDE, gl.MATRIX_STRIDE,
+		gl.TOP_LEVEL_ARRAY_SIZE, gl.TOP_LEVEL_ARRAY_STRIDE}
+
+	var blocks []StorageBlockInfo
+	for i := uint32(0); i < uint32(count); i++ {
+		var length int32
+		gl.GetProgramResourceName(p.ID, gl.SHADER_STORAGE_BLOCK, i, int32(len(nameBuf)), &length, &nameBuf[0])
+
+		var values [3]int32
+		gl.GetProgramResourceiv(p.ID, gl.SHADER_STORAGE_BLOCK, i, int32(len(blockProps)), &blockProps[0], int32(len(values)), nil, &values[0])
+		block := StorageBlockInfo{
+			Name:     string(nameBuf[:length]),
+			Index:    i,
+			Binding:  uint32(values[0]),
+			DataSize: values[1],
+		}
+
+		if values[2] > 0 {
+			indices := make([]int32, values[2])
+			prop := uint32(gl.ACTIVE_VARIABLES)
+			gl.GetProgramResourceiv(p.ID, gl.SHADER_STORAGE_BLOCK, i, 1, &prop, int32(len(indices)), nil, &indices[0])
+
+			for _, index := range indices {
+				gl.GetProgramResourceName(p.ID, gl.BUFFER_VARIABLE, uint32(index), int32(len(variableBuf)), &length, &variableBuf[0])
+				var v [7]int32
+				gl.GetProgramResourceiv(p.ID, gl.BUFFER_VARIABLE, uint32(index), int32(len(variableProps)), &variableProps[0], int32(len(v)), nil, &v[0])
+				block.Members = append(block.Members, BufferVariableInfo{
+					Name:                strings.TrimSuffix(string(variableBuf[:length]), "[0]"),
+					Type:                DataType(v[0]),
+					Size:                v[1],
+					Offset:              v[2],
+					ArrayStride:         v[3],
+					MatrixStride:        v[4],
+					TopLevelArraySize:   v[5],
+					TopLevelArrayStride: v[6],
+				})
+			}
+		}
+		sort.Slice(block.Members, func(a, b int) bool {
+			return block.Members[a].Offset < block.Members[b].Offset
+		})
+
+		blocks = append(blocks, block)
+	}
+	return blocks
+}
+
+// Uniforms returns all active uniforms sorted by name, including uniform block members
+func (p *Program) Uniforms() []UniformInfo {
+	if p.reflection == nil {
+		return nil
+	}
+	return p.reflection.uniforms
+}
+
+// Uniform looks up an active uniform by name; array uniforms may be
+// given with or without the "[0]" suffix
+func (p *Program) Uniform(name string) (UniformInfo, bool) {
+	if p.reflection == nil {
+		return UniformInfo{}, false
+	}
+	i, ok := p.reflection.uniformIndex[strings.TrimSuffix(name, "[0]")]
+	if !ok {
+		return UniformInfo{}, false
+	}
+	return p.reflection.uniforms[i], true
+}
+
+// uniformAt returns the reflected uniform whose base location is location
+func (p *Program) uniformAt(location int32) (UniformInfo, bool) {
+	if p.reflection == nil {
+		return UniformInfo{}, false
+	}
+	i, ok := p.reflection.locationIndex[location]
+	if !ok {
+		return UniformInfo{}, false
+	}
+	return p.reflection.uniforms[i], true
+}
+
+// Attributes returns all active vertex attributes sorted by location
+func (p *Program) Attributes() []AttributeInfo {
+	if p.reflection == nil {
+		return nil
+	}
+	return p.reflection.attributes
+}
+
+// Attribute looks up an active vertex attribute by name
+func (p *Program) Attribute(name string) (AttributeInfo, bool) {
+	if p.reflection == nil {
+		return AttributeInfo{}, false
+	}
+	i, ok := p.reflection.attributeIndex[strings.TrimSuffix(name, "[0]")]
+	if !ok {
+		return AttributeInfo{}, false
+	}
+	return p.reflection.attributes[i], true
+}
+
+// UniformBlocks returns all active uniform blocks in block index order
+func (p *Program) UniformBlocks() []UniformBlockInfo {
+	if p.reflection == nil {
+		return nil
+	}
+	return p.reflection.blocks
+}
+
+// UniformBlock looks up an active uniform block by its block name
+func (p *Program) UniformBlock(name string) (UniformBlockInfo, bool) {
+	if p.reflection == nil {
+		return UniformBlockInfo{}, false
+	}
+	i, ok := p.reflection.blockIndex[name]
+	if !ok {
+		return UniformBlockInfo{}, false
+	}
+	return p.reflection.blocks[i], true
+}
+
+// StorageBlocks returns all active shader storage blocks in block index
+// order. It is empty when the context cannot query them, see StorageBlocksReflected.
+func (p *Program) StorageBlocks() []StorageBlockInfo {
+	if p.reflection == nil {
+		return nil
+	}
+	return p.reflection.storage
+}
+
+// StorageBlock looks up an active shader storage block by its block name
+func (p *Program) StorageBlock(name string) (StorageBlockInfo, bool) {
+	if p.reflection == nil {
+		return StorageBlockInfo{}, false
+	}
+	i, ok := p.reflection.storageIndex[name]
+	if !ok {
+		return StorageBlockInfo{}, false
+	}
+	return p.reflection.storage[i], true
+}
+
+// StorageBlocksReflected reports whether shader storage blocks were
+// reflected, which requires OpenGL 4.3 or ARB_program_interface_query
+func (p *Program) StorageBlocksReflected() bool {
+	return p.reflection != nil && p.reflection.storageReflected
+}
@@ -0,0 +1,285 @@
+package shader
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+)
+
+// shaderOrigin records how a file-based shader was built so it can be rebuilt
+type shaderOrigin struct {
+	path         string
+	files        []string // Root file and every include it pulled in
+	defines      Defines
+	preprocessor *Preprocessor
+}
+
+// rebuild compiles a fresh shader from the current contents of the files
+func (o *shaderOrigin) rebuild(shaderType ShaderType) (*Shader, error) {
+	src, err := o.preprocessor.ProcessFile(o.path)
+	if err != nil {
+		return nil, err
+	}
+	return o.preprocessor.compileSource(src, o.path, shaderType, o.defines)
+}
+
+// Files returns the files the shader was compiled from, root file first.
+// It is empty for shaders compiled from in-memory sources.
+func (s *Shader) Files() []string {
+	if s.origin == nil {
+		return nil
+	}
+	return s.origin.files
+}
+
+// SourceFiles returns the files of every shader the program was linked from
+func (p *Program) SourceFiles() []string {
+	var files []string
+	seen := make(map[string]bool)
+	for _, shader := range p.shaders {
+		for _, file := range shader.Files() {
+			if !seen[file] {
+				seen[file] = true
+				files = append(files, file)
+			}
+		}
+	}
+	return files
+}
+
+// sourceFiles maps the program's source files to the preprocessor that reads them
+func (p *Program) sourceFiles() map[string]*Preprocessor {
+	files := make(map[string]*Preprocessor)
+	for _, shader := range p.shaders {
+		if shader.origin != nil {
+			for _, file := range shader.origin.files {
+				files[file] = shader.origin.preprocessor
+			}
+		}
+	}
+	return files
+}
+
+// Reload recompiles the program's file-based shaders from disk and relinks
+// it. The program is swapped in place only when the new build links; on
+// failure the previous program stays usable and the compile or link error is
+// returned. Must be called on the GL thread; call Use again afterwards since
+// the program ID changes. ProgramPipelines using its stages are updated.
+func (p *Program) Reload() error {
+	if p.ID == 0 {
+		return fmt.Errorf("program not initialized")
+	}
+
+	shaders := make([]*Shader, len(p.shaders))
+	var rebuilt []*Shader
+	release := func() {
+		for _, shader := range rebuilt {
+			shader.Delete()
+		}
+	}
+	for i, shader := range p.shaders {
+		if shader.origin == nil {
+			if shader.ID == 0 {
+				release()
+				return fmt.Errorf("cannot reload program %d: its in-memory %s shader was deleted", p.ID, shader.Type)
+			}
+			shaders[i] = shader // In-memory shaders are relinked as they are
+			continue
+		}
+		fresh, err := shader.origin.rebuild(shader.Type)
+		if err != nil {
+			release()
+			return err
+		}
+		shaders[i] = fresh
+		rebuilt = append(rebuilt, fresh)
+	}
+
+	next, err := linkProgram(shaders, p.options)
+	if err != nil {
+		release()
+		return err
+	}
+
+	old := p.shaders
+	gl.DeleteProgram(p.ID)
+	for i, shader := range old {
+		if shaders[i] != shader && p.owns(shader) {
+			shader.Delete()
+		}
+	}
+	if p.options.Ownership == BorrowShaders {
+		// The rebuilt shaders were never lent by the caller
+		adopted := make(map[*Shader]bool)
+		for _, shader := range rebuilt {
+			adopted[shader] = true
+		}
+		p.adopted = adopted
+	}
+
+	p.ID = next.ID
+	p.shaders = next.shaders
+	p.stages = next.stages
+	p.Diagnostics = next.Diagnostics
+	p.reflection = next.reflection
+	p.locations = next.locations
+	p.restoreSubroutines()
+	for pipeline := range p.pipelines {
+		if !pipeline.rebind(p) {
+			delete(p.pipelines, pipeline)
+		}
+	}
+	return nil
+}
+
+// ReloadError reports a program that failed to rebuild; the program keeps
+// running with its previous binary
+type ReloadError struct {
+	Program *Program
+	Err     error
+}
+
+func (e *ReloadError) Error() string {
+	return fmt.Sprintf("failed to reload program %d: %v", e.Program.ID, e.Err)
+}
+
+func (e *ReloadError) Unwrap() error {
+	return e.Err
+}
+
+// fileStamp identifies a version of a file
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// watchedFile is a stamped file and the preprocessor that reads it
+type watchedFile struct {
+	pp    *Preprocessor
+	stamp fileStamp
+}
+
+// stampFile records the current modification time and size of a file;
+// missing files get a zero stamp
+func stampFile(pp *Preprocessor, file string) fileStamp {
+	info, err := pp.stat(file)
+	if err != nil {
+		return fileStamp{}
+	}
+	return fileStamp{modTime: info.ModTime(), size: info.Size()}
+}
+
+// stampFiles stamps every file with its preprocessor
+func stampFiles(files map[string]*Preprocessor) map[string]watchedFile {
+	watched := make(map[string]watchedFile, len(files))
+	for file, pp := range files {
+		watched[file] = watchedFile{pp: pp, stamp: stampFile(pp, file)}
+	}
+	return watched
+}
+
+// changed reports whether any watched file differs from its stamp
+func changed(watched map[string]watchedFile) bool {
+	for file, w := range watched {
+		if stampFile(w.pp, file) != w.stamp {
+			return true
+		}
+	}
+	return false
+}
+
+// Reloader watches the source files of programs and reloads them when
+// they change. It polls file modification times, so Poll must be called
+// regularly from the GL thread, typically once per frame:
+//
+//	reloader := shader.NewReloader()
+//	reloader.Watch(program)
+//	for !window.ShouldClose() {
+//	    if err := reloader.Poll(); err != nil {
+//	        log.Println(err)
+//	    }
+//	    program.Use()
+//	    // ...
+//	}
+type Reloader struct {
+	// Interval is the minimum time between two file checks; 0 checks on every Poll
+	Interval time.Duration
+
+	// OnReload is called after a program was rebuilt and swapped in place
+	OnReload func(p *Program)
+
+	programs map[*Program]map[string]watchedFile
+	lastPoll time.Time
+}
+
+// NewReloader creates a reloader that checks files at most four times a second
+func NewReloader() *Reloader {
+	return &Reloader{
+		Interval: 250 * time.Millisecond,
+		programs: make(map[*Program]map[string]watchedFile),
+	}
+}
+
+// Watch starts tracking the files a program was built from
+func (r *Reloader) Watch(p *Program) error {
+	if p == nil || p.ID == 0 {
+		return fmt.Errorf("program not initialized")
+	}
+	files := p.sourceFiles()
+	if len(files) == 0 {
+		return fmt.Errorf("program %d has no shaders compiled from files", p.ID)
+	}
+	if r.programs == nil {
+		r.programs = make(map[*Program]map[string]watchedFile)
+	}
+	r.programs[p] = stampFiles(files)
+	return nil
+}
+
+// Unwatch stops tracking a program
+func (r *Reloader) Unwatch(p *Program) {
+	delete(r.programs, p)
+}
+
+// Poll reloads every watched program whose files changed since the last
+// check. Failed reloads are returned as *ReloadError values joined into one
+// error; use errors.As to reach the underlying CompileError or LinkError.
+// Deleted programs are dropped from the watch list.
+func (r *Reloader) Poll() error {
+	now := time.Now()
+	if r.Interval > 0 && now.Sub(r.lastPoll) < r.Interval {
+		return nil
+	}
+	r.lastPoll = now
+
+	var errs []error
+	for p, watched := range r.programs {
+		if p.ID == 0 {
+			delete(r.programs, p)
+			continue
+		}
+		if !changed(watched) {
+			continue
+		}
+
+		err := p.Reload()
+		// Restamp even on failure so a broken file is not rebuilt every poll
+		files := p.sourceFiles()
+		for file, w := range watched {
+			if _, ok := files[file]; !ok {
+				files[file] = w.pp
+			}
+		}
+		r.programs[p] = stampFiles(files)
+		if err != nil {
+			errs = append(errs, &ReloadError{Program: p, Err: err})
+			continue
+		}
+		if r.OnReload != nil {
+			r.OnReload(p)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,194 @@
+package shader
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// lookupUniform resolves a uniform name to its reflection entry, its
+// location and the array element the name refers to. Names may address a
+// single array element, e.g. "uWeights[2]".
+func (p *Program) lookupUniform(name string) (UniformInfo, int32, int, error) {
+	if info, ok := p.Uniform(name); ok {
+		if info.BlockIndex != -1 {
+			return info, -1, 0, fmt.Errorf("uniform %q is a uniform block member and must be set through a uniform buffer", name)
+		}
+		return info, info.Location, 0, nil
+	}
+
+	// Fall back to "base[index]" addressing of array elements
+	if open := strings.LastIndexByte(name, '['); open > 0 && strings.HasSuffix(name, "]") {
+		index, err := strconv.Atoi(name[open+1 : len(name)-1])
+		if info, ok := p.Uniform(name[:open]); ok && err == nil && info.BlockIndex == -1 {
+			if index < 0 || index >= int(info.Size) {
+				return info, -1, 0, fmt.Errorf("uniform %q: index %d out of range for %s[%d]", name, index, info.Type, info.Size)
+			}
+			return info, p.GetUniformLocation(name), index, nil
+		}
+	}
+
+	return UniformInfo{}, -1, 0, fmt.Errorf("uniform %q is not an active uniform of program %d", name, p.ID)
+}
+
+// uniformTypesFor returns the GLSL types a Go value can be assigned to and
+// the number of array elements it covers
+func uniformTypesFor(value interface{}) ([]DataType, int, bool) {
+	switch v := value.(type) {
+	case float32, float64:
+		return floatUniformTypes[1], 1, true
+	case mgl32.Vec2:
+		return floatUniformTypes[2], 1, true
+	case mgl32.Vec3:
+		return floatUniformTypes[3], 1, true
+	case mgl32.Vec4:
+		return floatUniformTypes[4], 1, true
+	case int32, int:
+		return intUniformTypes[1], 1, true
+	case uint32:
+		return uintUniformTypes[1], 1, true
+	case bool:
+		return []DataType{TypeBool}, 1, true
+	case mgl32.Mat2, *mgl32.Mat2:
+		return []DataType{TypeMat2}, 1, true
+	case mgl32.Mat3, *mgl32.Mat3:
+		return []DataType{TypeMat3}, 1, true
+	case mgl32.Mat4, *mgl32.Mat4:
+		return []DataType{TypeMat4}, 1, true
+	case mgl32.Mat3x2:
+		return []DataType{TypeMat2x3}, 1, true
+	case mgl32.Mat4x2:
+		return []DataType{TypeMat2x4}, 1, true
+	case mgl32.Mat2x3:
+		return []DataType{TypeMat3x2}, 1, true
+	case mgl32.Mat4x3:
+		return []DataType{TypeMat3x4}, 1, true
+	case mgl32.Mat2x4:
+		return []DataType{TypeMat4x2}, 1, true
+	case mgl32.Mat3x4:
+		return []DataType{TypeMat4x3}, 1, true
+	case []float32:
+		return floatUniformTypes[1], len(v), true
+	case []int32:
+		return intUniformTypes[1], len(v), true
+	case []uint32:
+		return uintUniformTypes[1], len(v), true
+	case []mgl32.Vec2:
+		return floatUniformTypes[2], len(v), true
+	case []mgl32.Vec3:
+		return floatUniformTypes[3], len(v), true
+	case []mgl32.Vec4:
+		return floatUniformTypes[4], len(v), true
+	case []mgl32.Mat2:
+		return []DataType{TypeMat2}, len(v), true
+	case []mgl32.Mat3:
+		return []DataType{TypeMat3}, len(v), true
+	case []mgl32.Mat4:
+		return []DataType{TypeMat4}, len(v), true
+	}
+	return nil, 0, false
+}
+
+// Set assigns value to the active uniform called name, choosing the setter
+// from the Go type of value. Locations come from the reflection cache, so no
+// driver lookup or string allocation happens for reflected names.
+//
+// Supported Go types are float32, float64, int, int32, uint32, bool, the
+// mgl32 vector and matrix types (non-square mgl32 matrices map to the
+// transposed GLSL name, e.g. mgl32.Mat3x2 to mat2x3) and slices of scalars,
+// vectors and square matrices for uniform arrays.
+//
+//	program.Set("uTime", float32(t))
+//	program.Set("uModel", model)
+//	program.Set("uWeights[1]", float32(0.5))
+func (p *Program) Set(name string, value interface{}) error {
+	info, location, index, err := p.lookupUniform(name)
+	if err != nil {
+		return err
+	}
+
+	accepted, count, ok := uniformTypesFor(value)
+	if !ok {
+		return fmt.Errorf("uniform %q: unsupported Go type %T", name, value)
+	}
+
+	compatible := false
+	for _, t := range accepted {
+		if info.Type == t {
+			compatible = true
+			break
+		}
+	}
+	if !compatible {
+		return fmt.Errorf("uniform %q is declared as %s, cannot set it from %T", name, info.Type, value)
+	}
+	if index+count > int(info.Size) {
+		return fmt.Errorf("uniform %q is %s[%d], cannot set %d elements at index %d", name, info.Type, info.Size, count, index)
+	}
+
+	switch v := value.(type) {
+	case float32:
+		return p.SetUniform1f(location, v)
+	case float64:
+		return p.SetUniform1f(location, float32(v))
+	case mgl32.Vec2:
+		return p.SetUniformVec2(location, v)
+	case mgl32.Vec3:
+		return p.SetUniformVec3(location, v)
+	case mgl32.Vec4:
+		return p.SetUniformVec4(location, v)
+	case int32:
+		return p.SetUniform1i(location, v)
+	case int:
+		return p.SetUniform1i(location, int32(v))
+	case uint32:
+		return p.SetUniform1ui(location, v)
+	case bool:
+		return p.SetUniformBool(location, v)
+	case mgl32.Mat2:
+		return p.SetUniformMatrix2fv(location, &v)
+	case *mgl32.Mat2:
+		return p.SetUniformMatrix2fv(location, v)
+	case mgl32.Mat3:
+		return p.SetUniformMatrix3fv(location, &v)
+	case *mgl32.Mat3:
+		return p.SetUniformMatrix3fv(location, v)
+	case mgl32.Mat4:
+		return p.SetUniformMatrix4fv(location, &v)
+	case *mgl32.Mat4:
+		return p.SetUniformMatrix4fv(location, v)
+	case mgl32.Mat3x2:
+		return p.SetUniformMatrix2x3fv(location, &v)
+	case mgl32.Mat4x2:
+		return p.SetUniformMatrix2x4fv(location, &v)
+	case mgl32.Mat2x3:
+		return p.SetUniformMatrix3x2fv(location, &v)
+	case mgl32.Mat4x3:
+		return p.SetUniformMatrix3x4fv(location, &v)
+	case mgl32.Mat2x4:
+		return p.SetUniformMatrix4x2fv(location, &v)
+	case mgl32.Mat3x4:
+		return p.SetUniformMatrix4x3fv(location, &v)
+	case []float32:
+		return p.SetUniform1fv(location, v)
+	case []int32:
+		return p.SetUniform1iv(location, v)
+	case []uint32:
+		return p.SetUniform1uiv(location, v)
+	case []mgl32.Vec2:
+		return p.SetUniformVec2Array(location, v)
+	case []mgl32.Vec3:
+		return p.SetUniformVec3Array(location, v)
+	case []mgl32.Vec4:
+		return p.SetUniformVec4Array(location, v)
+	case []mgl32.Mat2:
+		return p.SetUniformMatrix2fvArray(location, v)
+	case []mgl32.Mat3:
+		return p.SetUniformMatrix3fvArray(location, v)
+	case []mgl32.Mat4:
+		return p.SetUniformMatrix4fvArray(location, v)
+	}
+	return nil
+}
@@ -3,10 +3,24 @@
 // with performance optimizations and robust error handling.
 //
 // Key features:
-//   - Compile and link vertex, fragment, geometry, and compute shaders
+//   - Compile and link vertex, tessellation, geometry, fragment and compute shaders
 //   - Comprehensive error reporting with OpenGL error checking
+//   - Structured compile and link diagnostics (CompileError, LinkError)
 //   - Memory-efficient resource management with object pooling
 //   - Type-safe uniform setting with validation
+//   - Reflection of active uniforms, attributes, uniform blocks and shader storage blocks
+//   - #include preprocessing with errors mapped back to the original files
+//   - Loading from any fs.FS, including embed.FS
+//   - Shader variants from #define sets with a compiled-variant cache
+//   - Hot reload of file-based programs (Reloader)
+//   - On-disk program binary cache (BinaryCache)
+//   - Shared, reference-counted programs deduplicated by source (Manager)
+//   - Owned, borrowed or released-after-link shaders (ProgramOptions.Ownership)
+//   - Separable programs combined with ProgramPipeline
+//   - Transform feedback varyings captured at link time
+//   - Subroutine reflection and selection kept across Use
+//   - Asynchronous compile and link using KHR_parallel_shader_compile (CompileAsync, LinkAsync)
+//   - Translation to GLSL 330 core and 300 es for older and ES contexts
 //   - Cross-platform compatibility (OpenGL 4.1+)
 //
 // Example usage:
@@ -35,15 +49,20 @@
 //	program.Use()
 //	loc := program.GetUniformLocation("uTime")
 //	program.SetUniform1f(loc, time)
+//
+//	// Or set uniforms by name, dispatching on the Go type
+//	program.Set("uTime", time)
 package shader
 
 import (
 	"fmt"
-	"os"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"strings"
 	"sync"
 
 	"github.com/go-gl/gl/v4.1-core/gl"
-	"github.com/go-gl/mathgl/mgl32"
 )
 
 // Pool for reusing byte slices to reduce allocations
@@ -53,6 +72,47 @@ var logPool = sync.Pool{
 	},
 }
 
+// shaderInfoLog returns the info log of a shader without the null terminator
+func shaderInfoLog(shaderID uint32) string {
+	var logLength int32
+	gl.GetShaderiv(shaderID, gl.INFO_LOG_LENGTH, &logLength)
+	if logLength <= 1 {
+		return ""
+	}
+
+	// Use pooled buffer to reduce allocations
+	buf := logPool.Get().([]byte)
+	defer logPool.Put(buf[:0]) // Reset length but keep capacity
+
+	if cap(buf) < int(logLength) {
+		buf = make([]byte, logLength)
+	}
+	buf = buf[:logLength]
+
+	gl.GetShaderInfoLog(shaderID, logLength, nil, (*uint8)(&buf[0]))
+	return string(buf[:logLength-1]) // Remove null terminator
+}
+
+// programInfoLog returns the info log of a program without the null terminator
+func programInfoLog(programID uint32) string {
+	var logLength int32
+	gl.GetProgramiv(programID, gl.INFO_LOG_LENGTH, &logLength)
+	if logLength <= 1 {
+		return ""
+	}
+
+	buf := logPool.Get().([]byte)
+	defer logPool.Put(buf[:0])
+
+	if cap(buf) < int(logLength) {
+		buf = make([]byte, logLength)
+	}
+	buf = buf[:logLength]
+
+	gl.GetProgramInfoLog(programID, logLength, nil, (*uint8)(&buf[0]))
+	return string(buf[:logLength-1])
+}
+
 // checkGLError checks for OpenGL errors and returns a descriptive error
 func checkGLError(operation string) error {
 	if err := gl.GetError(); err != gl.NO_ERROR {
@@ -87,6 +147,10 @@ func getShaderTypeName(shaderType ShaderType) string {
 		return "geometry"
 	case ComputeShader:
 		return "compute"
+	case TessControlShader:
+		return "tessellation control"
+	case TessEvaluationShader:
+		return "tessellation evaluation"
 	default:
 		return "unknown"
 	}
@@ -95,27 +159,117 @@ func getShaderTypeName(shaderType ShaderType) string {
 // ShaderType represents the type of shader
 type ShaderType uint32
 
+func (t ShaderType) String() string {
+	return getShaderTypeName(t)
+}
+
 const (
 	VertexShader   ShaderType = gl.VERTEX_SHADER
 	FragmentShader ShaderType = gl.FRAGMENT_SHADER
 	GeometryShader ShaderType = gl.GEOMETRY_SHADER
 	ComputeShader  ShaderType = gl.COMPUTE_SHADER
+
+	TessControlShader    ShaderType = gl.TESS_CONTROL_SHADER
+	TessEvaluationShader ShaderType = gl.TESS_EVALUATION_SHADER
 )
 
+// stageExtensions maps conventional file extensions to shader stages
+var stageExtensions = map[string]ShaderType{
+	".vert": VertexShader,
+	".frag": FragmentShader,
+	".geom": GeometryShader,
+	".comp": ComputeShader,
+	".tesc": TessControlShader,
+	".tese": TessEvaluationShader,
+}
+
+// stageNames maps short and long stage names to shader stages
+var stageNames = map[string]ShaderType{
+	"vert": VertexShader, "vertex": VertexShader,
+	"frag": FragmentShader, "fragment": FragmentShader,
+	"geom": GeometryShader, "geometry": GeometryShader,
+	"comp": ComputeShader, "compute": ComputeShader,
+	"tesc": TessControlShader,
+	"tese": TessEvaluationShader,
+}
+
+// ParseShaderType returns the stage with a short or long name such as
+// "frag" or "fragment"
+func ParseShaderType(name string) (ShaderType, bool) {
+	t, ok := stageNames[name]
+	return t, ok
+}
+
+// StageFromPath infers the stage of a shader file from its extension
+// (.vert, .frag, .geom, .comp, .tesc, .tese) or, failing that, from the
+// name of its directory as in the shaders/ tree (vertex, fragment, ...)
+func StageFromPath(name string) (ShaderType, bool) {
+	name = filepath.ToSlash(name)
+	if t, ok := stageExtensions[path.Ext(name)]; ok {
+		return t, true
+	}
+	return ParseShaderType(path.Base(path.Dir(name)))
+}
+
 // Shader represents a compiled OpenGL shader
 type Shader struct {
 	ID   uint32
 	Type ShaderType
+
+	// Diagnostics holds warnings the driver reported for a successful compile
+	Diagnostics []Diagnostic
+
+	origin   *shaderOrigin // Set for shaders compiled from files, used by Reload
+	bindings []Binding     // Removed by Translate, applied by programs after linking
+	deleted  bool          // Set by Delete to report linking deleted shaders
 }
 
 // Program represents a linked shader program
 type Program struct {
 	ID      uint32
 	shaders []*Shader
+
+	// Diagnostics holds warnings the driver reported for a successful link
+	Diagnostics []Diagnostic
+
+	options    ProgramOptions
+	stages     []ShaderType // Linked stages in pipeline order
+	reflection *reflection
+	locations  map[string]int32 // Uniform location cache keyed by name
+
+	subroutines map[ShaderType]*subroutineSelection // Reapplied by Use
+	adopted     map[*Shader]bool                    // Shaders rebuilt by Reload while borrowing
+	pipelines   map[*ProgramPipeline]bool           // Pipelines using its stages, updated by Reload
 }
 
 // CompileShader compiles a shader from source code
 func CompileShader(source string, shaderType ShaderType) (*Shader, error) {
+	return compileShader(source, shaderType, "", nil)
+}
+
+// compileShader compiles source, translating info log locations through
+// smap when the source was preprocessed from the named file
+func compileShader(source string, shaderType ShaderType, name string, smap *SourceMap) (*Shader, error) {
+	job, err := startCompile(source, shaderType, name, smap)
+	if err != nil {
+		return nil, err
+	}
+	return job.finish()
+}
+
+// compileJob is a shader whose compile was issued but whose status has not
+// been queried yet
+type compileJob struct {
+	shader   *Shader
+	source   string // Source handed to the driver, after translation
+	name     string
+	smap     *SourceMap
+	warnings []Diagnostic // Reported by translation, before the driver's
+}
+
+// startCompile creates the shader object and issues the compile without
+// waiting for the driver
+func startCompile(source string, shaderType ShaderType, name string, smap *SourceMap) (*compileJob, error) {
 	// Input validation
 	if source == "" {
 		return nil, fmt.Errorf("shader source cannot be empty")
@@ -129,6 +283,14 @@ func CompileShader(source string, shaderType ShaderType) (*Shader, error) {
 		return nil, fmt.Errorf("failed to create shader: OpenGL context may not be initialized")
 	}
 
+	// Contexts older than 410 core or running GLSL ES get translated shaders
+	translated, warnings, err := translate(source, shaderType, name, smap)
+	if err != nil {
+		gl.DeleteShader(shaderID)
+		return nil, err
+	}
+	source, smap = translated.Source.Code, translated.Source.Map
+
 	cSource, free := gl.Strs(source + "\x00")
 	defer free()
 
@@ -144,75 +306,206 @@ func CompileShader(source string, shaderType ShaderType) (*Shader, error) {
 		return nil, err
 	}
 
+	return &compileJob{
+		shader:   &Shader{ID: shaderID, Type: shaderType, bindings: translated.Bindings},
+		source:   source,
+		name:     name,
+		smap:     smap,
+		warnings: warnings,
+	}, nil
+}
+
+// finish queries the compile status, waiting for the driver if needed. On
+// failure the shader object is deleted.
+func (j *compileJob) finish() (*Shader, error) {
+	shader := j.shader
 	var status int32
-	gl.GetShaderiv(shaderID, gl.COMPILE_STATUS, &status)
+	gl.GetShaderiv(shader.ID, gl.COMPILE_STATUS, &status)
+	log := shaderInfoLog(shader.ID)
+	diagnostics := append(j.warnings, resolveDiagnostics(ParseInfoLog(log), j.source, j.name, j.smap)...)
 	if status == gl.FALSE {
-		var logLength int32
-		gl.GetShaderiv(shaderID, gl.INFO_LOG_LENGTH, &logLength)
-
-		// Use pooled buffer to reduce allocations
-		buf := logPool.Get().([]byte)
-		defer logPool.Put(buf[:0]) // Reset length but keep capacity
-		
-		if cap(buf) < int(logLength) {
-			buf = make([]byte, logLength)
+		shader.Delete()
+		return nil, &CompileError{
+			Stage:       shader.Type,
+			File:        j.name,
+			Log:         j.smap.RewriteLog(log),
+			Diagnostics: diagnostics,
 		}
-		buf = buf[:logLength]
-
-		gl.GetShaderInfoLog(shaderID, logLength, nil, (*uint8)(&buf[0]))
-
-		gl.DeleteShader(shaderID)
-		return nil, fmt.Errorf("failed to compile %s shader: %s", 
-			getShaderTypeName(shaderType), string(buf[:logLength-1])) // Remove null terminator
 	}
 
-	return &Shader{
-		ID:   shaderID,
-		Type: shaderType,
-	}, nil
+	shader.Diagnostics = diagnostics
+	return shader, nil
 }
 
-// CompileShaderFromFile compiles a shader from a file
+// CompileShaderFromFile compiles a shader from a file, expanding #include
+// directives with DefaultPreprocessor
 func CompileShaderFromFile(filepath string, shaderType ShaderType) (*Shader, error) {
-	source, err := os.ReadFile(filepath)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read shader file %s: %w", filepath, err)
-	}
+	return DefaultPreprocessor.CompileShaderFromFile(filepath, shaderType)
+}
+
+// CompileShaderFromFS compiles a shader from a file in fsys, such as an
+// embed.FS, resolving #include directives inside the same filesystem
+func CompileShaderFromFS(fsys fs.FS, name string, shaderType ShaderType) (*Shader, error) {
+	return NewFSPreprocessor(fsys).CompileShaderFromFile(name, shaderType)
+}
+
+// ProgramOptions configures how a program is linked
+type ProgramOptions struct {
+	// BinaryRetrievable hints the driver that Program.Binary will be called
+	BinaryRetrievable bool
+
+	// Separable links the program for use in a ProgramPipeline. Separable
+	// programs may contain any subset of the graphics stages.
+	Separable bool
+
+	// TransformFeedbackVaryings names the outputs of the last vertex
+	// processing stage captured by transform feedback. A program that
+	// captures varyings may omit the fragment shader.
+	TransformFeedbackVaryings []string
+
+	// TransformFeedbackMode selects how the varyings are written to the
+	// bound buffers; the zero value is InterleavedAttribs
+	TransformFeedbackMode TransformFeedbackMode
+
+	// Ownership decides who deletes the shaders; the zero value is OwnShaders
+	Ownership ShaderOwnership
+}
 
-	return CompileShader(string(source), shaderType)
+// ShaderOwnership decides whether a program deletes the shaders it is
+// linked from
+type ShaderOwnership int
+
+const (
+	// OwnShaders deletes the shaders with the program, and when linking fails
+	OwnShaders ShaderOwnership = iota
+
+	// BorrowShaders leaves the shaders to the caller, who may link them into
+	// other programs and deletes them once no more programs are linked.
+	// Shaders rebuilt by Reload belong to the program.
+	BorrowShaders
+
+	// ReleaseShaders detaches and deletes the shaders right after a
+	// successful link to free their driver memory. Reload still works for
+	// file-based shaders, which it compiles again from disk.
+	ReleaseShaders
+)
+
+// String returns a readable name for the ownership mode
+func (o ShaderOwnership) String() string {
+	switch o {
+	case OwnShaders:
+		return "own"
+	case BorrowShaders:
+		return "borrow"
+	case ReleaseShaders:
+		return "release"
+	default:
+		return "unknown"
+	}
 }
 
 // CreateProgram creates a new shader program
 func CreateProgram(shaders ...*Shader) (*Program, error) {
+	return CreateProgramWithOptions(ProgramOptions{}, shaders...)
+}
+
+// CreateProgramWithOptions creates a new shader program linked with opts
+func CreateProgramWithOptions(opts ProgramOptions, shaders ...*Shader) (*Program, error) {
 	// Input validation
 	if len(shaders) == 0 {
 		return nil, fmt.Errorf("at least one shader is required")
 	}
 	
-	// Validate shader types - ensure we have at least vertex and fragment
-	hasVertex, hasFragment := false, false
+	if err := validateStages(shaders, opts); err != nil {
+		return nil, err
+	}
+
+	program, err := linkProgram(shaders, opts)
+	if err != nil {
+		if opts.Ownership != BorrowShaders {
+			for _, shader := range shaders {
+				shader.Delete()
+			}
+		}
+		return nil, err
+	}
+	return program, nil
+}
+
+// validateStages checks that shaders form a linkable stage combination:
+// a compute shader on its own, or a vertex shader, optional tessellation
+// control and evaluation shaders, an optional geometry shader and a
+// fragment shader. Separable programs may omit any graphics stage and
+// programs capturing transform feedback varyings the fragment shader.
+// Several shader objects may share a stage.
+func validateStages(shaders []*Shader, opts ProgramOptions) error {
+	stages := make(map[ShaderType]bool)
 	for _, shader := range shaders {
 		if shader == nil {
-			return nil, fmt.Errorf("shader cannot be nil")
+			return fmt.Errorf("shader cannot be nil")
+		}
+		if shader.deleted {
+			return fmt.Errorf("%s shader was deleted before linking; link shaders shared between programs with BorrowShaders ownership", shader.Type)
 		}
 		if shader.ID == 0 {
-			return nil, fmt.Errorf("invalid shader: ID is 0")
+			return fmt.Errorf("invalid shader: ID is 0")
 		}
 		switch shader.Type {
-		case VertexShader:
-			hasVertex = true
-		case FragmentShader:
-			hasFragment = true
+		case VertexShader, TessControlShader, TessEvaluationShader, GeometryShader, FragmentShader, ComputeShader:
+			stages[shader.Type] = true
+		default:
+			return fmt.Errorf("invalid shader type 0x%x", uint32(shader.Type))
 		}
 	}
-	
-	if !hasVertex {
-		return nil, fmt.Errorf("vertex shader is required")
+
+	if stages[ComputeShader] {
+		if len(stages) > 1 {
+			return fmt.Errorf("invalid stage combination %s: compute shaders cannot be linked with other stages", stageList(stages))
+		}
+		return nil
+	}
+	if opts.Separable {
+		return nil
+	}
+	if !stages[VertexShader] {
+		return fmt.Errorf("invalid stage combination %s: vertex shader is required", stageList(stages))
 	}
-	if !hasFragment {
-		return nil, fmt.Errorf("fragment shader is required")
+	if stages[TessControlShader] && !stages[TessEvaluationShader] {
+		return fmt.Errorf("invalid stage combination %s: tessellation control shader requires a tessellation evaluation shader", stageList(stages))
 	}
+	if !stages[FragmentShader] && len(opts.TransformFeedbackVaryings) == 0 {
+		return fmt.Errorf("invalid stage combination %s: fragment shader is required", stageList(stages))
+	}
+	return nil
+}
+
+// stageList formats a stage set in pipeline order, e.g. "[vertex, fragment]"
+func stageList(stages map[ShaderType]bool) string {
+	var names []string
+	for _, stage := range pipelineOrder {
+		if stages[stage] {
+			names = append(names, stage.String())
+		}
+	}
+	return "[" + strings.Join(names, ", ") + "]"
+}
+
+// linkProgram attaches shaders to a new program object and links it. On
+// failure the program object is deleted and the shaders are left untouched.
+func linkProgram(shaders []*Shader, opts ProgramOptions) (*Program, error) {
+	program, err := startLink(shaders, opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := program.finishLink(); err != nil {
+		return nil, err
+	}
+	return program, nil
+}
 
+// startLink attaches shaders to a new program object and issues the link
+// without waiting for the driver
+func startLink(shaders []*Shader, opts ProgramOptions) (*Program, error) {
 	programID := gl.CreateProgram()
 	if programID == 0 {
 		return nil, fmt.Errorf("failed to create program: OpenGL context may not be initialized")
@@ -221,83 +514,92 @@ func CreateProgram(shaders ...*Shader) (*Program, error) {
 	program := &Program{
 		ID:      programID,
 		shaders: make([]*Shader, len(shaders)),
+		options: opts,
+	}
+
+	if opts.BinaryRetrievable {
+		gl.ProgramParameteri(programID, gl.PROGRAM_BINARY_RETRIEVABLE_HINT, gl.TRUE)
+	}
+	if opts.Separable {
+		gl.ProgramParameteri(programID, gl.PROGRAM_SEPARABLE, gl.TRUE)
+	}
+
+	if len(opts.TransformFeedbackVaryings) > 0 {
+		setTransformFeedbackVaryings(programID, opts.TransformFeedbackVaryings, opts.TransformFeedbackMode)
 	}
 
 	// Attach all shaders
 	for i, shader := range shaders {
 		gl.AttachShader(programID, shader.ID)
 		program.shaders[i] = shader
+		program.addStage(shader.Type)
 	}
 
 	// Link the program
 	gl.LinkProgram(programID)
 	if err := checkGLError("glLinkProgram"); err != nil {
-		program.Delete()
+		program.deleteObject()
 		return nil, err
 	}
 
+	return program, nil
+}
+
+// finishLink queries the link status, waiting for the driver if needed,
+// and reflects the linked program. On failure the program object is deleted.
+func (p *Program) finishLink() error {
 	var status int32
-	gl.GetProgramiv(programID, gl.LINK_STATUS, &status)
+	gl.GetProgramiv(p.ID, gl.LINK_STATUS, &status)
+	log := programInfoLog(p.ID)
 	if status == gl.FALSE {
-		var logLength int32
-		gl.GetProgramiv(programID, gl.INFO_LOG_LENGTH, &logLength)
-
-		// Use pooled buffer to reduce allocations
-		buf := logPool.Get().([]byte)
-		defer logPool.Put(buf[:0])
-		
-		if cap(buf) < int(logLength) {
-			buf = make([]byte, logLength)
-		}
-		buf = buf[:logLength]
+		err := newLinkError(log, p.shaders)
+		p.deleteObject()
+		return err
+	}
 
-		gl.GetProgramInfoLog(programID, logLength, nil, (*uint8)(&buf[0]))
+	p.Diagnostics = ParseInfoLog(log)
+	for _, shader := range p.shaders {
+		p.applyBindings(shader.bindings)
+	}
+	p.reflect()
 
-		program.Delete()
-		return nil, fmt.Errorf("failed to link program: %s", string(buf[:logLength-1]))
+	if p.options.Ownership == ReleaseShaders {
+		for _, shader := range p.shaders {
+			if shader.ID != 0 {
+				gl.DetachShader(p.ID, shader.ID)
+				shader.Delete()
+			}
+		}
 	}
+	return nil
+}
 
-	return program, nil
+// owns reports whether the program deletes shader
+func (p *Program) owns(shader *Shader) bool {
+	return p.options.Ownership != BorrowShaders || p.adopted[shader]
 }
 
-// Use activates the shader program
+// Use activates the shader program and applies its subroutine selection
 func (p *Program) Use() {
 	gl.UseProgram(p.ID)
+	p.applySubroutines(0)
 }
 
-// GetUniformLocation returns the location of a uniform variable
+// GetUniformLocation returns the location of a uniform variable.
+// Locations are cached per name, so only the first lookup of a name that
+// was not reported by reflection reaches the driver.
 func (p *Program) GetUniformLocation(name string) int32 {
-	return gl.GetUniformLocation(p.ID, gl.Str(name+"\x00"))
-}
-
-// SetUniformMatrix4fv sets a mat4 uniform with validation
-func (p *Program) SetUniformMatrix4fv(location int32, matrix *mgl32.Mat4) error {
-	if location == -1 {
-		return fmt.Errorf("invalid uniform location: -1")
+	if loc, ok := p.locations[name]; ok {
+		return loc
 	}
-	if matrix == nil {
-		return fmt.Errorf("matrix cannot be nil")
-	}
-	gl.UniformMatrix4fv(location, 1, false, &matrix[0])
-	return checkGLError("glUniformMatrix4fv")
-}
-
-// SetUniform1f sets a float uniform with validation
-func (p *Program) SetUniform1f(location int32, value float32) error {
-	if location == -1 {
-		return fmt.Errorf("invalid uniform location: -1")
-	}
-	gl.Uniform1f(location, value)
-	return checkGLError("glUniform1f")
-}
-
-// SetUniform3f sets a vec3 uniform with validation
-func (p *Program) SetUniform3f(location int32, x, y, z float32) error {
-	if location == -1 {
-		return fmt.Errorf("invalid uniform location: -1")
+	loc := gl.GetUniformLocation(p.ID, gl.Str(name+"\x00"))
+	if p.ID != 0 {
+		if p.locations == nil {
+			p.locations = make(map[string]int32)
+		}
+		p.locations[name] = loc
 	}
-	gl.Uniform3f(location, x, y, z)
-	return checkGLError("glUniform3f")
+	return loc
 }
 
 // Validate validates the program (use only in debug builds)
@@ -314,47 +616,67 @@ func (p *Program) Validate() error {
 	var status int32
 	gl.GetProgramiv(p.ID, gl.VALIDATE_STATUS, &status)
 	if status == gl.FALSE {
-		var logLength int32
-		gl.GetProgramiv(p.ID, gl.INFO_LOG_LENGTH, &logLength)
-
-		// Use pooled buffer to reduce allocations
-		buf := logPool.Get().([]byte)
-		defer logPool.Put(buf[:0])
-		
-		if cap(buf) < int(logLength) {
-			buf = make([]byte, logLength)
-		}
-		buf = buf[:logLength]
-
-		gl.GetProgramInfoLog(p.ID, logLength, nil, (*uint8)(&buf[0]))
-
-		return fmt.Errorf("program validation failed: %s", string(buf[:logLength-1]))
+		return fmt.Errorf("program validation failed: %s", programInfoLog(p.ID))
 	}
 
 	return nil
 }
 
-// Delete cleans up the program and associated shaders
+// Delete cleans up the program and, depending on its ProgramOptions.Ownership,
+// the associated shaders. Program pipelines using its stages lose them.
 func (p *Program) Delete() {
 	if p.ID != 0 {
 		for _, shader := range p.shaders {
 			if shader != nil && shader.ID != 0 {
 				gl.DetachShader(p.ID, shader.ID)
-				gl.DeleteShader(shader.ID)
-				shader.ID = 0 // Mark as deleted
+				if p.owns(shader) {
+					shader.Delete()
+				}
 			}
 		}
+		for pipeline := range p.pipelines {
+			pipeline.clearProgram(p)
+		}
 		gl.DeleteProgram(p.ID)
 		p.ID = 0
 		p.shaders = nil // Clear references
+		p.stages = nil
+		p.reflection = nil
+		p.locations = nil
+		p.subroutines = nil
+		p.adopted = nil
+		p.pipelines = nil
+	}
+}
+
+// deleteObject detaches the shaders and deletes the program object without
+// deleting the shaders themselves
+func (p *Program) deleteObject() {
+	if p.ID == 0 {
+		return
+	}
+	for _, shader := range p.shaders {
+		if shader != nil && shader.ID != 0 {
+			gl.DetachShader(p.ID, shader.ID)
+		}
 	}
+	gl.DeleteProgram(p.ID)
+	p.ID = 0
+	p.shaders = nil
+	p.stages = nil
+	p.reflection = nil
+	p.locations = nil
+	p.subroutines = nil
+	p.pipelines = nil
 }
 
-// Delete cleans up the shader
+// Delete cleans up the shader. Programs already linked from it keep
+// working, but it can no longer be linked.
 func (s *Shader) Delete() {
 	if s.ID != 0 {
 		gl.DeleteShader(s.ID)
 		s.ID = 0
+		s.deleted = true
 	}
 }
 
@@ -0,0 +1,311 @@
+package shader
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+)
+
+// SubroutineInfo describes an active subroutine function of a stage
+type SubroutineInfo struct {
+	Name  string
+	Index uint32
+}
+
+// SubroutineUniformInfo describes an active subroutine uniform of a stage
+type SubroutineUniformInfo struct {
+	Name       string   // Name without the trailing "[0]" of arrays
+	Location   int32    // Base location; array element i is at Location+i
+	Size       int32    // Array size, 1 for non-arrays
+	Compatible []string // Subroutines that can be selected, in index order
+}
+
+// stageSubroutines holds the reflected subroutine interface of one stage
+type stageSubroutines struct {
+	functions []SubroutineInfo        // Sorted by index
+	uniforms  []SubroutineUniformInfo // Sorted by location
+	locations int32                   // Number of subroutine uniform locations
+}
+
+// subroutineSelection holds the subroutines chosen for one stage with
+// SetSubroutines, keyed by uniform element ("uLight" or "uFilters[1]")
+type subroutineSelection struct {
+	names   map[string]string
+	indices []uint32 // Subroutine index per uniform location
+}
+
+// supportsSubroutines reports whether the current context provides shader
+// subroutines (OpenGL 4.0 or ARB_shader_subroutine)
+func supportsSubroutines() bool {
+	var major int32
+	gl.GetIntegerv(gl.MAJOR_VERSION, &major)
+	if major >= 4 {
+		return true
+	}
+
+	return hasExtension("GL_ARB_shader_subroutine")
+}
+
+// reflectSubroutines introspects the subroutine uniforms and functions of
+// every linked stage. Programs loaded from a binary have no known stages,
+// so all graphics stages are queried. Contexts without subroutines, such
+// as 3.3 core, report none.
+func (p *Program) reflectSubroutines() map[ShaderType]*stageSubroutines {
+	if !supportsSubroutines() {
+		return nil
+	}
+
+	stages := p.stages
+	if len(stages) == 0 {
+		stages = pipelineOrder[:len(pipelineOrder)-1]
+	}
+
+	result := make(map[ShaderType]*stageSubroutines)
+	for _, stage := range stages {
+		var uniforms, locations, functions, maxLength int32
+		gl.GetProgramStageiv(p.ID, uint32(stage), gl.ACTIVE_SUBROUTINE_UNIFORMS, &uniforms)
+		if uniforms == 0 {
+			continue
+		}
+		gl.GetProgramStageiv(p.ID, uint32(stage), gl.ACTIVE_SUBROUTINE_UNIFORM_LOCATIONS, &locations)
+		gl.GetProgramStageiv(p.ID, uint32(stage), gl.ACTIVE_SUBROUTINES, &functions)
+
+		s := &stageSubroutines{locations: locations}
+		gl.GetProgramStageiv(p.ID, uint32(stage), gl.ACTIVE_SUBROUTINE_MAX_LENGTH, &maxLength)
+		nameBuf := make([]byte, maxLength+1)
+		names := make(map[uint32]string)
+		for i := uint32(0); i < uint32(functions); i++ {
+			var length int32
+			gl.GetActiveSubroutineName(p.ID, uint32(stage), i, int32(len(nameBuf)), &length, &nameBuf[0])
+			names[i] = string(nameBuf[:length])
+			s.functions = append(s.functions, SubroutineInfo{Name: names[i], Index: i})
+		}
+
+		gl.GetProgramStageiv(p.ID, uint32(stage), gl.ACTIVE_SUBROUTINE_UNIFORM_MAX_LENGTH, &maxLength)
+		nameBuf = make([]byte, maxLength+1)
+		for i := uint32(0); i < uint32(uniforms); i++ {
+			var length, size, count int32
+			gl.GetActiveSubroutineUniformName(p.ID, uint32(stage), i, int32(len(nameBuf)), &length, &nameBuf[0])
+			gl.GetActiveSubroutineUniformiv(p.ID, uint32(stage), i, gl.UNIFORM_SIZE, &size)
+			gl.GetActiveSubroutineUniformiv(p.ID, uint32(stage), i, gl.NUM_COMPATIBLE_SUBROUTINES, &count)
+
+			info := SubroutineUniformInfo{
+				Name:     strings.TrimSuffix(string(nameBuf[:length]), "[0]"),
+				Location: gl.GetSubroutineUniformLocation(p.ID, uint32(stage), &nameBuf[0]),
+				Size:     size,
+			}
+			if count > 0 {
+				compatible := make([]int32, count)
+				gl.GetActiveSubroutineUniformiv(p.ID, uint32(stage), i, gl.COMPATIBLE_SUBROUTINES, &compatible[0])
+				sort.Slice(compatible, func(a, b int) bool { return compatible[a] < compatible[b] })
+				for _, index := range compatible {
+					info.Compatible = append(info.Compatible, names[uint32(index)])
+				}
+			}
+			s.uniforms = append(s.uniforms, info)
+		}
+		sort.Slice(s.uniforms, func(a, b int) bool { return s.uniforms[a].Location < s.uniforms[b].Location })
+
+		result[stage] = s
+	}
+	return result
+}
+
+// Subroutines returns the active subroutine functions of a stage in index order
+func (p *Program) Subroutines(stage ShaderType) []SubroutineInfo {
+	if p.reflection == nil || p.reflection.subroutines[stage] == nil {
+		return nil
+	}
+	return p.reflection.subroutines[stage].functions
+}
+
+// SubroutineUniforms returns the active subroutine uniforms of a stage
+// sorted by location
+func (p *Program) SubroutineUniforms(stage ShaderType) []SubroutineUniformInfo {
+	if p.reflection == nil || p.reflection.subroutines[stage] == nil {
+		return nil
+	}
+	return p.reflection.subroutines[stage].uniforms
+}
+
+// SetSubroutines selects the subroutine function for subroutine uniforms of
+// a stage, e.g. {"uLighting": "blinnPhong"}. Array elements are given as
+// "uFilters[1]"; the bare array name selects every element. The selection
+// is merged with earlier calls, uniforms never selected use their first
+// compatible subroutine, and it is applied again on every Use since OpenGL
+// resets subroutine uniforms whenever a program is made current.
+func (p *Program) SetSubroutines(stage ShaderType, selection map[string]string) error {
+	if p.ID == 0 {
+		return fmt.Errorf("program not initialized")
+	}
+	if p.reflection == nil || p.reflection.subroutines[stage] == nil {
+		return fmt.Errorf("program %d has no subroutine uniforms in the %s stage", p.ID, stage)
+	}
+	s := p.reflection.subroutines[stage]
+
+	names := make(map[string]string)
+	if current := p.subroutines[stage]; current != nil {
+		for k, v := range current.names {
+			names[k] = v
+		}
+	}
+	for key, function := range selection {
+		elements, err := s.elements(key)
+		if err != nil {
+			return err
+		}
+		for _, element := range elements {
+			names[element.key] = function
+		}
+		if _, err := s.resolve(elements[0].uniform, function); err != nil {
+			return err
+		}
+	}
+
+	indices, err := s.indices(names)
+	if err != nil {
+		return err
+	}
+	if p.subroutines == nil {
+		p.subroutines = make(map[ShaderType]*subroutineSelection)
+	}
+	p.subroutines[stage] = &subroutineSelection{names: names, indices: indices}
+
+	var current int32
+	gl.GetIntegerv(gl.CURRENT_PROGRAM, &current)
+	if uint32(current) == p.ID {
+		p.applySubroutines(stage)
+	}
+	return nil
+}
+
+// applySubroutines uploads the selected subroutines of stage, or of every
+// stage when stage is 0, to the current program
+func (p *Program) applySubroutines(stage ShaderType) {
+	for s, selection := range p.subroutines {
+		if (stage == 0 || s == stage) && len(selection.indices) > 0 {
+			gl.UniformSubroutinesuiv(uint32(s), int32(len(selection.indices)), &selection.indices[0])
+		}
+	}
+}
+
+// restoreSubroutines resolves the selection again after the program was
+// relinked, dropping uniforms and subroutines that no longer exist
+func (p *Program) restoreSubroutines() {
+	for stage, selection := range p.subroutines {
+		s := p.reflection.subroutines[stage]
+		if s == nil {
+			delete(p.subroutines, stage)
+			continue
+		}
+		names := make(map[string]string)
+		for key, function := range selection.names {
+			if elements, err := s.elements(key); err == nil {
+				if _, err := s.resolve(elements[0].uniform, function); err == nil {
+					names[key] = function
+				}
+			}
+		}
+		indices, err := s.indices(names)
+		if err != nil {
+			delete(p.subroutines, stage)
+			continue
+		}
+		p.subroutines[stage] = &subroutineSelection{names: names, indices: indices}
+	}
+}
+
+// subroutineElement is one location of a subroutine uniform
+type subroutineElement struct {
+	key     string
+	uniform *SubroutineUniformInfo
+}
+
+// elements returns the uniform locations a selection key refers to
+func (s *stageSubroutines) elements(key string) ([]subroutineElement, error) {
+	name, index := key, -1
+	if open := strings.IndexByte(key, '['); open > 0 && strings.HasSuffix(key, "]") {
+		i, err := strconv.Atoi(key[open+1 : len(key)-1])
+		if err != nil || i < 0 {
+			return nil, fmt.Errorf("invalid subroutine uniform %s", key)
+		}
+		name, index = key[:open], i
+	}
+
+	for i := range s.uniforms {
+		u := &s.uniforms[i]
+		if u.Name != name {
+			continue
+		}
+		if u.Size == 1 {
+			if index > 0 {
+				break
+			}
+			return []subroutineElement{{key: u.Name, uniform: u}}, nil
+		}
+		if index >= int(u.Size) {
+			break
+		}
+		if index >= 0 {
+			return []subroutineElement{{key: key, uniform: u}}, nil
+		}
+		elements := make([]subroutineElement, u.Size)
+		for j := range elements {
+			elements[j] = subroutineElement{key: fmt.Sprintf("%s[%d]", u.Name, j), uniform: u}
+		}
+		return elements, nil
+	}
+	return nil, fmt.Errorf("unknown subroutine uniform %s", key)
+}
+
+// resolve returns the index of function, which must be compatible with u
+func (s *stageSubroutines) resolve(u *SubroutineUniformInfo, function string) (uint32, error) {
+	for _, compatible := range u.Compatible {
+		if compatible != function {
+			continue
+		}
+		for _, f := range s.functions {
+			if f.Name == function {
+				return f.Index, nil
+			}
+		}
+	}
+	for _, f := range s.functions {
+		if f.Name == function {
+			return 0, fmt.Errorf("subroutine %s is not compatible with %s", function, u.Name)
+		}
+	}
+	return 0, fmt.Errorf("unknown subroutine %s", function)
+}
+
+// indices builds the subroutine index of every uniform location from names
+func (s *stageSubroutines) indices(names map[string]string) ([]uint32, error) {
+	indices := make([]uint32, s.locations)
+	for i := range s.uniforms {
+		u := &s.uniforms[i]
+		for j := int32(0); j < u.Size; j++ {
+			key := u.Name
+			if u.Size > 1 {
+				key = fmt.Sprintf("%s[%d]", u.Name, j)
+			}
+			function, ok := names[key]
+			if !ok {
+				if len(u.Compatible) == 0 {
+					return nil, fmt.Errorf("subroutine uniform %s has no compatible subroutine", u.Name)
+				}
+				function = u.Compatible[0]
+			}
+			index, err := s.resolve(u, function)
+			if err != nil {
+				return nil, err
+			}
+			if location := u.Location + j; location >= 0 && location < s.locations {
+				indices[location] = index
+			}
+		}
+	}
+	return indices, nil
+}
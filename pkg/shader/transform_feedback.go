@@ -0,0 +1,93 @@
+package shader
+
+import (
+	"strings"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+)
+
+// TransformFeedbackMode selects how captured varyings are laid out in the
+// transform feedback buffers
+type TransformFeedbackMode uint32
+
+const (
+	// InterleavedAttribs writes all varyings to the buffer bound at index 0
+	InterleavedAttribs TransformFeedbackMode = gl.INTERLEAVED_ATTRIBS
+
+	// SeparateAttribs writes varying i to the buffer bound at index i
+	SeparateAttribs TransformFeedbackMode = gl.SEPARATE_ATTRIBS
+)
+
+// String returns a readable name for the mode
+func (m TransformFeedbackMode) String() string {
+	switch m {
+	case 0, InterleavedAttribs:
+		return "interleaved"
+	case SeparateAttribs:
+		return "separate"
+	default:
+		return "unknown"
+	}
+}
+
+// VaryingInfo describes a varying captured by transform feedback
+type VaryingInfo struct {
+	Name string   // Name as given in ProgramOptions, e.g. "vPosition" or "gl_SkipComponents2"
+	Type DataType // GLSL type
+	Size int32    // Array size, 1 for non-arrays
+}
+
+// setTransformFeedbackVaryings records the varyings to capture; it must be
+// called before the program is linked
+func setTransformFeedbackVaryings(programID uint32, varyings []string, mode TransformFeedbackMode) {
+	if mode == 0 {
+		mode = InterleavedAttribs
+	}
+	names := make([]string, len(varyings))
+	for i, name := range varyings {
+		names[i] = name + "\x00"
+	}
+	cstrs, free := gl.Strs(names...)
+	defer free()
+	gl.TransformFeedbackVaryings(programID, int32(len(names)), cstrs, uint32(mode))
+}
+
+// reflectVaryings introspects the varyings captured by transform feedback
+func (p *Program) reflectVaryings() []VaryingInfo {
+	var count, maxLength int32
+	gl.GetProgramiv(p.ID, gl.TRANSFORM_FEEDBACK_VARYINGS, &count)
+	if count == 0 {
+		return nil
+	}
+	gl.GetProgramiv(p.ID, gl.TRANSFORM_FEEDBACK_VARYING_MAX_LENGTH, &maxLength)
+	nameBuf := make([]byte, maxLength+1)
+
+	varyings := make([]VaryingInfo, 0, count)
+	for i := int32(0); i < count; i++ {
+		var length, size int32
+		var xtype uint32
+		gl.GetTransformFeedbackVarying(p.ID, uint32(i), int32(len(nameBuf)), &length, &size, &xtype, &nameBuf[0])
+		varyings = append(varyings, VaryingInfo{
+			Name: strings.TrimSuffix(string(nameBuf[:length]), "[0]"),
+			Type: DataType(xtype),
+			Size: size,
+		})
+	}
+	return varyings
+}
+
+// TransformFeedbackVaryings returns the captured varyings in capture order
+func (p *Program) TransformFeedbackVaryings() []VaryingInfo {
+	if p.reflection == nil {
+		return nil
+	}
+	return p.reflection.varyings
+}
+
+// TransformFeedbackMode returns the mode the program captures varyings with
+func (p *Program) TransformFeedbackMode() TransformFeedbackMode {
+	if p.options.TransformFeedbackMode == 0 {
+		return InterleavedAttribs
+	}
+	return p.options.TransformFeedbackMode
+}
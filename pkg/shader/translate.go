@@ -0,0 +1,571 @@
+package shader
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+	"github.com/yossideutsch/gogl/internal/platform"
+	"github.com/yossideutsch/gogl/pkg/glsl"
+)
+
+// translatedFile is the pseudo file name lines added by Translate map to
+const translatedFile = "<translated>"
+
+// Dialect is a GLSL version and profile
+type Dialect struct {
+	Version int  // e.g. 410, 330 or 300
+	ES      bool // OpenGL ES shading language
+}
+
+// Dialects the bundled shaders are written in or translated to
+var (
+	GLSL410Core = Dialect{Version: 410}
+	GLSL330Core = Dialect{Version: 330}
+	GLSL300ES   = Dialect{Version: 300, ES: true}
+)
+
+// String returns the dialect as written after #version, e.g. "330 core" or "300 es"
+func (d Dialect) String() string {
+	if d.ES {
+		return fmt.Sprintf("%d es", d.Version)
+	}
+	return fmt.Sprintf("%d core", d.Version)
+}
+
+// Translated reports whether shaders are translated before they are
+// compiled for the dialect. Desktop GLSL 410 and newer runs the bundled
+// 410 core shaders as they are.
+func (d Dialect) Translated() bool {
+	return d.ES || d.Version < 410
+}
+
+// supported reports whether Translate can target the dialect
+func (d Dialect) supported() bool {
+	if d.ES {
+		return d.Version >= 300
+	}
+	return d.Version >= 330
+}
+
+// has reports whether the dialect has a feature introduced in the given
+// desktop and ES versions; an ES version of 0 means ES lacks the feature
+func (d Dialect) has(desktop, es int) bool {
+	if d.ES {
+		return es != 0 && d.Version >= es
+	}
+	return d.Version >= desktop
+}
+
+// requirement describes the first dialects with a feature
+func requirement(desktop, es int) string {
+	if es == 0 {
+		return fmt.Sprintf("GLSL %d core", desktop)
+	}
+	return fmt.Sprintf("GLSL %d core or %d es", desktop, es)
+}
+
+// Versions introducing features that cannot be rewritten, as {desktop, es}
+var (
+	bindingVersion  = [2]int{420, 310} // layout(binding = N)
+	varyingLocation = [2]int{410, 310} // layout(location = N) between stages
+	storageVersion  = [2]int{430, 310} // Shader storage blocks
+	ioBlockVersion  = [2]int{150, 320} // in/out interface blocks
+)
+
+// stageVersions lists the stages older dialects lack
+var stageVersions = map[ShaderType][2]int{
+	GeometryShader:       {150, 320},
+	TessControlShader:    {400, 320},
+	TessEvaluationShader: {400, 320},
+	ComputeShader:        {430, 310},
+}
+
+// keywordVersions lists the types and qualifiers older dialects lack
+var keywordVersions = func() map[string][2]int {
+	versions := map[string][2]int{
+		"double":        {400, 0},
+		"subroutine":    {400, 0},
+		"precise":       {400, 320},
+		"noperspective": {130, 0},
+		"atomic_uint":   {420, 310},
+	}
+	for _, t := range []string{"dvec2", "dvec3", "dvec4", "dmat2", "dmat3", "dmat4", "dmat2x2", "dmat2x3", "dmat2x4", "dmat3x2", "dmat3x3", "dmat3x4", "dmat4x2", "dmat4x3", "dmat4x4"} {
+		versions[t] = [2]int{400, 0}
+	}
+	for _, prefix := range []string{"", "i", "u"} {
+		for _, t := range []string{"1D", "2D", "3D", "Cube", "2DRect", "1DArray", "2DArray", "CubeArray", "Buffer", "2DMS", "2DMSArray"} {
+			versions[prefix+"image"+t] = [2]int{420, 310}
+		}
+		for _, t := range []string{"1D", "1DArray", "2DRect", "Buffer"} {
+			versions[prefix+"sampler"+t] = [2]int{140, 0}
+		}
+		versions[prefix+"sampler2DMS"] = [2]int{150, 310}
+		versions[prefix+"sampler2DMSArray"] = [2]int{150, 320}
+		versions[prefix+"samplerCubeArray"] = [2]int{400, 320}
+	}
+	for _, t := range []string{"sampler1DShadow", "sampler1DArrayShadow", "sampler2DRectShadow"} {
+		versions[t] = [2]int{140, 0}
+	}
+	versions["samplerCubeArrayShadow"] = [2]int{400, 320}
+	return versions
+}()
+
+// esSamplers are the sampler types of GLSL ES 3.00, which get an explicit
+// highp default precision; most have no default precision at all
+var esSamplers = map[string]bool{
+	"sampler2D": true, "sampler3D": true, "samplerCube": true, "sampler2DArray": true,
+	"sampler2DShadow": true, "samplerCubeShadow": true, "sampler2DArrayShadow": true,
+	"isampler2D": true, "isampler3D": true, "isamplerCube": true, "isampler2DArray": true,
+	"usampler2D": true, "usampler3D": true, "usamplerCube": true, "usampler2DArray": true,
+}
+
+// legacyFunctions maps texture lookups removed from core GLSL to their
+// replacements
+var legacyFunctions = map[string]string{
+	"texture1D": "texture", "texture2D": "texture", "texture3D": "texture", "textureCube": "texture",
+	"texture1DProj": "textureProj", "texture2DProj": "textureProj", "texture3DProj": "textureProj",
+	"texture1DLod": "textureLod", "texture2DLod": "textureLod", "texture3DLod": "textureLod", "textureCubeLod": "textureLod",
+	"texture1DProjLod": "textureProjLod", "texture2DProjLod": "textureProjLod", "texture3DProjLod": "textureProjLod",
+}
+
+// fragColor replaces gl_FragColor, which core and ES 3.00 shaders lack
+const fragColor = "gogl_FragColor"
+
+// Binding is a layout(binding = N) qualifier removed by Translate because
+// the target dialect lacks it. Programs apply it after linking instead.
+type Binding struct {
+	Name  string // Sampler uniform or uniform block name
+	Block bool   // Whether Name is a uniform block
+	Unit  int    // Texture unit or uniform buffer binding point
+	Count int    // Array size, 0 for non-arrays
+}
+
+// Translation is a shader rewritten for another dialect
+type Translation struct {
+	Source   *Source
+	Bindings []Binding
+}
+
+// edit replaces source bytes [start, end) with text
+type edit struct {
+	start, end int
+	text       string
+}
+
+// translator accumulates the rewrites of one shader
+type translator struct {
+	src      *Source
+	ast      *glsl.Shader
+	stage    ShaderType
+	target   Dialect
+	edits    []edit
+	bindings []Binding
+}
+
+// Translate rewrites a shader for the target dialect, such as 330 core or
+// 300 es:
+//   - the #version line is replaced, or added when missing
+//   - ES targets get highp default precisions for float in fragment
+//     shaders and for every sampler type used
+//   - layout(binding = N) qualifiers are removed when the target lacks them
+//     and returned as Bindings, which programs apply after linking
+//   - locations of inputs and outputs between stages are removed when the
+//     target lacks them, so the stages match by name
+//   - texture2D-style lookups, attribute, varying and gl_FragColor are
+//     replaced by their core equivalents
+//
+// Rewrites keep every line where it was and added lines map to a pseudo
+// file, so compile errors still point at the original source. Features the
+// target cannot express, such as a storage block for 330 core, are errors.
+// Shaders already written for the target are returned unchanged.
+func Translate(src *Source, stage ShaderType, target Dialect) (*Translation, error) {
+	if !target.supported() {
+		return nil, fmt.Errorf("cannot translate shaders to GLSL %s: the oldest supported dialects are 330 core and 300 es", target)
+	}
+	ast, err := glsl.Parse(src.Code)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse shader for translation: %w", err)
+	}
+	if v := ast.Version; v != nil && v.Number == target.Version && (v.Profile == "es") == target.ES {
+		return &Translation{Source: src}, nil
+	}
+
+	t := &translator{src: src, ast: ast, stage: stage, target: target}
+	if err := t.check(); err != nil {
+		return nil, err
+	}
+	if err := t.layouts(); err != nil {
+		return nil, err
+	}
+	t.legacy()
+
+	code := src.Code
+	sort.Slice(t.edits, func(i, j int) bool { return t.edits[i].start > t.edits[j].start })
+	for _, e := range t.edits {
+		code = code[:e.start] + e.text + code[e.end:]
+	}
+	out := &Source{Code: code, Map: src.Map, Files: src.Files}
+
+	if header := t.header(); len(header) > 0 {
+		out = out.insertLines(t.headerLine(), []string{strings.Join(header, " ")}, translatedFile)
+	}
+	if ast.Version == nil {
+		out = out.insertLines(0, []string{"#version " + target.String()}, translatedFile)
+	}
+	return &Translation{Source: out, Bindings: t.bindings}, nil
+}
+
+// unsupported returns the error for a feature the target dialect lacks
+func (t *translator) unsupported(pos glsl.Pos, what string, version [2]int) error {
+	where := fmt.Sprintf("line %d", pos.Line)
+	if loc, ok := t.src.Map.Resolve(pos.Line); ok && loc.File != "" {
+		where = fmt.Sprintf("%s:%d", loc.File, loc.Line)
+	}
+	return fmt.Errorf("%s: %s needs %s and cannot be translated to %s", where, what, requirement(version[0], version[1]), t.target)
+}
+
+// check reports the first feature the target dialect lacks
+func (t *translator) check() error {
+	if version, ok := stageVersions[t.stage]; ok && !t.target.has(version[0], version[1]) {
+		pos := glsl.Pos{Line: 1, Column: 1}
+		if t.ast.Version != nil {
+			pos = t.ast.Version.Pos
+		}
+		return t.unsupported(pos, t.stage.String()+" shader", version)
+	}
+	for _, b := range t.ast.Blocks {
+		switch {
+		case b.Storage == glsl.StorageBuffer && !t.target.has(storageVersion[0], storageVersion[1]):
+			return t.unsupported(b.Pos, "storage block "+b.Name, storageVersion)
+		case (b.Storage == glsl.StorageIn || b.Storage == glsl.StorageOut) && !t.target.has(ioBlockVersion[0], ioBlockVersion[1]):
+			return t.unsupported(b.Pos, b.Storage.String()+" block "+b.Name, ioBlockVersion)
+		}
+	}
+	for _, tok := range t.ast.Tokens {
+		if version, ok := keywordVersions[tok.Text]; ok && tok.Kind == glsl.Ident && !t.target.has(version[0], version[1]) {
+			return t.unsupported(tok.Pos, tok.Text, version)
+		}
+	}
+	return nil
+}
+
+// isInterface reports whether a variable passes data between stages, as
+// opposed to vertex inputs and fragment outputs
+func (t *translator) isInterface(storage glsl.Storage) bool {
+	switch storage {
+	case glsl.StorageIn:
+		return t.stage != VertexShader
+	case glsl.StorageOut:
+		return t.stage != FragmentShader
+	}
+	return false
+}
+
+// layouts removes the binding and location qualifiers the target lacks,
+// recording sampler and uniform block bindings
+func (t *translator) layouts() error {
+	removed := make(map[int]bool)
+	bindings := t.target.has(bindingVersion[0], bindingVersion[1])
+	locations := t.target.has(varyingLocation[0], varyingLocation[1])
+
+	for _, v := range t.ast.Variables {
+		for _, q := range v.Layout {
+			switch {
+			case q.Name == "binding" && !bindings:
+				if v.Storage != glsl.StorageUniform || !strings.Contains(v.Type, "sampler") {
+					return t.unsupported(q.Pos, "layout(binding) on "+v.Name, bindingVersion)
+				}
+				n, _ := v.Layout.Int("binding")
+				t.bindings = append(t.bindings, Binding{Name: v.Name, Unit: n, Count: v.ArraySize})
+				removed[q.Pos.Offset] = true
+			case q.Name == "location" && !locations && t.isInterface(v.Storage):
+				removed[q.Pos.Offset] = true
+			}
+		}
+	}
+	for _, b := range t.ast.Blocks {
+		for _, q := range b.Layout {
+			switch {
+			case q.Name == "binding" && !bindings:
+				if b.Storage != glsl.StorageUniform {
+					return t.unsupported(q.Pos, "layout(binding) on block "+b.Name, bindingVersion)
+				}
+				n, _ := b.Layout.Int("binding")
+				t.bindings = append(t.bindings, Binding{Name: b.Name, Block: true, Unit: n, Count: b.ArraySize})
+				removed[q.Pos.Offset] = true
+			case q.Name == "location" && !locations && t.isInterface(b.Storage):
+				removed[q.Pos.Offset] = true
+			}
+		}
+	}
+	if len(removed) > 0 {
+		t.removeQualifiers(removed)
+	}
+	return nil
+}
+
+// removeQualifiers rewrites every layout(...) list containing a qualifier
+// whose name starts at one of the removed offsets, dropping the list when
+// no qualifier is left
+func (t *translator) removeQualifiers(removed map[int]bool) {
+	toks, code := t.ast.Tokens, t.src.Code
+	for i := 0; i+1 < len(toks); i++ {
+		if toks[i].Text != "layout" || toks[i+1].Text != "(" {
+			continue
+		}
+		var kept []string
+		changed := false
+		first, depth := i+2, 0
+		j := i + 1
+		for ; j < len(toks) && toks[j].Kind != glsl.EOF; j++ {
+			switch toks[j].Text {
+			case "(":
+				depth++
+			case ")":
+				depth--
+			}
+			if depth == 0 || depth == 1 && toks[j].Text == "," {
+				if first < j {
+					if removed[toks[first].Pos.Offset] {
+						changed = true
+					} else {
+						kept = append(kept, code[toks[first].Pos.Offset:toks[j-1].End()])
+					}
+				}
+				first = j + 1
+			}
+			if depth == 0 {
+				break
+			}
+		}
+		if j == len(toks) || toks[j].Kind == glsl.EOF {
+			return
+		}
+		if changed {
+			start, end := toks[i].Pos.Offset, toks[j].End()
+			text := ""
+			if len(kept) > 0 {
+				text = "layout(" + strings.Join(kept, ", ") + ")"
+			} else {
+				for end < len(code) && (code[end] == ' ' || code[end] == '\t') {
+					end++
+				}
+			}
+			t.replace(start, end, text)
+		}
+		i = j
+	}
+}
+
+// replace records an edit, keeping the newlines of the replaced text so
+// line numbers do not change
+func (t *translator) replace(start, end int, text string) {
+	text += strings.Repeat("\n", strings.Count(t.src.Code[start:end], "\n"))
+	t.edits = append(t.edits, edit{start, end, text})
+}
+
+// legacy rewrites the #version line and pre-130 keywords and built-ins
+func (t *translator) legacy() {
+	for _, d := range t.ast.Directives {
+		if d.Name == "version" {
+			t.replace(d.Pos.Offset, d.End, "#version "+t.target.String())
+			break
+		}
+	}
+
+	toks := t.ast.Tokens
+	for i, tok := range toks {
+		if tok.Kind != glsl.Ident {
+			continue
+		}
+		text := ""
+		switch {
+		case tok.Text == "attribute" && t.stage == VertexShader:
+			text = "in"
+		case tok.Text == "varying" && t.stage == VertexShader:
+			text = "out"
+		case tok.Text == "varying" && t.stage == FragmentShader:
+			text = "in"
+		case tok.Text == "gl_FragColor" && t.stage == FragmentShader:
+			text = fragColor
+		case legacyFunctions[tok.Text] != "" && i+1 < len(toks) && toks[i+1].Text == "(":
+			text = legacyFunctions[tok.Text]
+		default:
+			continue
+		}
+		t.replace(tok.Pos.Offset, tok.End(), text)
+	}
+}
+
+// header returns the declarations added after the #version and #extension
+// lines: default precisions for ES and the gl_FragColor replacement
+func (t *translator) header() []string {
+	var header []string
+	if t.target.ES {
+		declared := make(map[string]bool)
+		for _, p := range t.ast.Precisions {
+			declared[p.Type] = true
+		}
+		var types []string
+		if t.stage == FragmentShader {
+			types = append(types, "float")
+		}
+		for _, tok := range t.ast.Tokens {
+			if esSamplers[tok.Text] && tok.Kind == glsl.Ident {
+				types = append(types, tok.Text)
+			}
+		}
+		for _, typ := range types {
+			if !declared[typ] {
+				declared[typ] = true
+				header = append(header, "precision highp "+typ+";")
+			}
+		}
+	}
+	if t.stage == FragmentShader {
+		for _, tok := range t.ast.Tokens {
+			if tok.Text == "gl_FragColor" {
+				header = append(header, "out vec4 "+fragColor+";")
+				break
+			}
+		}
+	}
+	return header
+}
+
+// headerLine returns the number of lines before the first declaration:
+// the #version line and any #extension lines preceding the code
+func (t *translator) headerLine() int {
+	line := 0
+	code := t.ast.Tokens[0].Pos.Line
+	for _, d := range t.ast.Directives {
+		if (d.Name == "version" || d.Name == "extension") && d.Pos.Line < code && d.Pos.Line > line {
+			line = d.Pos.Line
+		}
+	}
+	return line
+}
+
+var (
+	dialectMu  sync.Mutex
+	dialect    *Dialect // Target dialect, nil until detected or set
+	dialectErr error    // Failed detection, kept so it is not retried per shader
+)
+
+// SetTargetDialect makes shaders compile for the given dialect instead of
+// the one detected from the context. The zero Dialect restores detection.
+func SetTargetDialect(d Dialect) {
+	dialectMu.Lock()
+	defer dialectMu.Unlock()
+	dialectErr = nil
+	if d == (Dialect{}) {
+		dialect = nil
+		return
+	}
+	dialect = &d
+}
+
+// TargetDialect returns the dialect shaders are compiled for: the one set
+// with SetTargetDialect, or else the shading language of the current
+// context, detected on first use. A failed detection is returned until
+// SetTargetDialect is called.
+func TargetDialect() (Dialect, error) {
+	dialectMu.Lock()
+	defer dialectMu.Unlock()
+	if dialect != nil {
+		return *dialect, nil
+	}
+	if dialectErr != nil {
+		return Dialect{}, dialectErr
+	}
+
+	info, err := platform.New().Detect()
+	if err != nil {
+		dialectErr = fmt.Errorf("failed to detect shading language version: %w", err)
+		return Dialect{}, dialectErr
+	}
+	d := Dialect{Version: info.GLSLVersion.Major*100 + info.GLSLVersion.Minor, ES: info.ES}
+	dialect = &d
+	return d, nil
+}
+
+// translate rewrites source for the target dialect when it is older than
+// 410 core or ES. Sources pkg/glsl cannot parse are left to the driver,
+// whose log is more familiar, with a warning that they were not translated.
+func translate(source string, shaderType ShaderType, name string, smap *SourceMap) (*Translation, []Diagnostic, error) {
+	src := &Source{Code: source, Map: smap}
+	target, err := TargetDialect()
+	if err != nil || !target.Translated() || !target.supported() {
+		return &Translation{Source: src}, nil, nil
+	}
+	t, err := Translate(src, shaderType, target)
+	if err != nil {
+		var syntax *glsl.Error
+		if errors.As(err, &syntax) {
+			warning := Diagnostic{
+				Line:     syntax.Pos.Line,
+				Column:   syntax.Pos.Column,
+				Severity: SeverityWarning,
+				Message:  fmt.Sprintf("not translated for GLSL %s: %s", target, syntax.Msg),
+			}
+			return &Translation{Source: src}, resolveDiagnostics([]Diagnostic{warning}, source, name, smap), nil
+		}
+		return nil, nil, err
+	}
+	return t, nil, nil
+}
+
+// applyBindings assigns the texture units and uniform block binding points
+// of layout(binding) qualifiers removed by Translate
+func (p *Program) applyBindings(bindings []Binding) {
+	if len(bindings) == 0 {
+		return
+	}
+	var current int32
+	gl.GetIntegerv(gl.CURRENT_PROGRAM, &current)
+	gl.UseProgram(p.ID)
+
+	for _, b := range bindings {
+		n := b.Count
+		if n < 1 {
+			n = 1
+		}
+		if b.Block {
+			for i := 0; i < n; i++ {
+				name := b.Name
+				if b.Count > 0 {
+					name = fmt.Sprintf("%s[%d]", b.Name, i)
+				}
+				if index := gl.GetUniformBlockIndex(p.ID, gl.Str(name+"\x00")); index != gl.INVALID_INDEX {
+					gl.UniformBlockBinding(p.ID, index, uint32(b.Unit+i))
+				}
+			}
+			continue
+		}
+		if loc := gl.GetUniformLocation(p.ID, gl.Str(b.Name+"\x00")); loc >= 0 {
+			units := make([]int32, n)
+			for i := range units {
+				units[i] = int32(b.Unit + i)
+			}
+			gl.Uniform1iv(loc, int32(n), &units[0])
+		}
+	}
+
+	gl.UseProgram(uint32(current))
+}
+
+// sourceBindings returns the bindings Translate removes from stage sources
+// for the target dialect, for programs loaded from binaries
+func sourceBindings(sources map[ShaderType]string) []Binding {
+	var bindings []Binding
+	for stage, source := range sources {
+		if t, _, err := translate(source, stage, "", nil); err == nil {
+			bindings = append(bindings, t.Bindings...)
+		}
+	}
+	return bindings
+}
@@ -0,0 +1,632 @@
+package shader
+
+import (
+	"fmt"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// Uniform types accepted by each setter family, indexed by component count.
+// GL allows booleans to be set through any scalar setter and samplers
+// through glUniform1i.
+var (
+	floatUniformTypes = [5][]DataType{
+		1: {TypeFloat, TypeBool},
+		2: {TypeVec2, TypeBVec2},
+		3: {TypeVec3, TypeBVec3},
+		4: {TypeVec4, TypeBVec4},
+	}
+	intUniformTypes = [5][]DataType{
+		1: append([]DataType{TypeInt, TypeBool}, samplerTypes()...),
+		2: {TypeIVec2, TypeBVec2},
+		3: {TypeIVec3, TypeBVec3},
+		4: {TypeIVec4, TypeBVec4},
+	}
+	uintUniformTypes = [5][]DataType{
+		1: {TypeUInt, TypeBool},
+		2: {TypeUVec2, TypeBVec2},
+		3: {TypeUVec3, TypeBVec3},
+		4: {TypeUVec4, TypeBVec4},
+	}
+)
+
+// samplerTypes returns every known sampler type
+func samplerTypes() []DataType {
+	var types []DataType
+	for t := range dataTypeNames {
+		if t.IsSampler() {
+			types = append(types, t)
+		}
+	}
+	return types
+}
+
+// checkUniform validates a uniform location and, when the program has been
+// reflected, that the uniform is declared with one of the accepted types and
+// has room for count array elements
+func (p *Program) checkUniform(location int32, operation string, count int, accepted []DataType) error {
+	if location == -1 {
+		return fmt.Errorf("invalid uniform location: -1")
+	}
+
+	info, ok := p.uniformAt(location)
+	if !ok {
+		return nil
+	}
+
+	compatible := false
+	for _, t := range accepted {
+		if info.Type == t {
+			compatible = true
+			break
+		}
+	}
+	if !compatible {
+		return fmt.Errorf("%s: uniform %s is declared as %s", operation, info.Name, info.Type)
+	}
+	if count > int(info.Size) {
+		return fmt.Errorf("%s: uniform %s has %d elements, cannot set %d", operation, info.Name, info.Size, count)
+	}
+	return nil
+}
+
+// checkArray validates the length of a flattened uniform array and returns the element count
+func checkArray(length, components int) (int, error) {
+	if length == 0 {
+		return 0, fmt.Errorf("values cannot be empty")
+	}
+	if length%components != 0 {
+		return 0, fmt.Errorf("value count %d is not a multiple of %d", length, components)
+	}
+	return length / components, nil
+}
+
+// SetUniform1f sets a float uniform with validation
+func (p *Program) SetUniform1f(location int32, value float32) error {
+	if err := p.checkUniform(location, "glUniform1f", 1, floatUniformTypes[1]); err != nil {
+		return err
+	}
+	gl.Uniform1f(location, value)
+	return checkGLError("glUniform1f")
+}
+
+// SetUniform2f sets a vec2 uniform with validation
+func (p *Program) SetUniform2f(location int32, x, y float32) error {
+	if err := p.checkUniform(location, "glUniform2f", 1, floatUniformTypes[2]); err != nil {
+		return err
+	}
+	gl.Uniform2f(location, x, y)
+	return checkGLError("glUniform2f")
+}
+
+// SetUniform3f sets a vec3 uniform with validation
+func (p *Program) SetUniform3f(location int32, x, y, z float32) error {
+	if err := p.checkUniform(location, "glUniform3f", 1, floatUniformTypes[3]); err != nil {
+		return err
+	}
+	gl.Uniform3f(location, x, y, z)
+	return checkGLError("glUniform3f")
+}
+
+// SetUniform4f sets a vec4 uniform with validation
+func (p *Program) SetUniform4f(location int32, x, y, z, w float32) error {
+	if err := p.checkUniform(location, "glUniform4f", 1, floatUniformTypes[4]); err != nil {
+		return err
+	}
+	gl.Uniform4f(location, x, y, z, w)
+	return checkGLError("glUniform4f")
+}
+
+// SetUniform1i sets an int, bool or sampler uniform with validation
+func (p *Program) SetUniform1i(location int32, value int32) error {
+	if err := p.checkUniform(location, "glUniform1i", 1, intUniformTypes[1]); err != nil {
+		return err
+	}
+	gl.Uniform1i(location, value)
+	return checkGLError("glUniform1i")
+}
+
+// SetUniform2i sets an ivec2 uniform with validation
+func (p *Program) SetUniform2i(location int32, x, y int32) error {
+	if err := p.checkUniform(location, "glUniform2i", 1, intUniformTypes[2]); err != nil {
+		return err
+	}
+	gl.Uniform2i(location, x, y)
+	return checkGLError("glUniform2i")
+}
+
+// SetUniform3i sets an ivec3 uniform with validation
+func (p *Program) SetUniform3i(location int32, x, y, z int32) error {
+	if err := p.checkUniform(location, "glUniform3i", 1, intUniformTypes[3]); err != nil {
+		return err
+	}
+	gl.Uniform3i(location, x, y, z)
+	return checkGLError("glUniform3i")
+}
+
+// SetUniform4i sets an ivec4 uniform with validation
+func (p *Program) SetUniform4i(location int32, x, y, z, w int32) error {
+	if err := p.checkUniform(location, "glUniform4i", 1, intUniformTypes[4]); err != nil {
+		return err
+	}
+	gl.Uniform4i(location, x, y, z, w)
+	return checkGLError("glUniform4i")
+}
+
+// SetUniform1ui sets a uint uniform with validation
+func (p *Program) SetUniform1ui(location int32, value uint32) error {
+	if err := p.checkUniform(location, "glUniform1ui", 1, uintUniformTypes[1]); err != nil {
+		return err
+	}
+	gl.Uniform1ui(location, value)
+	return checkGLError("glUniform1ui")
+}
+
+// SetUniform2ui sets a uvec2 uniform with validation
+func (p *Program) SetUniform2ui(location int32, x, y uint32) error {
+	if err := p.checkUniform(location, "glUniform2ui", 1, uintUniformTypes[2]); err != nil {
+		return err
+	}
+	gl.Uniform2ui(location, x, y)
+	return checkGLError("glUniform2ui")
+}
+
+// SetUniform3ui sets a uvec3 uniform with validation
+func (p *Program) SetUniform3ui(location int32, x, y, z uint32) error {
+	if err := p.checkUniform(location, "glUniform3ui", 1, uintUniformTypes[3]); err != nil {
+		return err
+	}
+	gl.Uniform3ui(location, x, y, z)
+	return checkGLError("glUniform3ui")
+}
+
+// SetUniform4ui sets a uvec4 uniform with validation
+func (p *Program) SetUniform4ui(location int32, x, y, z, w uint32) error {
+	if err := p.checkUniform(location, "glUniform4ui", 1, uintUniformTypes[4]); err != nil {
+		return err
+	}
+	gl.Uniform4ui(location, x, y, z, w)
+	return checkGLError("glUniform4ui")
+}
+
+// SetUniformBool sets a bool uniform with validation
+func (p *Program) SetUniformBool(location int32, value bool) error {
+	var v int32
+	if value {
+		v = 1
+	}
+	return p.SetUniform1i(location, v)
+}
+
+// SetUniformSampler binds a sampler uniform to a texture unit
+func (p *Program) SetUniformSampler(location int32, unit int32) error {
+	return p.SetUniform1i(location, unit)
+}
+
+// SetUniformVec2 sets a vec2 uniform from an mgl32 vector
+func (p *Program) SetUniformVec2(location int32, v mgl32.Vec2) error {
+	return p.SetUniform2f(location, v[0], v[1])
+}
+
+// SetUniformVec3 sets a vec3 uniform from an mgl32 vector
+func (p *Program) SetUniformVec3(location int32, v mgl32.Vec3) error {
+	return p.SetUniform3f(location, v[0], v[1], v[2])
+}
+
+// SetUniformVec4 sets a vec4 uniform from an mgl32 vector
+func (p *Program) SetUniformVec4(location int32, v mgl32.Vec4) error {
+	return p.SetUniform4f(location, v[0], v[1], v[2], v[3])
+}
+
+// SetUniform1fv sets a float array uniform with validation
+func (p *Program) SetUniform1fv(location int32, values []float32) error {
+	count, err := checkArray(len(values), 1)
+	if err != nil {
+		return err
+	}
+	if err := p.checkUniform(location, "glUniform1fv", count, floatUniformTypes[1]); err != nil {
+		return err
+	}
+	gl.Uniform1fv(location, int32(count), &values[0])
+	return checkGLError("glUniform1fv")
+}
+
+// SetUniform2fv sets a vec2 array uniform from flattened components
+func (p *Program) SetUniform2fv(location int32, values []float32) error {
+	count, err := checkArray(len(values), 2)
+	if err != nil {
+		return err
+	}
+	if err := p.checkUniform(location, "glUniform2fv", count, floatUniformTypes[2]); err != nil {
+		return err
+	}
+	gl.Uniform2fv(location, int32(count), &values[0])
+	return checkGLError("glUniform2fv")
+}
+
+// SetUniform3fv sets a vec3 array uniform from flattened components
+func (p *Program) SetUniform3fv(location int32, values []float32) error {
+	count, err := checkArray(len(values), 3)
+	if err != nil {
+		return err
+	}
+	if err := p.checkUniform(location, "glUniform3fv", count, floatUniformTypes[3]); err != nil {
+		return err
+	}
+	gl.Uniform3fv(location, int32(count), &values[0])
+	return checkGLError("glUniform3fv")
+}
+
+// SetUniform4fv sets a vec4 array uniform from flattened components
+func (p *Program) SetUniform4fv(location int32, values []float32) error {
+	count, err := checkArray(len(values), 4)
+	if err != nil {
+		return err
+	}
+	if err := p.checkUniform(location, "glUniform4fv", count, floatUniformTypes[4]); err != nil {
+		return err
+	}
+	gl.Uniform4fv(location, int32(count), &values[0])
+	return checkGLError("glUniform4fv")
+}
+
+// SetUniform1iv sets an int or sampler array uniform with validation
+func (p *Program) SetUniform1iv(location int32, values []int32) error {
+	count, err := checkArray(len(values), 1)
+	if err != nil {
+		return err
+	}
+	if err := p.checkUniform(location, "glUniform1iv", count, intUniformTypes[1]); err != nil {
+		return err
+	}
+	gl.Uniform1iv(location, int32(count), &values[0])
+	return checkGLError("glUniform1iv")
+}
+
+// SetUniform2iv sets an ivec2 array uniform from flattened components
+func (p *Program) SetUniform2iv(location int32, values []int32) error {
+	count, err := checkArray(len(values), 2)
+	if err != nil {
+		return err
+	}
+	if err := p.checkUniform(location, "glUniform2iv", count, intUniformTypes[2]); err != nil {
+		return err
+	}
+	gl.Uniform2iv(location, int32(count), &values[0])
+	return checkGLError("glUniform2iv")
+}
+
+// SetUniform3iv sets an ivec3 array uniform from flattened components
+func (p *Program) SetUniform3iv(location int32, values []int32) error {
+	count, err := checkArray(len(values), 3)
+	if err != nil {
+		return err
+	}
+	if err := p.checkUniform(location, "glUniform3iv", count, intUniformTypes[3]); err != nil {
+		return err
+	}
+	gl.Uniform3iv(location, int32(count), &values[0])
+	return checkGLError("glUniform3iv")
+}
+
+// SetUniform4iv sets an ivec4 array uniform from flattened components
+func (p *Program) SetUniform4iv(location int32, values []int32) error {
+	count, err := checkArray(len(values), 4)
+	if err != nil {
+		return err
+	}
+	if err := p.checkUniform(location, "glUniform4iv", count, intUniformTypes[4]); err != nil {
+		return err
+	}
+	gl.Uniform4iv(location, int32(count), &values[0])
+	return checkGLError("glUniform4iv")
+}
+
+// SetUniform1uiv sets a uint array uniform with validation
+func (p *Program) SetUniform1uiv(location int32, values []uint32) error {
+	count, err := checkArray(len(values), 1)
+	if err != nil {
+		return err
+	}
+	if err := p.checkUniform(location, "glUniform1uiv", count, uintUniformTypes[1]); err != nil {
+		return err
+	}
+	gl.Uniform1uiv(location, int32(count), &values[0])
+	return checkGLError("glUniform1uiv")
+}
+
+// SetUniform2uiv sets a uvec2 array uniform from flattened components
+func (p *Program) SetUniform2uiv(location int32, values []uint32) error {
+	count, err := checkArray(len(values), 2)
+	if err != nil {
+		return err
+	}
+	if err := p.checkUniform(location, "glUniform2uiv", count, uintUniformTypes[2]); err != nil {
+		return err
+	}
+	gl.Uniform2uiv(location, int32(count), &values[0])
+	return checkGLError("glUniform2uiv")
+}
+
+// SetUniform3uiv sets a uvec3 array uniform from flattened components
+func (p *Program) SetUniform3uiv(location int32, values []uint32) error {
+	count, err := checkArray(len(values), 3)
+	if err != nil {
+		return err
+	}
+	if err := p.checkUniform(location, "glUniform3uiv", count, uintUniformTypes[3]); err != nil {
+		return err
+	}
+	gl.Uniform3uiv(location, int32(count), &values[0])
+	return checkGLError("glUniform3uiv")
+}
+
+// SetUniform4uiv sets a uvec4 array uniform from flattened components
+func (p *Program) SetUniform4uiv(location int32, values []uint32) error {
+	count, err := checkArray(len(values), 4)
+	if err != nil {
+		return err
+	}
+	if err := p.checkUniform(location, "glUniform4uiv", count, uintUniformTypes[4]); err != nil {
+		return err
+	}
+	gl.Uniform4uiv(location, int32(count), &values[0])
+	return checkGLError("glUniform4uiv")
+}
+
+// SetUniformVec2Array sets a vec2 array uniform from mgl32 vectors
+func (p *Program) SetUniformVec2Array(location int32, values []mgl32.Vec2) error {
+	if len(values) == 0 {
+		return fmt.Errorf("values cannot be empty")
+	}
+	if err := p.checkUniform(location, "glUniform2fv", len(values), floatUniformTypes[2]); err != nil {
+		return err
+	}
+	gl.Uniform2fv(location, int32(len(values)), &values[0][0])
+	return checkGLError("glUniform2fv")
+}
+
+// SetUniformVec3Array sets a vec3 array uniform from mgl32 vectors
+func (p *Program) SetUniformVec3Array(location int32, values []mgl32.Vec3) error {
+	if len(values) == 0 {
+		return fmt.Errorf("values cannot be empty")
+	}
+	if err := p.checkUniform(location, "glUniform3fv", len(values), floatUniformTypes[3]); err != nil {
+		return err
+	}
+	gl.Uniform3fv(location, int32(len(values)), &values[0][0])
+	return checkGLError("glUniform3fv")
+}
+
+// SetUniformVec4Array sets a vec4 array uniform from mgl32 vectors
+func (p *Program) SetUniformVec4Array(location int32, values []mgl32.Vec4) error {
+	if len(values) == 0 {
+		return fmt.Errorf("values cannot be empty")
+	}
+	if err := p.checkUniform(location, "glUniform4fv", len(values), floatUniformTypes[4]); err != nil {
+		return err
+	}
+	gl.Uniform4fv(location, int32(len(values)), &values[0][0])
+	return checkGLError("glUniform4fv")
+}
+
+// SetUniformMatrix2fv sets a mat2 uniform with validation
+func (p *Program) SetUniformMatrix2fv(location int32, matrix *mgl32.Mat2) error {
+	if matrix == nil {
+		return fmt.Errorf("matrix cannot be nil")
+	}
+	if err := p.checkUniform(location, "glUniformMatrix2fv", 1, []DataType{TypeMat2}); err != nil {
+		return err
+	}
+	gl.UniformMatrix2fv(location, 1, false, &matrix[0])
+	return checkGLError("glUniformMatrix2fv")
+}
+
+// SetUniformMatrix3fv sets a mat3 uniform with validation
+func (p *Program) SetUniformMatrix3fv(location int32, matrix *mgl32.Mat3) error {
+	if matrix == nil {
+		return fmt.Errorf("matrix cannot be nil")
+	}
+	if err := p.checkUniform(location, "glUniformMatrix3fv", 1, []DataType{TypeMat3}); err != nil {
+		return err
+	}
+	gl.UniformMatrix3fv(location, 1, false, &matrix[0])
+	return checkGLError("glUniformMatrix3fv")
+}
+
+// SetUniformMatrix4fv sets a mat4 uniform with validation
+func (p *Program) SetUniformMatrix4fv(location int32, matrix *mgl32.Mat4) error {
+	if matrix == nil {
+		return fmt.Errorf("matrix cannot be nil")
+	}
+	if err := p.checkUniform(location, "glUniformMatrix4fv", 1, []DataType{TypeMat4}); err != nil {
+		return err
+	}
+	gl.UniformMatrix4fv(location, 1, false, &matrix[0])
+	return checkGLError("glUniformMatrix4fv")
+}
+
+// The non-square setters follow GLSL naming (matCxR has C columns and R
+// rows) while mgl32 names matrices by rows first, so a GLSL mat2x3 is
+// backed by an mgl32.Mat3x2.
+
+// SetUniformMatrix2x3fv sets a mat2x3 uniform with validation
+func (p *Program) SetUniformMatrix2x3fv(location int32, matrix *mgl32.Mat3x2) error {
+	if matrix == nil {
+		return fmt.Errorf("matrix cannot be nil")
+	}
+	if err := p.checkUniform(location, "glUniformMatrix2x3fv", 1, []DataType{TypeMat2x3}); err != nil {
+		return err
+	}
+	gl.UniformMatrix2x3fv(location, 1, false, &matrix[0])
+	return checkGLError("glUniformMatrix2x3fv")
+}
+
+// SetUniformMatrix2x4fv sets a mat2x4 uniform with validation
+func (p *Program) SetUniformMatrix2x4fv(location int32, matrix *mgl32.Mat4x2) error {
+	if matrix == nil {
+		return fmt.Errorf("matrix cannot be nil")
+	}
+	if err := p.checkUniform(location, "glUniformMatrix2x4fv", 1, []DataType{TypeMat2x4}); err != nil {
+		return err
+	}
+	gl.UniformMatrix2x4fv(location, 1, false, &matrix[0])
+	return checkGLError("glUniformMatrix2x4fv")
+}
+
+// SetUniformMatrix3x2fv sets a mat3x2 uniform with validation
+func (p *Program) SetUniformMatrix3x2fv(location int32, matrix *mgl32.Mat2x3) error {
+	if matrix == nil {
+		return fmt.Errorf("matrix cannot be nil")
+	}
+	if err := p.checkUniform(location, "glUniformMatrix3x2fv", 1, []DataType{TypeMat3x2}); err != nil {
+		return err
+	}
+	gl.UniformMatrix3x2fv(location, 1, false, &matrix[0])
+	return checkGLError("glUniformMatrix3x2fv")
+}
+
+// SetUniformMatrix3x4fv sets a mat3x4 uniform with validation
+func (p *Program) SetUniformMatrix3x4fv(location int32, matrix *mgl32.Mat4x3) error {
+	if matrix == nil {
+		return fmt.Errorf("matrix cannot be nil")
+	}
+	if err := p.checkUniform(location, "glUniformMatrix3x4fv", 1, []DataType{TypeMat3x4}); err != nil {
+		return err
+	}
+	gl.UniformMatrix3x4fv(location, 1, false, &matrix[0])
+	return checkGLError("glUniformMatrix3x4fv")
+}
+
+// SetUniformMatrix4x2fv sets a mat4x2 uniform with validation
+func (p *Program) SetUniformMatrix4x2fv(location int32, matrix *mgl32.Mat2x4) error {
+	if matrix == nil {
+		return fmt.Errorf("matrix cannot be nil")
+	}
+	if err := p.checkUniform(location, "glUniformMatrix4x2fv", 1, []DataType{TypeMat4x2}); err != nil {
+		return err
+	}
+	gl.UniformMatrix4x2fv(location, 1, false, &matrix[0])
+	return checkGLError("glUniformMatrix4x2fv")
+}
+
+// SetUniformMatrix4x3fv sets a mat4x3 uniform with validation
+func (p *Program) SetUniformMatrix4x3fv(location int32, matrix *mgl32.Mat3x4) error {
+	if matrix == nil {
+		return fmt.Errorf("matrix cannot be nil")
+	}
+	if err := p.checkUniform(location, "glUniformMatrix4x3fv", 1, []DataType{TypeMat4x3}); err != nil {
+		return err
+	}
+	gl.UniformMatrix4x3fv(location, 1, false, &matrix[0])
+	return checkGLError("glUniformMatrix4x3fv")
+}
+
+// SetUniformMatrix2fvArray sets a mat2 array uniform with validation
+func (p *Program) SetUniformMatrix2fvArray(location int32, matrices []mgl32.Mat2) error {
+	if len(matrices) == 0 {
+		return fmt.Errorf("matrices cannot be empty")
+	}
+	if err := p.checkUniform(location, "glUniformMatrix2fv", len(matrices), []DataType{TypeMat2}); err != nil {
+		return err
+	}
+	gl.UniformMatrix2fv(location, int32(len(matrices)), false, &matrices[0][0])
+	return checkGLError("glUniformMatrix2fv")
+}
+
+// SetUniformMatrix3fvArray sets a mat3 array uniform with validation
+func (p *Program) SetUniformMatrix3fvArray(location int32, matrices []mgl32.Mat3) error {
+	if len(matrices) == 0 {
+		return fmt.Errorf("matrices cannot be empty")
+	}
+	if err := p.checkUniform(location, "glUniformMatrix3fv", len(matrices), []DataType{TypeMat3}); err != nil {
+		return err
+	}
+	gl.UniformMatrix3fv(location, int32(len(matrices)), false, &matrices[0][0])
+	return checkGLError("glUniformMatrix3fv")
+}
+
+// SetUniformMatrix4fvArray sets a mat4 array uniform with validation
+func (p *Program) SetUniformMatrix4fvArray(location int32, matrices []mgl32.Mat4) error {
+	if len(matrices) == 0 {
+		return fmt.Errorf("matrices cannot be empty")
+	}
+	if err := p.checkUniform(location, "glUniformMatrix4fv", len(matrices), []DataType{TypeMat4}); err != nil {
+		return err
+	}
+	gl.UniformMatrix4fv(location, int32(len(matrices)), false, &matrices[0][0])
+	return checkGLError("glUniformMatrix4fv")
+}
+
+// SetUniformMatrix2x3fvArray sets a mat2x3 array uniform with validation
+func (p *Program) SetUniformMatrix2x3fvArray(location int32, matrices []mgl32.Mat3x2) error {
+	if len(matrices) == 0 {
+		return fmt.Errorf("matrices cannot be empty")
+	}
+	if err := p.checkUniform(location, "glUniformMatrix2x3fv", len(matrices), []DataType{TypeMat2x3}); err != nil {
+		return err
+	}
+	gl.UniformMatrix2x3fv(location, int32(len(matrices)), false, &matrices[0][0])
+	return checkGLError("glUniformMatrix2x3fv")
+}
+
+// SetUniformMatrix2x4fvArray sets a mat2x4 array uniform with validation
+func (p *Program) SetUniformMatrix2x4fvArray(location int32, matrices []mgl32.Mat4x2) error {
+	if len(matrices) == 0 {
+		return fmt.Errorf("matrices cannot be empty")
+	}
+	if err := p.checkUniform(location, "glUniformMatrix2x4fv", len(matrices), []DataType{TypeMat2x4}); err != nil {
+		return err
+	}
+	gl.UniformMatrix2x4fv(location, int32(len(matrices)), false, &matrices[0][0])
+	return checkGLError("glUniformMatrix2x4fv")
+}
+
+// SetUniformMatrix3x2fvArray sets a mat3x2 array uniform with validation
+func (p *Program) SetUniformMatrix3x2fvArray(location int32, matrices []mgl32.Mat2x3) error {
+	if len(matrices) == 0 {
+		return fmt.Errorf("matrices cannot be empty")
+	}
+	if err := p.checkUniform(location, "glUniformMatrix3x2fv", len(matrices), []DataType{TypeMat3x2}); err != nil {
+		return err
+	}
+	gl.UniformMatrix3x2fv(location, int32(len(matrices)), false, &matrices[0][0])
+	return checkGLError("glUniformMatrix3x2fv")
+}
+
+// SetUniformMatrix3x4fvArray sets a mat3x4 array uniform with validation
+func (p *Program) SetUniformMatrix3x4fvArray(location int32, matrices []mgl32.Mat4x3) error {
+	if len(matrices) == 0 {
+		return fmt.Errorf("matrices cannot be empty")
+	}
+	if err := p.checkUniform(location, "glUniformMatrix3x4fv", len(matrices), []DataType{TypeMat3x4}); err != nil {
+		return err
+	}
+	gl.UniformMatrix3x4fv(location, int32(len(matrices)), false, &matrices[0][0])
+	return checkGLError("glUniformMatrix3x4fv")
+}
+
+// SetUniformMatrix4x2fvArray sets a mat4x2 array uniform with validation
+func (p *Program) SetUniformMatrix4x2fvArray(location int32, matrices []mgl32.Mat2x4) error {
+	if len(matrices) == 0 {
+		return fmt.Errorf("matrices cannot be empty")
+	}
+	if err := p.checkUniform(location, "glUniformMatrix4x2fv", len(matrices), []DataType{TypeMat4x2}); err != nil {
+		return err
+	}
+	gl.UniformMatrix4x2fv(location, int32(len(matrices)), false, &matrices[0][0])
+	return checkGLError("glUniformMatrix4x2fv")
+}
+
+// SetUniformMatrix4x3fvArray sets a mat4x3 array uniform with validation
+func (p *Program) SetUniformMatrix4x3fvArray(location int32, matrices []mgl32.Mat3x4) error {
+	if len(matrices) == 0 {
+		return fmt.Errorf("matrices cannot be empty")
+	}
+	if err := p.checkUniform(location, "glUniformMatrix4x3fv", len(matrices), []DataType{TypeMat4x3}); err != nil {
+		return err
+	}
+	gl.UniformMatrix4x3fv(location, int32(len(matrices)), false, &matrices[0][0])
+	return checkGLError("glUniformMatrix4x3fv")
+}
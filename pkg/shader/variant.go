@@ -0,0 +1,245 @@
+package shader
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// definesFile is the pseudo file name injected define lines map to
+const definesFile = "<defines>"
+
+// Defines is a set of preprocessor macros for a shader variant. An empty
+// value defines the macro without replacement text, e.g. USE_TEXTURE.
+type Defines map[string]string
+
+// names returns the macro names in sorted order
+func (d Defines) names() []string {
+	names := make([]string, 0, len(d))
+	for name := range d {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// validate checks that every macro is a GLSL identifier with a single-line value
+func (d Defines) validate() error {
+	for name, value := range d {
+		if !isIdentifier(name) {
+			return fmt.Errorf("invalid define name %q", name)
+		}
+		if strings.HasPrefix(name, "GL_") {
+			return fmt.Errorf("define %s uses the reserved GL_ prefix", name)
+		}
+		if strings.ContainsAny(value, "\r\n") {
+			return fmt.Errorf("define %s: value must be a single line", name)
+		}
+	}
+	return nil
+}
+
+// String returns the canonical "NAME=VALUE" form of the set, sorted by name
+func (d Defines) String() string {
+	parts := make([]string, 0, len(d))
+	for _, name := range d.names() {
+		if d[name] == "" {
+			parts = append(parts, name)
+		} else {
+			parts = append(parts, name+"="+d[name])
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// isIdentifier reports whether s is a valid GLSL identifier
+func isIdentifier(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i, r := range s {
+		switch {
+		case r == '_', r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z':
+		case i > 0 && r >= '0' && r <= '9':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// identityMap maps every line of source to itself in the named file
+func identityMap(source, name string) *SourceMap {
+	n := strings.Count(strings.TrimSuffix(source, "\n"), "\n") + 1
+	m := &SourceMap{lines: make([]SourceLocation, n)}
+	for i := range m.lines {
+		m.lines[i] = SourceLocation{File: name, Line: i + 1}
+	}
+	return m
+}
+
+// WithDefines returns a copy of the source with #define lines injected right
+// after the #version line (or at the top when there is none). The source map
+// is shifted so diagnostics still point at the original lines.
+func (s *Source) WithDefines(defines Defines) (*Source, error) {
+	if len(defines) == 0 {
+		return s, nil
+	}
+	if err := defines.validate(); err != nil {
+		return nil, err
+	}
+
+	insertAt := 0
+	for i, line := range strings.SplitAfter(s.Code, "\n") {
+		if name, _ := directive(line); name == "version" {
+			insertAt = i + 1
+			break
+		}
+	}
+
+	lines := make([]string, 0, len(defines))
+	for _, name := range defines.names() {
+		lines = append(lines, fmt.Sprintf("#define %s %s", name, defines[name]))
+	}
+	return s.insertLines(insertAt, lines, definesFile), nil
+}
+
+// insertLines returns a copy of the source with lines inserted after its
+// first n lines. The inserted lines map to the named pseudo file and the
+// source map is shifted so the original lines keep their locations.
+func (s *Source) insertLines(n int, inserted []string, file string) *Source {
+	lines := strings.SplitAfter(s.Code, "\n")
+	if n > len(lines) {
+		n = len(lines)
+	}
+	if n > 0 && !strings.HasSuffix(lines[n-1], "\n") {
+		lines[n-1] += "\n"
+	}
+
+	var b strings.Builder
+	for _, line := range lines[:n] {
+		b.WriteString(line)
+	}
+	injected := make([]SourceLocation, 0, len(inserted))
+	for i, line := range inserted {
+		b.WriteString(line + "\n")
+		injected = append(injected, SourceLocation{File: file, Line: i + 1})
+	}
+	for _, line := range lines[n:] {
+		b.WriteString(line)
+	}
+
+	smap := s.Map
+	if smap == nil {
+		smap = identityMap(s.Code, "")
+	}
+	at := n
+	if at > len(smap.lines) {
+		at = len(smap.lines)
+	}
+	mapped := make([]SourceLocation, 0, len(smap.lines)+len(injected))
+	mapped = append(mapped, smap.lines[:at]...)
+	mapped = append(mapped, injected...)
+	mapped = append(mapped, smap.lines[at:]...)
+
+	return &Source{
+		Code:  b.String(),
+		Map:   &SourceMap{lines: mapped},
+		Files: s.Files,
+	}
+}
+
+// CompileShaderVariant compiles source with the given defines injected after
+// its #version line
+func CompileShaderVariant(source string, shaderType ShaderType, defines Defines) (*Shader, error) {
+	src, err := (&Source{Code: source}).WithDefines(defines)
+	if err != nil {
+		return nil, err
+	}
+	return compileShader(src.Code, shaderType, "", src.Map)
+}
+
+// VariantCache compiles shader variants on demand and caches them by the
+// hash of their source, stage and define set, so a single source file can
+// serve many materials
+type VariantCache struct {
+	// Preprocessor expands includes of file-based variants; nil uses DefaultPreprocessor
+	Preprocessor *Preprocessor
+
+	shaders map[string]*Shader
+}
+
+// NewVariantCache creates an empty variant cache. The zero VariantCache is
+// also ready to use.
+func NewVariantCache() *VariantCache {
+	return &VariantCache{
+		shaders: make(map[string]*Shader),
+	}
+}
+
+// variantKey hashes a source, stage and define set into a cache key
+func variantKey(source string, shaderType ShaderType, defines Defines) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%d\x00%s\x00", shaderType, source)
+	for _, name := range defines.names() {
+		fmt.Fprintf(h, "%s=%s\x00", name, defines[name])
+	}
+	return string(h.Sum(nil))
+}
+
+// Get returns the variant of an in-memory source for the given defines,
+// compiling it on first use
+func (c *VariantCache) Get(source string, shaderType ShaderType, defines Defines) (*Shader, error) {
+	return c.get(source, shaderType, defines, func() (*Shader, error) {
+		return CompileShaderVariant(source, shaderType, defines)
+	})
+}
+
+// GetFile returns the variant of a shader file for the given defines. The
+// file is preprocessed on every call so edits produce a new variant.
+func (c *VariantCache) GetFile(path string, shaderType ShaderType, defines Defines) (*Shader, error) {
+	pp := c.Preprocessor
+	if pp == nil {
+		pp = DefaultPreprocessor
+	}
+	src, err := pp.ProcessFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return c.get(src.Code, shaderType, defines, func() (*Shader, error) {
+		return pp.compileSource(src, path, shaderType, defines)
+	})
+}
+
+func (c *VariantCache) get(code string, shaderType ShaderType, defines Defines, compile func() (*Shader, error)) (*Shader, error) {
+	key := variantKey(code, shaderType, defines)
+	// Shaders deleted behind the cache's back (e.g. by Program.Delete) are recompiled
+	if shader, ok := c.shaders[key]; ok && shader.ID != 0 {
+		return shader, nil
+	}
+
+	shader, err := compile()
+	if err != nil {
+		return nil, err
+	}
+
+	if c.shaders == nil {
+		c.shaders = make(map[string]*Shader)
+	}
+	c.shaders[key] = shader
+	return shader, nil
+}
+
+// Len returns the number of cached variants
+func (c *VariantCache) Len() int {
+	return len(c.shaders)
+}
+
+// Clear deletes all cached shaders
+func (c *VariantCache) Clear() {
+	for _, shader := range c.shaders {
+		shader.Delete()
+	}
+	c.shaders = make(map[string]*Shader)
+}
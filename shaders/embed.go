@@ -0,0 +1,11 @@
+// Package shaders embeds the bundled GLSL shader library so binaries do not
+// need the shaders/ directory at run time. Paths are relative to this
+// directory, e.g. "vertex/phong.vert".
+package shaders
+
+import "embed"
+
+// FS holds every bundled shader file
+//
+//go:embed vertex fragment geometry compute
+var FS embed.FS
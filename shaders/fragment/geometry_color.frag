@@ -0,0 +1,9 @@
+#version 410 core
+
+in vec3 fColor;
+
+out vec4 fragColor;
+
+void main() {
+    fragColor = vec4(fColor, 1.0);
+}
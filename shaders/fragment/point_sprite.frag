@@ -0,0 +1,17 @@
+#version 410 core
+
+in vec3 fColor;
+in vec2 fTexCoord;
+
+out vec4 fragColor;
+
+void main() {
+    // Round sprite with a soft edge
+    float dist = length(fTexCoord - vec2(0.5));
+    if (dist > 0.5) {
+        discard;
+    }
+
+    float alpha = 1.0 - smoothstep(0.4, 0.5, dist);
+    fragColor = vec4(fColor, alpha);
+}
@@ -0,0 +1,24 @@
+#version 410 core
+
+layout(location = 0) in vec3 aPosition;
+layout(location = 1) in vec3 aNormal;
+layout(location = 2) in vec3 aColor;
+
+uniform mat4 uModel;
+
+out vec3 vPosition;
+out vec3 vFragPos;
+out vec3 vNormal;
+out vec3 vColor;
+
+void main() {
+    vec4 worldPos = uModel * vec4(aPosition, 1.0);
+
+    vPosition = aPosition;
+    vFragPos = worldPos.xyz;
+    vNormal = mat3(transpose(inverse(uModel))) * aNormal;
+    vColor = aColor;
+
+    // Geometry shaders apply the view and projection transforms
+    gl_Position = worldPos;
+}
@@ -0,0 +1,119 @@
+package shader_test
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/yossideutsch/gogl/pkg/shader"
+)
+
+func TestLinkAsync(t *testing.T) {
+	// Start several programs before waiting for any of them
+	handles := make([]*shader.AsyncProgram, 8)
+	for i := range handles {
+		fragmentSource := fmt.Sprintf(`#version 410 core
+out vec4 fragColor;
+void main() { fragColor = vec4(%d.0 / 8.0); }`, i)
+		handles[i] = shader.LinkAsync(
+			shader.CompileAsync(testVertexShaderSource, shader.VertexShader),
+			shader.CompileAsync(fragmentSource, shader.FragmentShader),
+		)
+	}
+
+	deadline := time.Now().Add(10 * time.Second)
+	for _, h := range handles {
+		for !h.Ready() {
+			if time.Now().After(deadline) {
+				t.Fatal("Programs did not become ready")
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+	for i, h := range handles {
+		program, err := h.Wait()
+		if err != nil {
+			t.Fatalf("Program %d failed: %v", i, err)
+		}
+		if again, _ := h.Wait(); again != program {
+			t.Error("Wait should return the same program on every call")
+		}
+		if _, ok := program.Attribute("aPosition"); !ok {
+			t.Errorf("Program %d was not reflected", i)
+		}
+		program.Delete()
+	}
+}
+
+func TestLinkAsyncErrors(t *testing.T) {
+	// Compile errors surface from the program handle
+	vs := shader.CompileAsync(testVertexShaderSource, shader.VertexShader)
+	broken := shader.CompileAsync("#version 410 core\nout vec4 c;\nvoid main() { c = undeclared; }", shader.FragmentShader)
+	_, err := shader.LinkAsync(vs, broken).Wait()
+	var compileErr *shader.CompileError
+	if !errors.As(err, &compileErr) || compileErr.Stage != shader.FragmentShader {
+		t.Errorf("Expected fragment *shader.CompileError, got %T: %v", err, err)
+	}
+	if s, _ := vs.Wait(); s != nil && s.ID != 0 {
+		t.Error("Shaders of a failed program should be deleted")
+	}
+
+	// Shaders still pending when an earlier one fails are finished with an error
+	brokenVS := shader.CompileAsync("#version 410 core\nvoid main() { gl_Position = undeclared; }", shader.VertexShader)
+	fs := shader.CompileAsync("#version 410 core\nout vec4 c;\nvoid main() { c = vec4(1.0); }", shader.FragmentShader)
+	if _, err := shader.LinkAsync(brokenVS, fs).Wait(); err == nil {
+		t.Error("Expected broken vertex shader to fail")
+	}
+	if !fs.Ready() {
+		t.Error("Pending shader of a failed program should be ready")
+	}
+	if s, err := fs.Wait(); s != nil || err == nil {
+		t.Errorf("Expected deleted pending shader to report an error, got %v, %v", s, err)
+	}
+
+	// Link errors
+	missing := shader.CompileAsync("#version 410 core\nvec3 missing();\nout vec4 c;\nvoid main() { c = vec4(missing(), 1.0); }", shader.FragmentShader)
+	_, err = shader.LinkAsync(shader.CompileAsync(testVertexShaderSource, shader.VertexShader), missing).Wait()
+	var linkErr *shader.LinkError
+	if !errors.As(err, &linkErr) {
+		t.Errorf("Expected *shader.LinkError, got %T: %v", err, err)
+	}
+
+	// Errors found before the driver is involved are ready at once
+	empty := shader.CompileAsync("", shader.VertexShader)
+	if !empty.Ready() {
+		t.Error("Failed compile should be ready")
+	}
+	if _, err := empty.Wait(); err == nil {
+		t.Error("Expected empty source to fail")
+	}
+	h := shader.LinkAsync(shader.CompileAsync(testVertexShaderSource, shader.VertexShader))
+	if _, err := h.Wait(); err == nil || !h.Ready() {
+		t.Errorf("Expected missing fragment shader to fail, got %v", err)
+	}
+	if _, err := shader.LinkAsync().Wait(); err == nil {
+		t.Error("Expected LinkAsync without shaders to fail")
+	}
+}
+
+func TestCompileFileAsync(t *testing.T) {
+	dir := writeShaderFiles(t, map[string]string{
+		"broken.frag":  "#version 410 core\n#include \"helpers.glsl\"\nout vec4 fragColor;\nvoid main() { fragColor = vec4(helper(), 1.0); }\n",
+		"helpers.glsl": "vec3 helper() {\n    return undeclaredValue;\n}\n",
+	})
+
+	_, err := shader.NewPreprocessor().CompileFileAsync(filepath.Join(dir, "broken.frag"), shader.FragmentShader).Wait()
+	var compileErr *shader.CompileError
+	if !errors.As(err, &compileErr) || len(compileErr.Diagnostics) == 0 {
+		t.Fatalf("Expected *shader.CompileError with diagnostics, got %T: %v", err, err)
+	}
+	if d := compileErr.Diagnostics[0]; filepath.Base(d.File) != "helpers.glsl" || d.Line != 2 {
+		t.Errorf("Expected error at helpers.glsl:2, got %s:%d", d.File, d.Line)
+	}
+
+	if _, err := shader.NewPreprocessor().CompileFileAsync(filepath.Join(dir, "missing.frag"), shader.FragmentShader).Wait(); err == nil {
+		t.Error("Expected missing file to fail")
+	}
+}
@@ -0,0 +1,263 @@
+package bindgen_test
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/yossideutsch/gogl/internal/bindgen"
+	"github.com/yossideutsch/gogl/pkg/shader"
+)
+
+const shaderDir = "../../../shaders"
+
+// generate runs the generator and parses its output
+func generate(t *testing.T, programs ...bindgen.Program) (string, *ast.File, []string) {
+	t.Helper()
+	result, err := bindgen.Generate(bindgen.Config{Package: "demo", Programs: programs, Preprocessor: shader.NewPreprocessor()})
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	file, err := parser.ParseFile(token.NewFileSet(), "gen.go", result.Code, 0)
+	if err != nil {
+		t.Fatalf("Generated code does not parse: %v\n%s", err, result.Code)
+	}
+	return string(result.Code), file, result.Warnings
+}
+
+// declared returns the names of top-level functions, methods, types and constants
+func declared(file *ast.File) map[string]bool {
+	names := make(map[string]bool)
+	for _, decl := range file.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			names[d.Name.Name] = true
+		case *ast.GenDecl:
+			for _, spec := range d.Specs {
+				switch s := spec.(type) {
+				case *ast.TypeSpec:
+					names[s.Name.Name] = true
+				case *ast.ValueSpec:
+					for _, n := range s.Names {
+						names[n.Name] = true
+					}
+				}
+			}
+		}
+	}
+	return names
+}
+
+// writeShader writes a GLSL file into dir and returns its path
+func writeShader(t *testing.T, dir, name, source string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(source), 0644); err != nil {
+		t.Fatalf("Failed to write %s: %v", name, err)
+	}
+	return path
+}
+
+func TestParseFile(t *testing.T) {
+	tests := []struct {
+		arg   string
+		path  string
+		stage shader.ShaderType
+	}{
+		{"shaders/vertex/phong.vert", "shaders/vertex/phong.vert", shader.VertexShader},
+		{"lit.frag", "lit.frag", shader.FragmentShader},
+		{"shaders/geometry/explode.glsl", "shaders/geometry/explode.glsl", shader.GeometryShader},
+		{"shaders/compute/particle_simulation.glsl", "shaders/compute/particle_simulation.glsl", shader.ComputeShader},
+		{"frag:common/lighting.glsl", "common/lighting.glsl", shader.FragmentShader},
+		{"tese:terrain.glsl", "terrain.glsl", shader.TessEvaluationShader},
+	}
+	for _, tt := range tests {
+		file, err := bindgen.ParseFile(tt.arg)
+		if err != nil {
+			t.Errorf("ParseFile(%q) failed: %v", tt.arg, err)
+			continue
+		}
+		if file.Path != tt.path || file.Stage != tt.stage {
+			t.Errorf("ParseFile(%q) = %+v, expected path %s stage %d", tt.arg, file, tt.path, tt.stage)
+		}
+	}
+
+	if _, err := bindgen.ParseFile("common/lighting.glsl"); err == nil {
+		t.Error("Expected an error for a file without a stage")
+	}
+}
+
+func TestGeneratePhong(t *testing.T) {
+	code, file, warnings := generate(t, bindgen.Program{Name: "Phong", Files: []bindgen.File{
+		{Path: filepath.Join(shaderDir, "vertex", "phong.vert"), Stage: shader.VertexShader},
+		{Path: filepath.Join(shaderDir, "fragment", "phong.frag"), Stage: shader.FragmentShader},
+	}})
+	if len(warnings) != 0 {
+		t.Errorf("Unexpected warnings: %v", warnings)
+	}
+	if !strings.HasPrefix(code, "// Code generated by gogl-bindgen. DO NOT EDIT.") {
+		t.Error("Generated code is missing the generated-code header")
+	}
+	if file.Name.Name != "demo" {
+		t.Errorf("Expected package demo, got %s", file.Name.Name)
+	}
+
+	names := declared(file)
+	for _, name := range []string{
+		"Phong", "NewPhong", "NewPhongVertexLayout",
+		"PhongAPositionLocation", "PhongANormalLocation", "PhongAColorLocation",
+		"SetUModel", "SetUView", "SetUProjection", "SetULightPos", "SetUShininess",
+	} {
+		if !names[name] {
+			t.Errorf("Generated code does not declare %s", name)
+		}
+	}
+
+	for _, want := range []string{
+		"PhongANormalLocation   = 1",
+		"func (p *Phong) SetUModel(v mgl32.Mat4) error",
+		"return p.SetUniformMatrix4fv(loc, &v)",
+		"func (p *Phong) SetUShininess(v float32) error",
+		`p.Uniform("uLightPos"); ok && info.Type != shader.TypeVec3`,
+		"AddFloat(0, 3).\n\t\tAddFloat(1, 3).\n\t\tAddFloat(2, 3)",
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("Generated code does not contain %q", want)
+		}
+	}
+}
+
+func TestGenerateBlocksAndStructs(t *testing.T) {
+	dir := t.TempDir()
+	writeShader(t, dir, "common.glsl", `
+#define MAX_LIGHTS 4
+struct Light {
+    vec3 position;
+    float intensity;
+};
+`)
+	vert := writeShader(t, dir, "lit.vert", `#version 410 core
+layout(location = 0) in vec3 aPosition;
+layout(location = 1) in ivec2 aBones;
+layout(location = 2) in mat4 aInstance;
+
+layout(std140) uniform Camera {
+    mat4 view;
+    mat4 projection;
+    vec4 planes[6];
+};
+
+void main() {
+    gl_Position = projection * view * aInstance * vec4(aPosition, 1.0);
+}
+`)
+	frag := writeShader(t, dir, "lit.frag", `#version 410 core
+#include "common.glsl"
+
+uniform Light uLights[MAX_LIGHTS];
+uniform sampler2D uAlbedo;
+uniform ivec3 uGrid;
+uniform float uWeights[3];
+uniform bvec2 uFlags; // No typed setter
+uniform Camera2 { mat4 m; } unused; /* shared layout */
+
+out vec4 FragColor;
+
+float attenuate(float d) { return 1.0 / (d * d); }
+
+void main() {
+    FragColor = texture(uAlbedo, vec2(0.0));
+}
+`)
+
+	code, file, warnings := generate(t, bindgen.Program{Name: "lit", Files: []bindgen.File{
+		{Path: vert, Stage: shader.VertexShader},
+		{Path: frag, Stage: shader.FragmentShader},
+	}})
+
+	names := declared(file)
+	for _, name := range []string{"Lit", "NewLit", "LitCamera", "NewLitVertexLayout", "SetULightsPosition", "SetULightsIntensity", "SetUAlbedo", "SetUGrid", "SetUWeights"} {
+		if !names[name] {
+			t.Errorf("Generated code does not declare %s", name)
+		}
+	}
+	if names["SetUFlags"] || names["LitCamera2"] {
+		t.Error("Unsupported declarations should be skipped")
+	}
+
+	for _, want := range []string{
+		"func (p *Lit) SetULightsPosition(i int, v mgl32.Vec3) error",
+		`p.GetUniformLocation(fmt.Sprintf("uLights[%d].position", i))`,
+		"func (p *Lit) SetUAlbedo(v int32) error",
+		"return p.SetUniformSampler(loc, v)",
+		"func (p *Lit) SetUGrid(v [3]int32) error",
+		"return p.SetUniform3i(loc, v[0], v[1], v[2])",
+		"func (p *Lit) SetUWeights(v []float32) error",
+		"Planes     [6]mgl32.Vec4 `glsl:\"planes\"`",
+		`layout.Std140.Validate(p, "Camera", LitCamera{})`,
+		"AddInt(1, 2).\n\t\tAddFloat(2, 4).\n\t\tAddFloat(3, 4).\n\t\tAddFloat(4, 4).\n\t\tAddFloat(5, 4)",
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("Generated code does not contain %q", want)
+		}
+	}
+
+	if len(warnings) != 2 {
+		t.Errorf("Expected warnings for uFlags and Camera2, got %v", warnings)
+	}
+}
+
+func TestGenerateStorageBlock(t *testing.T) {
+	code, file, _ := generate(t, bindgen.Program{Name: "Particles", Files: []bindgen.File{
+		{Path: filepath.Join(shaderDir, "compute", "particle_simulation.glsl"), Stage: shader.ComputeShader},
+	}})
+
+	names := declared(file)
+	for _, name := range []string{"ParticlesParticle", "ParticlesParticleBuffer", "SetUDeltaTime"} {
+		if !names[name] {
+			t.Errorf("Generated code does not declare %s", name)
+		}
+	}
+	if names["NewParticlesVertexLayout"] {
+		t.Error("Compute programs should not get a vertex layout")
+	}
+	for _, want := range []string{
+		"Particles []ParticlesParticle `glsl:\"particles\"`",
+		`layout.Std430.Validate(p, "ParticleBuffer", ParticlesParticleBuffer{})`,
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("Generated code does not contain %q", want)
+		}
+	}
+}
+
+func TestGenerateErrors(t *testing.T) {
+	dir := t.TempDir()
+	vert := writeShader(t, dir, "a.vert", "#version 410 core\nuniform vec3 uTint;\nvoid main() {}\n")
+	frag := writeShader(t, dir, "a.frag", "#version 410 core\nuniform vec4 uTint;\nout vec4 c;\nvoid main() { c = uTint; }\n")
+	broken := writeShader(t, dir, "b.frag", "#version 410 core\n\nuniform float uValues[N];\nvoid main() {}\n")
+
+	tests := []struct {
+		name    string
+		program bindgen.Program
+		want    string
+	}{
+		{"conflicting uniform", bindgen.Program{Name: "A", Files: []bindgen.File{{Path: vert, Stage: shader.VertexShader}, {Path: frag, Stage: shader.FragmentShader}}}, "uniform uTint is declared as vec3"},
+		{"syntax error", bindgen.Program{Name: "B", Files: []bindgen.File{{Path: broken, Stage: shader.FragmentShader}}}, "b.frag:3: expected an integer constant, found \"N\""},
+		{"no files", bindgen.Program{Name: "C"}, "has no shader files"},
+	}
+	for _, tt := range tests {
+		_, err := bindgen.Generate(bindgen.Config{Package: "demo", Programs: []bindgen.Program{tt.program}})
+		if err == nil {
+			t.Errorf("%s: expected an error", tt.name)
+			continue
+		}
+		if !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: expected error containing %q, got %v", tt.name, tt.want, err)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package shader_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/yossideutsch/gogl/pkg/shader"
+)
+
+func TestParseInfoLog(t *testing.T) {
+	tests := []struct {
+		vendor string
+		log    string
+		want   []shader.Diagnostic
+	}{
+		{
+			vendor: "Mesa",
+			log:    "0:3(3): error: `x' undeclared\n0:5(10): warning: unused variable\n",
+			want: []shader.Diagnostic{
+				{Line: 3, Column: 3, Severity: shader.SeverityError, Message: "`x' undeclared"},
+				{Line: 5, Column: 10, Severity: shader.SeverityWarning, Message: "unused variable"},
+			},
+		},
+		{
+			vendor: "NVIDIA",
+			log:    "0(3) : error C1008: undefined variable \"x\"\n0(7) : warning C7022: unrecognized profile specifier\n",
+			want: []shader.Diagnostic{
+				{Line: 3, Severity: shader.SeverityError, Message: "undefined variable \"x\""},
+				{Line: 7, Severity: shader.SeverityWarning, Message: "unrecognized profile specifier"},
+			},
+		},
+		{
+			vendor: "AMD",
+			log:    "ERROR: 0:3: 'x' : undeclared identifier \nERROR: 1 compilation errors.  No code generated.\n",
+			want: []shader.Diagnostic{
+				{Line: 3, Severity: shader.SeverityError, Message: "'x' : undeclared identifier"},
+			},
+		},
+		{
+			vendor: "Apple",
+			log:    "ERROR: 0:3: Use of undeclared identifier 'x'\nWARNING: 0:4: Overflow in implicit constant conversion\n",
+			want: []shader.Diagnostic{
+				{Line: 3, Severity: shader.SeverityError, Message: "Use of undeclared identifier 'x'"},
+				{Line: 4, Severity: shader.SeverityWarning, Message: "Overflow in implicit constant conversion"},
+			},
+		},
+		{
+			vendor: "Mesa link",
+			log:    "error: fragment shader output `fragColor' is not written\n  more detail\n",
+			want: []shader.Diagnostic{
+				{Severity: shader.SeverityError, Message: "fragment shader output `fragColor' is not written\nmore detail"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		got := shader.ParseInfoLog(tt.log)
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: expected %d diagnostics, got %d: %+v", tt.vendor, len(tt.want), len(got), got)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("%s: diagnostic %d: got %+v, want %+v", tt.vendor, i, got[i], tt.want[i])
+			}
+		}
+	}
+}
+
+func TestFormatDiagnostics(t *testing.T) {
+	out := shader.FormatDiagnostics([]shader.Diagnostic{
+		{File: "test.vert", Line: 3, Column: 3, Severity: shader.SeverityError, Message: "`x' undeclared", SourceLine: "  x = 1;"},
+		{File: "test.vert", Line: 4, Severity: shader.SeverityWarning, Message: "unused", SourceLine: "    float y;"},
+	})
+
+	want := "test.vert:3:3: error: `x' undeclared\n" +
+		"      x = 1;\n" +
+		"      ^\n" +
+		"test.vert:4: warning: unused\n" +
+		"        float y;\n" +
+		"        ^\n"
+	if out != want {
+		t.Errorf("Unexpected formatting:\n%s\nwant:\n%s", out, want)
+	}
+	if strings.Count(out, "^") != 2 {
+		t.Error("Expected one caret per diagnostic")
+	}
+}
+
+func TestFormatDiagnosticsTabs(t *testing.T) {
+	out := shader.FormatDiagnostics([]shader.Diagnostic{
+		{Line: 2, Column: 3, Severity: shader.SeverityError, Message: "`x' undeclared", SourceLine: "\t\tx = 1;"},
+		{Line: 3, Column: 8, Severity: shader.SeverityError, Message: "`y' undeclared", SourceLine: "\tfoo = y;"},
+	})
+
+	want := "2:3: error: `x' undeclared\n" +
+		"            x = 1;\n" +
+		"            ^\n" +
+		"3:8: error: `y' undeclared\n" +
+		"        foo = y;\n" +
+		"              ^\n"
+	if out != want {
+		t.Errorf("Caret should follow tab expansion:\n%s\nwant:\n%s", out, want)
+	}
+}
@@ -0,0 +1,430 @@
+package glsl_test
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/yossideutsch/gogl/pkg/glsl"
+)
+
+const shaderDir = "../../../shaders"
+
+func TestParseBundledShaders(t *testing.T) {
+	count := 0
+	err := filepath.WalkDir(shaderDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !strings.HasSuffix(path, ".vert") && !strings.HasSuffix(path, ".frag") && !strings.HasSuffix(path, ".glsl") {
+			return err
+		}
+		source, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		count++
+
+		s, err := glsl.ParseWithOptions(string(source), glsl.Options{Name: path})
+		if err != nil {
+			t.Errorf("Failed to parse %s: %v", path, err)
+			return nil
+		}
+		if s.Version == nil || s.Version.Number < 410 {
+			t.Errorf("%s: expected #version 410 or later, got %+v", path, s.Version)
+		}
+		if main, ok := s.Function("main"); !ok || main.Prototype || len(main.Body) == 0 {
+			t.Errorf("%s: main function not found", path)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Failed to walk shaders: %v", err)
+	}
+	if count == 0 {
+		t.Fatal("No shaders found")
+	}
+}
+
+const declarations = `#version 410 core
+#extension GL_ARB_shading_language_420pack : enable
+#define MAX_LIGHTS 4
+#define KERNEL (MAX_LIGHTS * 2)
+
+precision highp float;
+
+const int SAMPLES = 3;
+
+struct Light {
+    vec3 position;
+    float intensity;
+};
+
+layout(location = 0) in vec3 aPosition;
+layout(location = 1) in vec3 aNormal, aTangent;
+in vec2 aUV; // No location
+
+flat out int vMaterial;
+noperspective centroid out vec2 vUV;
+
+out Vertex {
+    vec3 normal;
+} vOut;
+
+uniform mat4 uMVP;
+uniform Light uLights[MAX_LIGHTS];
+uniform float uKernel[KERNEL], uSamples[SAMPLES];
+uniform float uExposure = 1.0;
+
+layout(std140) uniform Camera {
+    mat4 view;
+    layout(row_major) mat4 projection;
+} camera;
+
+layout(std430, binding = 2) readonly buffer Particles {
+    uint count;
+    vec4 positions[];
+};
+
+vec3 shade(in Light light, const vec3 n, out float weight);
+
+vec3 shade(in Light light, const vec3 n, out float weight) {
+    weight = light.intensity;
+    return light.position * max(dot(n, vec3(0.0, 1.0, 0.0)), 0.0);
+}
+
+void main() {
+    float w;
+    vec3 c = shade(uLights[0], aNormal, w) * uExposure;
+    vUV = aUV;
+    vMaterial = 1;
+    vOut.normal = c;
+    gl_Position = uMVP * vec4(aPosition, 1.0);
+}
+`
+
+func TestParseDeclarations(t *testing.T) {
+	s, err := glsl.Parse(declarations)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	if s.Version.Number != 410 || s.Version.Profile != "core" {
+		t.Errorf("Unexpected version %+v", s.Version)
+	}
+	if len(s.Extensions) != 1 || s.Extensions[0].Name != "GL_ARB_shading_language_420pack" || s.Extensions[0].Behavior != "enable" {
+		t.Errorf("Unexpected extensions %+v", s.Extensions)
+	}
+	if len(s.Precisions) != 1 || s.Precisions[0].Qualifier != "highp" || s.Precisions[0].Type != "float" {
+		t.Errorf("Unexpected precisions %+v", s.Precisions)
+	}
+
+	// Inputs with explicit and implicit locations
+	inputs := s.Inputs()
+	if len(inputs) != 4 {
+		t.Fatalf("Expected 4 inputs, got %d", len(inputs))
+	}
+	for i, want := range []int{0, 1, 2, -1} {
+		loc, ok := inputs[i].Location()
+		if want < 0 {
+			if ok {
+				t.Errorf("Input %s should have no location", inputs[i].Name)
+			}
+			continue
+		}
+		if !ok || loc != want {
+			t.Errorf("Input %s: expected location %d, got %d (%v)", inputs[i].Name, want, loc, ok)
+		}
+	}
+
+	// Outputs and interpolation
+	outputs := s.Outputs()
+	if len(outputs) != 2 {
+		t.Fatalf("Expected 2 outputs, got %d", len(outputs))
+	}
+	if outputs[0].Interpolation() != "flat" || outputs[1].Interpolation() != "noperspective" || !outputs[1].HasQualifier("centroid") {
+		t.Errorf("Unexpected output qualifiers %v and %v", outputs[0].Qualifiers, outputs[1].Qualifiers)
+	}
+	if inputs[0].Interpolation() != "smooth" {
+		t.Errorf("Expected default smooth interpolation, got %s", inputs[0].Interpolation())
+	}
+
+	// Uniforms and array sizes from literals, macros and constants
+	uniforms := map[string]string{}
+	for _, u := range s.Uniforms() {
+		uniforms[u.Name] = u.TypeString()
+	}
+	for name, want := range map[string]string{
+		"uMVP": "mat4", "uLights": "Light[4]", "uKernel": "float[8]", "uSamples": "float[3]", "uExposure": "float",
+	} {
+		if uniforms[name] != want {
+			t.Errorf("Uniform %s: expected %s, got %q", name, want, uniforms[name])
+		}
+	}
+	if u, _ := s.Variable("uExposure"); len(u.Init) != 1 || u.Init[0].Text != "1.0" {
+		t.Errorf("Unexpected initializer %+v", u.Init)
+	}
+
+	// Structs
+	light, ok := s.Struct("Light")
+	if !ok || len(light.Members) != 2 || light.Members[1].Name != "intensity" {
+		t.Errorf("Unexpected struct %+v", light)
+	}
+
+	// Blocks
+	if len(s.Blocks) != 3 {
+		t.Fatalf("Expected 3 blocks, got %d", len(s.Blocks))
+	}
+	out, _ := s.Block("Vertex")
+	if out.Storage != glsl.StorageOut || out.Instance != "vOut" {
+		t.Errorf("Unexpected out block %+v", out)
+	}
+	camera, _ := s.Block("Camera")
+	if camera.Storage != glsl.StorageUniform || camera.Instance != "camera" || camera.MemoryLayout() != "std140" {
+		t.Errorf("Unexpected uniform block %+v", camera)
+	}
+	if m, ok := camera.Member("projection"); !ok || !m.Layout.Has("row_major") {
+		t.Errorf("Expected row_major projection, got %+v", m)
+	}
+	particles, _ := s.Block("Particles")
+	if binding, _ := particles.Layout.Int("binding"); particles.Storage != glsl.StorageBuffer || binding != 2 || particles.MemoryLayout() != "std430" {
+		t.Errorf("Unexpected buffer block %+v", particles)
+	}
+	if len(particles.Qualifiers) != 1 || particles.Qualifiers[0] != "readonly" {
+		t.Errorf("Expected readonly buffer, got %v", particles.Qualifiers)
+	}
+	if m, _ := particles.Member("positions"); m.ArraySize != -1 {
+		t.Errorf("Expected a runtime-sized array, got size %d", m.ArraySize)
+	}
+
+	// Functions
+	if len(s.Functions) != 3 {
+		t.Fatalf("Expected 3 functions, got %d", len(s.Functions))
+	}
+	shade, _ := s.Function("shade")
+	if shade.Prototype || shade.ReturnType != "vec3" || len(shade.Params) != 3 {
+		t.Fatalf("Unexpected function %+v", shade)
+	}
+	if !s.Functions[0].Prototype {
+		t.Error("Expected the first declaration of shade to be a prototype")
+	}
+	params := shade.Params
+	if params[0].Type != "Light" || params[0].Storage != glsl.StorageIn ||
+		params[1].Storage != glsl.StorageIn || !params[1].HasQualifier("const") ||
+		params[2].Name != "weight" || params[2].Storage != glsl.StorageOut {
+		t.Errorf("Unexpected parameters %+v %+v %+v", params[0], params[1], params[2])
+	}
+}
+
+func TestDefaultLayouts(t *testing.T) {
+	s, err := glsl.Parse(`#version 430 core
+layout(local_size_x = 16, local_size_y = 8) in;
+layout(rgba8, binding = 0) uniform writeonly image2D uOutput;
+shared vec4 tile[128];
+void main() {}
+`)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	layout := s.Layout(glsl.StorageIn)
+	x, _ := layout.Int("local_size_x")
+	y, _ := layout.Int("local_size_y")
+	if x != 16 || y != 8 || layout.Has("local_size_z") {
+		t.Errorf("Unexpected compute layout %+v", layout)
+	}
+	if u, ok := s.Variable("uOutput"); !ok || !u.Layout.Has("rgba8") || !u.HasQualifier("writeonly") {
+		t.Errorf("Unexpected image uniform %+v", u)
+	}
+	if v, ok := s.Variable("tile"); !ok || v.Storage != glsl.StorageShared || v.ArraySize != 128 {
+		t.Errorf("Unexpected shared variable %+v", v)
+	}
+
+	s, err = glsl.Parse("#version 410 core\nlayout(triangles) in;\nlayout(line_strip, max_vertices = 6) out;\nvoid main() {}\n")
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if max, _ := s.Layout(glsl.StorageOut).Int("max_vertices"); max != 6 || !s.Layout(glsl.StorageIn).Has("triangles") {
+		t.Errorf("Unexpected geometry layouts %+v", s.Layouts)
+	}
+}
+
+func TestConditionalCompilation(t *testing.T) {
+	source := `#version 410 core
+#ifdef USE_TEXTURE
+uniform sampler2D uTexture;
+#else
+uniform vec4 uColor;
+#endif
+#if defined(QUALITY) && QUALITY >= 2
+uniform float uHigh;
+#elif QUALITY == 1
+uniform float uMedium;
+#else
+uniform float uLow;
+  #if 0
+  this is not GLSL @
+  #endif
+#endif
+#ifndef MAX_BONES
+#define MAX_BONES 4
+#endif
+uniform mat4 uBones[MAX_BONES];
+void main() {}
+`
+	tests := []struct {
+		defines map[string]string
+		want    []string
+	}{
+		{nil, []string{"uColor", "uLow", "uBones"}},
+		{map[string]string{"USE_TEXTURE": "", "QUALITY": "2"}, []string{"uTexture", "uHigh", "uBones"}},
+		{map[string]string{"QUALITY": "1", "MAX_BONES": "64"}, []string{"uColor", "uMedium", "uBones"}},
+	}
+	for _, tt := range tests {
+		s, err := glsl.ParseWithOptions(source, glsl.Options{Defines: tt.defines})
+		if err != nil {
+			t.Fatalf("Parse with %v failed: %v", tt.defines, err)
+		}
+		var names []string
+		for _, u := range s.Uniforms() {
+			names = append(names, u.Name)
+		}
+		if strings.Join(names, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("With %v expected uniforms %v, got %v", tt.defines, tt.want, names)
+		}
+	}
+
+	s, _ := glsl.ParseWithOptions(source, glsl.Options{Defines: map[string]string{"MAX_BONES": "64"}})
+	if bones, _ := s.Variable("uBones"); bones.ArraySize != 64 {
+		t.Errorf("Expected the predefined MAX_BONES to be used, got %d", bones.ArraySize)
+	}
+}
+
+func TestReferences(t *testing.T) {
+	s, err := glsl.Parse(`#version 410 core
+uniform float uScale;
+uniform float uUnused;
+uniform vec3 offset;
+struct S { float uUnused; };
+const float twice = uScale * 2.0;
+out vec4 color;
+void main() {
+    S s;
+    s.uUnused = uScale;
+    color = vec4(offset * s.uUnused, twice);
+}
+`)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if refs := s.References("uScale"); len(refs) != 2 {
+		t.Errorf("Expected 2 references to uScale, got %v", refs)
+	}
+	if refs := s.References("uUnused"); len(refs) != 0 {
+		t.Errorf("Member selections should not count as references, got %v", refs)
+	}
+	if refs := s.References("offset"); len(refs) != 1 || refs[0].Line != 11 {
+		t.Errorf("Expected one reference to offset on line 11, got %v", refs)
+	}
+}
+
+func TestConstantExpressions(t *testing.T) {
+	s, err := glsl.ParseWithOptions(`#version 410 core
+const int kLights = 4;
+const int kSlots = kLights * 2;
+#define CASCADES (1 + 2)
+uniform float uWeights[2*3];
+uniform vec3 uLights[kLights + 1];
+uniform mat4 uCascades[CASCADES << 1];
+uniform float uSlots[kSlots];
+layout(location = 1 + 2) in vec3 aNormal;
+layout(std140, binding = 2*2) uniform Camera { mat4 view; };
+void main() {}
+`, glsl.Options{})
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	for name, want := range map[string]int{"uWeights": 6, "uLights": 5, "uCascades": 6, "uSlots": 8} {
+		if v, ok := s.Variable(name); !ok || v.ArraySize != want {
+			t.Errorf("Expected %s[%d], got %+v", name, want, v)
+		}
+	}
+	if normal, _ := s.Variable("aNormal"); normal == nil {
+		t.Error("Missing aNormal")
+	} else if loc, _ := normal.Location(); loc != 3 {
+		t.Errorf("Expected aNormal at location 3, got %d", loc)
+	}
+	if camera, ok := s.Block("Camera"); !ok {
+		t.Error("Missing Camera block")
+	} else if binding, _ := camera.Layout.Int("binding"); binding != 4 {
+		t.Errorf("Expected Camera binding 4, got %d", binding)
+	}
+}
+
+func TestSubroutines(t *testing.T) {
+	s, err := glsl.Parse(`#version 410 core
+subroutine vec4 Shade(vec3 n);
+subroutine vec3 Tint(vec3 color);
+subroutine(Shade) vec4 unlit(vec3 n) { return vec4(1.0); }
+subroutine(Shade, Tint) vec4 lit(vec3 n) { return vec4(n, 1.0); }
+subroutine uniform Shade uShade;
+layout(location = 1) subroutine uniform Tint uTints[2*2], uExtra;
+uniform float uScale;
+out vec4 fragColor;
+void main() { fragColor = uShade(vec3(uScale)); }
+`)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	if len(s.SubroutineTypes) != 2 || s.SubroutineTypes[0].Name != "Shade" || s.SubroutineTypes[0].ReturnType != "vec4" || len(s.SubroutineTypes[0].Params) != 1 {
+		t.Errorf("Unexpected subroutine types %+v", s.SubroutineTypes)
+	}
+	if _, ok := s.Function("Shade"); ok {
+		t.Error("Subroutine types should not be listed as functions")
+	}
+	if f, ok := s.Function("lit"); !ok || strings.Join(f.Subroutines, ",") != "Shade,Tint" || f.Prototype {
+		t.Errorf("Unexpected subroutine function %+v", f)
+	}
+
+	if len(s.SubroutineUniforms) != 3 {
+		t.Fatalf("Expected 3 subroutine uniforms, got %+v", s.SubroutineUniforms)
+	}
+	tints := s.SubroutineUniforms[1]
+	if tints.Name != "uTints" || tints.Type != "Tint" || tints.ArraySize != 4 {
+		t.Errorf("Unexpected subroutine uniform %+v", tints)
+	}
+	if loc, ok := tints.Location(); !ok || loc != 1 {
+		t.Errorf("Expected uTints at location 1, got %d", loc)
+	}
+	if uniforms := s.Uniforms(); len(uniforms) != 1 || uniforms[0].Name != "uScale" {
+		t.Errorf("Subroutine uniforms should not be listed as uniforms, got %+v", uniforms)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   string
+	}{
+		{"missing semicolon", "#version 410 core\nuniform vec3 uColor\nvoid main() {}\n", "3:1: expected \";\", found \"void\""},
+		{"undefined array size", "#version 410 core\nuniform float uValues[COUNT];\n", "2:23: expected an integer constant, found \"COUNT\""},
+		{"unterminated if", "#version 410 core\n#ifdef A\nvoid main() {}\n", "2:1: unterminated #if"},
+		{"late version", "uniform float x;\n#version 410 core\n", "2:1: #version must come before any code"},
+		{"unterminated body", "#version 410 core\nvoid main() {\n", "2:13: unterminated body of function main"},
+		{"bad character", "#version 410 core\nuniform float x; @\n", "2:18: unexpected character '@'"},
+		{"arrays of arrays", "#version 410 core\nuniform float x[2][2];\n", "arrays of arrays are not supported"},
+		{"float array size", "#version 410 core\nuniform float x[2 * 1.5];\n", "2:21: expected an integer constant, found \"1.5\""},
+		{"subroutine without type", "#version 410 core\nsubroutine uniform uShade;\n", "2:26: expected name, found \";\""},
+	}
+	for _, tt := range tests {
+		_, err := glsl.ParseWithOptions(tt.source, glsl.Options{Name: "test.glsl"})
+		if err == nil {
+			t.Errorf("%s: expected an error", tt.name)
+			continue
+		}
+		if !strings.Contains(err.Error(), tt.want) || !strings.HasPrefix(err.Error(), "test.glsl:") {
+			t.Errorf("%s: expected error containing %q, got %q", tt.name, tt.want, err)
+		}
+	}
+}
@@ -0,0 +1,490 @@
+package layout_test
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+	"github.com/go-gl/glfw/v3.3/glfw"
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/yossideutsch/gogl/pkg/layout"
+	"github.com/yossideutsch/gogl/pkg/shader"
+)
+
+func TestMain(m *testing.M) {
+	if err := glfw.Init(); err != nil {
+		panic("Failed to initialize GLFW: " + err.Error())
+	}
+	defer glfw.Terminate()
+
+	glfw.WindowHint(glfw.ContextVersionMajor, 4)
+	glfw.WindowHint(glfw.ContextVersionMinor, 1)
+	glfw.WindowHint(glfw.OpenGLProfile, glfw.OpenGLCoreProfile)
+	glfw.WindowHint(glfw.OpenGLForwardCompatible, glfw.True)
+	glfw.WindowHint(glfw.Visible, glfw.False)
+
+	window, err := glfw.CreateWindow(100, 100, "Test", nil, nil)
+	if err != nil {
+		panic("Failed to create test window: " + err.Error())
+	}
+	defer window.Destroy()
+	window.MakeContextCurrent()
+
+	if err := gl.Init(); err != nil {
+		panic("Failed to initialize OpenGL: " + err.Error())
+	}
+
+	os.Exit(m.Run())
+}
+
+type light struct {
+	Position  mgl32.Vec3 `glsl:"position"`
+	Intensity float32    `glsl:"intensity"`
+	Color     mgl32.Vec3 `glsl:"color"`
+}
+
+type scene struct {
+	Tint     mgl32.Vec3 `glsl:"tint"`
+	Exposure float32
+	Normal   mgl32.Mat3 `glsl:"normalMatrix"`
+	Weights  [3]float32 `glsl:"weights"`
+	Lights   [2]light   `glsl:"lights"`
+	Enabled  bool       `glsl:"enabled"`
+	Offset   mgl32.Vec2 `glsl:"offset"`
+	Scratch  int        `glsl:"-"`
+}
+
+// sceneBlock declares scene in GLSL
+const sceneBlock = `
+struct Light {
+    vec3 position;
+    float intensity;
+    vec3 color;
+};
+
+layout(std140) uniform Scene {
+    vec3 tint;
+    float exposure;
+    mat3 normalMatrix;
+    float weights[3];
+    Light lights[2];
+    bool enabled;
+    vec2 offset;
+};
+`
+
+func TestStd140Members(t *testing.T) {
+	members, err := layout.Std140.Members(scene{})
+	if err != nil {
+		t.Fatalf("Members failed: %v", err)
+	}
+
+	expected := []layout.Member{
+		{Name: "tint", Type: shader.TypeVec3, Offset: 0, Size: 1},
+		{Name: "exposure", Type: shader.TypeFloat, Offset: 12, Size: 1},
+		{Name: "normalMatrix", Type: shader.TypeMat3, Offset: 16, Size: 1, MatrixStride: 16},
+		{Name: "weights", Type: shader.TypeFloat, Offset: 64, Size: 3, ArrayStride: 16},
+		{Name: "lights[0].position", Type: shader.TypeVec3, Offset: 112, Size: 1},
+		{Name: "lights[0].intensity", Type: shader.TypeFloat, Offset: 124, Size: 1},
+		{Name: "lights[0].color", Type: shader.TypeVec3, Offset: 128, Size: 1},
+		{Name: "lights[1].position", Type: shader.TypeVec3, Offset: 144, Size: 1},
+		{Name: "lights[1].intensity", Type: shader.TypeFloat, Offset: 156, Size: 1},
+		{Name: "lights[1].color", Type: shader.TypeVec3, Offset: 160, Size: 1},
+		{Name: "enabled", Type: shader.TypeBool, Offset: 176, Size: 1},
+		{Name: "offset", Type: shader.TypeVec2, Offset: 184, Size: 1},
+	}
+	if len(members) != len(expected) {
+		t.Fatalf("Expected %d members, got %d: %+v", len(expected), len(members), members)
+	}
+	for i, m := range members {
+		if m != expected[i] {
+			t.Errorf("Member %d: expected %+v, got %+v", i, expected[i], m)
+		}
+	}
+
+	size, err := layout.Std140.Size(&scene{})
+	if err != nil {
+		t.Fatalf("Size failed: %v", err)
+	}
+	if size != 192 {
+		t.Errorf("Expected size 192, got %d", size)
+	}
+}
+
+func TestStd140Marshal(t *testing.T) {
+	s := scene{
+		Tint:     mgl32.Vec3{1, 2, 3},
+		Exposure: 4,
+		Normal:   mgl32.Mat3{1, 2, 3, 4, 5, 6, 7, 8, 9},
+		Weights:  [3]float32{10, 11, 12},
+		Enabled:  true,
+	}
+	s.Lights[1].Color = mgl32.Vec3{13, 14, 15}
+
+	data, err := layout.Std140.Marshal(s)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if len(data) != 192 {
+		t.Fatalf("Expected 192 bytes, got %d", len(data))
+	}
+
+	float := func(offset int) float32 {
+		return math.Float32frombits(binary.LittleEndian.Uint32(data[offset:]))
+	}
+	checks := map[int]float32{
+		0: 1, 4: 2, 8: 3, 12: 4,
+		// mat3 columns are padded to vec4
+		16: 1, 20: 2, 24: 3, 28: 0, 32: 4, 48: 7, 56: 9,
+		// float arrays have a 16-byte stride
+		64: 10, 80: 11, 96: 12, 68: 0,
+		160: 13, 164: 14, 168: 15,
+	}
+	for offset, want := range checks {
+		if got := float(offset); got != want {
+			t.Errorf("Offset %d: expected %v, got %v", offset, want, got)
+		}
+	}
+	if got := binary.LittleEndian.Uint32(data[176:]); got != 1 {
+		t.Errorf("Expected bool encoded as 1, got %d", got)
+	}
+}
+
+func TestStd140UnsupportedTypes(t *testing.T) {
+	if _, err := layout.Std140.Marshal(struct{ Count int }{}); err == nil {
+		t.Error("Expected error for int field")
+	}
+	if _, err := layout.Std140.Marshal(struct{ Values []float32 }{}); err == nil {
+		t.Error("Expected error for slice field")
+	}
+	if _, err := layout.Std140.Marshal(mgl32.Vec4{}); err == nil {
+		t.Error("Expected error for non-struct value")
+	}
+	if _, err := layout.Std140.Marshal((*scene)(nil)); err == nil {
+		t.Error("Expected error for nil pointer")
+	}
+}
+
+func TestStd140Check(t *testing.T) {
+	block := shader.UniformBlockInfo{
+		Name:     "Scene",
+		DataSize: 192,
+		Members: []shader.UniformInfo{
+			{Name: "tint", Type: shader.TypeVec3, Size: 1, Offset: 0},
+			{Name: "exposure", Type: shader.TypeFloat, Size: 1, Offset: 12},
+			{Name: "weights", Type: shader.TypeFloat, Size: 3, Offset: 64, ArrayStride: 16},
+			{Name: "lights[1].color", Type: shader.TypeVec3, Size: 1, Offset: 160},
+		},
+	}
+	if err := layout.Std140.Check(block, scene{}); err != nil {
+		t.Errorf("Expected matching block, got %v", err)
+	}
+
+	// Go struct with a vec4 where the block has a vec3
+	type wrong struct {
+		Tint     mgl32.Vec4 `glsl:"tint"`
+		Exposure float32
+	}
+	err := layout.Std140.Check(block, wrong{})
+	var mismatch *layout.MismatchError
+	if !errors.As(err, &mismatch) {
+		t.Fatalf("Expected *MismatchError, got %v", err)
+	}
+	problems := make(map[string]string)
+	for _, m := range mismatch.Mismatches {
+		problems[m.Member] = m.Problem
+	}
+	if !strings.Contains(problems["tint"], "type is vec3 in the block, vec4 in Go") {
+		t.Errorf("Expected tint type mismatch, got %q", problems["tint"])
+	}
+	if !strings.Contains(problems["exposure"], "offset is 12 in the block, 16 in Go") {
+		t.Errorf("Expected exposure offset mismatch, got %q", problems["exposure"])
+	}
+	if problems["weights"] != "no matching Go field" {
+		t.Errorf("Expected missing weights, got %q", problems["weights"])
+	}
+	if _, ok := problems["Scene"]; !ok {
+		t.Error("Expected block size mismatch")
+	}
+}
+
+func TestStd140ValidateProgram(t *testing.T) {
+	vertexSource := `#version 410 core
+layout(location = 0) in vec3 aPosition;
+` + sceneBlock + `
+out vec3 vColor;
+void main() {
+    vec3 color = tint * exposure * (normalMatrix * aPosition);
+    for (int i = 0; i < 3; i++) {
+        color += weights[i] * lights[i % 2].color * lights[i % 2].intensity + lights[i % 2].position;
+    }
+    vColor = enabled ? color : vec3(offset, 0.0);
+    gl_Position = vec4(aPosition, 1.0);
+}
+`
+	fragmentSource := `#version 410 core
+in vec3 vColor;
+out vec4 fragColor;
+void main() {
+    fragColor = vec4(vColor, 1.0);
+}
+`
+	vs, err := shader.CompileShader(vertexSource, shader.VertexShader)
+	if err != nil {
+		t.Fatalf("Failed to compile vertex shader: %v", err)
+	}
+	fs, err := shader.CompileShader(fragmentSource, shader.FragmentShader)
+	if err != nil {
+		t.Fatalf("Failed to compile fragment shader: %v", err)
+	}
+	program, err := shader.CreateProgram(vs, fs)
+	if err != nil {
+		t.Fatalf("Failed to link program: %v", err)
+	}
+	defer program.Delete()
+
+	if err := layout.Std140.Validate(program, "Scene", scene{}); err != nil {
+		t.Errorf("Expected scene to match the reflected block: %v", err)
+	}
+
+	type swapped struct {
+		Exposure float32    `glsl:"exposure"`
+		Tint     mgl32.Vec3 `glsl:"tint"`
+	}
+	if err := layout.Std140.Validate(program, "Scene", swapped{}); err == nil {
+		t.Error("Expected mismatch for reordered fields")
+	}
+	if err := layout.Std140.Validate(program, "Missing", scene{}); err == nil {
+		t.Error("Expected error for unknown block")
+	}
+}
+
+type particle struct {
+	Position mgl32.Vec2 `glsl:"position"`
+	Velocity mgl32.Vec2 `glsl:"velocity"`
+	Color    mgl32.Vec4 `glsl:"color"`
+	Life     float32    `glsl:"life"`
+	Size     float32    `glsl:"size"`
+}
+
+type particleBuffer struct {
+	Count     uint32     `glsl:"count"`
+	Transform mgl32.Mat3 `glsl:"transform"`
+	Particles []particle `glsl:"particles"`
+}
+
+func TestStd430Layout(t *testing.T) {
+	stride, err := layout.Std430.Stride(particle{})
+	if err != nil {
+		t.Fatalf("Stride failed: %v", err)
+	}
+	if stride != 48 {
+		t.Errorf("Expected particle stride 48, got %d", stride)
+	}
+
+	// std430 packs scalar arrays and vec2 matrix columns tightly
+	type packed struct {
+		Weights [4]float32 `glsl:"weights"`
+		M       mgl32.Mat2 `glsl:"m"`
+	}
+	members, err := layout.Std430.Members(packed{})
+	if err != nil {
+		t.Fatalf("Members failed: %v", err)
+	}
+	expected := []layout.Member{
+		{Name: "weights", Type: shader.TypeFloat, Offset: 0, Size: 4, ArrayStride: 4},
+		{Name: "m", Type: shader.TypeMat2, Offset: 16, Size: 1, MatrixStride: 8},
+	}
+	for i, m := range members {
+		if m != expected[i] {
+			t.Errorf("Member %d: expected %+v, got %+v", i, expected[i], m)
+		}
+	}
+	if size, _ := layout.Std430.Size(packed{}); size != 32 {
+		t.Errorf("Expected std430 size 32, got %d", size)
+	}
+	if size, _ := layout.Std140.Size(packed{}); size != 96 {
+		t.Errorf("Expected std140 size 96, got %d", size)
+	}
+
+	members, err = layout.Std430.Members(particleBuffer{})
+	if err != nil {
+		t.Fatalf("Members failed: %v", err)
+	}
+	last := members[len(members)-1]
+	if last.Name != "particles[0].size" || last.Offset != 64+36 {
+		t.Errorf("Expected particles[0].size at 100, got %+v", last)
+	}
+}
+
+func TestStd430RoundTrip(t *testing.T) {
+	particles := []particle{
+		{Position: mgl32.Vec2{1, 2}, Velocity: mgl32.Vec2{3, 4}, Color: mgl32.Vec4{5, 6, 7, 8}, Life: 9, Size: 10},
+		{Position: mgl32.Vec2{-1, -2}, Life: 0.5, Size: 2},
+		{Color: mgl32.Vec4{1, 1, 1, 1}},
+	}
+	data, err := layout.Std430.Marshal(particles)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if len(data) != 3*48 {
+		t.Fatalf("Expected %d bytes, got %d", 3*48, len(data))
+	}
+
+	var decoded []particle
+	if err := layout.Std430.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if len(decoded) != len(particles) {
+		t.Fatalf("Expected %d particles, got %d", len(particles), len(decoded))
+	}
+	for i := range particles {
+		if decoded[i] != particles[i] {
+			t.Errorf("Particle %d: expected %+v, got %+v", i, particles[i], decoded[i])
+		}
+	}
+
+	buffer := particleBuffer{
+		Count:     2,
+		Transform: mgl32.Ident3(),
+		Particles: particles[:2],
+	}
+	data, err = layout.Std430.Marshal(&buffer)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if len(data) != 64+2*48 {
+		t.Fatalf("Expected %d bytes, got %d", 64+2*48, len(data))
+	}
+	var decodedBuffer particleBuffer
+	if err := layout.Std430.Unmarshal(data, &decodedBuffer); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if decodedBuffer.Count != 2 || decodedBuffer.Transform != mgl32.Ident3() || len(decodedBuffer.Particles) != 2 {
+		t.Errorf("Unexpected decoded buffer %+v", decodedBuffer)
+	}
+	if decodedBuffer.Particles[1] != particles[1] {
+		t.Errorf("Expected %+v, got %+v", particles[1], decodedBuffer.Particles[1])
+	}
+
+	if err := layout.Std430.Unmarshal(data[:10], &decodedBuffer); err == nil {
+		t.Error("Expected error for truncated data")
+	}
+	if err := layout.Std430.Unmarshal(data, decodedBuffer); err == nil {
+		t.Error("Expected error for non-pointer target")
+	}
+}
+
+func TestStd430RuntimeArrayRules(t *testing.T) {
+	if _, err := layout.Std140.Marshal(particleBuffer{}); err == nil {
+		t.Error("Expected std140 to reject runtime-sized arrays")
+	}
+	type notLast struct {
+		Particles []particle
+		Count     uint32
+	}
+	if _, err := layout.Std430.Marshal(notLast{}); err == nil {
+		t.Error("Expected error for runtime-sized array before another field")
+	}
+	type nested struct {
+		Inner particleBuffer
+	}
+	if _, err := layout.Std430.Marshal(nested{}); err == nil {
+		t.Error("Expected error for nested runtime-sized array")
+	}
+}
+
+func TestStd430CheckStorage(t *testing.T) {
+	block := shader.StorageBlockInfo{
+		Name:     "ParticleBuffer",
+		DataSize: 48,
+		Members: []shader.BufferVariableInfo{
+			{Name: "particles[0].position", Type: shader.TypeVec2, Size: 1, Offset: 0, TopLevelArrayStride: 48},
+			{Name: "particles[0].color", Type: shader.TypeVec4, Size: 1, Offset: 16, TopLevelArrayStride: 48},
+			{Name: "particles[0].size", Type: shader.TypeFloat, Size: 1, Offset: 36, TopLevelArrayStride: 48},
+		},
+	}
+	if err := layout.Std430.CheckStorage(block, []particle{}); err != nil {
+		t.Errorf("Expected slice to match the block: %v", err)
+	}
+
+	// A padded Go struct changes the stride
+	type padded struct {
+		Position mgl32.Vec2 `glsl:"position"`
+		Velocity mgl32.Vec2 `glsl:"velocity"`
+		Color    mgl32.Vec4 `glsl:"color"`
+		Life     float32    `glsl:"life"`
+		Size     float32    `glsl:"size"`
+		Extra    mgl32.Vec4 `glsl:"extra"`
+	}
+	err := layout.Std430.CheckStorage(block, []padded{})
+	var mismatch *layout.MismatchError
+	if !errors.As(err, &mismatch) {
+		t.Fatalf("Expected *MismatchError, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "top-level array stride is 48 in the block, 64 in Go") {
+		t.Errorf("Expected stride mismatch, got %v", err)
+	}
+}
+
+func TestStd430ValidateProgram(t *testing.T) {
+	computeSource := `#version 430
+layout(local_size_x = 16) in;
+
+struct Particle {
+    vec2 position;
+    vec2 velocity;
+    vec4 color;
+    float life;
+    float size;
+};
+
+layout(std430, binding = 0) buffer ParticleBuffer {
+    uint count;
+    mat3 transform;
+    Particle particles[];
+};
+
+void main() {
+    uint i = gl_GlobalInvocationID.x;
+    if (i >= count) {
+        return;
+    }
+    Particle p = particles[i];
+    p.position = (transform * vec3(p.position + p.velocity, 1.0)).xy;
+    p.life -= 1.0;
+    p.color.a *= p.size;
+    particles[i] = p;
+}
+`
+	cs, err := shader.CompileShader(computeSource, shader.ComputeShader)
+	if err != nil {
+		t.Skipf("Compute shaders not supported by this context: %v", err)
+	}
+	program, err := shader.CreateProgram(cs)
+	if err != nil {
+		t.Fatalf("Failed to link compute program: %v", err)
+	}
+	defer program.Delete()
+
+	err = layout.Std430.Validate(program, "ParticleBuffer", particleBuffer{})
+	if errors.Is(err, layout.ErrNoInterfaceQuery) {
+		t.Skip("Storage blocks cannot be reflected by this context")
+	}
+	if err != nil {
+		t.Errorf("Expected particleBuffer to match the reflected block: %v", err)
+	}
+
+	type wrong struct {
+		Count     uint32     `glsl:"count"`
+		Transform mgl32.Mat4 `glsl:"transform"`
+		Particles []particle `glsl:"particles"`
+	}
+	if err := layout.Std430.Validate(program, "ParticleBuffer", wrong{}); err == nil {
+		t.Error("Expected mismatch for mat4 transform")
+	}
+}
@@ -0,0 +1,184 @@
+package library_test
+
+import (
+	"io/fs"
+	"os"
+	"testing"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+	"github.com/go-gl/glfw/v3.3/glfw"
+	"github.com/yossideutsch/gogl/pkg/library"
+	"github.com/yossideutsch/gogl/pkg/shader"
+	"github.com/yossideutsch/gogl/shaders"
+)
+
+func TestMain(m *testing.M) {
+	if err := glfw.Init(); err != nil {
+		panic("Failed to initialize GLFW: " + err.Error())
+	}
+	defer glfw.Terminate()
+
+	glfw.WindowHint(glfw.ContextVersionMajor, 4)
+	glfw.WindowHint(glfw.ContextVersionMinor, 1)
+	glfw.WindowHint(glfw.OpenGLProfile, glfw.OpenGLCoreProfile)
+	glfw.WindowHint(glfw.OpenGLForwardCompatible, glfw.True)
+	glfw.WindowHint(glfw.Visible, glfw.False)
+
+	window, err := glfw.CreateWindow(100, 100, "Test", nil, nil)
+	if err != nil {
+		panic("Failed to create test window: " + err.Error())
+	}
+	defer window.Destroy()
+	window.MakeContextCurrent()
+
+	if err := gl.Init(); err != nil {
+		panic("Failed to initialize OpenGL: " + err.Error())
+	}
+
+	os.Exit(m.Run())
+}
+
+func TestCatalogCoversEmbeddedFiles(t *testing.T) {
+	err := fs.WalkDir(shaders.FS, ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		if _, ok := library.Shader(path); !ok {
+			t.Errorf("embedded file %s has no library metadata", path)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, info := range library.Shaders() {
+		if _, err := fs.Stat(shaders.FS, info.Path); err != nil {
+			t.Errorf("metadata for %s has no embedded file: %v", info.Path, err)
+		}
+	}
+	for _, program := range library.Programs() {
+		for _, path := range program.Shaders {
+			if _, ok := library.Shader(path); !ok {
+				t.Errorf("program %s uses unknown shader %s", program.Name, path)
+			}
+		}
+	}
+}
+
+func TestProgramMetadata(t *testing.T) {
+	info, ok := library.Lookup("point_sprites")
+	if !ok {
+		t.Fatal("point_sprites not found")
+	}
+	if info.Kind != library.KindGeometry {
+		t.Errorf("Expected geometry kind, got %s", info.Kind)
+	}
+	if got := info.MinGLVersion(); got != (library.GLVersion{Major: 4, Minor: 1}) {
+		t.Errorf("Expected OpenGL 4.1, got %s", got)
+	}
+	// uView and uProjection come from the geometry stage, uModel from the vertex stage
+	names := make(map[string]bool)
+	for _, u := range info.Uniforms() {
+		if names[u.Name] {
+			t.Errorf("Uniform %s listed twice", u.Name)
+		}
+		names[u.Name] = true
+	}
+	for _, name := range []string{"uModel", "uView", "uProjection", "uPointSize"} {
+		if !names[name] {
+			t.Errorf("Expected uniform %s", name)
+		}
+	}
+
+	compute, _ := library.Lookup("particle_simulation")
+	if got := compute.MinGLVersion(); got != (library.GLVersion{Major: 4, Minor: 3}) {
+		t.Errorf("Expected OpenGL 4.3 for compute, got %s", got)
+	}
+	if len(library.PostEffects()) != 6 {
+		t.Errorf("Expected 6 post effects, got %v", library.PostEffects())
+	}
+}
+
+func TestBuildPrograms(t *testing.T) {
+	for _, info := range library.Programs() {
+		if info.Kind == library.KindCompute {
+			continue
+		}
+		t.Run(info.Name, func(t *testing.T) {
+			program, err := library.Build(info.Name)
+			if err != nil {
+				t.Fatalf("Failed to build: %v", err)
+			}
+			defer program.Delete()
+
+			uniforms := make(map[string]shader.DataType)
+			for _, u := range info.Uniforms() {
+				uniforms[u.Name] = u.Type
+			}
+			for _, u := range program.Uniforms() {
+				if declared, ok := uniforms[u.Name]; !ok {
+					t.Errorf("Active uniform %s missing from metadata", u.Name)
+				} else if declared != u.Type {
+					t.Errorf("Uniform %s: metadata says %s, driver reports %s", u.Name, declared, u.Type)
+				}
+			}
+
+			inputs := make(map[string]library.Variable)
+			for _, in := range info.Inputs() {
+				inputs[in.Name] = in
+			}
+			for _, a := range program.Attributes() {
+				declared, ok := inputs[a.Name]
+				if !ok {
+					t.Errorf("Active attribute %s missing from metadata", a.Name)
+					continue
+				}
+				if declared.Type != a.Type {
+					t.Errorf("Attribute %s: metadata says %s, driver reports %s", a.Name, declared.Type, a.Type)
+				}
+				if declared.Location != a.Location {
+					t.Errorf("Attribute %s: metadata location %d, driver reports %d", a.Name, declared.Location, a.Location)
+				}
+			}
+		})
+	}
+}
+
+func TestConstructors(t *testing.T) {
+	for name, build := range map[string]func() (*shader.Program, error){
+		"Phong":        library.Phong,
+		"PointSprites": library.PointSprites,
+		"Wireframe":    library.Wireframe,
+	} {
+		program, err := build()
+		if err != nil {
+			t.Errorf("%s failed: %v", name, err)
+			continue
+		}
+		program.Delete()
+	}
+
+	program, err := library.PostProcess("grayscale")
+	if err != nil {
+		t.Fatalf("PostProcess failed: %v", err)
+	}
+	program.Delete()
+
+	if _, err := library.PostProcess("phong"); err == nil {
+		t.Error("Expected error for a program that is not a post effect")
+	}
+	if _, err := library.Build("missing"); err == nil {
+		t.Error("Expected error for unknown program")
+	}
+	// Drivers may hand out a newer context than requested
+	var major, minor int32
+	gl.GetIntegerv(gl.MAJOR_VERSION, &major)
+	gl.GetIntegerv(gl.MINOR_VERSION, &minor)
+	if (library.GLVersion{Major: int(major), Minor: int(minor)}).IsAtLeast(library.GLVersion{Major: 4, Minor: 3}) {
+		return
+	}
+	if _, err := library.ParticleSimulation(); err == nil {
+		t.Error("Expected error building a 4.3 program on an older context")
+	}
+}
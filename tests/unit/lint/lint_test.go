@@ -0,0 +1,256 @@
+package lint_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/yossideutsch/gogl/internal/lint"
+	"github.com/yossideutsch/gogl/pkg/library"
+	"github.com/yossideutsch/gogl/pkg/shader"
+)
+
+const shaderDir = "../../../shaders"
+
+// writeShader writes a GLSL file into dir and returns its path
+func writeShader(t *testing.T, dir, name, source string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(source), 0644); err != nil {
+		t.Fatalf("Failed to write %s: %v", name, err)
+	}
+	return path
+}
+
+// byRule groups findings by rule ID
+func byRule(findings []lint.Finding) map[string][]lint.Finding {
+	rules := make(map[string][]lint.Finding)
+	for _, f := range findings {
+		rules[f.Rule] = append(rules[f.Rule], f)
+	}
+	return rules
+}
+
+func TestLintBundledShaders(t *testing.T) {
+	var cfg lint.Config
+	for _, info := range library.Shaders() {
+		cfg.Files = append(cfg.Files, lint.File{Path: filepath.Join(shaderDir, filepath.FromSlash(info.Path)), Stage: info.Stage})
+	}
+	for _, info := range library.Programs() {
+		prog := lint.Program{Name: info.Name}
+		for _, s := range info.Stages() {
+			prog.Files = append(prog.Files, lint.File{Path: filepath.Join(shaderDir, filepath.FromSlash(s.Path)), Stage: s.Stage})
+		}
+		cfg.Programs = append(cfg.Programs, prog)
+	}
+
+	for _, f := range lint.Lint(cfg) {
+		if f.Rule != "requires-gl43" || !strings.Contains(f.File, "/compute/") {
+			t.Errorf("Unexpected finding: %s", f)
+		}
+	}
+}
+
+func TestLintRules(t *testing.T) {
+	dir := t.TempDir()
+	vert := writeShader(t, dir, "a.vert", `#version 410 core
+layout(location = 0) in vec3 aPosition;
+in vec2 aTexCoord;
+
+out vec3 vNormal;
+out vec2 vTexCoord;
+flat out int vId;
+
+uniform mat4 uMVP;
+uniform float uUnused;
+
+void main() {
+    vNormal = aPosition;
+    vTexCoord = aTexCoord;
+    vId = gl_VertexID;
+    gl_Position = uMVP * vec4(aPosition, 1.0);
+}
+`)
+	frag := writeShader(t, dir, "a.frag", `#version 430 core
+in vec4 vNormal;
+in vec2 vTexCoord;
+in int vId;
+in vec3 vMissing;
+
+out vec4 fragColor;
+
+void main() {
+    fragColor = vec4(vTexCoord, float(vId), 1.0) + vNormal + vec4(vMissing, 0.0);
+}
+`)
+	comp := writeShader(t, dir, "a.comp", `#version 430 core
+layout(local_size_x = 64) in;
+void main() {}
+`)
+
+	findings := lint.Lint(lint.Config{
+		Files: []lint.File{{Path: comp, Stage: shader.ComputeShader}},
+		Programs: []lint.Program{{Name: "a", Files: []lint.File{
+			{Path: frag, Stage: shader.FragmentShader},
+			{Path: vert, Stage: shader.VertexShader},
+		}}},
+	})
+	rules := byRule(findings)
+
+	tests := []struct {
+		rule string
+		file string
+		line int
+		want string
+	}{
+		{"missing-location", "a.vert", 3, "vertex input aTexCoord"},
+		{"unused-uniform", "a.vert", 10, "uniform uUnused"},
+		{"version", "a.frag", 1, "#version 430 exceeds 410"},
+		{"interface-mismatch", "a.frag", 2, "vNormal is vec4 but the vertex output is vec3"},
+		{"interface-mismatch", "a.frag", 4, "vId is smooth but the vertex output is flat"},
+		{"interface-mismatch", "a.frag", 5, "vMissing has no matching vertex output"},
+		{"requires-gl43", "a.comp", 1, "compute shaders need OpenGL 4.3"},
+	}
+	for _, tt := range tests {
+		found := false
+		for _, f := range rules[tt.rule] {
+			if strings.HasSuffix(f.File, "/"+tt.file) && f.Line == tt.line && strings.Contains(f.Message, tt.want) {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("Expected %s finding at %s:%d containing %q, got %v", tt.rule, tt.file, tt.line, tt.want, findings)
+		}
+	}
+	if len(findings) != len(tests) {
+		t.Errorf("Expected %d findings, got %d: %v", len(tests), len(findings), findings)
+	}
+	for _, f := range rules["version"] {
+		if f.Level != lint.LevelError {
+			t.Errorf("Expected version findings to be errors, got %s", f.Level)
+		}
+	}
+}
+
+func TestLintIncludeAndSyntax(t *testing.T) {
+	dir := t.TempDir()
+	writeShader(t, dir, "common.glsl", "uniform vec3 uTint;\nuniform float uIgnored;\n")
+	frag := writeShader(t, dir, "b.frag", `#version 410 core
+#include "common.glsl"
+out vec4 fragColor;
+void main() { fragColor = vec4(uTint, 1.0); }
+`)
+	broken := writeShader(t, dir, "c.frag", "#version 410 core\nout vec4 fragColor;\nuniform float uValues[N];\nvoid main() { fragColor = vec4(uValues[0]); }\n")
+
+	findings := lint.Lint(lint.Config{
+		Files:        []lint.File{{Path: frag, Stage: shader.FragmentShader}, {Path: broken, Stage: shader.FragmentShader}},
+		Preprocessor: shader.NewPreprocessor(),
+	})
+	if len(findings) != 2 {
+		t.Fatalf("Expected 2 findings, got %v", findings)
+	}
+
+	rules := byRule(findings)
+	if len(rules["unused-uniform"]) != 1 || len(rules["syntax"]) != 1 {
+		t.Fatalf("Expected one unused-uniform and one syntax finding, got %v", findings)
+	}
+	unused := rules["unused-uniform"][0]
+	if !strings.HasSuffix(unused.File, "/common.glsl") || unused.Line != 2 {
+		t.Errorf("Expected unused uIgnored reported at common.glsl:2, got %s", unused)
+	}
+	syntax := rules["syntax"][0]
+	if syntax.Rule != "syntax" || syntax.Level != lint.LevelError || !strings.HasSuffix(syntax.File, "/c.frag") || syntax.Line != 3 {
+		t.Errorf("Expected a syntax error at c.frag:3, got %s", syntax)
+	}
+}
+
+func TestWriteReports(t *testing.T) {
+	findings := []lint.Finding{
+		{Rule: "unused-uniform", Level: lint.LevelWarning, File: "shaders/a.frag", Line: 4, Column: 14, Message: "uniform uTime is declared but never used"},
+		{Rule: "version", Level: lint.LevelError, File: "/abs/b.vert", Line: 1, Column: 1, Message: "#version 450 exceeds 410 core"},
+	}
+
+	var text bytes.Buffer
+	if err := lint.WriteText(&text, findings); err != nil {
+		t.Fatalf("WriteText failed: %v", err)
+	}
+	want := "shaders/a.frag:4:14: warning: uniform uTime is declared but never used [unused-uniform]\n"
+	if !strings.HasPrefix(text.String(), want) || !strings.HasSuffix(text.String(), "1 error(s), 1 warning(s)\n") {
+		t.Errorf("Unexpected text report:\n%s", text.String())
+	}
+
+	var js bytes.Buffer
+	if err := lint.WriteJSON(&js, nil); err != nil || strings.TrimSpace(js.String()) != "[]" {
+		t.Errorf("Expected an empty JSON array, got %q (%v)", js.String(), err)
+	}
+	js.Reset()
+	if err := lint.WriteJSON(&js, findings); err != nil {
+		t.Fatalf("WriteJSON failed: %v", err)
+	}
+	var decoded []lint.Finding
+	if err := json.Unmarshal(js.Bytes(), &decoded); err != nil || len(decoded) != 2 || decoded[1] != findings[1] {
+		t.Errorf("JSON report does not round-trip: %v %v", decoded, err)
+	}
+
+	var sarif bytes.Buffer
+	if err := lint.WriteSARIF(&sarif, findings); err != nil {
+		t.Fatalf("WriteSARIF failed: %v", err)
+	}
+	var log struct {
+		Version string `json:"version"`
+		Runs    []struct {
+			Tool struct {
+				Driver struct {
+					Name  string `json:"name"`
+					Rules []struct {
+						ID string `json:"id"`
+					} `json:"rules"`
+				} `json:"driver"`
+			} `json:"tool"`
+			Results []struct {
+				RuleID    string `json:"ruleId"`
+				RuleIndex int    `json:"ruleIndex"`
+				Level     string `json:"level"`
+				Locations []struct {
+					PhysicalLocation struct {
+						ArtifactLocation struct {
+							URI       string `json:"uri"`
+							URIBaseID string `json:"uriBaseId"`
+						} `json:"artifactLocation"`
+						Region struct {
+							StartLine   int `json:"startLine"`
+							StartColumn int `json:"startColumn"`
+						} `json:"region"`
+					} `json:"physicalLocation"`
+				} `json:"locations"`
+			} `json:"results"`
+		} `json:"runs"`
+	}
+	if err := json.Unmarshal(sarif.Bytes(), &log); err != nil {
+		t.Fatalf("SARIF report is not valid JSON: %v", err)
+	}
+	if log.Version != "2.1.0" || len(log.Runs) != 1 || log.Runs[0].Tool.Driver.Name != "gogl-lint" {
+		t.Fatalf("Unexpected SARIF header: %+v", log)
+	}
+	run := log.Runs[0]
+	if len(run.Tool.Driver.Rules) != len(lint.Rules) || len(run.Results) != 2 {
+		t.Fatalf("Expected %d rules and 2 results, got %d and %d", len(lint.Rules), len(run.Tool.Driver.Rules), len(run.Results))
+	}
+	for i, r := range run.Results {
+		if run.Tool.Driver.Rules[r.RuleIndex].ID != r.RuleID || r.RuleID != findings[i].Rule || r.Level != string(findings[i].Level) {
+			t.Errorf("Result %d has rule %s (index %d) level %s", i, r.RuleID, r.RuleIndex, r.Level)
+		}
+	}
+
+	rel := run.Results[0].Locations[0].PhysicalLocation
+	if rel.ArtifactLocation.URI != "shaders/a.frag" || rel.ArtifactLocation.URIBaseID != "%SRCROOT%" || rel.Region.StartLine != 4 || rel.Region.StartColumn != 14 {
+		t.Errorf("Unexpected relative location: %+v", rel)
+	}
+	abs := run.Results[1].Locations[0].PhysicalLocation.ArtifactLocation
+	if abs.URI != "file:///abs/b.vert" || abs.URIBaseID != "" {
+		t.Errorf("Unexpected absolute location: %+v", abs)
+	}
+}
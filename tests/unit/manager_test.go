@@ -0,0 +1,179 @@
+package shader_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+	"github.com/yossideutsch/gogl/pkg/shader"
+)
+
+// liveShaders counts the shader objects among the first few hundred names
+func liveShaders() int {
+	n := 0
+	for id := uint32(1); id < 512; id++ {
+		if gl.IsShader(id) {
+			n++
+		}
+	}
+	return n
+}
+
+func TestManagerSharesPrograms(t *testing.T) {
+	m := shader.NewManager()
+	defer m.Clear()
+
+	sources := map[shader.ShaderType]string{
+		shader.VertexShader:   testVertexShaderSource,
+		shader.FragmentShader: "#version 410 core\nout vec4 fragColor;\nvoid main() { fragColor = vec4(1.0); }",
+	}
+	a, err := m.Program(sources)
+	if err != nil {
+		t.Fatal("Failed to build managed program:", err)
+	}
+	b, err := m.Program(map[shader.ShaderType]string{
+		shader.FragmentShader: sources[shader.FragmentShader],
+		shader.VertexShader:   sources[shader.VertexShader],
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a != b || m.Refs(a) != 2 || m.Len() != 1 {
+		t.Fatalf("Expected one shared program with 2 references, got %p %p refs=%d len=%d", a, b, m.Refs(a), m.Len())
+	}
+
+	// Different options make a different program
+	c, err := m.ProgramWithOptions(shader.ProgramOptions{Separable: true}, sources)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c == a || m.Len() != 2 {
+		t.Error("Programs with different options must not be shared")
+	}
+
+	// ReleaseShaders programs cannot be rebuilt the same way, so they are not
+	// shared with programs owning their shaders; borrowing is the same as owning
+	released, err := m.ProgramWithOptions(shader.ProgramOptions{Ownership: shader.ReleaseShaders}, sources)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if released == a {
+		t.Error("Programs with different ownership modes must not be shared")
+	}
+	borrowed, err := m.ProgramWithOptions(shader.ProgramOptions{Ownership: shader.BorrowShaders}, sources)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if borrowed != a {
+		t.Error("Borrowing requests should share the program owning its shaders")
+	}
+	m.Release(released)
+	m.Release(borrowed)
+
+	if err := m.Release(a); err != nil || a.ID == 0 {
+		t.Errorf("First release should keep the program alive: %v", err)
+	}
+	if err := m.Release(b); err != nil || a.ID != 0 || m.Refs(a) != 0 {
+		t.Errorf("Last release should delete the program: %v", err)
+	}
+	if err := m.Release(a); err == nil {
+		t.Error("Expected releasing a deleted program to fail")
+	}
+	if err := m.Release(c); err != nil || m.Len() != 0 {
+		t.Errorf("Expected an empty manager, got %d programs (%v)", m.Len(), err)
+	}
+
+	// Released programs are built again on the next request
+	d, err := m.Program(sources)
+	if err != nil || d.ID == 0 || m.Refs(d) != 1 {
+		t.Fatalf("Failed to rebuild released program: %v", err)
+	}
+}
+
+func TestManagerErrors(t *testing.T) {
+	m := shader.NewManager()
+	defer m.Clear()
+
+	if _, err := m.Program(nil); err == nil {
+		t.Error("Expected a program without shaders to fail")
+	}
+	if _, err := m.Program(map[shader.ShaderType]string{
+		shader.VertexShader:   testVertexShaderSource,
+		shader.FragmentShader: "#version 410 core\nout vec4 c;\nvoid main() { c = undeclared; }",
+	}); err == nil {
+		t.Error("Expected a compile error")
+	}
+	if m.Len() != 0 {
+		t.Errorf("Failed builds must not be cached, got %d programs", m.Len())
+	}
+
+	// Stage sets rejected before linking must not leak the compiled shaders
+	before := liveShaders()
+	if _, err := m.Program(map[shader.ShaderType]string{shader.VertexShader: testVertexShaderSource}); err == nil {
+		t.Error("Expected a vertex-only program to fail")
+	}
+	if after := liveShaders(); after != before {
+		t.Errorf("Vertex-only request leaked %d shaders", after-before)
+	}
+
+	unmanaged, err := shader.CreateShaderProgram(testVertexShaderSource, shader.VertexShader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unmanaged.Delete()
+	if err := m.Release(unmanaged); err == nil {
+		t.Error("Expected releasing an unmanaged program to fail")
+	}
+}
+
+func TestManagerProgramFromFiles(t *testing.T) {
+	dir := writeShaderFiles(t, map[string]string{
+		"a.vert":      testVertexShaderSource,
+		"a.frag":      "#version 410 core\n#include \"color.glsl\"\nout vec4 fragColor;\nvoid main() { fragColor = color(); }\n",
+		"color.glsl":  "vec4 color() { return vec4(1.0); }\n",
+		"copy/a.frag": "#version 410 core\n#include \"../color.glsl\"\nout vec4 fragColor;\nvoid main() { fragColor = color(); }\n",
+	})
+
+	m := shader.NewManager()
+	defer m.Clear()
+	files := map[shader.ShaderType]string{
+		shader.VertexShader:   filepath.Join(dir, "a.vert"),
+		shader.FragmentShader: filepath.Join(dir, "a.frag"),
+	}
+	a, err := m.ProgramFromFiles(files)
+	if err != nil {
+		t.Fatal("Failed to build program from files:", err)
+	}
+	b, err := m.ProgramFromFiles(files)
+	if err != nil || a != b {
+		t.Fatalf("Expected the same program for the same files: %v", err)
+	}
+
+	// Programs are keyed by preprocessed source, not by path
+	files[shader.FragmentShader] = filepath.Join(dir, "copy", "a.frag")
+	if c, err := m.ProgramFromFiles(files); err != nil || c != a || m.Refs(a) != 3 {
+		t.Errorf("Expected identical preprocessed sources to share a program: %v", err)
+	}
+}
+
+func TestManagerZeroValue(t *testing.T) {
+	m := &shader.Manager{Preprocessor: shader.NewPreprocessor()}
+	defer m.Clear()
+
+	if err := m.Release(nil); err == nil {
+		t.Error("Expected releasing from an empty manager to fail")
+	}
+	p, err := m.Program(map[shader.ShaderType]string{
+		shader.VertexShader:   testVertexShaderSource,
+		shader.FragmentShader: "#version 410 core\nout vec4 fragColor;\nvoid main() { fragColor = vec4(1.0); }",
+	})
+	if err != nil {
+		t.Fatal("Zero-value manager failed to build a program:", err)
+	}
+	if m.Refs(p) != 1 || m.Len() != 1 {
+		t.Errorf("Expected one program with one reference, got refs=%d len=%d", m.Refs(p), m.Len())
+	}
+	if err := m.Release(p); err != nil || p.ID != 0 {
+		t.Errorf("Release should delete the program: %v", err)
+	}
+}
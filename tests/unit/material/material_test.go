@@ -0,0 +1,352 @@
+package material_test
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+	"github.com/go-gl/glfw/v3.3/glfw"
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/yossideutsch/gogl/pkg/material"
+	"github.com/yossideutsch/gogl/pkg/resource"
+)
+
+func TestMain(m *testing.M) {
+	if err := glfw.Init(); err != nil {
+		panic("Failed to initialize GLFW: " + err.Error())
+	}
+	defer glfw.Terminate()
+
+	glfw.WindowHint(glfw.ContextVersionMajor, 4)
+	glfw.WindowHint(glfw.ContextVersionMinor, 1)
+	glfw.WindowHint(glfw.OpenGLProfile, glfw.OpenGLCoreProfile)
+	glfw.WindowHint(glfw.OpenGLForwardCompatible, glfw.True)
+	glfw.WindowHint(glfw.Visible, glfw.False)
+
+	window, err := glfw.CreateWindow(100, 100, "Test", nil, nil)
+	if err != nil {
+		panic("Failed to create test window: " + err.Error())
+	}
+	defer window.Destroy()
+	window.MakeContextCurrent()
+
+	if err := gl.Init(); err != nil {
+		panic("Failed to initialize OpenGL: " + err.Error())
+	}
+
+	os.Exit(m.Run())
+}
+
+const arrayVertexSource = `#version 410 core
+layout(location = 0) in vec3 aPosition;
+uniform mat4 uModel;
+void main() { gl_Position = uModel * vec4(aPosition, 1.0); }`
+
+const arrayFragmentSource = `#version 410 core
+uniform sampler2DArray uLayers;
+uniform vec3 uColors[2];
+uniform float uWeights[3];
+uniform int uMode;
+uniform bool uEnabled;
+out vec4 fragColor;
+void main() {
+    vec3 color = uColors[0] * uWeights[0] + uColors[1] * uWeights[2] + texture(uLayers, vec3(0.5, 0.5, float(uMode))).rgb;
+    fragColor = vec4(uEnabled ? color : vec3(uWeights[1]), 1.0);
+}`
+
+// writeFiles writes files into a temporary directory and returns it
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+// writeImage writes a 2x2 PNG filled with c
+func writeImage(t *testing.T, path string, c color.RGBA) {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, 2, 2))
+	for i := 0; i < len(img.Pix); i += 4 {
+		img.Pix[i], img.Pix[i+1], img.Pix[i+2], img.Pix[i+3] = c.R, c.G, c.B, c.A
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	if err := png.Encode(file, img); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadLibraryMaterial(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"brick.json": `{
+			"name": "brick",
+			"program": "textured",
+			"uniforms": {"uLightColor": [1, 0.5, 0.25]},
+			"textures": {"uTexture": "brick.png"}
+		}`,
+	})
+	writeImage(t, filepath.Join(dir, "brick.png"), color.RGBA{200, 80, 40, 255})
+
+	loader := material.NewLoader()
+	defer loader.Delete()
+
+	brick, err := loader.Load(filepath.Join(dir, "brick.json"))
+	if err != nil {
+		t.Fatal("Failed to load material:", err)
+	}
+	if value, _ := brick.Value("uLightColor"); value != (mgl32.Vec3{1, 0.5, 0.25}) {
+		t.Errorf("Expected uLightColor as mgl32.Vec3, got %T %v", value, value)
+	}
+	texture, ok := brick.Texture("uTexture")
+	if !ok {
+		t.Fatal("Expected uTexture to be loaded")
+	}
+	if tex, ok := texture.(*resource.Texture2D); !ok || tex.Width != 2 {
+		t.Fatalf("Expected a 2x2 *resource.Texture2D, got %T", texture)
+	}
+
+	if err := brick.Apply(); err != nil {
+		t.Fatal("Apply failed:", err)
+	}
+	var lightColor [3]float32
+	gl.GetUniformfv(brick.Program.ID, brick.Program.GetUniformLocation("uLightColor"), &lightColor[0])
+	if lightColor != [3]float32{1, 0.5, 0.25} {
+		t.Errorf("uLightColor is %v after Apply", lightColor)
+	}
+	var unit, bound int32
+	gl.GetUniformiv(brick.Program.ID, brick.Program.GetUniformLocation("uTexture"), &unit)
+	gl.ActiveTexture(gl.TEXTURE0)
+	gl.GetIntegerv(gl.TEXTURE_BINDING_2D, &bound)
+	if unit != 0 || uint32(bound) != texture.(*resource.Texture2D).ID {
+		t.Errorf("Expected uTexture on unit 0 with its texture bound, got unit %d texture %d", unit, bound)
+	}
+
+	// Materials from the same loader share programs and textures
+	again, err := loader.Load(filepath.Join(dir, "brick.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if shared, _ := again.Texture("uTexture"); again.Program != brick.Program || shared != texture {
+		t.Error("Expected the second material to share the program and texture")
+	}
+	gl.UseProgram(0)
+}
+
+func TestLoadShaderFileMaterial(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"array.vert": arrayVertexSource,
+		"array.frag": arrayFragmentSource,
+		"layers.json": `{
+			"shaders": {"vertex": "array.vert", "fragment": "array.frag"},
+			"uniforms": {
+				"uModel": [2, 0, 0, 0, 0, 2, 0, 0, 0, 0, 2, 0, 0, 0, 0, 1],
+				"uColors": [[1, 0, 0], [0, 1, 0]],
+				"uWeights": [0.5, 0.25],
+				"uMode": 1,
+				"uEnabled": true
+			},
+			"textures": {"uLayers": {"layers": ["a.png", "b.png"], "filter": "nearest", "wrap": "clamp"}}
+		}`,
+	})
+	writeImage(t, filepath.Join(dir, "a.png"), color.RGBA{255, 0, 0, 255})
+	writeImage(t, filepath.Join(dir, "b.png"), color.RGBA{0, 0, 255, 255})
+
+	loader := material.NewLoader()
+	defer loader.Delete()
+
+	m, err := loader.Load(filepath.Join(dir, "layers.json"))
+	if err != nil {
+		t.Fatal("Failed to load material:", err)
+	}
+
+	if v, _ := m.Value("uModel"); v != mgl32.Scale3D(2, 2, 2) {
+		t.Errorf("Unexpected uModel %T %v", v, v)
+	}
+	if v, _ := m.Value("uColors"); len(v.([]mgl32.Vec3)) != 2 {
+		t.Errorf("Unexpected uColors %T %v", v, v)
+	}
+	if v, _ := m.Value("uWeights"); len(v.([]float32)) != 2 {
+		t.Errorf("Unexpected uWeights %T %v", v, v)
+	}
+	if v, _ := m.Value("uMode"); v != int32(1) {
+		t.Errorf("Unexpected uMode %T %v", v, v)
+	}
+	if v, _ := m.Value("uEnabled"); v != true {
+		t.Errorf("Unexpected uEnabled %T %v", v, v)
+	}
+	texture, _ := m.Texture("uLayers")
+	if array, ok := texture.(*resource.TextureArray); !ok || array.Layers != 2 || array.Config.MinFilter != resource.FilterNearest {
+		t.Errorf("Expected a 2-layer nearest-filtered *resource.TextureArray, got %T", texture)
+	}
+
+	if err := m.Apply(); err != nil {
+		t.Fatal("Apply failed:", err)
+	}
+	gl.UseProgram(0)
+}
+
+func TestSaveMaterial(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"brick.json": `{"name": "brick", "program": "textured", "textures": {"uTexture": "brick.png"}}`,
+	})
+	writeImage(t, filepath.Join(dir, "brick.png"), color.RGBA{200, 80, 40, 255})
+
+	loader := material.NewLoader()
+	defer loader.Delete()
+
+	brick, err := loader.Load(filepath.Join(dir, "brick.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := brick.Set("uLightPos", mgl32.Vec3{1, 2, 3}); err != nil {
+		t.Fatal(err)
+	}
+	saved := filepath.Join(dir, "saved.json")
+	if err := brick.Save(saved); err != nil {
+		t.Fatal("Save failed:", err)
+	}
+	data, err := os.ReadFile(saved)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"uTexture": "brick.png"`) {
+		t.Errorf("Expected the texture written as its path, got:\n%s", data)
+	}
+
+	reloaded, err := loader.Load(saved)
+	if err != nil {
+		t.Fatal("Failed to load saved material:", err)
+	}
+	if v, _ := reloaded.Value("uLightPos"); v != (mgl32.Vec3{1, 2, 3}) || reloaded.Name != "brick" {
+		t.Errorf("Saved material did not round-trip: %q %v", reloaded.Name, v)
+	}
+
+	// Textures created in code have no file to save
+	tex, err := resource.NewTexture2D(1, 1, resource.FormatRGBA, resource.DefaultTextureConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tex.Delete()
+	if err := reloaded.SetTexture("uTexture", tex); err != nil {
+		t.Fatal(err)
+	}
+	if err := reloaded.Save(saved); err == nil || !strings.Contains(err.Error(), "was not loaded from a file") {
+		t.Errorf("Expected saving a texture without a file to fail, got %v", err)
+	}
+
+	if err := material.New("code", brick.Program).Save(saved); err == nil {
+		t.Error("Expected saving a material without a program source to fail")
+	}
+}
+
+func TestMaterialErrors(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"array.vert": arrayVertexSource,
+		"array.frag": arrayFragmentSource,
+	})
+	writeImage(t, filepath.Join(dir, "brick.png"), color.RGBA{200, 80, 40, 255})
+
+	loader := material.NewLoader()
+	defer loader.Delete()
+
+	cases := []struct {
+		doc  string
+		want string
+	}{
+		{`{"name": "m"}`, `needs either a library "program" or "shaders"`},
+		{`{"program": "textured", "shaders": {"vertex": "array.vert"}}`, `needs either a library "program" or "shaders"`},
+		{`{"program": "missing"}`, `unknown library program "missing"`},
+		{`{"shaders": {"pixel": "array.frag"}}`, `unknown shader stage "pixel"`},
+		{`{"program": "textured", "uniforms": {"uMissing": 1}}`, `uniform "uMissing" is not an active uniform`},
+		{`{"program": "textured", "uniforms": {"uLightColor": [1, 0]}}`, "expected an array of 3 numbers for vec3"},
+		{`{"program": "textured", "uniforms": {"uLightColor": 1}}`, "expected an array of 3 numbers for vec3"},
+		{`{"program": "textured", "uniforms": {"uTexture": 0}}`, "use SetTexture"},
+		{`{"program": "textured", "textures": {"uLightColor": "brick.png"}}`, "not a sampler"},
+		{`{"program": "textured", "textures": {"uTexture": {"path": "brick.png", "filter": "cubic"}}}`, `unknown texture filter "cubic"`},
+		{`{"program": "textured", "textures": {"uTexture": "missing.png"}}`, "failed to open texture file"},
+		{`{"shaders": {"vertex": "array.vert", "fragment": "array.frag"}, "uniforms": {"uWeights": [1, 2, 3, 4]}}`, "expected 1 to 3 elements for float[3]"},
+		{`{"program": 1}`, "invalid material JSON"},
+	}
+	for _, tt := range cases {
+		if _, err := loader.Parse([]byte(tt.doc), dir); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Parse(%s): expected error containing %q, got %v", tt.doc, tt.want, err)
+		}
+	}
+
+	m, err := loader.Parse([]byte(`{"program": "textured"}`), dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.SetTexture("uTexture", nil); err == nil {
+		t.Error("Expected a nil texture to be rejected")
+	}
+	if err := m.Set("uLightColor", float32(1)); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Apply(); err == nil || !strings.Contains(err.Error(), "cannot set it from float32") {
+		t.Errorf("Expected Apply to report the mismatched Go type, got %v", err)
+	}
+	gl.UseProgram(0)
+}
+
+func TestMaterialZeroValue(t *testing.T) {
+	dir := t.TempDir()
+	writeImage(t, filepath.Join(dir, "brick.png"), color.RGBA{200, 80, 40, 255})
+
+	var loader material.Loader
+	defer loader.Delete()
+	loaded, err := loader.Parse([]byte(`{"program": "textured", "textures": {"uTexture": "brick.png"}}`), dir)
+	if err != nil {
+		t.Fatal("Zero Loader failed to parse a material:", err)
+	}
+
+	m := &material.Material{Name: "literal", Program: loaded.Program}
+	if err := m.Set("uLightColor", mgl32.Vec3{1, 1, 1}); err != nil {
+		t.Fatal("Set failed on a Material literal:", err)
+	}
+	texture, _ := loaded.Texture("uTexture")
+	if err := m.SetTexture("uTexture", texture); err != nil {
+		t.Fatal("SetTexture failed on a Material literal:", err)
+	}
+	if err := m.Apply(); err != nil {
+		t.Error("Apply failed on a Material literal:", err)
+	}
+	gl.UseProgram(0)
+}
+
+func TestLoaderRejectedStagesDeleteShaders(t *testing.T) {
+	dir := writeFiles(t, map[string]string{"array.vert": arrayVertexSource})
+
+	// liveShaders counts the shader objects among the first few hundred names
+	liveShaders := func() int {
+		n := 0
+		for id := uint32(1); id < 512; id++ {
+			if gl.IsShader(id) {
+				n++
+			}
+		}
+		return n
+	}
+
+	loader := material.NewLoader()
+	defer loader.Delete()
+	before := liveShaders()
+	if _, err := loader.Parse([]byte(`{"shaders": {"vertex": "array.vert"}}`), dir); err == nil {
+		t.Error("Expected a vertex-only material to fail")
+	}
+	if after := liveShaders(); after != before {
+		t.Errorf("Rejected program leaked %d shaders", after-before)
+	}
+}
@@ -0,0 +1,118 @@
+package shader_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/yossideutsch/gogl/pkg/shader"
+)
+
+const ownershipFragmentSource = "#version 410 core\nout vec4 fragColor;\nvoid main() { fragColor = vec4(1.0); }"
+
+// compilePair compiles the test vertex shader and a fragment shader
+func compilePair(t *testing.T) (*shader.Shader, *shader.Shader) {
+	t.Helper()
+	vs, err := shader.CompileShader(testVertexShaderSource, shader.VertexShader)
+	if err != nil {
+		t.Fatal("Failed to compile vertex shader:", err)
+	}
+	fs, err := shader.CompileShader(ownershipFragmentSource, shader.FragmentShader)
+	if err != nil {
+		vs.Delete()
+		t.Fatal("Failed to compile fragment shader:", err)
+	}
+	return vs, fs
+}
+
+func TestOwnShaders(t *testing.T) {
+	vs, fs := compilePair(t)
+	program, err := shader.CreateProgram(vs, fs)
+	if err != nil {
+		t.Fatal("Failed to link program:", err)
+	}
+	program.Delete()
+	if vs.ID != 0 || fs.ID != 0 {
+		t.Error("Deleting the program should delete its shaders")
+	}
+
+	// Linking a deleted shader explains what went wrong
+	_, err = shader.CreateProgram(vs, fs)
+	if err == nil || !strings.Contains(err.Error(), "vertex shader was deleted before linking") {
+		t.Errorf("Expected a deleted shader error, got %v", err)
+	}
+}
+
+func TestBorrowShaders(t *testing.T) {
+	vs, fs := compilePair(t)
+	defer vs.Delete()
+	defer fs.Delete()
+
+	opts := shader.ProgramOptions{Ownership: shader.BorrowShaders}
+	a, err := shader.CreateProgramWithOptions(opts, vs, fs)
+	if err != nil {
+		t.Fatal("Failed to link first program:", err)
+	}
+	b, err := shader.CreateProgramWithOptions(opts, vs, fs)
+	if err != nil {
+		t.Fatal("Failed to link second program:", err)
+	}
+	defer b.Delete()
+
+	a.Delete()
+	if vs.ID == 0 || fs.ID == 0 {
+		t.Fatal("Deleting a borrowing program must not delete its shaders")
+	}
+	if err := b.Validate(); err != nil {
+		t.Errorf("Remaining program should still work: %v", err)
+	}
+
+	// Failed links leave borrowed shaders alone too
+	if _, err := shader.CreateProgramWithOptions(shader.ProgramOptions{
+		Ownership:                 shader.BorrowShaders,
+		TransformFeedbackVaryings: []string{"missing"},
+	}, vs, fs); err == nil {
+		t.Error("Expected linking an unknown varying to fail")
+	}
+	if vs.ID == 0 || fs.ID == 0 {
+		t.Error("A failed link must not delete borrowed shaders")
+	}
+}
+
+func TestReleaseShaders(t *testing.T) {
+	vs, fs := compilePair(t)
+	program, err := shader.CreateProgramWithOptions(shader.ProgramOptions{Ownership: shader.ReleaseShaders}, vs, fs)
+	if err != nil {
+		t.Fatal("Failed to link program:", err)
+	}
+	defer program.Delete()
+
+	if vs.ID != 0 || fs.ID != 0 {
+		t.Error("Shaders should be deleted right after linking")
+	}
+	if _, ok := program.Attribute("aPosition"); !ok {
+		t.Error("Program should still be reflected")
+	}
+	if err := program.Validate(); err != nil {
+		t.Errorf("Program should keep working without its shaders: %v", err)
+	}
+
+	// In-memory shaders cannot be compiled again
+	if err := program.Reload(); err == nil || !strings.Contains(err.Error(), "was deleted") {
+		t.Errorf("Expected reloading released in-memory shaders to fail, got %v", err)
+	}
+	if program.ID == 0 {
+		t.Error("Failed reload should keep the program")
+	}
+}
+
+func TestShaderOwnershipString(t *testing.T) {
+	for ownership, want := range map[shader.ShaderOwnership]string{
+		shader.OwnShaders:     "own",
+		shader.BorrowShaders:  "borrow",
+		shader.ReleaseShaders: "release",
+	} {
+		if got := ownership.String(); got != want {
+			t.Errorf("%d.String() = %q, want %q", int(ownership), got, want)
+		}
+	}
+}
@@ -267,4 +267,94 @@ func TestPipelineClearOperations(t *testing.T) {
 	p.Clear(true, true, false)
 	p.Clear(false, true, true)
 	p.Clear(true, false, true)
-}
\ No newline at end of file
+}
+
+func TestProgramPipelineState(t *testing.T) {
+	p := pipeline.New()
+
+	vertexProgram, err := shader.CreateShaderProgram(`#version 410 core
+layout(location = 0) in vec2 aPosition;
+out gl_PerVertex { vec4 gl_Position; };
+layout(location = 0) out vec2 vTexCoord;
+void main() {
+    vTexCoord = aPosition * 0.5 + 0.5;
+    gl_Position = vec4(aPosition, 0.0, 1.0);
+}`, shader.VertexShader)
+	if err != nil {
+		t.Fatal("Failed to create vertex program:", err)
+	}
+	defer vertexProgram.Delete()
+
+	fragmentProgram, err := shader.CreateShaderProgram(`#version 410 core
+layout(location = 0) in vec2 vTexCoord;
+out vec4 fragColor;
+void main() {
+    fragColor = vec4(vTexCoord, 0.0, 1.0);
+}`, shader.FragmentShader)
+	if err != nil {
+		t.Fatal("Failed to create fragment program:", err)
+	}
+	defer fragmentProgram.Delete()
+
+	programPipeline, err := shader.NewProgramPipeline()
+	if err != nil {
+		t.Fatal("Failed to create program pipeline:", err)
+	}
+	defer programPipeline.Delete()
+
+	if err := programPipeline.UseStages(vertexProgram); err != nil {
+		t.Fatal("Failed to use vertex stage:", err)
+	}
+	if err := programPipeline.UseStages(fragmentProgram, shader.FragmentShader); err != nil {
+		t.Fatal("Failed to use fragment stage:", err)
+	}
+	if err := programPipeline.UseStages(fragmentProgram, shader.VertexShader); err == nil {
+		t.Error("Using a stage the program does not contain should fail")
+	}
+
+	state := pipeline.NewBuilder().
+		WithProgramPipeline(programPipeline).
+		Build()
+	if err := state.Validate(); err != nil {
+		t.Fatal("Pipeline state should be valid:", err)
+	}
+	if err := p.SetState(state); err != nil {
+		t.Fatal("Failed to set pipeline state:", err)
+	}
+	if err := programPipeline.Validate(); err != nil {
+		t.Error("Program pipeline should validate:", err)
+	}
+
+	var bound, current int32
+	gl.GetIntegerv(gl.PROGRAM_PIPELINE_BINDING, &bound)
+	gl.GetIntegerv(gl.CURRENT_PROGRAM, &current)
+	if uint32(bound) != programPipeline.ID || current != 0 {
+		t.Errorf("Expected pipeline %d bound without a current program, got pipeline %d and program %d", programPipeline.ID, bound, current)
+	}
+
+	state.Program = vertexProgram
+	if err := state.Validate(); err == nil {
+		t.Error("State with both Program and ProgramPipeline should be invalid")
+	}
+
+	// The most recent of SetProgram and SetProgramPipeline is current
+	p.SetProgram(fragmentProgram)
+	p.SetProgramPipeline(programPipeline)
+	gl.GetIntegerv(gl.PROGRAM_PIPELINE_BINDING, &bound)
+	gl.GetIntegerv(gl.CURRENT_PROGRAM, &current)
+	if uint32(bound) != programPipeline.ID || current != 0 {
+		t.Errorf("SetProgramPipeline should replace the program, got pipeline %d and program %d", bound, current)
+	}
+	if p.GetState().Program != nil || p.GetState().ProgramPipeline != programPipeline {
+		t.Error("Pipeline state should hold only the program pipeline")
+	}
+
+	p.SetProgram(fragmentProgram)
+	gl.GetIntegerv(gl.CURRENT_PROGRAM, &current)
+	if uint32(current) != fragmentProgram.ID {
+		t.Errorf("SetProgram should make program %d current, got %d", fragmentProgram.ID, current)
+	}
+	if p.GetState().ProgramPipeline != nil || p.GetState().Validate() != nil {
+		t.Error("Pipeline state should hold only the program")
+	}
+}
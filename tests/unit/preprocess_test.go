@@ -0,0 +1,203 @@
+package shader_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/yossideutsch/gogl/pkg/shader"
+)
+
+// writeShaderFiles creates the given files below a temporary directory
+func writeShaderFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestPreprocessorIncludes(t *testing.T) {
+	dir := writeShaderFiles(t, map[string]string{
+		"main.frag": `#version 410 core
+#include "common/random.glsl"
+#include <lighting.glsl>
+out vec4 fragColor;
+void main() { fragColor = vec4(random(1u)); }
+`,
+		"common/random.glsl": `#pragma once
+#include "hash.glsl"
+float random(uint seed) { return float(hash(seed)) / 4294967296.0; }
+`,
+		"common/hash.glsl": `#ifndef HASH_GLSL
+#define HASH_GLSL
+uint hash(uint x) { return x * 2654435761u; }
+#endif
+`,
+		"lib/lighting.glsl": `#include "../common/random.glsl"
+#include "../common/hash.glsl"
+vec3 ambient() { return vec3(0.1); }
+`,
+	})
+
+	pp := shader.NewPreprocessor(filepath.Join(dir, "lib"))
+	src, err := pp.ProcessFile(filepath.Join(dir, "main.frag"))
+	if err != nil {
+		t.Fatal("Failed to preprocess:", err)
+	}
+
+	if n := strings.Count(src.Code, "uint hash(uint x)"); n != 1 {
+		t.Errorf("Expected hash() once, found %d times", n)
+	}
+	if n := strings.Count(src.Code, "float random("); n != 1 {
+		t.Errorf("Expected random() once, found %d times", n)
+	}
+	if strings.Contains(src.Code, "#include") {
+		t.Error("Expanded source still contains #include directives")
+	}
+	if len(src.Files) != 4 {
+		t.Errorf("Expected 4 contributing files, got %v", src.Files)
+	}
+
+	lines := strings.Split(src.Code, "\n")
+	for i, line := range lines {
+		if strings.HasPrefix(line, "vec3 ambient()") {
+			loc, ok := src.Map.Resolve(i + 1)
+			if !ok || filepath.Base(loc.File) != "lighting.glsl" || loc.Line != 3 {
+				t.Errorf("ambient() mapped to %v, expected lighting.glsl:3", loc)
+			}
+		}
+		if strings.HasPrefix(line, "out vec4 fragColor") {
+			loc, ok := src.Map.Resolve(i + 1)
+			if !ok || filepath.Base(loc.File) != "main.frag" || loc.Line != 4 {
+				t.Errorf("fragColor mapped to %v, expected main.frag:4", loc)
+			}
+		}
+	}
+}
+
+func TestPreprocessorErrors(t *testing.T) {
+	dir := writeShaderFiles(t, map[string]string{
+		"a.glsl":       "#include \"b.glsl\"\n",
+		"b.glsl":       "#include \"a.glsl\"\n",
+		"missing.glsl": "void f() {}\n#include \"nope.glsl\"\n",
+		"bad.glsl":     "#include nope.glsl\n",
+	})
+
+	pp := shader.NewPreprocessor()
+	tests := []struct {
+		file string
+		want string
+	}{
+		{"a.glsl", "include cycle"},
+		{"missing.glsl", "missing.glsl:2: cannot find include"},
+		{"bad.glsl", "malformed #include"},
+	}
+	for _, tt := range tests {
+		_, err := pp.ProcessFile(filepath.Join(dir, tt.file))
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: expected error containing %q, got %v", tt.file, tt.want, err)
+		}
+	}
+}
+
+func TestPreprocessorMutualGuards(t *testing.T) {
+	dir := writeShaderFiles(t, map[string]string{
+		"main.frag": "#version 410 core\n#include \"light.glsl\"\nvoid main() {}\n",
+		"light.glsl": `#ifndef LIGHT_GLSL
+#define LIGHT_GLSL
+#include "shadow.glsl"
+struct Light { vec3 position; };
+#endif
+`,
+		"shadow.glsl": `#ifndef SHADOW_GLSL
+#define SHADOW_GLSL
+#include "light.glsl"
+float shadow(vec3 p) { return 1.0; }
+#endif
+`,
+	})
+
+	src, err := shader.NewPreprocessor().ProcessFile(filepath.Join(dir, "main.frag"))
+	if err != nil {
+		t.Fatal("Guarded headers including each other should not be a cycle:", err)
+	}
+	if n := strings.Count(src.Code, "struct Light"); n != 1 {
+		t.Errorf("Expected Light once, found %d times", n)
+	}
+	if n := strings.Count(src.Code, "float shadow("); n != 1 {
+		t.Errorf("Expected shadow() once, found %d times", n)
+	}
+}
+
+func TestSourceMapRewriteLog(t *testing.T) {
+	pp := shader.NewPreprocessor()
+	src, err := pp.Process("#version 410 core\nvoid main() {\n  x = 1;\n}\n", "shaders/test.vert")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		vendor string
+		log    string
+		want   string
+	}{
+		{"Mesa", "0:3(3): error: `x' undeclared", "shaders/test.vert:3:3: error: `x' undeclared"},
+		{"NVIDIA", "0(3) : error C1008: undefined variable \"x\"", "shaders/test.vert:3 : error C1008: undefined variable \"x\""},
+		{"AMD", "ERROR: 0:3: 'x' : undeclared identifier", "ERROR: shaders/test.vert:3: 'x' : undeclared identifier"},
+	}
+	for _, tt := range tests {
+		if got := src.Map.RewriteLog(tt.log); got != filepath.FromSlash(tt.want) {
+			t.Errorf("%s: got %q, want %q", tt.vendor, got, tt.want)
+		}
+	}
+}
+
+func TestPreprocessorFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"shaders/fragment/post.frag": {Data: []byte(`#version 410 core
+#include "../common/tonemap.glsl"
+#include <noise.glsl>
+out vec4 fragColor;
+void main() { fragColor = vec4(tonemap(vec3(noise())), 1.0); }
+`)},
+		"shaders/common/tonemap.glsl": {Data: []byte("#pragma once\nvec3 tonemap(vec3 c) { return c / (c + 1.0); }\n")},
+		"shaders/lib/noise.glsl":      {Data: []byte("float noise() { return 0.5; }\n")},
+		"shaders/escape.frag":         {Data: []byte("#include \"../../outside.glsl\"\n")},
+	}
+
+	pp := shader.NewFSPreprocessor(fsys, "shaders/lib")
+	src, err := pp.ProcessFile("shaders/fragment/post.frag")
+	if err != nil {
+		t.Fatal("Failed to preprocess from FS:", err)
+	}
+	want := []string{"shaders/fragment/post.frag", "shaders/common/tonemap.glsl", "shaders/lib/noise.glsl"}
+	if strings.Join(src.Files, ",") != strings.Join(want, ",") {
+		t.Errorf("Expected files %v, got %v", want, src.Files)
+	}
+
+	lines := strings.Split(src.Code, "\n")
+	for i, line := range lines {
+		if strings.HasPrefix(line, "vec3 tonemap") {
+			if loc, _ := src.Map.Resolve(i + 1); loc.String() != "shaders/common/tonemap.glsl:2" {
+				t.Errorf("tonemap() mapped to %v", loc)
+			}
+		}
+	}
+
+	if _, err := pp.ProcessFile("shaders/escape.frag"); err == nil || !strings.Contains(err.Error(), "shaders/escape.frag:1: cannot find include") {
+		t.Errorf("Expected include outside the FS root to be reported, got %v", err)
+	}
+	if _, err := pp.ProcessFile("shaders/missing.frag"); err == nil || !strings.Contains(err.Error(), "shaders/missing.frag") {
+		t.Errorf("Expected missing file to be named in the error, got %v", err)
+	}
+}
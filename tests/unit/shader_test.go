@@ -1,12 +1,17 @@
 package shader_test
 
 import (
+	"errors"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+	"testing/fstest"
+	"time"
 
 	"github.com/go-gl/gl/v4.1-core/gl"
 	"github.com/go-gl/glfw/v3.3/glfw"
+	"github.com/go-gl/mathgl/mgl32"
 	"github.com/yossideutsch/gogl/pkg/shader"
 )
 
@@ -282,4 +287,750 @@ void main() {
 	if err == nil {
 		t.Error("Expected error for nil matrix in SetUniformMatrix4fv")
 	}
-}
\ No newline at end of file
+}
+func TestProgramReflection(t *testing.T) {
+	vertexSource := `#version 410 core
+layout(location = 0) in vec3 aPosition;
+layout(location = 1) in vec2 aTexCoord;
+uniform mat4 uModelViewProjection;
+out vec2 vTexCoord;
+void main() {
+    vTexCoord = aTexCoord;
+    gl_Position = uModelViewProjection * vec4(aPosition, 1.0);
+}`
+
+	fragmentSource := `#version 410 core
+in vec2 vTexCoord;
+uniform sampler2D uTexture;
+uniform float uWeights[4];
+layout(std140) uniform Lighting {
+    vec3 uLightPos;
+    float uIntensity;
+};
+out vec4 fragColor;
+void main() {
+    float w = uWeights[0] + uWeights[1] + uWeights[2] + uWeights[3];
+    fragColor = texture(uTexture, vTexCoord) * w * uIntensity + vec4(uLightPos, 0.0);
+}`
+
+	vertexShader, err := shader.CompileShader(vertexSource, shader.VertexShader)
+	if err != nil {
+		t.Fatal("Failed to compile vertex shader:", err)
+	}
+	fragmentShader, err := shader.CompileShader(fragmentSource, shader.FragmentShader)
+	if err != nil {
+		t.Fatal("Failed to compile fragment shader:", err)
+	}
+
+	program, err := shader.CreateProgram(vertexShader, fragmentShader)
+	if err != nil {
+		t.Fatal("Failed to create program:", err)
+	}
+	defer program.Delete()
+
+	tests := []struct {
+		name     string
+		dataType shader.DataType
+		size     int32
+		inBlock  bool
+	}{
+		{"uModelViewProjection", shader.TypeMat4, 1, false},
+		{"uTexture", shader.TypeSampler2D, 1, false},
+		{"uWeights", shader.TypeFloat, 4, false},
+		{"uLightPos", shader.TypeVec3, 1, true},
+		{"uIntensity", shader.TypeFloat, 1, true},
+	}
+
+	for _, tt := range tests {
+		info, ok := program.Uniform(tt.name)
+		if !ok {
+			t.Errorf("Uniform %s not reflected", tt.name)
+			continue
+		}
+		if info.Type != tt.dataType {
+			t.Errorf("Uniform %s: expected type %s, got %s", tt.name, tt.dataType, info.Type)
+		}
+		if info.Size != tt.size {
+			t.Errorf("Uniform %s: expected size %d, got %d", tt.name, tt.size, info.Size)
+		}
+		if tt.inBlock != (info.BlockIndex >= 0) {
+			t.Errorf("Uniform %s: unexpected block index %d", tt.name, info.BlockIndex)
+		}
+		if !tt.inBlock && info.Location != program.GetUniformLocation(tt.name) {
+			t.Errorf("Uniform %s: reflected location does not match GetUniformLocation", tt.name)
+		}
+	}
+
+	if _, ok := program.Uniform("uWeights[0]"); !ok {
+		t.Error("Array uniform should be found with [0] suffix")
+	}
+
+	attr, ok := program.Attribute("aTexCoord")
+	if !ok {
+		t.Fatal("Attribute aTexCoord not reflected")
+	}
+	if attr.Location != 1 || attr.Type != shader.TypeVec2 {
+		t.Errorf("Unexpected aTexCoord reflection: %+v", attr)
+	}
+
+	block, ok := program.UniformBlock("Lighting")
+	if !ok {
+		t.Fatal("Uniform block Lighting not reflected")
+	}
+	if len(block.Members) != 2 {
+		t.Fatalf("Expected 2 block members, got %d", len(block.Members))
+	}
+	if block.Members[0].Name != "uLightPos" || block.Members[1].Offset != 12 {
+		t.Errorf("Unexpected std140 block layout: %+v", block.Members)
+	}
+}
+
+func TestTypedUniformSetters(t *testing.T) {
+	vertexSource := `#version 410 core
+layout(location = 0) in vec3 aPosition;
+layout(location = 1) in vec3 aNormal;
+uniform mat4 uModel;
+uniform mat3 uNormalMatrix;
+uniform mat2x3 uOffsets[2];
+out vec3 vNormal;
+void main() {
+    vNormal = uNormalMatrix * aNormal;
+    vec3 offset = uOffsets[0] * vec2(1.0) + uOffsets[1] * vec2(1.0);
+    gl_Position = uModel * vec4(aPosition + offset, 1.0);
+}`
+
+	fragmentSource := `#version 410 core
+in vec3 vNormal;
+uniform sampler2D uTexture;
+uniform vec2 uViewportSize;
+uniform vec4 uTint;
+uniform bool uEnabled;
+uniform uint uMode;
+uniform float uWeights[3];
+out vec4 fragColor;
+void main() {
+    vec4 base = texture(uTexture, gl_FragCoord.xy / uViewportSize) * uTint;
+    float w = uWeights[0] + uWeights[1] + uWeights[2] + float(uMode);
+    fragColor = uEnabled ? base * w + vec4(vNormal, 0.0) : vec4(0.0);
+}`
+
+	vertexShader, err := shader.CompileShader(vertexSource, shader.VertexShader)
+	if err != nil {
+		t.Fatal("Failed to compile vertex shader:", err)
+	}
+	fragmentShader, err := shader.CompileShader(fragmentSource, shader.FragmentShader)
+	if err != nil {
+		t.Fatal("Failed to compile fragment shader:", err)
+	}
+
+	program, err := shader.CreateProgram(vertexShader, fragmentShader)
+	if err != nil {
+		t.Fatal("Failed to create program:", err)
+	}
+	defer program.Delete()
+	program.Use()
+
+	normalMatrix := mgl32.Ident3()
+	valid := []struct {
+		name string
+		set  func() error
+	}{
+		{"mat3", func() error {
+			return program.SetUniformMatrix3fv(program.GetUniformLocation("uNormalMatrix"), &normalMatrix)
+		}},
+		{"sampler2D", func() error { return program.SetUniformSampler(program.GetUniformLocation("uTexture"), 0) }},
+		{"vec2", func() error { return program.SetUniform2f(program.GetUniformLocation("uViewportSize"), 800, 600) }},
+		{"vec4", func() error {
+			return program.SetUniformVec4(program.GetUniformLocation("uTint"), mgl32.Vec4{1, 1, 1, 1})
+		}},
+		{"bool", func() error { return program.SetUniformBool(program.GetUniformLocation("uEnabled"), true) }},
+		{"uint", func() error { return program.SetUniform1ui(program.GetUniformLocation("uMode"), 2) }},
+		{"float[]", func() error {
+			return program.SetUniform1fv(program.GetUniformLocation("uWeights"), []float32{0.2, 0.3, 0.5})
+		}},
+		{"mat2x3[]", func() error {
+			return program.SetUniformMatrix2x3fvArray(program.GetUniformLocation("uOffsets"), make([]mgl32.Mat3x2, 2))
+		}},
+	}
+	for _, tt := range valid {
+		if err := tt.set(); err != nil {
+			t.Errorf("Setting %s uniform failed: %v", tt.name, err)
+		}
+	}
+
+	invalid := []struct {
+		name string
+		set  func() error
+	}{
+		{"float as mat3", func() error { return program.SetUniform1f(program.GetUniformLocation("uNormalMatrix"), 1) }},
+		{"vec3 as vec2", func() error { return program.SetUniform3f(program.GetUniformLocation("uViewportSize"), 1, 2, 3) }},
+		{"int as uint", func() error { return program.SetUniform1i(program.GetUniformLocation("uMode"), 1) }},
+		{"array overflow", func() error { return program.SetUniform1fv(program.GetUniformLocation("uWeights"), make([]float32, 4)) }},
+		{"mat3x2[] as mat2x3[]", func() error {
+			return program.SetUniformMatrix3x2fvArray(program.GetUniformLocation("uOffsets"), make([]mgl32.Mat2x3, 2))
+		}},
+		{"mat2x3[] overflow", func() error {
+			return program.SetUniformMatrix2x3fvArray(program.GetUniformLocation("uOffsets"), make([]mgl32.Mat3x2, 3))
+		}},
+		{"ragged vec2 array", func() error {
+			return program.SetUniform2fv(program.GetUniformLocation("uViewportSize"), []float32{1, 2, 3})
+		}},
+	}
+	for _, tt := range invalid {
+		if err := tt.set(); err == nil {
+			t.Errorf("Expected error for %s", tt.name)
+		}
+	}
+}
+
+func TestSetUniformByName(t *testing.T) {
+	vertexSource := `#version 410 core
+layout(location = 0) in vec3 aPosition;
+uniform mat4 uModel;
+void main() {
+    gl_Position = uModel * vec4(aPosition, 1.0);
+}`
+
+	fragmentSource := `#version 410 core
+uniform float uTime;
+uniform sampler2D uTexture;
+uniform vec3 uColors[2];
+out vec4 fragColor;
+void main() {
+    fragColor = texture(uTexture, vec2(sin(uTime))) + vec4(uColors[0] + uColors[1], 1.0);
+}`
+
+	vertexShader, err := shader.CompileShader(vertexSource, shader.VertexShader)
+	if err != nil {
+		t.Fatal("Failed to compile vertex shader:", err)
+	}
+	fragmentShader, err := shader.CompileShader(fragmentSource, shader.FragmentShader)
+	if err != nil {
+		t.Fatal("Failed to compile fragment shader:", err)
+	}
+
+	program, err := shader.CreateProgram(vertexShader, fragmentShader)
+	if err != nil {
+		t.Fatal("Failed to create program:", err)
+	}
+	defer program.Delete()
+	program.Use()
+
+	tests := []struct {
+		name    string
+		value   interface{}
+		wantErr bool
+	}{
+		{"uTime", float32(1.5), false},
+		{"uTime", 1.5, false},
+		{"uModel", mgl32.Ident4(), false},
+		{"uTexture", 0, false},
+		{"uColors", []mgl32.Vec3{{1, 0, 0}, {0, 1, 0}}, false},
+		{"uColors[1]", mgl32.Vec3{0, 0, 1}, false},
+		{"uTime", mgl32.Ident4(), true},
+		{"uModel", float32(1), true},
+		{"uColors[2]", mgl32.Vec3{}, true},
+		{"uColors[1]", []mgl32.Vec3{{}, {}}, true},
+		{"uTiem", float32(1), true},
+		{"uTime", "1.5", true},
+	}
+
+	for _, tt := range tests {
+		err := program.Set(tt.name, tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Set(%q, %T): unexpected error state: %v", tt.name, tt.value, err)
+		}
+	}
+}
+
+func TestStructuredCompileError(t *testing.T) {
+	dir := writeShaderFiles(t, map[string]string{
+		"broken.frag": `#version 410 core
+#include "helpers.glsl"
+out vec4 fragColor;
+void main() {
+    fragColor = vec4(helper(), 1.0);
+}
+`,
+		"helpers.glsl": `vec3 helper() {
+    return undeclaredValue;
+}
+`,
+	})
+
+	_, err := shader.CompileShaderFromFile(filepath.Join(dir, "broken.frag"), shader.FragmentShader)
+	var compileErr *shader.CompileError
+	if !errors.As(err, &compileErr) {
+		t.Fatalf("Expected *shader.CompileError, got %T: %v", err, err)
+	}
+	if compileErr.Stage != shader.FragmentShader {
+		t.Errorf("Expected fragment stage, got %s", compileErr.Stage)
+	}
+	if len(compileErr.Diagnostics) == 0 {
+		t.Fatalf("Expected parsed diagnostics, log was: %s", compileErr.Log)
+	}
+
+	d := compileErr.Diagnostics[0]
+	if filepath.Base(d.File) != "helpers.glsl" || d.Line != 2 {
+		t.Errorf("Expected error at helpers.glsl:2, got %s:%d", d.File, d.Line)
+	}
+	if !strings.Contains(d.SourceLine, "undeclaredValue") {
+		t.Errorf("Expected source excerpt of the failing line, got %q", d.SourceLine)
+	}
+}
+
+func TestCompileSource(t *testing.T) {
+	dir := writeShaderFiles(t, map[string]string{
+		"source.frag": "#version 410 core\n#include \"color.glsl\"\nout vec4 fragColor;\nvoid main() { fragColor = vec4(color(), 1.0); }\n",
+		"color.glsl":  "vec3 color() { return vec3(1.0); }\n",
+	})
+	path := filepath.Join(dir, "source.frag")
+
+	pp := shader.NewPreprocessor()
+	src, err := pp.ProcessFile(path)
+	if err != nil {
+		t.Fatal("Failed to preprocess:", err)
+	}
+	// Changing the file afterwards must not affect the inspected source
+	if err := os.WriteFile(path, []byte("broken"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	s, err := pp.CompileSource(src, path, shader.FragmentShader)
+	if err != nil {
+		t.Fatal("Failed to compile preprocessed source:", err)
+	}
+	defer s.Delete()
+
+	if _, err := pp.CompileSource(nil, path, shader.FragmentShader); err == nil {
+		t.Error("Expected an error for a nil source")
+	}
+}
+
+func TestStructuredLinkError(t *testing.T) {
+	fragmentSource := `#version 410 core
+vec3 missing();
+out vec4 fragColor;
+void main() {
+    fragColor = vec4(missing(), 1.0);
+}`
+
+	vertexShader, err := shader.CompileShader(testVertexShaderSource, shader.VertexShader)
+	if err != nil {
+		t.Fatal("Failed to compile vertex shader:", err)
+	}
+	defer vertexShader.Delete()
+
+	fragmentShader, err := shader.CompileShader(fragmentSource, shader.FragmentShader)
+	if err != nil {
+		t.Fatal("Failed to compile fragment shader:", err)
+	}
+	defer fragmentShader.Delete()
+
+	_, err = shader.CreateProgram(vertexShader, fragmentShader)
+	var linkErr *shader.LinkError
+	if !errors.As(err, &linkErr) {
+		t.Fatalf("Expected *shader.LinkError, got %T: %v", err, err)
+	}
+	if linkErr.Log == "" {
+		t.Error("Link error should carry the driver log")
+	}
+	if len(linkErr.Stages) != 2 || linkErr.Stages[0] != shader.VertexShader || linkErr.Stages[1] != shader.FragmentShader {
+		t.Errorf("Expected vertex and fragment stages, got %v", linkErr.Stages)
+	}
+}
+
+func TestReloadProgram(t *testing.T) {
+	dir := writeShaderFiles(t, map[string]string{
+		"reload.frag": `#version 410 core
+#include "color.glsl"
+out vec4 fragColor;
+void main() {
+    fragColor = vec4(baseColor(), 1.0);
+}
+`,
+		"color.glsl": "vec3 baseColor() { return vec3(1.0); }\n",
+	})
+	fragPath := filepath.Join(dir, "reload.frag")
+	colorPath := filepath.Join(dir, "color.glsl")
+
+	vertexShader, err := shader.CompileShader(testVertexShaderSource, shader.VertexShader)
+	if err != nil {
+		t.Fatal("Failed to compile vertex shader:", err)
+	}
+	fragmentShader, err := shader.CompileShaderFromFile(fragPath, shader.FragmentShader)
+	if err != nil {
+		t.Fatal("Failed to compile fragment shader:", err)
+	}
+	program, err := shader.CreateProgram(vertexShader, fragmentShader)
+	if err != nil {
+		t.Fatal("Failed to create program:", err)
+	}
+	defer program.Delete()
+
+	if files := program.SourceFiles(); len(files) != 2 {
+		t.Fatalf("Expected 2 source files, got %v", files)
+	}
+
+	reloader := shader.NewReloader()
+	reloader.Interval = 0
+	reloads := 0
+	reloader.OnReload = func(*shader.Program) { reloads++ }
+	if err := reloader.Watch(program); err != nil {
+		t.Fatal("Failed to watch program:", err)
+	}
+
+	// touch rewrites a file and moves its modification time forward
+	touch := func(path, content string, age time.Duration) {
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		stamp := time.Now().Add(age)
+		if err := os.Chtimes(path, stamp, stamp); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := reloader.Poll(); err != nil || reloads != 0 {
+		t.Fatalf("Unchanged files should not reload: %d reloads, err %v", reloads, err)
+	}
+
+	// A broken include keeps the old program running
+	oldID := program.ID
+	touch(colorPath, "vec3 baseColor() { return missingValue; }\n", time.Second)
+	err = reloader.Poll()
+	var reloadErr *shader.ReloadError
+	var compileErr *shader.CompileError
+	if !errors.As(err, &reloadErr) || !errors.As(err, &compileErr) {
+		t.Fatalf("Expected ReloadError wrapping CompileError, got %v", err)
+	}
+	if program.ID != oldID {
+		t.Error("Failed reload must not replace the program")
+	}
+	if filepath.Base(compileErr.Diagnostics[0].File) != "color.glsl" {
+		t.Errorf("Expected diagnostics in color.glsl, got %v", compileErr.Diagnostics)
+	}
+
+	// Fixing it swaps the program in place
+	touch(colorPath, "uniform vec3 uTint;\nvec3 baseColor() { return uTint; }\n", 2*time.Second)
+	if err := reloader.Poll(); err != nil {
+		t.Fatal("Reload failed:", err)
+	}
+	if reloads != 1 || program.ID == oldID || program.ID == 0 {
+		t.Errorf("Expected program to be swapped, reloads=%d id=%d", reloads, program.ID)
+	}
+	if _, ok := program.Uniform("uTint"); !ok {
+		t.Error("Reloaded program should reflect the new uniform")
+	}
+	if vertexShader.ID == 0 {
+		t.Error("In-memory shaders should be kept across reloads")
+	}
+}
+
+func TestReloaderLiteral(t *testing.T) {
+	dir := writeShaderFiles(t, map[string]string{
+		"plain.frag": "#version 410 core\nout vec4 fragColor;\nvoid main() { fragColor = vec4(1.0); }\n",
+	})
+
+	vertexShader, err := shader.CompileShader(testVertexShaderSource, shader.VertexShader)
+	if err != nil {
+		t.Fatal("Failed to compile vertex shader:", err)
+	}
+	fragmentShader, err := shader.CompileShaderFromFile(filepath.Join(dir, "plain.frag"), shader.FragmentShader)
+	if err != nil {
+		t.Fatal("Failed to compile fragment shader:", err)
+	}
+	program, err := shader.CreateProgram(vertexShader, fragmentShader)
+	if err != nil {
+		t.Fatal("Failed to create program:", err)
+	}
+	defer program.Delete()
+
+	reloader := &shader.Reloader{Interval: time.Second}
+	if err := reloader.Watch(program); err != nil {
+		t.Fatal("Failed to watch program:", err)
+	}
+	if err := reloader.Poll(); err != nil {
+		t.Error("Unchanged files should not fail to reload:", err)
+	}
+	reloader.Unwatch(program)
+}
+
+func TestBinaryCache(t *testing.T) {
+	dir := t.TempDir()
+	cache, err := shader.NewBinaryCache(dir)
+	if err != nil {
+		t.Fatal("Failed to create binary cache:", err)
+	}
+	if !cache.Supported() {
+		t.Skip("Driver exposes no program binary formats")
+	}
+
+	sources := map[shader.ShaderType]string{
+		shader.VertexShader: testVertexShaderSource,
+		shader.FragmentShader: `#version 410 core
+uniform vec3 uColor;
+out vec4 fragColor;
+void main() { fragColor = vec4(uColor, 1.0); }`,
+	}
+
+	first, err := cache.Program(sources)
+	if err != nil {
+		t.Fatal("Failed to build program:", err)
+	}
+	first.Delete()
+
+	second, err := cache.Program(sources)
+	if err != nil {
+		t.Fatal("Failed to load cached program:", err)
+	}
+	defer second.Delete()
+	if hits, misses := cache.Stats(); hits != 1 || misses != 1 {
+		t.Errorf("Expected 1 hit and 1 miss, got %d hits and %d misses", hits, misses)
+	}
+	if _, ok := second.Uniform("uColor"); !ok {
+		t.Error("Cached program should be reflected")
+	}
+
+	// Corrupt entries fall back to a full build
+	entries, _ := filepath.Glob(filepath.Join(dir, "*.bin"))
+	if len(entries) != 1 {
+		t.Fatalf("Expected 1 cache entry, got %v", entries)
+	}
+	if err := os.WriteFile(entries[0], []byte("garbage binary"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	third, err := cache.Program(sources)
+	if err != nil {
+		t.Fatal("Expected fallback to compile, got:", err)
+	}
+	defer third.Delete()
+	if hits, misses := cache.Stats(); hits != 1 || misses != 2 {
+		t.Errorf("Expected 1 hit and 2 misses, got %d hits and %d misses", hits, misses)
+	}
+
+	// The retrieval hint does not change the program, so it shares the entry
+	fourth, err := cache.ProgramWithOptions(shader.ProgramOptions{BinaryRetrievable: true}, sources)
+	if err != nil {
+		t.Fatal("Failed to load cached program with options:", err)
+	}
+	defer fourth.Delete()
+	if hits, misses := cache.Stats(); hits != 2 || misses != 2 {
+		t.Errorf("Expected 2 hits and 2 misses, got %d hits and %d misses", hits, misses)
+	}
+
+	// Link options that change the program get their own entry; borrowing
+	// makes no difference since the cache compiles the shaders itself
+	separable := shader.ProgramOptions{Separable: true}
+	for i := 0; i < 2; i++ {
+		program, err := cache.ProgramWithOptions(separable, sources)
+		if err != nil {
+			t.Fatal("Failed to get separable program:", err)
+		}
+		if !program.Separable() {
+			t.Error("Cached program should keep its link options")
+		}
+		program.Delete()
+	}
+	borrowed, err := cache.ProgramWithOptions(shader.ProgramOptions{Ownership: shader.BorrowShaders}, sources)
+	if err != nil {
+		t.Fatal("Failed to load cached program with borrowed shaders:", err)
+	}
+	defer borrowed.Delete()
+	if hits, misses := cache.Stats(); hits != 4 || misses != 3 {
+		t.Errorf("Expected 4 hits and 3 misses, got %d hits and %d misses", hits, misses)
+	}
+}
+
+func TestStageCombinations(t *testing.T) {
+	// Invalid combinations are rejected before any GL call, so placeholder IDs suffice
+	stage := func(shaderType shader.ShaderType) *shader.Shader {
+		return &shader.Shader{ID: 1, Type: shaderType}
+	}
+	invalid := []struct {
+		name    string
+		shaders []*shader.Shader
+		want    string
+	}{
+		{"fragment only", []*shader.Shader{stage(shader.FragmentShader)}, "vertex shader is required"},
+		{"vertex only", []*shader.Shader{stage(shader.VertexShader)}, "fragment shader is required"},
+		{"compute with vertex", []*shader.Shader{stage(shader.ComputeShader), stage(shader.VertexShader)}, "compute shaders cannot be linked"},
+		{"control without evaluation", []*shader.Shader{stage(shader.VertexShader), stage(shader.TessControlShader), stage(shader.FragmentShader)}, "requires a tessellation evaluation shader"},
+		{"unknown type", []*shader.Shader{stage(shader.ShaderType(0x1234))}, "invalid shader type"},
+	}
+	for _, tt := range invalid {
+		_, err := shader.CreateProgram(tt.shaders...)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: expected error containing %q, got %v", tt.name, tt.want, err)
+		}
+	}
+
+	tessControlSource := `#version 410 core
+layout(vertices = 3) out;
+void main() {
+    gl_out[gl_InvocationID].gl_Position = gl_in[gl_InvocationID].gl_Position;
+    gl_TessLevelOuter[0] = 4.0;
+    gl_TessLevelOuter[1] = 4.0;
+    gl_TessLevelOuter[2] = 4.0;
+    gl_TessLevelInner[0] = 4.0;
+}`
+	tessEvaluationSource := `#version 410 core
+layout(triangles, equal_spacing, ccw) in;
+void main() {
+    gl_Position = gl_TessCoord.x * gl_in[0].gl_Position +
+                  gl_TessCoord.y * gl_in[1].gl_Position +
+                  gl_TessCoord.z * gl_in[2].gl_Position;
+}`
+	fragmentSource := `#version 410 core
+out vec4 fragColor;
+void main() { fragColor = vec4(1.0); }`
+
+	var shaders []*shader.Shader
+	for _, s := range []struct {
+		source string
+		stage  shader.ShaderType
+	}{
+		{testVertexShaderSource, shader.VertexShader},
+		{tessControlSource, shader.TessControlShader},
+		{tessEvaluationSource, shader.TessEvaluationShader},
+		{fragmentSource, shader.FragmentShader},
+	} {
+		compiled, err := shader.CompileShader(s.source, s.stage)
+		if err != nil {
+			t.Fatalf("Failed to compile %s shader: %v", s.stage, err)
+		}
+		shaders = append(shaders, compiled)
+	}
+
+	program, err := shader.CreateProgram(shaders...)
+	if err != nil {
+		t.Fatal("Failed to link tessellation program:", err)
+	}
+	program.Delete()
+}
+
+func TestSeparablePrograms(t *testing.T) {
+	fragmentShader, err := shader.CompileShader(`#version 410 core
+out vec4 fragColor;
+void main() { fragColor = vec4(1.0); }`, shader.FragmentShader)
+	if err != nil {
+		t.Fatal("Failed to compile fragment shader:", err)
+	}
+
+	// Separable programs may consist of a single graphics stage
+	fragmentProgram, err := shader.CreateProgramWithOptions(shader.ProgramOptions{Separable: true}, fragmentShader)
+	if err != nil {
+		t.Fatal("Failed to link separable fragment program:", err)
+	}
+	defer fragmentProgram.Delete()
+
+	if !fragmentProgram.Separable() || !fragmentProgram.HasStage(shader.FragmentShader) || fragmentProgram.HasStage(shader.VertexShader) {
+		t.Errorf("Unexpected separable program stages %v", fragmentProgram.Stages())
+	}
+
+	vertexShader, err := shader.CompileShader(testVertexShaderSource, shader.VertexShader)
+	if err != nil {
+		t.Fatal("Failed to compile vertex shader:", err)
+	}
+	monolithic, err := shader.CreateProgram(vertexShader, fragmentShader)
+	if err != nil {
+		t.Fatal("Failed to create program:", err)
+	}
+	defer monolithic.Delete()
+
+	programPipeline, err := shader.NewProgramPipeline()
+	if err != nil {
+		t.Fatal("Failed to create program pipeline:", err)
+	}
+	defer programPipeline.Delete()
+
+	if err := programPipeline.UseStages(monolithic); err == nil {
+		t.Error("Non-separable programs must be rejected")
+	}
+	if err := programPipeline.UseStages(fragmentProgram); err != nil {
+		t.Fatal("Failed to use fragment stage:", err)
+	}
+	if programPipeline.Stage(shader.FragmentShader) != fragmentProgram {
+		t.Error("Fragment stage not recorded")
+	}
+
+	_, err = shader.CreateShaderProgram("#version 410 core\nvoid main() { undeclared = 1.0; }", shader.FragmentShader)
+	var compileErr *shader.CompileError
+	if !errors.As(err, &compileErr) {
+		t.Errorf("Expected *shader.CompileError, got %T: %v", err, err)
+	}
+}
+
+func TestReloadSeparableProgram(t *testing.T) {
+	dir := writeShaderFiles(t, map[string]string{
+		"stage.frag": "#version 410 core\nout vec4 fragColor;\nvoid main() { fragColor = vec4(1.0); }\n",
+	})
+
+	fragmentShader, err := shader.CompileShaderFromFile(filepath.Join(dir, "stage.frag"), shader.FragmentShader)
+	if err != nil {
+		t.Fatal("Failed to compile fragment shader:", err)
+	}
+	fragmentProgram, err := shader.CreateProgramWithOptions(shader.ProgramOptions{Separable: true}, fragmentShader)
+	if err != nil {
+		t.Fatal("Failed to link separable fragment program:", err)
+	}
+	defer fragmentProgram.Delete()
+
+	programPipeline, err := shader.NewProgramPipeline()
+	if err != nil {
+		t.Fatal("Failed to create program pipeline:", err)
+	}
+	defer programPipeline.Delete()
+	if err := programPipeline.UseStages(fragmentProgram); err != nil {
+		t.Fatal("Failed to use fragment stage:", err)
+	}
+	programPipeline.SetActiveProgram(fragmentProgram)
+
+	oldID := fragmentProgram.ID
+	if err := fragmentProgram.Reload(); err != nil {
+		t.Fatal("Reload failed:", err)
+	}
+	if fragmentProgram.ID == oldID {
+		t.Fatal("Expected reload to replace the program object")
+	}
+
+	// The pipeline has to follow the new program object
+	var stage, active int32
+	gl.GetProgramPipelineiv(programPipeline.ID, gl.FRAGMENT_SHADER, &stage)
+	gl.GetProgramPipelineiv(programPipeline.ID, gl.ACTIVE_PROGRAM, &active)
+	if uint32(stage) != fragmentProgram.ID || uint32(active) != fragmentProgram.ID {
+		t.Errorf("Pipeline uses program %d (active %d), expected %d", stage, active, fragmentProgram.ID)
+	}
+
+	// Deleting the program removes it from the pipeline
+	fragmentProgram.Delete()
+	if programPipeline.Stage(shader.FragmentShader) != nil || programPipeline.ActiveProgram() != nil {
+		t.Error("Deleted program should no longer provide pipeline stages")
+	}
+}
+
+func TestCompileShaderFromFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"shaders/color.frag":  {Data: []byte("#version 410 core\n#include \"util.glsl\"\nout vec4 fragColor;\nvoid main() { fragColor = vec4(color(), 1.0); }\n")},
+		"shaders/util.glsl":   {Data: []byte("vec3 color() { return vec3(1.0); }\n")},
+		"shaders/bad.frag":    {Data: []byte("#version 410 core\n#include \"broken.glsl\"\nvoid main() {}\n")},
+		"shaders/broken.glsl": {Data: []byte("float f() {\n    return missing;\n}\n")},
+	}
+
+	compiled, err := shader.CompileShaderFromFS(fsys, "shaders/color.frag", shader.FragmentShader)
+	if err != nil {
+		t.Fatal("Failed to compile shader from FS:", err)
+	}
+	defer compiled.Delete()
+	if files := compiled.Files(); len(files) != 2 || files[1] != "shaders/util.glsl" {
+		t.Errorf("Unexpected source files %v", files)
+	}
+
+	_, err = shader.CompileShaderFromFS(fsys, "shaders/bad.frag", shader.FragmentShader)
+	var compileErr *shader.CompileError
+	if !errors.As(err, &compileErr) || len(compileErr.Diagnostics) == 0 {
+		t.Fatalf("Expected *shader.CompileError with diagnostics, got %v", err)
+	}
+	if d := compileErr.Diagnostics[0]; d.File != "shaders/broken.glsl" || d.Line != 2 {
+		t.Errorf("Expected error at shaders/broken.glsl:2, got %s:%d", d.File, d.Line)
+	}
+}
@@ -0,0 +1,197 @@
+package shaderinfo_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+	"github.com/go-gl/glfw/v3.3/glfw"
+	"github.com/yossideutsch/gogl/internal/shaderinfo"
+	"github.com/yossideutsch/gogl/pkg/shader"
+)
+
+func TestMain(m *testing.M) {
+	if err := glfw.Init(); err != nil {
+		panic("Failed to initialize GLFW: " + err.Error())
+	}
+	defer glfw.Terminate()
+
+	glfw.WindowHint(glfw.ContextVersionMajor, 4)
+	glfw.WindowHint(glfw.ContextVersionMinor, 1)
+	glfw.WindowHint(glfw.OpenGLProfile, glfw.OpenGLCoreProfile)
+	glfw.WindowHint(glfw.OpenGLForwardCompatible, glfw.True)
+	glfw.WindowHint(glfw.Visible, glfw.False)
+
+	window, err := glfw.CreateWindow(100, 100, "Test", nil, nil)
+	if err != nil {
+		panic("Failed to create test window: " + err.Error())
+	}
+	defer window.Destroy()
+	window.MakeContextCurrent()
+
+	if err := gl.Init(); err != nil {
+		panic("Failed to initialize OpenGL: " + err.Error())
+	}
+
+	os.Exit(m.Run())
+}
+
+var testFiles = map[string]string{
+	"info.vert": `#version 410 core
+layout(location = 0) in vec3 aPosition;
+layout(location = 1) in vec3 aUnused;
+uniform mat4 uModel;
+void main() { gl_Position = uModel * vec4(aPosition, 1.0); }
+`,
+	"info.frag": `#version 410 core
+#include "common.glsl"
+layout(std140) uniform Material {
+    vec4 color;
+    float roughness;
+} material;
+layout(std140) uniform Unused {
+    vec4 nothing;
+};
+uniform float uWeights[2];
+out vec4 fragColor;
+void main() { fragColor = vec4(material.color.rgb * uWeights[1], 1.0); }
+`,
+	"common.glsl": "uniform float uDoesNothing;\n",
+	"subroutine.frag": `#version 410 core
+subroutine vec3 Shade(vec3 color);
+subroutine(Shade) vec3 plain(vec3 color) { return color; }
+subroutine(Shade) vec3 dark(vec3 color) { return color * 0.5; }
+subroutine uniform Shade uShade;
+subroutine uniform Shade uUnusedShade;
+out vec4 fragColor;
+void main() { fragColor = vec4(uShade(vec3(1.0)), 1.0); }
+`,
+}
+
+// inspect links the vertex test shader and fragment and inspects the program
+func inspect(t *testing.T, fragment string) *shaderinfo.Report {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range testFiles {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	pp := shader.NewPreprocessor()
+	var stages []shaderinfo.Stage
+	var compiled []*shader.Shader
+	for _, f := range []struct {
+		name  string
+		stage shader.ShaderType
+	}{{"info.vert", shader.VertexShader}, {fragment, shader.FragmentShader}} {
+		path := filepath.Join(dir, f.name)
+		src, err := pp.ProcessFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		s, err := shader.CompileShader(src.Code, f.stage)
+		if err != nil {
+			t.Fatalf("Failed to compile %s: %v", f.name, err)
+		}
+		compiled = append(compiled, s)
+		stages = append(stages, shaderinfo.Stage{Stage: f.stage, Path: path, Source: src})
+	}
+	program, err := shader.CreateProgramWithOptions(shader.ProgramOptions{BinaryRetrievable: true}, compiled...)
+	if err != nil {
+		t.Fatal("Failed to link program:", err)
+	}
+	t.Cleanup(program.Delete)
+	return shaderinfo.Inspect(program, stages)
+}
+
+func TestInspect(t *testing.T) {
+	r := inspect(t, "info.frag")
+
+	uniforms := make(map[string]shaderinfo.Uniform)
+	for _, u := range r.Uniforms {
+		uniforms[u.Name] = u
+	}
+	if u, ok := uniforms["uModel"]; !ok || u.Type != "mat4" || u.Location < 0 {
+		t.Errorf("Unexpected uModel %+v", u)
+	}
+	if u := uniforms["uWeights"]; u.Type != "float" || u.Size != 2 {
+		t.Errorf("Unexpected uWeights %+v", u)
+	}
+
+	inactive := make(map[string]shaderinfo.Declaration)
+	for _, d := range r.Inactive {
+		inactive[d.Name] = d
+	}
+	if d, ok := inactive["uDoesNothing"]; !ok || filepath.Base(d.File) != "common.glsl" || d.Line != 1 || d.Type != "float" {
+		t.Errorf("Expected uDoesNothing reported inactive at common.glsl:1, got %+v", r.Inactive)
+	}
+	if d, ok := inactive["Unused"]; !ok || d.Kind != "uniform block" {
+		t.Errorf("Expected the Unused block reported inactive, got %+v", r.Inactive)
+	}
+	for _, name := range []string{"uModel", "uWeights", "Material"} {
+		if _, ok := inactive[name]; ok {
+			t.Errorf("Active %s reported inactive", name)
+		}
+	}
+
+	if len(r.Attributes) != 1 || r.Attributes[0].Name != "aPosition" || r.Attributes[0].Location != 0 {
+		t.Errorf("Expected only aPosition at location 0, got %+v", r.Attributes)
+	}
+	if len(r.UniformBlocks) != 1 || r.UniformBlocks[0].Name != "Material" || len(r.UniformBlocks[0].Members) != 2 {
+		t.Errorf("Unexpected uniform blocks %+v", r.UniformBlocks)
+	}
+	if len(r.Notes) != 0 {
+		t.Errorf("Unexpected notes %v", r.Notes)
+	}
+	if r.WorkGroupSize != nil {
+		t.Error("Graphics programs have no work group size")
+	}
+	if r.BinarySize <= 0 {
+		t.Log("Driver keeps no program binary")
+	}
+}
+
+func TestInspectSubroutines(t *testing.T) {
+	r := inspect(t, "subroutine.frag")
+	if len(r.Subroutines) != 1 || r.Subroutines[0].Name != "uShade" || r.Subroutines[0].Stage != "fragment" || len(r.Subroutines[0].Compatible) != 2 {
+		t.Errorf("Unexpected subroutines %+v", r.Subroutines)
+	}
+
+	if len(r.Notes) != 0 {
+		t.Errorf("Expected the fragment shader to be checked, got %v", r.Notes)
+	}
+	if len(r.Inactive) != 1 || r.Inactive[0].Name != "uUnusedShade" || r.Inactive[0].Kind != "subroutine uniform" || r.Inactive[0].Type != "Shade" {
+		t.Errorf("Expected uUnusedShade to be inactive, got %+v", r.Inactive)
+	}
+}
+
+func TestWriteReport(t *testing.T) {
+	r := inspect(t, "info.frag")
+
+	var text bytes.Buffer
+	if err := shaderinfo.WriteText(&text, r); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"Uniforms (", "Inactive, optimized away by the driver (2):", "uDoesNothing", "Attributes (1):", "Uniform blocks (1):", "float[2]"} {
+		if !strings.Contains(text.String(), want) {
+			t.Errorf("Text report is missing %q:\n%s", want, text.String())
+		}
+	}
+
+	var out bytes.Buffer
+	if err := shaderinfo.WriteJSON(&out, r); err != nil {
+		t.Fatal(err)
+	}
+	var decoded shaderinfo.Report
+	if err := json.Unmarshal(out.Bytes(), &decoded); err != nil {
+		t.Fatalf("Invalid JSON report: %v\n%s", err, out.String())
+	}
+	if len(decoded.Uniforms) != len(r.Uniforms) || len(decoded.Inactive) != 2 || decoded.Stages[1].Stage != "fragment" {
+		t.Errorf("JSON report did not round-trip: %+v", decoded)
+	}
+}
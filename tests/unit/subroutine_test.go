@@ -0,0 +1,144 @@
+package shader_test
+
+import (
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+	"github.com/yossideutsch/gogl/pkg/shader"
+)
+
+const subroutineFragmentSource = `#version 410 core
+subroutine vec3 Shade(vec3 color);
+subroutine vec3 Tint(vec3 color);
+
+subroutine(Shade) vec3 identity(vec3 color) { return color; }
+subroutine(Shade) vec3 invert(vec3 color) { return 1.0 - color; }
+subroutine(Tint) vec3 tintNone(vec3 color) { return color; }
+subroutine(Tint) vec3 tintRed(vec3 color) { return color * vec3(1.0, 0.5, 0.5); }
+
+subroutine uniform Shade uShade;
+subroutine uniform Tint uTints[2];
+
+out vec4 fragColor;
+
+void main() {
+    vec3 color = uShade(vec3(0.25));
+    color = uTints[0](color);
+    color = uTints[1](color);
+    fragColor = vec4(color, 1.0);
+}`
+
+// selectedSubroutine returns the name of the fragment subroutine the current
+// program uses at location
+func selectedSubroutine(program *shader.Program, location int32) string {
+	var index uint32
+	gl.GetUniformSubroutineuiv(gl.FRAGMENT_SHADER, location, &index)
+	for _, f := range program.Subroutines(shader.FragmentShader) {
+		if f.Index == index {
+			return f.Name
+		}
+	}
+	return ""
+}
+
+func TestSubroutines(t *testing.T) {
+	vs, err := shader.CompileShader(testVertexShaderSource, shader.VertexShader)
+	if err != nil {
+		t.Fatal("Failed to compile vertex shader:", err)
+	}
+	fs, err := shader.CompileShader(subroutineFragmentSource, shader.FragmentShader)
+	if err != nil {
+		vs.Delete()
+		t.Fatal("Failed to compile subroutine fragment shader:", err)
+	}
+	program, err := shader.CreateProgram(vs, fs)
+	if err != nil {
+		t.Fatal("Failed to link subroutine program:", err)
+	}
+	defer program.Delete()
+
+	var functions []string
+	for _, f := range program.Subroutines(shader.FragmentShader) {
+		functions = append(functions, f.Name)
+	}
+	sort.Strings(functions)
+	if strings.Join(functions, ",") != "identity,invert,tintNone,tintRed" {
+		t.Errorf("Unexpected subroutines %v", functions)
+	}
+	if len(program.Subroutines(shader.VertexShader)) != 0 {
+		t.Error("Vertex stage should have no subroutines")
+	}
+
+	uniforms := program.SubroutineUniforms(shader.FragmentShader)
+	if len(uniforms) != 2 {
+		t.Fatalf("Expected 2 subroutine uniforms, got %+v", uniforms)
+	}
+	locations := make(map[string]shader.SubroutineUniformInfo)
+	for _, u := range uniforms {
+		compatible := append([]string(nil), u.Compatible...)
+		sort.Strings(compatible)
+		u.Compatible = compatible
+		locations[u.Name] = u
+	}
+	if u := locations["uShade"]; u.Size != 1 || strings.Join(u.Compatible, ",") != "identity,invert" {
+		t.Errorf("Unexpected uShade %+v", u)
+	}
+	if u := locations["uTints"]; u.Size != 2 || strings.Join(u.Compatible, ",") != "tintNone,tintRed" {
+		t.Errorf("Unexpected uTints %+v", u)
+	}
+
+	if err := program.SetSubroutines(shader.FragmentShader, map[string]string{"uShade": "invert", "uTints": "tintRed"}); err != nil {
+		t.Fatal("SetSubroutines failed:", err)
+	}
+	if err := program.SetSubroutines(shader.FragmentShader, map[string]string{"uTints[1]": "tintNone"}); err != nil {
+		t.Fatal("SetSubroutines failed for an array element:", err)
+	}
+
+	errorCases := []struct {
+		stage     shader.ShaderType
+		selection map[string]string
+		want      string
+	}{
+		{shader.VertexShader, map[string]string{"uShade": "invert"}, "no subroutine uniforms in the vertex stage"},
+		{shader.FragmentShader, map[string]string{"uMissing": "invert"}, "unknown subroutine uniform uMissing"},
+		{shader.FragmentShader, map[string]string{"uTints[2]": "tintRed"}, "unknown subroutine uniform uTints[2]"},
+		{shader.FragmentShader, map[string]string{"uShade": "missing"}, "unknown subroutine missing"},
+		{shader.FragmentShader, map[string]string{"uShade": "tintRed"}, "subroutine tintRed is not compatible with uShade"},
+	}
+	for _, tt := range errorCases {
+		if err := program.SetSubroutines(tt.stage, tt.selection); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("SetSubroutines(%v): expected error containing %q, got %v", tt.selection, tt.want, err)
+		}
+	}
+
+	// Making another program current resets subroutine uniforms; Use restores them
+	other, err := shader.CreateShaderProgram(testVertexShaderSource, shader.VertexShader)
+	if err != nil {
+		t.Fatal("Failed to create program:", err)
+	}
+	defer other.Delete()
+	other.Use()
+	program.Use()
+
+	want := map[int32]string{
+		locations["uShade"].Location:     "invert",
+		locations["uTints"].Location:     "tintRed",
+		locations["uTints"].Location + 1: "tintNone",
+	}
+	for location, name := range want {
+		if got := selectedSubroutine(program, location); got != name {
+			t.Errorf("Location %d selects %q, want %q", location, got, name)
+		}
+	}
+
+	// Selections made while the program is current apply immediately
+	if err := program.SetSubroutines(shader.FragmentShader, map[string]string{"uShade": "identity"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := selectedSubroutine(program, locations["uShade"].Location); got != "identity" {
+		t.Errorf("uShade selects %q after SetSubroutines on the current program, want identity", got)
+	}
+	gl.UseProgram(0)
+}
@@ -0,0 +1,186 @@
+package shader_test
+
+import (
+	"testing"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+	"github.com/yossideutsch/gogl/pkg/resource"
+	"github.com/yossideutsch/gogl/pkg/shader"
+)
+
+const captureVertexSource = `#version 410 core
+layout(location = 0) in float aValue;
+out float vDouble;
+out vec2 vPair;
+void main() {
+    vDouble = aValue * 2.0;
+    vPair = vec2(aValue, float(gl_VertexID));
+    gl_Position = vec4(0.0);
+}`
+
+// captureProgram links captureVertexSource on its own with varyings captured in mode
+func captureProgram(t *testing.T, mode shader.TransformFeedbackMode) *shader.Program {
+	t.Helper()
+	vs, err := shader.CompileShader(captureVertexSource, shader.VertexShader)
+	if err != nil {
+		t.Fatal("Failed to compile capture shader:", err)
+	}
+	defer vs.Delete()
+
+	program, err := shader.CreateProgramWithOptions(shader.ProgramOptions{
+		TransformFeedbackVaryings: []string{"vDouble", "vPair"},
+		TransformFeedbackMode:     mode,
+	}, vs)
+	if err != nil {
+		t.Fatal("Failed to link vertex-only capture program:", err)
+	}
+	return program
+}
+
+// capture draws values as points through program into the capture buffers of tf
+func capture(t *testing.T, program *shader.Program, tf *resource.TransformFeedback, values []float32) {
+	t.Helper()
+	input, err := resource.NewVertexBuffer(values, resource.StaticDraw)
+	if err != nil {
+		t.Fatal("Failed to create input buffer:", err)
+	}
+	defer input.Delete()
+	va, err := resource.NewVertexArray()
+	if err != nil {
+		t.Fatal("Failed to create vertex array:", err)
+	}
+	defer va.Delete()
+	va.SetVertexBuffer(input)
+	va.AddFloatAttribute(0, 1, 0, 0)
+
+	program.Use()
+	if err := tf.Begin(gl.POINTS); err != nil {
+		t.Fatal("Failed to begin transform feedback:", err)
+	}
+	if !tf.IsActive() {
+		t.Error("Expected transform feedback to be active after Begin")
+	}
+	if err := tf.Begin(gl.POINTS); err == nil {
+		t.Error("Expected nested Begin to fail")
+	}
+	va.Draw(gl.POINTS, int32(len(values)), 0)
+	tf.End()
+}
+
+func TestTransformFeedbackInterleaved(t *testing.T) {
+	program := captureProgram(t, 0)
+	defer program.Delete()
+
+	varyings := program.TransformFeedbackVaryings()
+	if len(varyings) != 2 || varyings[0] != (shader.VaryingInfo{Name: "vDouble", Type: shader.TypeFloat, Size: 1}) || varyings[1].Type != shader.TypeVec2 {
+		t.Errorf("Unexpected varyings %+v", varyings)
+	}
+	if program.TransformFeedbackMode() != shader.InterleavedAttribs {
+		t.Errorf("Expected interleaved mode, got %s", program.TransformFeedbackMode())
+	}
+
+	output, err := resource.NewVertexBufferSize(4*3*4, resource.StreamDraw)
+	if err != nil {
+		t.Fatal("Failed to create capture buffer:", err)
+	}
+	defer output.Delete()
+
+	tf, err := resource.NewTransformFeedback()
+	if err != nil {
+		t.Fatal("Failed to create transform feedback:", err)
+	}
+	defer tf.Delete()
+	if err := tf.Begin(gl.POINTS); err == nil {
+		t.Error("Expected Begin without a capture buffer to fail")
+	}
+	if err := tf.BindBuffer(0, output); err != nil {
+		t.Fatal(err)
+	}
+	tf.RasterizerDiscard = true
+
+	capture(t, program, tf, []float32{1, 2, 3, 4})
+	if gl.IsEnabled(gl.RASTERIZER_DISCARD) {
+		t.Error("Rasterizer discard left enabled after End")
+	}
+	if written := tf.PrimitivesWritten(); written != 4 {
+		t.Errorf("Expected 4 primitives written, got %d", written)
+	}
+
+	got, err := output.ReadFloat32(0, 12)
+	if err != nil {
+		t.Fatal("Failed to read captured varyings:", err)
+	}
+	want := []float32{2, 1, 0, 4, 2, 1, 6, 3, 2, 8, 4, 3}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("Captured %v, want %v", got, want)
+		}
+	}
+	if _, err := output.ReadFloat32(4, 12); err == nil {
+		t.Error("Expected read past the end of the buffer to fail")
+	}
+}
+
+func TestTransformFeedbackSeparate(t *testing.T) {
+	program := captureProgram(t, shader.SeparateAttribs)
+	defer program.Delete()
+
+	doubles, err := resource.NewVertexBufferSize(3*4, resource.StreamDraw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer doubles.Delete()
+	pairs, err := resource.NewVertexBufferSize(4*2*4, resource.StreamDraw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pairs.Delete()
+
+	tf, err := resource.NewTransformFeedback()
+	if err != nil {
+		t.Fatal("Failed to create transform feedback:", err)
+	}
+	defer tf.Delete()
+	if err := tf.BindBufferRange(0, doubles, 2, 8); err == nil {
+		t.Error("Expected unaligned range to fail")
+	}
+	if err := tf.BindBuffer(0, nil); err == nil {
+		t.Error("Expected nil capture buffer to fail")
+	}
+	if err := tf.BindBufferRange(0, nil, 0, 8); err == nil {
+		t.Error("Expected nil capture buffer range to fail")
+	}
+	if err := tf.BindBuffer(0, doubles); err != nil {
+		t.Fatal(err)
+	}
+	// Skip the first pair to check the range offset
+	if err := tf.BindBufferRange(1, pairs, 8, 24); err != nil {
+		t.Fatal(err)
+	}
+
+	// Capture stops at the smallest buffer: three points fit
+	capture(t, program, tf, []float32{5, 6, 7, 8})
+	if written := tf.PrimitivesWritten(); written != 3 {
+		t.Errorf("Expected 3 primitives written, got %d", written)
+	}
+
+	if got, err := doubles.ReadFloat32(0, 3); err != nil || got[0] != 10 || got[1] != 12 || got[2] != 14 {
+		t.Errorf("Captured doubles %v (%v)", got, err)
+	}
+	if got, err := pairs.ReadFloat32(8, 6); err != nil || got[0] != 5 || got[1] != 0 || got[4] != 7 || got[5] != 2 {
+		t.Errorf("Captured pairs %v (%v)", got, err)
+	}
+}
+
+func TestTransformFeedbackRequiresVaryingsForVertexOnly(t *testing.T) {
+	vs, err := shader.CompileShader(captureVertexSource, shader.VertexShader)
+	if err != nil {
+		t.Fatal("Failed to compile capture shader:", err)
+	}
+	defer vs.Delete()
+
+	// A fragment shader is only optional when varyings are captured
+	if _, err := shader.CreateProgramWithOptions(shader.ProgramOptions{TransformFeedbackMode: shader.SeparateAttribs}, vs); err == nil {
+		t.Error("Expected vertex-only program without varyings to fail")
+	}
+}
@@ -0,0 +1,248 @@
+package shader_test
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+	"github.com/yossideutsch/gogl/pkg/library"
+	"github.com/yossideutsch/gogl/pkg/shader"
+)
+
+// translate translates an in-memory source and fails the test on error
+func translate(t *testing.T, source string, stage shader.ShaderType, target shader.Dialect) *shader.Translation {
+	t.Helper()
+	result, err := shader.Translate(&shader.Source{Code: source}, stage, target)
+	if err != nil {
+		t.Fatalf("Translate to %s failed: %v", target, err)
+	}
+	return result
+}
+
+func TestTranslateDialects(t *testing.T) {
+	vert := `#version 410 core
+layout(location = 0) in vec3 aPosition;
+layout(location = 1) in vec2 aTexCoord;
+layout(location = 0) out vec2 vTexCoord;
+layout(std140, binding = 2) uniform Camera {
+    mat4 viewProjection;
+};
+void main() {
+    vTexCoord = aTexCoord;
+    gl_Position = viewProjection * vec4(aPosition, 1.0);
+}
+`
+	frag := `#version 410 core
+#extension GL_ARB_shading_language_420pack : enable
+layout(location = 0) in vec2 vTexCoord;
+layout(binding = 3) uniform sampler2D uAlbedo;
+layout(binding = 4) uniform usampler2D uIds[2];
+out vec4 fragColor;
+void main() {
+    fragColor = texture(uAlbedo, vTexCoord) + vec4(texture(uIds[1], vTexCoord));
+}
+`
+
+	core := translate(t, vert, shader.VertexShader, shader.GLSL330Core)
+	lines := strings.Split(core.Source.Code, "\n")
+	for i, want := range map[int]string{
+		0: "#version 330 core",
+		1: "layout(location = 0) in vec3 aPosition;",
+		3: "out vec2 vTexCoord;",
+		4: "layout(std140) uniform Camera {",
+	} {
+		if lines[i] != want {
+			t.Errorf("330 core line %d: got %q, want %q", i+1, lines[i], want)
+		}
+	}
+	if len(core.Bindings) != 1 || core.Bindings[0] != (shader.Binding{Name: "Camera", Block: true, Unit: 2}) {
+		t.Errorf("Unexpected bindings %+v", core.Bindings)
+	}
+
+	es := translate(t, frag, shader.FragmentShader, shader.GLSL300ES)
+	lines = strings.Split(es.Source.Code, "\n")
+	for i, want := range map[int]string{
+		0: "#version 300 es",
+		2: "precision highp float; precision highp sampler2D; precision highp usampler2D;",
+		3: "in vec2 vTexCoord;",
+		4: "uniform sampler2D uAlbedo;",
+		5: "uniform usampler2D uIds[2];",
+	} {
+		if lines[i] != want {
+			t.Errorf("300 es line %d: got %q, want %q", i+1, lines[i], want)
+		}
+	}
+	if loc, ok := es.Source.Map.Resolve(4); !ok || loc.Line != 3 {
+		t.Errorf("Line after the precision statements mapped to %v, expected line 3", loc)
+	}
+	if loc, ok := es.Source.Map.Resolve(3); !ok || loc.File != "<translated>" {
+		t.Errorf("Precision statements mapped to %v, expected <translated>", loc)
+	}
+	want := []shader.Binding{{Name: "uAlbedo", Unit: 3}, {Name: "uIds", Unit: 4, Count: 2}}
+	if len(es.Bindings) != 2 || es.Bindings[0] != want[0] || es.Bindings[1] != want[1] {
+		t.Errorf("Bindings = %+v, want %+v", es.Bindings, want)
+	}
+
+	// Sources already in the target dialect are returned as they are
+	src := &shader.Source{Code: frag}
+	if same, err := shader.Translate(src, shader.FragmentShader, shader.GLSL410Core); err != nil || same.Source != src {
+		t.Errorf("Expected 410 core source to be unchanged, got %v", err)
+	}
+}
+
+func TestTranslateLegacy(t *testing.T) {
+	vert := "attribute vec3 aPosition;\nvarying vec2 vUV;\nvoid main() { vUV = aPosition.xy; gl_Position = vec4(aPosition, 1.0); }\n"
+	frag := "#version 120\nvarying vec2 vUV;\nuniform sampler2D uTex;\nvoid main() {\n    gl_FragColor = texture2D(uTex, vUV) + textureCube2(vUV);\n}\n"
+
+	v := translate(t, vert, shader.VertexShader, shader.GLSL330Core)
+	if want := "#version 330 core\nin vec3 aPosition;\nout vec2 vUV;\n"; !strings.HasPrefix(v.Source.Code, want) {
+		t.Errorf("Unexpected vertex shader:\n%s", v.Source.Code)
+	}
+	if loc, ok := v.Source.Map.Resolve(2); !ok || loc.Line != 1 {
+		t.Errorf("First line mapped to %v, expected line 1", loc)
+	}
+
+	f := translate(t, frag, shader.FragmentShader, shader.GLSL300ES)
+	for _, want := range []string{
+		"#version 300 es\nprecision highp float; precision highp sampler2D; out vec4 gogl_FragColor;\nin vec2 vUV;",
+		"gogl_FragColor = texture(uTex, vUV) + textureCube2(vUV);",
+	} {
+		if !strings.Contains(f.Source.Code, want) {
+			t.Errorf("Translated fragment shader does not contain %q:\n%s", want, f.Source.Code)
+		}
+	}
+}
+
+func TestTranslateErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		stage  shader.ShaderType
+		target shader.Dialect
+		want   string
+	}{
+		{"compute", "#version 430 core\nlayout(local_size_x = 1) in;\nvoid main() {}\n", shader.ComputeShader, shader.GLSL330Core, "line 1: compute shader needs GLSL 430 core or 310 es"},
+		{"geometry on ES", "#version 410 core\nlayout(points) in;\nvoid main() {}\n", shader.GeometryShader, shader.GLSL300ES, "geometry shader needs GLSL 150 core or 320 es"},
+		{"storage block", "#version 410 core\nbuffer Data { float v[]; };\nvoid main() {}\n", shader.VertexShader, shader.GLSL330Core, "line 2: storage block Data needs GLSL 430 core"},
+		{"double", "#version 410 core\nout vec4 c;\nvoid main() { dvec2 d = dvec2(1.0); c = vec4(d, 0.0, 1.0); }\n", shader.FragmentShader, shader.GLSL300ES, "dvec2 needs GLSL 400 core and cannot be translated to 300 es"},
+		{"target", "#version 410 core\nvoid main() {}\n", shader.VertexShader, shader.Dialect{Version: 150}, "cannot translate shaders to GLSL 150 core"},
+		{"syntax", "#version 410 core\nuniform float u[N];\n", shader.VertexShader, shader.GLSL330Core, "failed to parse shader for translation"},
+	}
+	for _, tt := range tests {
+		_, err := shader.Translate(&shader.Source{Code: tt.source}, tt.stage, tt.target)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: expected error containing %q, got %v", tt.name, tt.want, err)
+		}
+	}
+
+	// Errors point at the original file
+	pp := shader.NewPreprocessor()
+	src, err := pp.Process("#version 410 core\n\nbuffer Data { float v[]; };\n", "data.vert")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := shader.Translate(src, shader.VertexShader, shader.GLSL330Core); err == nil || !strings.HasPrefix(err.Error(), "data.vert:3: ") {
+		t.Errorf("Expected error at data.vert:3, got %v", err)
+	}
+}
+
+func TestTranslateBundledShaders(t *testing.T) {
+	for _, info := range library.Shaders() {
+		src, err := shader.NewPreprocessor().ProcessFile(filepath.Join("../../shaders", filepath.FromSlash(info.Path)))
+		if err != nil {
+			t.Fatalf("Failed to load %s: %v", info.Path, err)
+		}
+		for _, target := range []shader.Dialect{shader.GLSL330Core, shader.GLSL300ES} {
+			_, err := shader.Translate(src, info.Stage, target)
+			unsupported := info.Stage == shader.ComputeShader || target.ES && info.Stage == shader.GeometryShader
+			if unsupported != (err != nil) {
+				t.Errorf("Translating %s to %s: unexpected result %v", info.Path, target, err)
+			}
+		}
+	}
+}
+
+func TestCompileTranslatedPrograms(t *testing.T) {
+	// A 4.1 core context also compiles 330 core shaders
+	shader.SetTargetDialect(shader.GLSL330Core)
+	defer shader.SetTargetDialect(shader.Dialect{})
+
+	for _, name := range []string{"phong", "textured", "wireframe", "standard"} {
+		program, err := library.Build(name)
+		if err != nil {
+			t.Errorf("Failed to build %s for 330 core: %v", name, err)
+			continue
+		}
+		program.Delete()
+	}
+
+	vs, err := shader.CompileShader(`#version 410 core
+layout(location = 0) in vec3 aPosition;
+layout(std140, binding = 2) uniform Camera {
+    mat4 viewProjection;
+};
+void main() {
+    gl_Position = viewProjection * vec4(aPosition, 1.0);
+}`, shader.VertexShader)
+	if err != nil {
+		t.Fatal("Failed to compile translated vertex shader:", err)
+	}
+	fs, err := shader.CompileShader(`#version 410 core
+layout(binding = 3) uniform sampler2D uAlbedo;
+out vec4 fragColor;
+void main() {
+    fragColor = texture(uAlbedo, vec2(0.5));
+}`, shader.FragmentShader)
+	if err != nil {
+		vs.Delete()
+		t.Fatal("Failed to compile translated fragment shader:", err)
+	}
+	program, err := shader.CreateProgram(vs, fs)
+	if err != nil {
+		t.Fatal("Failed to link translated program:", err)
+	}
+	defer program.Delete()
+
+	if block, ok := program.UniformBlock("Camera"); !ok || block.Binding != 2 {
+		t.Errorf("Expected Camera bound to 2, got %+v", block)
+	}
+	var unit int32
+	gl.GetUniformiv(program.ID, program.GetUniformLocation("uAlbedo"), &unit)
+	if unit != 3 {
+		t.Errorf("Expected uAlbedo on texture unit 3, got %d", unit)
+	}
+}
+
+func TestCreateShaderProgramTranslated(t *testing.T) {
+	shader.SetTargetDialect(shader.GLSL330Core)
+	defer shader.SetTargetDialect(shader.Dialect{})
+
+	program, err := shader.CreateShaderProgram(`#version 410 core
+layout(binding = 3) uniform sampler2D uAlbedo;
+out vec4 fragColor;
+void main() {
+    fragColor = texture(uAlbedo, vec2(0.5));
+}`, shader.FragmentShader)
+	if err != nil {
+		t.Fatal("Failed to create translated separable program:", err)
+	}
+	defer program.Delete()
+
+	var unit int32
+	gl.GetUniformiv(program.ID, program.GetUniformLocation("uAlbedo"), &unit)
+	if unit != 3 {
+		t.Errorf("Expected uAlbedo on texture unit 3, got %d", unit)
+	}
+
+	// Sources the parser rejects reach the driver untranslated, with a warning
+	_, err = shader.CompileShader("#version 410 core\nout vec4 fragColor\nvoid main() { fragColor = vec4(1.0); }\n", shader.FragmentShader)
+	var compileErr *shader.CompileError
+	if !errors.As(err, &compileErr) {
+		t.Fatalf("Expected *shader.CompileError, got %T: %v", err, err)
+	}
+	if d := compileErr.Diagnostics[0]; d.Severity != shader.SeverityWarning || !strings.Contains(d.Message, "not translated") || d.Line != 3 {
+		t.Errorf("Expected a translation warning on line 3, got %v", d)
+	}
+}
@@ -0,0 +1,127 @@
+package shader_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/yossideutsch/gogl/pkg/shader"
+)
+
+func TestSourceWithDefines(t *testing.T) {
+	pp := shader.NewPreprocessor()
+	src, err := pp.Process("// header\n#version 410 core\nout vec4 fragColor;\nvoid main() {}\n", "phong.frag")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	variant, err := src.WithDefines(shader.Defines{"USE_TEXTURE": "", "MAX_LIGHTS": "8"})
+	if err != nil {
+		t.Fatal("Failed to inject defines:", err)
+	}
+
+	lines := strings.Split(variant.Code, "\n")
+	want := []string{"// header", "#version 410 core", "#define MAX_LIGHTS 8", "#define USE_TEXTURE ", "out vec4 fragColor;"}
+	for i, line := range want {
+		if lines[i] != line {
+			t.Errorf("Line %d: got %q, want %q", i+1, lines[i], line)
+		}
+	}
+
+	if loc, ok := variant.Map.Resolve(5); !ok || loc.File != "phong.frag" || loc.Line != 3 {
+		t.Errorf("Line after defines mapped to %v, expected phong.frag:3", loc)
+	}
+	if loc, ok := variant.Map.Resolve(3); !ok || loc.File != "<defines>" {
+		t.Errorf("Injected define mapped to %v, expected <defines>", loc)
+	}
+
+	// Sources without a #version get the defines at the top
+	bare, err := (&shader.Source{Code: "void main() {}\n"}).WithDefines(shader.Defines{"LIT": "1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(bare.Code, "#define LIT 1\nvoid main()") {
+		t.Errorf("Unexpected code %q", bare.Code)
+	}
+	if loc, ok := bare.Map.Resolve(2); !ok || loc.Line != 1 {
+		t.Errorf("main() mapped to %v, expected line 1", loc)
+	}
+}
+
+func TestDefinesValidation(t *testing.T) {
+	src := &shader.Source{Code: "#version 410 core\n"}
+	for _, defines := range []shader.Defines{
+		{"1ABC": ""},
+		{"BAD NAME": ""},
+		{"GL_FOO": ""},
+		{"MULTI": "1\n2"},
+	} {
+		if _, err := src.WithDefines(defines); err == nil {
+			t.Errorf("Expected error for defines %v", defines)
+		}
+	}
+
+	if got := (shader.Defines{"B": "2", "A": ""}).String(); got != "A B=2" {
+		t.Errorf("Defines.String() = %q, want %q", got, "A B=2")
+	}
+}
+
+func TestVariantCache(t *testing.T) {
+	source := `#version 410 core
+out vec4 fragColor;
+void main() {
+#ifdef USE_TEXTURE
+	fragColor = vec4(1.0);
+#else
+	fragColor = vec4(float(MAX_LIGHTS) / 8.0);
+#endif
+}
+`
+	cache := shader.NewVariantCache()
+	defer cache.Clear()
+
+	plain, err := cache.Get(source, shader.FragmentShader, shader.Defines{"MAX_LIGHTS": "8"})
+	if err != nil {
+		t.Fatal("Failed to compile variant:", err)
+	}
+	textured, err := cache.Get(source, shader.FragmentShader, shader.Defines{"MAX_LIGHTS": "8", "USE_TEXTURE": ""})
+	if err != nil {
+		t.Fatal("Failed to compile variant:", err)
+	}
+	if plain == textured {
+		t.Error("Different define sets returned the same shader")
+	}
+
+	again, err := cache.Get(source, shader.FragmentShader, shader.Defines{"MAX_LIGHTS": "8"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again != plain {
+		t.Error("Expected cached variant for identical define set")
+	}
+	if cache.Len() != 2 {
+		t.Errorf("Expected 2 cached variants, got %d", cache.Len())
+	}
+
+	// Without MAX_LIGHTS the untextured branch does not compile
+	if _, err := cache.Get(source, shader.FragmentShader, nil); err == nil {
+		t.Error("Expected compile error for variant without MAX_LIGHTS")
+	}
+}
+
+func TestVariantCacheZeroValue(t *testing.T) {
+	var cache shader.VariantCache
+	defer cache.Clear()
+
+	source := "#version 410 core\nout vec4 fragColor;\nvoid main() { fragColor = vec4(1.0); }\n"
+	first, err := cache.Get(source, shader.FragmentShader, nil)
+	if err != nil {
+		t.Fatal("Failed to compile variant:", err)
+	}
+	again, err := cache.Get(source, shader.FragmentShader, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again != first || cache.Len() != 1 {
+		t.Errorf("Expected one cached variant, got %d", cache.Len())
+	}
+}